@@ -0,0 +1,121 @@
+package coreutils
+
+import (
+	"sync"
+)
+
+// IOCategory is the category of operation bytes are accounted under
+type IOCategory string
+
+const (
+	// IOCategoryCopy is file content read and written by copies
+	IOCategoryCopy IOCategory = "copy"
+
+	// IOCategoryWrite is file content written by WriteOrUpdateFile and WriteFromReader
+	IOCategoryWrite IOCategory = "write"
+
+	// IOCategoryArchive is file content added to archives
+	IOCategoryArchive IOCategory = "archive"
+
+	// IOCategoryExtract is file content written when extracting archives and images
+	IOCategoryExtract IOCategory = "extract"
+
+	// IOCategoryHash is file content read to compute hashes
+	IOCategoryHash IOCategory = "hash"
+)
+
+// IOAccountant receives the number of bytes read and written by the package's operations
+type IOAccountant interface {
+	AddRead(category IOCategory, bytes int64)
+	AddWritten(category IOCategory, bytes int64)
+}
+
+// IOCounts is the number of bytes read and written in a category
+type IOCounts struct {
+	Read    int64
+	Written int64
+}
+
+// IOCounter is an IOAccountant which keeps running totals per category. It is safe for concurrent use.
+type IOCounter struct {
+	lock   sync.Mutex
+	totals map[IOCategory]IOCounts
+}
+
+var (
+	accountantLock    sync.RWMutex
+	packageAccountant IOAccountant
+)
+
+// SetIOAccountant sets the IOAccountant the package reports bytes read and written to. Passing nil disables accounting.
+func SetIOAccountant(accountant IOAccountant) {
+	accountantLock.Lock()
+	packageAccountant = accountant
+	accountantLock.Unlock()
+}
+
+// NewIOCounter creates an IOCounter with empty totals
+func NewIOCounter() *IOCounter {
+	return &IOCounter{totals: make(map[IOCategory]IOCounts)}
+}
+
+// AddRead adds to the bytes read in the category
+func (counter *IOCounter) AddRead(category IOCategory, bytes int64) {
+	counter.lock.Lock()
+	counts := counter.totals[category]
+	counts.Read += bytes
+	counter.totals[category] = counts
+	counter.lock.Unlock()
+}
+
+// AddWritten adds to the bytes written in the category
+func (counter *IOCounter) AddWritten(category IOCategory, bytes int64) {
+	counter.lock.Lock()
+	counts := counter.totals[category]
+	counts.Written += bytes
+	counter.totals[category] = counts
+	counter.lock.Unlock()
+}
+
+// Totals returns a snapshot of the totals of each category
+func (counter *IOCounter) Totals() map[IOCategory]IOCounts {
+	counter.lock.Lock()
+	defer counter.lock.Unlock()
+
+	totals := make(map[IOCategory]IOCounts, len(counter.totals))
+
+	for category, counts := range counter.totals {
+		totals[category] = counts
+	}
+
+	return totals
+}
+
+// Reset clears the totals of every category
+func (counter *IOCounter) Reset() {
+	counter.lock.Lock()
+	counter.totals = make(map[IOCategory]IOCounts)
+	counter.lock.Unlock()
+}
+
+// accountRead reports bytes read to the package IOAccountant, if one is set
+func accountRead(category IOCategory, bytes int64) {
+	accountantLock.RLock()
+	accountant := packageAccountant
+	accountantLock.RUnlock()
+
+	if accountant != nil && bytes > 0 {
+		accountant.AddRead(category, bytes)
+	}
+}
+
+// accountWritten reports bytes written to the package IOAccountant, if one is set
+func accountWritten(category IOCategory, bytes int64) {
+	accountantLock.RLock()
+	accountant := packageAccountant
+	accountantLock.RUnlock()
+
+	if accountant != nil && bytes > 0 {
+		accountant.AddWritten(category, bytes)
+	}
+}
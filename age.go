@@ -0,0 +1,86 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileAge will return how long ago the file or directory at path was last modified
+func FileAge(path string) (time.Duration, error) {
+	var age time.Duration
+
+	stat, statErr := os.Stat(path) // Get the stats of the path
+
+	if statErr == nil { // If we got the stats of the path
+		age = GetClock().Now().Sub(stat.ModTime()) // Get the duration since the last modification
+	} else { // If we failed to stat the path
+		statErr = openError(path, statErr)
+	}
+
+	return age, statErr
+}
+
+// IsOlderThan checks if the file or directory at path was last modified longer than d ago
+func IsOlderThan(path string, d time.Duration) (bool, error) {
+	age, ageErr := FileAge(path)
+	return (ageErr == nil) && (age > d), ageErr
+}
+
+// NewestFile will return the most recently modified file in the directory provided
+func NewestFile(dir string) (string, error) {
+	var newestFile string       // Define newestFile as the path to the newest file we've found
+	var newestModTime time.Time // Define newestModTime as the modification time of newestFile
+	var newestFileError error   // Define newestFileError as an error
+
+	newestFileError = readDirectory(dir, func(entry os.DirEntry) error {
+		if entry.IsDir() { // Only files are considered, so don't bother stat-ing directories
+			return nil
+		}
+
+		if info, infoErr := entry.Info(); infoErr == nil && (newestFile == "" || info.ModTime().After(newestModTime)) { // If this file is newer than our current newest
+			newestFile = filepath.Join(dir, entry.Name())
+			newestModTime = info.ModTime()
+		}
+
+		return nil
+	})
+
+	if newestFileError == nil && newestFile == "" { // If we did not find any files
+		newestFileError = errors.New(dir + " does not contain any files.")
+	}
+
+	return newestFile, newestFileError
+}
+
+// IsOutOfDate checks if any of the sources were modified more recently than the target, in the same manner as make.
+// A target that does not exist yet is always considered out of date.
+func IsOutOfDate(target string, sources ...string) (bool, error) {
+	var outOfDate bool
+	var outOfDateError error
+
+	if targetStat, targetStatErr := os.Stat(target); targetStatErr == nil { // If the target exists
+		targetModTime := targetStat.ModTime()
+
+		for _, source := range sources { // For each source we depend on
+			sourceStat, sourceStatErr := os.Stat(source)
+
+			if sourceStatErr != nil { // If the source does not exist
+				outOfDateError = openError(source, sourceStatErr)
+				break
+			}
+
+			if sourceStat.ModTime().After(targetModTime) { // If the source is newer than the target
+				outOfDate = true
+				break
+			}
+		}
+	} else if os.IsNotExist(targetStatErr) { // If the target has not been created yet
+		outOfDate = true
+	} else { // If we failed to stat the target for some other reason
+		outOfDateError = readError(target, targetStatErr)
+	}
+
+	return outOfDate, outOfDateError
+}
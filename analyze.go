@@ -0,0 +1,189 @@
+package coreutils
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// binarySniffLength is the number of bytes at the start of a file checked for NUL bytes to detect binary content, as git does
+const binarySniffLength = 8000
+
+// LanguageStats counts the files and lines of a single language
+type LanguageStats struct {
+	Files   int64 // Number of files in the language
+	Lines   int64 // Total number of lines
+	Blank   int64 // Lines which are empty or only whitespace
+	Comment int64 // Lines which are only comments
+	Code    int64 // Every other line
+}
+
+// LangStats maps the name of each language found, such as "Go", to its statistics
+type LangStats map[string]LanguageStats
+
+// language describes how to recognize comments in the source of a language
+type language struct {
+	name         string
+	lineComments []string // Prefixes starting a comment running to the end of the line
+	blockStart   string   // Start of a block comment, if the language has them
+	blockEnd     string   // End of a block comment
+}
+
+var (
+	cStyleLanguage = func(name string) language { return language{name, []string{"//"}, "/*", "*/"} }
+	hashLanguage   = func(name string) language { return language{name, []string{"#"}, "", ""} }
+
+	languagesByExtension = map[string]language{
+		".go": cStyleLanguage("Go"), ".c": cStyleLanguage("C"), ".h": cStyleLanguage("C"),
+		".cpp": cStyleLanguage("C++"), ".cc": cStyleLanguage("C++"), ".cxx": cStyleLanguage("C++"), ".hpp": cStyleLanguage("C++"), ".hh": cStyleLanguage("C++"),
+		".cs": cStyleLanguage("C#"), ".java": cStyleLanguage("Java"), ".kt": cStyleLanguage("Kotlin"), ".swift": cStyleLanguage("Swift"),
+		".rs": cStyleLanguage("Rust"), ".js": cStyleLanguage("JavaScript"), ".mjs": cStyleLanguage("JavaScript"), ".cjs": cStyleLanguage("JavaScript"),
+		".jsx": cStyleLanguage("JavaScript"), ".ts": cStyleLanguage("TypeScript"), ".tsx": cStyleLanguage("TypeScript"), ".scss": cStyleLanguage("SCSS"),
+		".css": {"CSS", nil, "/*", "*/"}, ".php": {"PHP", []string{"//", "#"}, "/*", "*/"},
+		".py": hashLanguage("Python"), ".rb": hashLanguage("Ruby"), ".pl": hashLanguage("Perl"), ".sh": hashLanguage("Shell"),
+		".bash": hashLanguage("Shell"), ".zsh": hashLanguage("Shell"), ".yml": hashLanguage("YAML"), ".yaml": hashLanguage("YAML"),
+		".toml": hashLanguage("TOML"), ".r": hashLanguage("R"), ".ps1": {"PowerShell", []string{"#"}, "<#", "#>"},
+		".lua": {"Lua", []string{"--"}, "--[[", "]]"}, ".sql": {"SQL", []string{"--"}, "/*", "*/"},
+		".html": {"HTML", nil, "<!--", "-->"}, ".htm": {"HTML", nil, "<!--", "-->"}, ".xml": {"XML", nil, "<!--", "-->"},
+		".md": {"Markdown", nil, "", ""}, ".json": {"JSON", nil, "", ""},
+	}
+
+	languagesByName = map[string]language{ // Files recognized by their whole name rather than extension
+		"Makefile": hashLanguage("Makefile"), "makefile": hashLanguage("Makefile"), "GNUmakefile": hashLanguage("Makefile"),
+		"Dockerfile": hashLanguage("Dockerfile"), "CMakeLists.txt": hashLanguage("CMake"),
+	}
+
+	languagesByInterpreter = map[string]language{ // Extensionless scripts recognized by their shebang
+		"sh": hashLanguage("Shell"), "bash": hashLanguage("Shell"), "zsh": hashLanguage("Shell"), "dash": hashLanguage("Shell"), "ksh": hashLanguage("Shell"),
+		"python": hashLanguage("Python"), "python2": hashLanguage("Python"), "python3": hashLanguage("Python"),
+		"ruby": hashLanguage("Ruby"), "perl": hashLanguage("Perl"), "node": cStyleLanguage("JavaScript"),
+		"lua": {"Lua", []string{"--"}, "--[[", "]]"}, "Rscript": hashLanguage("R"), "pwsh": {"PowerShell", []string{"#"}, "<#", "#>"},
+	}
+)
+
+// AnalyzeTree will count the files and the code, comment, and blank lines of each language within path, as a lightweight
+// alternative to cloc. Languages are recognized by file extension or name, and extensionless scripts by their shebang.
+// Binary files, unrecognized files, and hidden directories such as .git are skipped.
+func AnalyzeTree(path string) (LangStats, error) {
+	stats := make(LangStats)
+
+	walkErr := walkTree(path, newOperationOptions(nil), func(entryPath, relativePath string, info os.FileInfo) error {
+		if info.IsDir() {
+			if relativePath != "." && strings.HasPrefix(info.Name(), ".") { // If this is a hidden directory, like .git
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if info.Mode().IsRegular() {
+			analyzeFile(entryPath, stats)
+		}
+
+		return nil
+	})
+
+	return stats, walkErr
+}
+
+// analyzeFile adds the line counts of the file to stats, if it is source in a language we recognize
+func analyzeFile(path string, stats LangStats) {
+	lang, known := languagesByName[filepath.Base(path)]
+
+	if !known {
+		lang, known = languagesByExtension[strings.ToLower(filepath.Ext(path))]
+	}
+
+	openFiles.acquire()
+	defer openFiles.release()
+
+	file, openErr := os.Open(path)
+
+	if openErr != nil {
+		return
+	}
+
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(binarySniffLength)
+
+	if isBinary(head) {
+		return
+	}
+
+	if !known && filepath.Ext(path) == "" { // If this may be a script
+		firstLine, _, _ := bytes.Cut(head, []byte("\n"))
+		interpreter, _ := parseShebang(string(firstLine))
+		lang, known = languagesByInterpreter[filepath.Base(interpreter)]
+	}
+
+	if !known {
+		return
+	}
+
+	counts := stats[lang.name]
+	counts.Files++
+	inBlock := false // Whether we're inside a block comment
+
+	for {
+		line, readErr := reader.ReadString('\n')
+
+		if line != "" {
+			counts.Lines++
+
+			switch trimmed := strings.TrimSpace(line); {
+			case trimmed == "":
+				counts.Blank++
+			case inBlock || lang.isComment(trimmed):
+				counts.Comment++
+				inBlock = lang.endsInBlock(trimmed, inBlock)
+			default:
+				counts.Code++
+			}
+		}
+
+		if readErr != nil { // If we reached the end of the file, or it became unreadable, count what we read
+			break
+		}
+	}
+
+	stats[lang.name] = counts
+}
+
+// isComment checks if the trimmed line starts with a comment
+func (lang language) isComment(trimmed string) bool {
+	for _, prefix := range lang.lineComments {
+		if strings.HasPrefix(trimmed, prefix) && !(lang.blockStart != "" && strings.HasPrefix(trimmed, lang.blockStart)) {
+			return true
+		}
+	}
+
+	return lang.blockStart != "" && strings.HasPrefix(trimmed, lang.blockStart)
+}
+
+// endsInBlock checks if a block comment is still open at the end of the trimmed comment line
+func (lang language) endsInBlock(trimmed string, inBlock bool) bool {
+	if lang.blockStart == "" {
+		return false
+	}
+
+	if !inBlock { // The line starts a block comment, unless it is a line comment
+		rest, isBlock := strings.CutPrefix(trimmed, lang.blockStart)
+
+		if !isBlock {
+			return false
+		}
+
+		trimmed = rest
+	}
+
+	return !strings.Contains(trimmed, lang.blockEnd)
+}
+
+// isBinary checks if the content, usually the start of a file, looks binary rather than text
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) != -1
+}
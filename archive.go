@@ -0,0 +1,737 @@
+package coreutils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveFormat is the container format of an archive
+type ArchiveFormat int
+
+const (
+	// ArchiveTar is an uncompressed tar archive
+	ArchiveTar ArchiveFormat = iota
+
+	// ArchiveTarGz is a gzip compressed tar archive
+	ArchiveTarGz
+
+	// ArchiveZip is a zip archive
+	ArchiveZip
+)
+
+// paxXattrPrefix is the prefix of the PAX records holding extended attributes in tar archives
+const paxXattrPrefix = "SCHILY.xattr."
+
+// ArchiveEntry describes a single entry in an archive
+type ArchiveEntry struct {
+	Name       string // Slash separated path of the entry within the archive
+	Mode       os.FileMode
+	Size       int64
+	ModTime    time.Time
+	LinkTarget string            // Target of the entry if it is a symlink
+	Xattrs     map[string]string // Extended attributes of the entry by name, which only tar archives record
+}
+
+// ArchiveWriter incrementally builds an archive, one file at a time
+type ArchiveWriter struct {
+	format    ArchiveFormat
+	options   *operationOptions
+	gzWriter  *gzip.Writer
+	tarWriter *tar.Writer
+	zipWriter *zip.Writer
+}
+
+// ArchiveReader incrementally reads an archive, one entry at a time
+type ArchiveReader struct {
+	closer     io.Closer
+	tarReader  *tar.Reader
+	zipFiles   []*zip.File
+	zipIndex   int
+	zipContent io.ReadCloser
+	options    *operationOptions // Options of ExtractArchive, nil for the defaults
+}
+
+// IsDir checks if the entry is a directory
+func (entry *ArchiveEntry) IsDir() bool {
+	return entry.Mode.IsDir()
+}
+
+// ArchiveFormatFromName determines the ArchiveFormat from the extension of the file name
+func ArchiveFormatFromName(name string) (ArchiveFormat, error) {
+	var format ArchiveFormat
+	var formatErr error
+
+	lowerName := strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(lowerName, ".tar.gz"), strings.HasSuffix(lowerName, ".tgz"):
+		format = ArchiveTarGz
+	case strings.HasSuffix(lowerName, ".tar"):
+		format = ArchiveTar
+	case strings.HasSuffix(lowerName, ".zip"):
+		format = ArchiveZip
+	default:
+		formatErr = errors.New(name + " is not a supported archive format.")
+	}
+
+	return format, formatErr
+}
+
+// CreateTar will create an uncompressed tar archive at archivePath holding the contents of the directory src, with entry names
+// relative to src. Honors WithExclude, WithProgress, and WithDeterministic.
+func CreateTar(src, archivePath string, opts ...Option) error {
+	return tracedCreateArchive("CreateTar", src, archivePath, ArchiveTar, opts)
+}
+
+// CreateTarGz will create a gzip compressed tar archive at archivePath holding the contents of the directory src, like CreateTar
+func CreateTarGz(src, archivePath string, opts ...Option) error {
+	return tracedCreateArchive("CreateTarGz", src, archivePath, ArchiveTarGz, opts)
+}
+
+// CreateZip will create a zip archive at archivePath holding the contents of the directory src, like CreateTar
+func CreateZip(src, archivePath string, opts ...Option) error {
+	return tracedCreateArchive("CreateZip", src, archivePath, ArchiveZip, opts)
+}
+
+// ExtractArchive will extract the archive at archivePath into the destination directory, determining its format from the file
+// extension. Entries which would be written or linked outside of the destination are rejected. Honors WithExactMode, so extracted
+// files keep exactly the permissions recorded in the archive rather than having the umask applied, WithModeMap, WithOwnerMap, and
+// WithSecurityAttributes.
+func ExtractArchive(archivePath, destination string, opts ...Option) error {
+	start := time.Now()
+	extractErr := preHooks("ExtractArchive", archivePath, destination, 0)
+
+	if extractErr == nil {
+		extractErr = extractArchive(archivePath, destination, newOperationOptions(opts))
+	}
+
+	trace(TraceEvent{Op: "ExtractArchive", Path: archivePath, Destination: destination, Duration: time.Since(start), Err: extractErr})
+	return extractErr
+}
+
+// extractArchive extracts the archive at archivePath into the destination directory
+func extractArchive(archivePath, destination string, options *operationOptions) error {
+	archive, openErr := OpenArchive(archivePath)
+
+	if openErr != nil { // If this isn't an archive we can read
+		return openErr
+	}
+
+	defer archive.Close()
+
+	archive.options = options
+
+	return archive.extractTo(destination)
+}
+
+// tracedCreateArchive creates the archive like createArchive, tracing it as the named operation
+func tracedCreateArchive(name, src, archivePath string, format ArchiveFormat, opts []Option) error {
+	start := time.Now()
+	createErr := createArchive(src, archivePath, format, opts)
+	var written int64
+
+	if info, statErr := os.Stat(archivePath); createErr == nil && statErr == nil {
+		written = info.Size()
+	}
+
+	trace(TraceEvent{Op: name, Path: src, Destination: archivePath, Bytes: written, Duration: time.Since(start), Err: createErr})
+	return createErr
+}
+
+// createArchive writes an archive of the provided format holding the contents of src to archivePath, removing it if anything fails
+func createArchive(src, archivePath string, format ArchiveFormat, opts []Option) error {
+	if !IsDir(src) { // If this isn't a directory
+		return notDirectoryError(src, nil)
+	}
+
+	if overlapErr := checkOverlap(src, archivePath); overlapErr != nil { // If the archive would end up containing itself
+		return overlapErr
+	}
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(archivePath), GetDefaults().DefaultDirMode); mkdirErr != nil { // If we failed to make the directories leading up to archivePath
+		return writeError(archivePath, "Failed to create the path leading up to "+archivePath, mkdirErr)
+	}
+
+	file, createErr := os.OpenFile(archivePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, GetDefaults().DefaultFileMode)
+
+	if createErr != nil { // If we failed to create the archive
+		return writeError(archivePath, "Failed to create "+archivePath, createErr)
+	}
+
+	archive := NewArchiveWriter(file, format, opts...)
+	archiveErr := archive.AddDirectory(src)
+
+	if closeErr := archive.Close(); archiveErr == nil {
+		archiveErr = closeErr
+	}
+
+	if closeErr := file.Close(); archiveErr == nil {
+		archiveErr = closeErr
+	}
+
+	if archiveErr != nil { // Don't leave a truncated archive behind
+		os.Remove(archivePath)
+	}
+
+	return archiveErr
+}
+
+// NewArchiveWriter creates an ArchiveWriter which writes an archive of the provided format to w.
+// Honors WithExclude (in AddDirectory), WithProgress (reporting the bytes added of each file), WithDeterministic, and
+// WithSecurityAttributes (recording them in tar archives).
+func NewArchiveWriter(w io.Writer, format ArchiveFormat, opts ...Option) *ArchiveWriter {
+	archive := &ArchiveWriter{format: format, options: newOperationOptions(opts)}
+
+	switch format {
+	case ArchiveZip:
+		archive.zipWriter = zip.NewWriter(w)
+	case ArchiveTarGz:
+		archive.gzWriter = gzip.NewWriter(w)
+		archive.tarWriter = tar.NewWriter(archive.gzWriter)
+	default:
+		archive.tarWriter = tar.NewWriter(w)
+	}
+
+	return archive
+}
+
+// AddFile adds the file, directory, or symlink at filePath to the archive under the same relative path
+func (archive *ArchiveWriter) AddFile(filePath string) error {
+	return archive.AddFileAs(filePath, filePath)
+}
+
+// AddFileAs adds the file, directory, or symlink at filePath to the archive under the name provided
+func (archive *ArchiveWriter) AddFileAs(filePath, name string) error {
+	stat, statErr := os.Lstat(filePath)
+
+	if statErr != nil { // If the file does not exist
+		return openError(filePath, statErr)
+	}
+
+	entry := ArchiveEntry{
+		Name:    archiveName(name),
+		Mode:    stat.Mode(),
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	}
+
+	if archive.options.securityAttributes && archive.zipWriter == nil && stat.Mode()&os.ModeSymlink == 0 { // If the attributes should be recorded, and the archive can hold them
+		attributes, readErr := readSecurityAttributes(filePath)
+
+		if readErr != nil {
+			return readErr
+		}
+
+		entry.Xattrs = archiveSecurityAttributes(attributes)
+	}
+
+	var content io.Reader
+
+	if stat.Mode()&os.ModeSymlink != 0 { // If this is a symlink
+		entry.Size = 0
+		entry.LinkTarget, statErr = os.Readlink(filePath)
+
+		if statErr != nil { // If we failed to read the link
+			return readError(filePath, statErr)
+		}
+	} else if stat.Mode().IsRegular() { // If this is a regular file
+		openFiles.acquire()
+		defer openFiles.release()
+
+		file, openErr := os.Open(filePath)
+
+		if openErr != nil { // If we failed to open the file
+			return openError(filePath, openErr)
+		}
+
+		defer file.Close()
+		content = file
+	} else { // Directories and other special files have no content
+		entry.Size = 0
+	}
+
+	return archive.AddEntry(entry, content)
+}
+
+// AddDirectory adds the directory at dirPath and all of its contents to the archive, with entry names relative to dirPath
+func (archive *ArchiveWriter) AddDirectory(dirPath string) error {
+	if !IsDir(dirPath) { // If this isn't a directory
+		return notDirectoryError(dirPath, nil)
+	}
+
+	return filepath.WalkDir(dirPath, func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil { // If we failed to read part of the tree
+			return walkErr
+		}
+
+		relativePath, _ := filepath.Rel(dirPath, filePath)
+
+		if relativePath == "." { // Don't add the root itself
+			return nil
+		}
+
+		if archive.options.excluded(relativePath) { // If this should be skipped
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		return archive.AddFileAs(filePath, relativePath)
+	})
+}
+
+// AddEntry adds an entry to the archive, reading the entry's content from content if it is a regular file
+func (archive *ArchiveWriter) AddEntry(entry ArchiveEntry, content io.Reader) error {
+	var addErr error
+	var entryWriter io.Writer
+
+	if entry.IsDir() && !strings.HasSuffix(entry.Name, "/") { // If this is a directory without a trailing slash
+		entry.Name += "/"
+	}
+
+	if archive.options.deterministic { // Timestamps would make every build of the same content differ
+		entry.ModTime = deterministicModTime()
+	}
+
+	if archive.zipWriter != nil { // If we are writing a zip
+		header := &zip.FileHeader{
+			Name:     entry.Name,
+			Modified: entry.ModTime,
+			Method:   zip.Deflate,
+		}
+
+		header.SetMode(entry.Mode)
+
+		if entry.IsDir() { // Directories are stored rather than deflated
+			header.Method = zip.Store
+		}
+
+		if entryWriter, addErr = archive.zipWriter.CreateHeader(header); addErr == nil && entry.LinkTarget != "" { // If this is a symlink, zip stores the target as the content
+			_, addErr = io.WriteString(entryWriter, entry.LinkTarget)
+		}
+	} else { // If we are writing a tar
+		header := &tar.Header{
+			Name:    entry.Name,
+			Mode:    int64(entry.Mode.Perm()),
+			Size:    entry.Size,
+			ModTime: entry.ModTime,
+			Format:  tar.FormatPAX,
+		}
+
+		switch {
+		case entry.IsDir():
+			header.Typeflag = tar.TypeDir
+		case entry.LinkTarget != "":
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = entry.LinkTarget
+		default:
+			header.Typeflag = tar.TypeReg
+		}
+
+		for name, value := range entry.Xattrs { // For each extended attribute, record it as GNU tar and bsdtar do
+			if header.PAXRecords == nil {
+				header.PAXRecords = make(map[string]string, len(entry.Xattrs))
+			}
+
+			header.PAXRecords[paxXattrPrefix+name] = value
+		}
+
+		addErr = archive.tarWriter.WriteHeader(header)
+		entryWriter = archive.tarWriter
+	}
+
+	if addErr == nil && content != nil && entry.Mode.IsRegular() { // If we have content to write
+		if archive.options.progress != nil { // If we should report progress as the content is written
+			entryWriter = &progressWriter{writer: entryWriter, path: entry.Name, total: entry.Size, report: archive.options.reportProgress}
+		}
+
+		var written int64
+		written, addErr = io.CopyBuffer(entryWriter, content, make([]byte, archive.options.BufferSize))
+		accountRead(IOCategoryArchive, written)
+	}
+
+	if addErr != nil {
+		addErr = errors.New("Failed to add " + entry.Name + " to the archive: " + addErr.Error())
+	}
+
+	return addErr
+}
+
+// Close finishes writing the archive. It does not close the underlying io.Writer.
+func (archive *ArchiveWriter) Close() error {
+	var closeErr error
+
+	if archive.zipWriter != nil { // If we are writing a zip
+		closeErr = archive.zipWriter.Close()
+	} else {
+		closeErr = archive.tarWriter.Close()
+
+		if archive.gzWriter != nil { // If we are compressing the tar
+			if gzCloseErr := archive.gzWriter.Close(); closeErr == nil {
+				closeErr = gzCloseErr
+			}
+		}
+	}
+
+	return closeErr
+}
+
+// OpenArchive opens the archive file at archivePath, determining its format from the file extension
+func OpenArchive(archivePath string) (*ArchiveReader, error) {
+	format, formatErr := ArchiveFormatFromName(archivePath)
+
+	if formatErr != nil { // If this is not a supported archive
+		return nil, formatErr
+	}
+
+	file, openErr := os.Open(archivePath)
+
+	if openErr != nil { // If we failed to open the archive
+		return nil, openError(archivePath, openErr)
+	}
+
+	var archive *ArchiveReader
+	var readerErr error
+
+	if format == ArchiveZip { // Zip requires random access to read its central directory
+		stat, _ := file.Stat()
+
+		if zipReader, zipErr := zip.NewReader(file, stat.Size()); zipErr == nil {
+			archive = &ArchiveReader{zipFiles: zipReader.File}
+		} else {
+			readerErr = errors.New(archivePath + " is not a valid zip archive.")
+		}
+	} else {
+		archive, readerErr = NewArchiveReader(file, format)
+	}
+
+	if readerErr != nil { // If we failed to create the reader
+		file.Close()
+		return nil, readerErr
+	}
+
+	archive.closer = file
+
+	return archive, nil
+}
+
+// NewArchiveReader creates an ArchiveReader which streams entries from r. Zip archives need random access, so use OpenArchive for those.
+func NewArchiveReader(r io.Reader, format ArchiveFormat) (*ArchiveReader, error) {
+	archive := &ArchiveReader{}
+
+	switch format {
+	case ArchiveTarGz:
+		gzReader, gzErr := gzip.NewReader(r)
+
+		if gzErr != nil { // If this is not gzip compressed
+			return nil, errors.New("Unable to decompress archive: " + gzErr.Error())
+		}
+
+		archive.tarReader = tar.NewReader(gzReader)
+	case ArchiveTar:
+		archive.tarReader = tar.NewReader(r)
+	default:
+		return nil, errors.New("Zip archives can not be streamed. Please use OpenArchive instead.")
+	}
+
+	return archive, nil
+}
+
+// Next advances to the next entry in the archive, returning io.EOF when there are no more entries.
+// The content of a regular file entry can be read from the ArchiveReader until the next call to Next.
+func (archive *ArchiveReader) Next() (*ArchiveEntry, error) {
+	if archive.tarReader != nil { // If we are reading a tar
+		for {
+			header, nextErr := archive.tarReader.Next()
+
+			if nextErr != nil {
+				return nil, nextErr
+			}
+
+			entry := &ArchiveEntry{
+				Name:    header.Name,
+				Mode:    header.FileInfo().Mode(),
+				Size:    header.Size,
+				ModTime: header.ModTime,
+			}
+
+			for record, value := range header.PAXRecords { // For each extended attribute
+				if name, isXattr := strings.CutPrefix(record, paxXattrPrefix); isXattr {
+					if entry.Xattrs == nil {
+						entry.Xattrs = make(map[string]string)
+					}
+
+					entry.Xattrs[name] = value
+				}
+			}
+
+			switch header.Typeflag {
+			case tar.TypeReg, tar.TypeDir:
+			case tar.TypeSymlink:
+				entry.LinkTarget = header.Linkname
+			default: // Skip entries like hard links and devices we do not support
+				continue
+			}
+
+			return entry, nil
+		}
+	}
+
+	if archive.zipContent != nil { // If we still have the previous entry open
+		archive.zipContent.Close()
+		archive.zipContent = nil
+	}
+
+	if archive.zipIndex >= len(archive.zipFiles) { // If we have gone through all the files
+		return nil, io.EOF
+	}
+
+	zipFile := archive.zipFiles[archive.zipIndex]
+	archive.zipIndex++
+
+	entry := &ArchiveEntry{
+		Name:    zipFile.Name,
+		Mode:    zipFile.Mode(),
+		Size:    int64(zipFile.UncompressedSize64),
+		ModTime: zipFile.Modified,
+	}
+
+	content, openErr := zipFile.Open()
+
+	if openErr != nil { // If we failed to open the entry
+		return nil, errors.New("Unable to read " + zipFile.Name + " from the archive.")
+	}
+
+	if entry.Mode&os.ModeSymlink != 0 { // If this is a symlink, the content is the target
+		target, readErr := io.ReadAll(content)
+		content.Close()
+
+		if readErr != nil {
+			return nil, errors.New("Unable to read " + zipFile.Name + " from the archive.")
+		}
+
+		entry.LinkTarget = string(target)
+		entry.Size = 0
+	} else {
+		archive.zipContent = content
+	}
+
+	return entry, nil
+}
+
+// Read reads the content of the current entry
+func (archive *ArchiveReader) Read(p []byte) (int, error) {
+	if archive.tarReader != nil { // If we are reading a tar
+		return archive.tarReader.Read(p)
+	}
+
+	if archive.zipContent == nil { // If the current entry has no content
+		return 0, io.EOF
+	}
+
+	return archive.zipContent.Read(p)
+}
+
+// Close closes the archive, as well as the underlying file if it was opened by OpenArchive
+func (archive *ArchiveReader) Close() error {
+	var closeErr error
+
+	if archive.zipContent != nil { // If we still have an entry open
+		archive.zipContent.Close()
+	}
+
+	if archive.closer != nil { // If we opened the underlying file
+		closeErr = archive.closer.Close()
+	}
+
+	return closeErr
+}
+
+// ExtractTo extracts all remaining entries of the archive into the destination directory.
+// Entries which would be written outside of the destination directory are rejected.
+func (archive *ArchiveReader) ExtractTo(destination string) error {
+	start := time.Now()
+	extractErr := archive.extractTo(destination)
+	trace(TraceEvent{Op: "ExtractTo", Path: destination, Duration: time.Since(start), Err: extractErr})
+	return extractErr
+}
+
+// extractTo extracts all remaining entries of the archive into the destination directory
+func (archive *ArchiveReader) extractTo(destination string) error {
+	var extractErr error
+	var directories []*ArchiveEntry // Directories are given their mapped mode and owner last, since a read-only mode would stop their contents being extracted
+	options := archive.options
+
+	if options == nil {
+		options = newOperationOptions(nil)
+	}
+
+	if extractErr = checkPathPolicy(destination, true); extractErr != nil {
+		return extractErr
+	}
+
+	if extractErr = os.MkdirAll(destination, GetDefaults().DefaultDirMode); extractErr != nil { // If we failed to create the destination
+		return writeError(destination, "Failed to create "+destination, extractErr)
+	}
+
+	for {
+		entry, nextErr := archive.Next()
+
+		if nextErr == io.EOF { // If we have extracted everything
+			break
+		} else if nextErr != nil {
+			extractErr = nextErr
+			break
+		}
+
+		entryPath, pathErr := archiveEntryPath(destination, entry.Name)
+
+		if pathErr != nil { // If this entry would escape the destination
+			extractErr = pathErr
+			break
+		}
+
+		if extractErr = extractEntry(destination, entryPath, entry, archive, options); extractErr != nil {
+			break
+		}
+
+		if entry.IsDir() && (options.modeMap != nil || options.ownerMap != nil) {
+			directories = append(directories, entry)
+		}
+	}
+
+	for _, directory := range directories { // For each directory, if everything was extracted
+		if extractErr != nil {
+			break
+		}
+
+		directoryPath, _ := archiveEntryPath(destination, directory.Name)
+		extractErr = options.applyMaps(directoryPath, directory.Name, true)
+	}
+
+	return extractErr
+}
+
+// archiveName converts a file path into a clean, relative, slash separated archive entry name
+func archiveName(name string) string {
+	name = path.Clean(filepath.ToSlash(name))
+	name = strings.TrimLeft(name, "/")
+
+	for strings.HasPrefix(name, "../") { // Strip any leading parent references
+		name = strings.TrimPrefix(name, "../")
+	}
+
+	if name == ".." {
+		name = "."
+	}
+
+	return name
+}
+
+// archiveEntryPath joins the entry name to the destination, ensuring the result does not escape the destination
+func archiveEntryPath(destination, name string) (string, error) {
+	entryPath := filepath.Join(destination, filepath.FromSlash(name))
+
+	if !isWithin(destination, entryPath) { // If the entry resolves outside of the destination
+		return "", errors.New(name + " would be extracted outside of " + destination)
+	}
+
+	return entryPath, nil
+}
+
+// extractEntry writes a single archive entry to entryPath within destination, reading any content from content. Files are given
+// exactly the permissions of the entry with WithExactMode, or their mode under WithModeMap, and their owner under WithOwnerMap.
+func extractEntry(destination, entryPath string, entry *ArchiveEntry, content io.Reader, options *operationOptions) error {
+	var extractErr error
+
+	if extractErr = checkPathPolicy(entryPath, true); extractErr != nil {
+		return extractErr
+	}
+
+	switch {
+	case entry.IsDir():
+		extractErr = os.MkdirAll(entryPath, entry.Mode.Perm()|0700)
+	case entry.LinkTarget != "":
+		if filepath.IsAbs(entry.LinkTarget) { // Absolute symlinks could point anywhere on the system
+			return errors.New(entry.Name + " is a symlink to an absolute path.")
+		}
+
+		os.MkdirAll(filepath.Dir(entryPath), GetDefaults().DefaultDirMode)
+
+		if !linkWithin(destination, filepath.Dir(entryPath), entry.LinkTarget) { // If the symlink would point outside of the destination
+			return errors.New(entry.Name + " is a symlink outside of the destination.")
+		}
+
+		os.Remove(entryPath)
+		extractErr = os.Symlink(entry.LinkTarget, entryPath)
+	default:
+		os.MkdirAll(filepath.Dir(entryPath), GetDefaults().DefaultDirMode)
+
+		var file *os.File
+
+		if file, extractErr = os.OpenFile(entryPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, entry.Mode.Perm()); extractErr == nil {
+			var written int64
+			written, extractErr = io.Copy(file, content)
+			accountWritten(IOCategoryExtract, written)
+
+			if closeErr := file.Close(); extractErr == nil {
+				extractErr = closeErr
+			}
+
+			if extractErr == nil && options.exactMode { // OpenFile only applies the mode to new files, and the umask to it, so set it directly
+				extractErr = os.Chmod(entryPath, entry.Mode.Perm())
+			}
+		}
+	}
+
+	if extractErr == nil && entry.LinkTarget == "" && options.securityAttributes { // If the SELinux context and capabilities should be restored
+		extractErr = writeSecurityAttributes(entryPath, entrySecurityAttributes(entry.Xattrs))
+	}
+
+	if extractErr == nil && !entry.IsDir() { // Directories are mapped once their contents are extracted
+		extractErr = options.applyMaps(entryPath, entry.Name, false)
+	}
+
+	if extractErr != nil {
+		extractErr = errors.New("Failed to extract " + entry.Name + ": " + extractErr.Error())
+	}
+
+	return extractErr
+}
+
+// linkWithin checks if a symlink in linkDirectory to the relative target stays within destination. Parent references are only
+// allowed at the start of the target, since "link/.." leaves the directory link points to rather than link itself, and the
+// link's directory is resolved so a parent reference can't climb out through a symlink extracted earlier.
+func linkWithin(destination, linkDirectory, target string) bool {
+	parentsDone := false
+
+	for _, component := range strings.Split(filepath.ToSlash(target), "/") { // For each component of the target
+		if component != ".." {
+			parentsDone = true
+		} else if parentsDone { // If this parent reference follows a name, which may be a symlink
+			return false
+		}
+	}
+
+	resolvedDestination, destinationErr := filepath.EvalSymlinks(destination)
+	resolvedDirectory, directoryErr := filepath.EvalSymlinks(linkDirectory)
+
+	return destinationErr == nil && directoryErr == nil && isWithin(resolvedDestination, filepath.Join(resolvedDirectory, target))
+}
+
+// isWithin checks if the target path is root or lexically contained within root
+func isWithin(root, target string) bool {
+	relativePath, relErr := filepath.Rel(root, target)
+	return (relErr == nil) && (relativePath != "..") && !strings.HasPrefix(relativePath, ".."+string(os.PathSeparator))
+}
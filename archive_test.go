@@ -0,0 +1,47 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractArchiveTraced(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "source")
+	archivePath := filepath.Join(root, "archive.tar.gz")
+
+	if mkdirErr := os.Mkdir(source, 0755); mkdirErr != nil {
+		t.Fatal(mkdirErr)
+	}
+
+	if writeErr := os.WriteFile(filepath.Join(source, "file.txt"), []byte("extracted"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if createErr := CreateTarGz(source, archivePath); createErr != nil {
+		t.Fatal(createErr)
+	}
+
+	var extractErr error
+	ops := traceOps(archivePath, func() { extractErr = ExtractArchive(archivePath, filepath.Join(root, "out")) })
+
+	if extractErr != nil {
+		t.Fatal(extractErr)
+	}
+
+	if len(ops) != 1 || ops[0] != "ExtractArchive" {
+		t.Errorf("expected a single ExtractArchive trace, got %v", ops)
+	}
+
+	if content, readErr := os.ReadFile(filepath.Join(root, "out", "file.txt")); readErr != nil || string(content) != "extracted" {
+		t.Errorf("expected the archive to be extracted, got %q, %v", content, readErr)
+	}
+
+	missingArchive := filepath.Join(root, "missing.zip")
+	ops = traceOps(missingArchive, func() { extractErr = ExtractArchive(missingArchive, filepath.Join(root, "missing")) })
+
+	if extractErr == nil || len(ops) != 1 {
+		t.Errorf("expected a traced failure to extract a missing archive, got %v, %v", extractErr, ops)
+	}
+}
@@ -0,0 +1,319 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrHelp is returned by ParseArgs when -h or --help is given, so the caller can print ArgSpec.Usage and exit
+var ErrHelp = errors.New("help requested")
+
+// FlagKind is the type of value a flag takes
+type FlagKind int
+
+const (
+	// FlagBool is a flag which is either present or not, such as --verbose. "--verbose=false" is also accepted.
+	FlagBool FlagKind = iota
+
+	// FlagString is a flag taking any value, such as --output file.txt
+	FlagString
+
+	// FlagInt is a flag taking a whole number, such as --workers 4
+	FlagInt
+
+	// FlagDuration is a flag taking a duration accepted by time.ParseDuration, such as --timeout 30s
+	FlagDuration
+)
+
+// FlagSpec describes a flag
+type FlagSpec struct {
+	Name     string   // Long name, used as --name and to look up the value
+	Short    string   // Optional single letter name, used as -s
+	Kind     FlagKind // Type of value the flag takes
+	Default  string   // Value of the flag when it isn't given
+	Usage    string   // Description shown in the usage text
+	Required bool     // Whether ParseArgs fails if the flag isn't given
+}
+
+// CommandSpec describes a subcommand, such as "build" in "tool build --release"
+type CommandSpec struct {
+	Name        string     // Name of the subcommand
+	Description string     // Description shown in the usage text
+	Flags       []FlagSpec // Flags only accepted by this subcommand, in addition to the global flags
+}
+
+// ArgSpec describes the arguments of a program for ParseArgs
+type ArgSpec struct {
+	Program     string        // Name of the program shown in the usage text, the base name of os.Args[0] if empty
+	Description string        // Description shown at the top of the usage text
+	Flags       []FlagSpec    // Flags accepted by the program and every subcommand
+	Commands    []CommandSpec // Subcommands, one of which must be given if there are any
+	Positional  string        // Description of the positional arguments in the usage text, such as "FILE..."
+}
+
+// Args is the result of ParseArgs
+type Args struct {
+	Command    string   // Subcommand given, if the ArgSpec has any
+	Positional []string // Arguments which aren't flags or the subcommand, in order
+
+	flags  map[string]FlagSpec // Every flag accepted, by long name
+	values map[string]string   // Value of each flag given
+}
+
+// ParseArgs will parse the command line arguments of the program according to the spec. Flags may be given as --name value,
+// --name=value, or -s value, anywhere among the positional arguments, and "--" ends the flags. Returns ErrHelp if help was
+// requested, and otherwise describes the mistake, so the caller can print it alongside spec.Usage().
+func ParseArgs(spec ArgSpec) (*Args, error) {
+	return parseArgs(spec, os.Args[1:])
+}
+
+// parseArgs parses the arguments according to the spec
+func parseArgs(spec ArgSpec, arguments []string) (*Args, error) {
+	args := &Args{flags: make(map[string]FlagSpec), values: make(map[string]string)}
+	shortNames := make(map[string]string) // Long name of each short name
+
+	addFlags := func(flags []FlagSpec) {
+		for _, flag := range flags {
+			args.flags[flag.Name] = flag
+
+			if flag.Short != "" {
+				shortNames[flag.Short] = flag.Name
+			}
+		}
+	}
+
+	addFlags(spec.Flags)
+	flagsEnded := false
+
+	for i := 0; i < len(arguments); i++ {
+		argument := arguments[i]
+
+		if flagsEnded || argument == "-" || !strings.HasPrefix(argument, "-") { // If this is a positional argument or the subcommand
+			if len(spec.Commands) != 0 && args.Command == "" {
+				command, found := spec.command(argument)
+
+				if !found {
+					return nil, errors.New("Unknown command " + argument)
+				}
+
+				args.Command = command.Name
+				addFlags(command.Flags)
+			} else {
+				args.Positional = append(args.Positional, argument)
+			}
+
+			continue
+		}
+
+		if argument == "--" {
+			flagsEnded = true
+			continue
+		}
+
+		if argument == "-h" || argument == "--help" {
+			return nil, ErrHelp
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(argument, "-"), "=")
+
+		if !strings.HasPrefix(argument, "--") { // If this is a short name
+			name = shortNames[name]
+		}
+
+		flag, known := args.flags[name]
+
+		if !known {
+			return nil, errors.New("Unknown flag " + argument)
+		}
+
+		if !hasValue && flag.Kind != FlagBool { // If the value is the next argument
+			if i+1 == len(arguments) {
+				return nil, errors.New("Flag " + argument + " needs a value")
+			}
+
+			i++
+			value = arguments[i]
+		} else if !hasValue {
+			value = "true"
+		}
+
+		if parseErr := flag.validate(value); parseErr != nil {
+			return nil, parseErr
+		}
+
+		args.values[flag.Name] = value
+	}
+
+	if len(spec.Commands) != 0 && args.Command == "" {
+		return nil, errors.New("No command given")
+	}
+
+	for _, flag := range args.flags {
+		if _, given := args.values[flag.Name]; flag.Required && !given {
+			return nil, errors.New("Flag --" + flag.Name + " is required")
+		}
+	}
+
+	return args, nil
+}
+
+// command looks up the subcommand by name
+func (spec ArgSpec) command(name string) (CommandSpec, bool) {
+	for _, command := range spec.Commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+
+	return CommandSpec{}, false
+}
+
+// validate checks the value is valid for the kind of flag
+func (flag FlagSpec) validate(value string) error {
+	var parseErr error
+
+	switch flag.Kind {
+	case FlagBool:
+		_, parseErr = strconv.ParseBool(value)
+	case FlagInt:
+		_, parseErr = strconv.Atoi(value)
+	case FlagDuration:
+		_, parseErr = time.ParseDuration(value)
+	}
+
+	if parseErr != nil {
+		return errors.New("Invalid value " + strconv.Quote(value) + " for flag --" + flag.Name)
+	}
+
+	return nil
+}
+
+// value returns the value given for the flag, or its default
+func (args *Args) value(name string) string {
+	if value, given := args.values[name]; given {
+		return value
+	}
+
+	return args.flags[name].Default
+}
+
+// IsSet will check if the flag was given, rather than having its default value
+func (args *Args) IsSet(name string) bool {
+	_, given := args.values[name]
+	return given
+}
+
+// String will return the value of the flag
+func (args *Args) String(name string) string {
+	return args.value(name)
+}
+
+// Bool will return the value of a FlagBool flag, false if it wasn't given and has no default
+func (args *Args) Bool(name string) bool {
+	value, _ := strconv.ParseBool(args.value(name))
+	return value
+}
+
+// Int will return the value of a FlagInt flag, 0 if it wasn't given and has no default
+func (args *Args) Int(name string) int {
+	value, _ := strconv.Atoi(args.value(name))
+	return value
+}
+
+// Duration will return the value of a FlagDuration flag, 0 if it wasn't given and has no default
+func (args *Args) Duration(name string) time.Duration {
+	value, _ := time.ParseDuration(args.value(name))
+	return value
+}
+
+// Usage will return the usage text describing the program's subcommands and flags
+func (spec ArgSpec) Usage() string {
+	var usage strings.Builder
+
+	program := spec.Program
+
+	if program == "" {
+		program = filepath.Base(os.Args[0])
+	}
+
+	usage.WriteString("Usage: " + program)
+
+	if len(spec.Commands) != 0 {
+		usage.WriteString(" COMMAND")
+	}
+
+	if len(spec.Flags) != 0 || len(spec.Commands) != 0 {
+		usage.WriteString(" [FLAGS]")
+	}
+
+	if spec.Positional != "" {
+		usage.WriteString(" " + spec.Positional)
+	}
+
+	usage.WriteString("\n")
+
+	if spec.Description != "" {
+		usage.WriteString("\n" + spec.Description + "\n")
+	}
+
+	if len(spec.Commands) != 0 {
+		usage.WriteString("\nCommands:\n")
+
+		for _, command := range spec.Commands {
+			fmt.Fprintf(&usage, "  %-20s %s\n", command.Name, command.Description)
+		}
+	}
+
+	if len(spec.Flags) != 0 {
+		usage.WriteString("\nFlags:\n")
+		writeFlagUsage(&usage, spec.Flags)
+	}
+
+	for _, command := range spec.Commands {
+		if len(command.Flags) != 0 {
+			usage.WriteString("\nFlags of " + command.Name + ":\n")
+			writeFlagUsage(&usage, command.Flags)
+		}
+	}
+
+	return usage.String()
+}
+
+// writeFlagUsage writes a line describing each flag, sorted by name
+func writeFlagUsage(usage *strings.Builder, flags []FlagSpec) {
+	sorted := append([]FlagSpec{}, flags...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for _, flag := range sorted {
+		names := "    --" + flag.Name
+
+		if flag.Short != "" {
+			names = "-" + flag.Short + ", --" + flag.Name
+		}
+
+		switch flag.Kind {
+		case FlagString:
+			names += " string"
+		case FlagInt:
+			names += " int"
+		case FlagDuration:
+			names += " duration"
+		}
+
+		description := flag.Usage
+
+		if flag.Required {
+			description += " (required)"
+		} else if flag.Default != "" {
+			description += " (default " + flag.Default + ")"
+		}
+
+		fmt.Fprintf(usage, "  %-20s %s\n", names, description)
+	}
+}
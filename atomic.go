@@ -0,0 +1,77 @@
+package coreutils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WithAtomicWrite sets whether WriteOrUpdateFile and WriteFromReader write to a temporary file beside the destination, sync it,
+// and rename it into place, so readers and crashes never leave a half-written file behind. The previous content stays in place
+// until the new content is complete.
+func WithAtomicWrite(atomic bool) Option {
+	return func(options *operationOptions) {
+		options.atomicWrite = atomic
+	}
+}
+
+// WriteFileAtomic will write the content to the file at path like WriteOrUpdateFile with WithAtomicWrite, so the file either
+// has its previous content or all of the new content, even if the process crashes part way through
+func WriteFileAtomic(path string, content []byte, mode os.FileMode, opts ...Option) error {
+	options := newOperationOptions(opts)
+	options.atomicWrite = true
+	return tracedWriteOrUpdateFile("WriteFileAtomic", path, content, mode, options)
+}
+
+// atomicWrite writes the content of r to a temporary file beside path, syncs it, and renames it over path. The temporary file
+// is removed if anything fails. Returns the number of bytes written.
+func atomicWrite(path string, r io.Reader, mode os.FileMode, options *operationOptions) (int64, error) {
+	directory := filepath.Dir(path)
+
+	if mkdirErr := os.MkdirAll(directory, options.DefaultDirMode); mkdirErr != nil { // If we failed to make the directories leading up to path
+		return 0, writeError(path, "Failed to create the path leading up to "+path, mkdirErr)
+	}
+
+	openFiles.acquire()
+	defer openFiles.release()
+
+	partialFile, createErr := os.CreateTemp(directory, "."+filepath.Base(path)+".partial-*")
+
+	if createErr != nil { // If we failed to create the temporary file
+		return 0, writeError(path, "Failed to create "+path, createErr)
+	}
+
+	written, writeErr := io.CopyBuffer(partialFile, r, make([]byte, options.BufferSize))
+
+	if writeErr == nil {
+		writeErr = partialFile.Sync() // The content must be on disk before the rename is, or a crash could leave an empty file
+	}
+
+	if closeErr := partialFile.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	if !options.exactMode { // CreateTemp always uses 0600, so apply the mode as creating the file would have
+		mode = EffectiveMode(mode)
+	}
+
+	if writeErr == nil {
+		writeErr = os.Chmod(partialFile.Name(), mode)
+	}
+
+	if writeErr == nil {
+		writeErr = os.Rename(partialFile.Name(), path)
+	}
+
+	if writeErr != nil { // If any part of writing failed, clean up the temporary file
+		os.Remove(partialFile.Name())
+		return 0, writeError(path, "Failed to write "+path, writeErr)
+	}
+
+	if directoryFile, openErr := os.Open(directory); openErr == nil { // Sync the directory so the rename itself survives a crash. Not every platform supports this, so it is best effort.
+		directoryFile.Sync()
+		directoryFile.Close()
+	}
+
+	return written, nil
+}
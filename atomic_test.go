@@ -0,0 +1,48 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// traceOps returns the Op of every TraceEvent of path traced while fn runs
+func traceOps(path string, fn func()) []string {
+	var lock sync.Mutex
+	var ops []string
+
+	removeTrace := AddTraceHook(func(event TraceEvent) {
+		if event.Path == path {
+			lock.Lock()
+			ops = append(ops, event.Op)
+			lock.Unlock()
+		}
+	})
+
+	fn()
+	removeTrace()
+
+	return ops
+}
+
+func TestWriteFileAtomicTraced(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "atomic.txt")
+	var writeErr error
+
+	ops := traceOps(file, func() {
+		writeErr = WriteFileAtomic(file, []byte("atomic"), 0644)
+	})
+
+	if writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if len(ops) != 1 || ops[0] != "WriteFileAtomic" {
+		t.Errorf("expected a single WriteFileAtomic trace, got %v", ops)
+	}
+
+	if content, readErr := os.ReadFile(file); readErr != nil || string(content) != "atomic" {
+		t.Errorf("expected the content to be written, got %q, %v", content, readErr)
+	}
+}
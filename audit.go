@@ -0,0 +1,118 @@
+package coreutils
+
+import (
+	"encoding/json"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditedOperations are the traced operations which modify the filesystem, and so are recorded in the audit log
+var auditedOperations = map[string]bool{
+	"CollectDiagnostics":         true,
+	"CopyDirectory":              true,
+	"CopyFile":                   true,
+	"CopyFileMulti":              true,
+	"CopyFromReader":             true,
+	"CreateSelfExtractingBundle": true,
+	"CreateTar":                  true,
+	"CreateTarGz":                true,
+	"CreateZip":                  true,
+	"ExtractArchive":             true,
+	"ExtractEmbeddedFS":          true,
+	"ExtractImage":               true,
+	"ExtractTo":                  true,
+	"GenerateSSHKeyPair":         true,
+	"MoveToTrash":                true,
+	"NormalizeFilenames":         true,
+	"PipeCommandToFile":          true,
+	"ReceiveDirectory":           true,
+	"RemoveDirectoryContents":    true,
+	"RemoveIfEmpty":              true,
+	"RemoveTree":                 true,
+	"RepairPermissions":          true,
+	"Resume":                     true,
+	"ServeDirectory":             true,
+	"WriteCrashReport":           true,
+	"WriteFileAtomic":            true,
+	"WriteFromReader":            true,
+	"WriteOrUpdateFile":          true,
+}
+
+// auditLock serializes writes to the audit log, so records from concurrent operations never interleave
+var auditLock sync.Mutex
+
+// auditRecord is a single line of the audit log
+type auditRecord struct {
+	Time        time.Time `json:"time"`
+	Op          string    `json:"op"`
+	Path        string    `json:"path"`
+	Destination string    `json:"destination,omitempty"`
+	Mode        string    `json:"mode,omitempty"`
+	Bytes       int64     `json:"bytes"`
+	Result      string    `json:"result"`
+	Error       string    `json:"error,omitempty"`
+	Caller      string    `json:"caller,omitempty"`
+}
+
+// audit appends the event to the audit log, if one is configured in the Defaults and the operation modified the filesystem
+func audit(event TraceEvent) {
+	auditLog := GetDefaults().AuditLog
+
+	if auditLog == "" || !auditedOperations[event.Op] { // If auditing is disabled or this operation doesn't need auditing
+		return
+	}
+
+	record := auditRecord{
+		Time:        time.Now().UTC(),
+		Op:          event.Op,
+		Path:        event.Path,
+		Destination: event.Destination,
+		Bytes:       event.Bytes,
+		Result:      "success",
+		Caller:      auditCaller(),
+	}
+
+	if event.Mode != 0 {
+		record.Mode = event.Mode.String()
+	}
+
+	if event.Err != nil {
+		record.Result = "failure"
+		record.Error = event.Err.Error()
+	}
+
+	line, _ := json.Marshal(record)
+
+	auditLock.Lock()
+	defer auditLock.Unlock()
+
+	openFiles.acquire()
+	defer openFiles.release()
+
+	if logFile, openErr := os.OpenFile(auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600); openErr == nil { // Append only, so existing records are never rewritten
+		logFile.Write(append(line, '\n'))
+		logFile.Close()
+	}
+}
+
+// auditCaller returns the function and location outside of this package which started the operation
+func auditCaller() string {
+	programCounters := make([]uintptr, 32)
+	frames := runtime.CallersFrames(programCounters[:runtime.Callers(3, programCounters)])
+
+	for {
+		frame, more := frames.Next()
+
+		if !strings.HasPrefix(frame.Function, "github.com/StroblIndustries/coreutils.") { // If this frame is outside of the package
+			return frame.Function + " (" + frame.File + ":" + strconv.Itoa(frame.Line) + ")"
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
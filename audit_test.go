@@ -0,0 +1,81 @@
+package coreutils
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAuditRecordsWriters(t *testing.T) {
+	root := t.TempDir()
+	auditLog := filepath.Join(root, "audit.jsonl")
+	src := filepath.Join(root, "src")
+	os.MkdirAll(src, 0755)
+	os.WriteFile(filepath.Join(src, "file.txt"), []byte("content"), 0644)
+
+	defaults := GetDefaults()
+	defer SetDefaults(defaults)
+
+	audited := defaults
+	audited.AuditLog = auditLog
+	SetDefaults(audited)
+
+	writers := []struct {
+		op  string
+		run func() error
+	}{
+		{"CreateTar", func() error { return CreateTar(src, filepath.Join(root, "out.tar")) }},
+		{"CreateTarGz", func() error { return CreateTarGz(src, filepath.Join(root, "out.tar.gz")) }},
+		{"CreateZip", func() error { return CreateZip(src, filepath.Join(root, "out.zip")) }},
+		{"ExtractTo", func() error {
+			archive, openErr := OpenArchive(filepath.Join(root, "out.tar"))
+
+			if openErr != nil {
+				return openErr
+			}
+
+			defer archive.Close()
+			return archive.ExtractTo(filepath.Join(root, "extracted"))
+		}},
+		{"ExtractEmbeddedFS", func() error {
+			return ExtractEmbeddedFS(fstest.MapFS{"asset.txt": {Data: []byte("asset")}}, "", filepath.Join(root, "assets"), ExtractFSOptions{})
+		}},
+		{"CreateSelfExtractingBundle", func() error {
+			return CreateSelfExtractingBundle(src, filepath.Join(src, "file.txt"), filepath.Join(root, "bundle"))
+		}},
+		{"GenerateSSHKeyPair", func() error { return GenerateSSHKeyPair(KeyEd25519, filepath.Join(root, "ssh", "id_ed25519")) }},
+		{"WriteCrashReport", func() error {
+			_, reportErr := WriteCrashReport(filepath.Join(root, "crashes"), nil)
+			return reportErr
+		}},
+		{"CollectDiagnostics", func() error { return CollectDiagnostics([]string{src}, filepath.Join(root, "diagnostics.tar.gz")) }},
+		{"ServeDirectory", func() error {
+			if response := serveRequest(ServeDirectory(src), http.MethodPut, "/uploaded.txt", "uploaded", nil); response.Code != http.StatusCreated {
+				t.Errorf("expected the upload to succeed, got %d", response.Code)
+			}
+
+			return nil
+		}},
+	}
+
+	for _, writer := range writers {
+		if opErr := writer.run(); opErr != nil {
+			t.Fatalf("%s failed: %v", writer.op, opErr)
+		}
+	}
+
+	records, readErr := os.ReadFile(auditLog)
+
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+
+	for _, writer := range writers {
+		if !strings.Contains(string(records), `"op":"`+writer.op+`"`) {
+			t.Errorf("expected %s to be audited", writer.op)
+		}
+	}
+}
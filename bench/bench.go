@@ -0,0 +1,189 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/StroblIndustries/coreutils"
+)
+
+// Benchmark is a single benchmark of the suite
+type Benchmark struct {
+	Name string
+	F    func(b *testing.B)
+}
+
+// Result is the outcome of running a Benchmark
+type Result struct {
+	Name string
+	testing.BenchmarkResult
+}
+
+// Regression is a Benchmark which got slower than the tolerance allows
+type Regression struct {
+	Name     string
+	Baseline int64   // Nanoseconds per operation before
+	Current  int64   // Nanoseconds per operation now
+	Change   float64 // Fractional change, such as 0.25 for 25% slower
+}
+
+// String describes the regression, such as "CopyDirectory/100x4096B/d2: 1200000ns/op -> 1500000ns/op (+25.0%)"
+func (regression Regression) String() string {
+	return fmt.Sprintf("%s: %dns/op -> %dns/op (%+.1f%%)", regression.Name, regression.Baseline, regression.Current, regression.Change*100)
+}
+
+// copyWorkers is the number of workers of the parallel CopyDirectory benchmark
+const copyWorkers = 8
+
+// Suite returns the benchmarks of GetFiles, CopyDirectory both sequential and parallel, syncing, and hashing on a tree generated from spec. Each benchmark
+// generates its tree in a temporary directory outside of the timed section, and removes it when done.
+func Suite(spec TreeSpec) []Benchmark {
+	return []Benchmark{
+		{Name: "GetFiles/" + spec.String(), F: func(b *testing.B) { benchmarkGetFiles(b, spec) }},
+		{Name: "CopyDirectory/" + spec.String(), F: func(b *testing.B) { benchmarkCopyDirectory(b, spec) }},
+		{Name: "CopyDirectoryWorkers/" + spec.String(), F: func(b *testing.B) { benchmarkCopyDirectory(b, spec, coreutils.WithWorkers(copyWorkers)) }},
+		{Name: "Sync/" + spec.String(), F: func(b *testing.B) { benchmarkSync(b, spec) }},
+		{Name: "FileChanged/" + spec.String(), F: func(b *testing.B) { benchmarkHashing(b, spec) }},
+	}
+}
+
+// Run will run the Suite of spec, returning the result of each benchmark
+func Run(spec TreeSpec) []Result {
+	var results []Result
+
+	for _, benchmark := range Suite(spec) {
+		results = append(results, Result{Name: benchmark.Name, BenchmarkResult: testing.Benchmark(benchmark.F)})
+	}
+
+	return results
+}
+
+// Compare will return the benchmarks of current which are slower than in baseline by more than tolerance, such as 0.10
+// for 10%. Benchmarks missing from baseline are skipped.
+func Compare(baseline, current []Result, tolerance float64) []Regression {
+	baselineTimes := make(map[string]int64)
+	var regressions []Regression
+
+	for _, result := range baseline {
+		baselineTimes[result.Name] = result.NsPerOp()
+	}
+
+	for _, result := range current {
+		before, found := baselineTimes[result.Name]
+
+		if !found || before == 0 {
+			continue
+		}
+
+		change := float64(result.NsPerOp()-before) / float64(before)
+
+		if change > tolerance { // If this got slower than we allow
+			regressions = append(regressions, Regression{Name: result.Name, Baseline: before, Current: result.NsPerOp(), Change: change})
+		}
+	}
+
+	return regressions
+}
+
+// benchmarkTree generates the tree of spec for a benchmark, failing it if the tree can't be created
+func benchmarkTree(b *testing.B, spec TreeSpec) (workDir, treeDir string) {
+	b.Helper()
+	b.StopTimer()
+
+	workDir, tempErr := os.MkdirTemp("", "coreutils-bench-*")
+
+	if tempErr != nil {
+		b.Fatal(tempErr)
+	}
+
+	treeDir = filepath.Join(workDir, "tree")
+
+	if generateErr := GenerateTree(treeDir, spec); generateErr != nil {
+		os.RemoveAll(workDir)
+		b.Fatal(generateErr)
+	}
+
+	b.SetBytes(int64(spec.Files) * spec.Size)
+	b.ResetTimer()
+	b.StartTimer()
+
+	return workDir, treeDir
+}
+
+// benchmarkGetFiles lists the tree recursively
+func benchmarkGetFiles(b *testing.B, spec TreeSpec) {
+	workDir, treeDir := benchmarkTree(b, spec)
+	defer os.RemoveAll(workDir)
+
+	for iteration := 0; iteration < b.N; iteration++ {
+		if _, listErr := coreutils.GetFiles(treeDir, true); listErr != nil {
+			b.Fatal(listErr)
+		}
+	}
+}
+
+// benchmarkCopyDirectory copies the tree to a new destination each iteration, with the Options provided
+func benchmarkCopyDirectory(b *testing.B, spec TreeSpec, opts ...coreutils.Option) {
+	workDir, treeDir := benchmarkTree(b, spec)
+	defer os.RemoveAll(workDir)
+
+	for iteration := 0; iteration < b.N; iteration++ {
+		destination := filepath.Join(workDir, fmt.Sprintf("copy-%d", iteration))
+
+		if copyErr := coreutils.CopyDirectory(treeDir, destination, opts...); copyErr != nil {
+			b.Fatal(copyErr)
+		}
+
+		b.StopTimer()
+		os.RemoveAll(destination) // Keep disk usage flat across iterations
+		b.StartTimer()
+	}
+}
+
+// benchmarkSync copies the tree onto an up to date copy of itself each iteration, so only the checks of unchanged files are timed
+func benchmarkSync(b *testing.B, spec TreeSpec) {
+	workDir, treeDir := benchmarkTree(b, spec)
+	defer os.RemoveAll(workDir)
+
+	destination := filepath.Join(workDir, "sync")
+	opts := []coreutils.Option{coreutils.WithOverwrite(coreutils.OverwriteNewer), coreutils.WithPreserveTimes(true)}
+
+	b.StopTimer()
+
+	if copyErr := coreutils.CopyDirectory(treeDir, destination, opts...); copyErr != nil {
+		b.Fatal(copyErr)
+	}
+
+	b.StartTimer()
+
+	for iteration := 0; iteration < b.N; iteration++ {
+		if syncErr := coreutils.CopyDirectory(treeDir, destination, opts...); syncErr != nil {
+			b.Fatal(syncErr)
+		}
+	}
+}
+
+// benchmarkHashing hashes the content of every file in the tree
+func benchmarkHashing(b *testing.B, spec TreeSpec) {
+	workDir, treeDir := benchmarkTree(b, spec)
+	defer os.RemoveAll(workDir)
+
+	b.StopTimer()
+	files, listErr := coreutils.GetFiles(treeDir, true)
+
+	if listErr != nil {
+		b.Fatal(listErr)
+	}
+
+	b.StartTimer()
+
+	for iteration := 0; iteration < b.N; iteration++ {
+		for _, file := range files {
+			if _, _, hashErr := coreutils.FileChanged(file, ""); hashErr != nil {
+				b.Fatal(hashErr)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package bench
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StroblIndustries/coreutils"
+)
+
+// benchmarkSpecs are the trees benchmarked by go test -bench, a few large files and many small ones
+var benchmarkSpecs = []TreeSpec{
+	{Files: 16, Size: 1 << 20, Depth: 1},
+	{Files: 2000, Size: 4096, Depth: 3},
+}
+
+// runSuite runs the benchmark of the Suite of each spec whose name starts with prefix
+func runSuite(b *testing.B, prefix string) {
+	for _, spec := range benchmarkSpecs {
+		for _, benchmark := range Suite(spec) {
+			if strings.HasPrefix(benchmark.Name, prefix) {
+				b.Run(strings.TrimPrefix(benchmark.Name, prefix), benchmark.F)
+			}
+		}
+	}
+}
+
+func BenchmarkGetFiles(b *testing.B) {
+	runSuite(b, "GetFiles/")
+}
+
+func BenchmarkCopyDirectory(b *testing.B) {
+	runSuite(b, "CopyDirectory/")
+}
+
+// BenchmarkCopyDirectoryWorkers copies each tree with increasing WithWorkers counts, so the speedup of parallel copying over
+// BenchmarkCopyDirectory can be compared. Expect little from more workers than the machine has cores.
+func BenchmarkCopyDirectoryWorkers(b *testing.B) {
+	for _, spec := range benchmarkSpecs {
+		for _, workers := range []int{1, 2, 4, copyWorkers, 2 * copyWorkers} {
+			b.Run(fmt.Sprintf("%s/w%d", spec, workers), func(b *testing.B) {
+				benchmarkCopyDirectory(b, spec, coreutils.WithWorkers(workers))
+			})
+		}
+	}
+}
+
+func BenchmarkSync(b *testing.B) {
+	runSuite(b, "Sync/")
+}
+
+func BenchmarkFileChanged(b *testing.B) {
+	runSuite(b, "FileChanged/")
+}
+
+func TestGenerateTree(t *testing.T) {
+	spec := TreeSpec{Files: 10, Size: 100, Depth: 2, Width: 2}
+	first, second := filepath.Join(t.TempDir(), "tree"), filepath.Join(t.TempDir(), "tree")
+
+	for _, dir := range []string{first, second} {
+		if generateErr := GenerateTree(dir, spec); generateErr != nil {
+			t.Fatal(generateErr)
+		}
+	}
+
+	if GenerateTree(first, spec) == nil {
+		t.Error("expected generating into an existing directory to fail")
+	}
+
+	firstHash, firstErr := coreutils.HashDirectory(first, coreutils.HashSHA256)
+	secondHash, secondErr := coreutils.HashDirectory(second, coreutils.HashSHA256)
+
+	if firstErr != nil || secondErr != nil || firstHash != secondHash {
+		t.Error("expected trees of the same spec to be identical")
+	}
+}
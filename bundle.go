@@ -0,0 +1,144 @@
+package coreutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// bundleMagic marks the end of a self-extracting bundle's index
+const bundleMagic = "CUBUNDL1"
+
+// bundleIndexSize is the size of the index appended after the payload: payload offset, payload size, and the magic
+const bundleIndexSize = 8 + 8 + len(bundleMagic)
+
+// CreateSelfExtractingBundle will create output from the stubBinary with a tar.gz of payloadDir appended to it.
+// The stub should call ExtractEmbeddedPayload to unpack the payload at runtime.
+func CreateSelfExtractingBundle(payloadDir, stubBinary, output string) error {
+	start := time.Now()
+	bundleErr := createSelfExtractingBundle(payloadDir, stubBinary, output)
+	trace(TraceEvent{Op: "CreateSelfExtractingBundle", Path: payloadDir, Destination: output, Duration: time.Since(start), Err: bundleErr})
+	return bundleErr
+}
+
+// createSelfExtractingBundle writes the stubBinary followed by a tar.gz of payloadDir and its index to output
+func createSelfExtractingBundle(payloadDir, stubBinary, output string) error {
+	if !IsDir(payloadDir) { // If the payload isn't a directory
+		return notDirectoryError(payloadDir, nil)
+	} else if policyErr := checkPathPolicy(output, true); policyErr != nil {
+		return policyErr
+	}
+
+	stub, stubOpenErr := os.Open(stubBinary)
+
+	if stubOpenErr != nil { // If the stub doesn't exist
+		return openError(stubBinary, stubOpenErr)
+	}
+
+	defer stub.Close()
+
+	stubStats, _ := stub.Stat()
+	bundle, bundleCreateErr := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, stubStats.Mode().Perm()|0111) // Ensure the bundle is executable
+
+	if bundleCreateErr != nil { // If we failed to create the output
+		return writeError(output, "Failed to create "+output, bundleCreateErr)
+	}
+
+	payloadOffset, bundleErr := io.Copy(bundle, stub) // Write the stub first so it remains a valid executable
+	var payloadSize int64
+
+	if bundleErr == nil { // If we wrote the stub
+		archive := NewArchiveWriter(bundle, ArchiveTarGz)
+
+		if bundleErr = archive.AddDirectory(payloadDir); bundleErr == nil {
+			bundleErr = archive.Close()
+		}
+	}
+
+	if bundleErr == nil { // If we wrote the payload
+		var payloadEnd int64
+		payloadEnd, bundleErr = bundle.Seek(0, io.SeekCurrent)
+		payloadSize = payloadEnd - payloadOffset
+	}
+
+	if bundleErr == nil { // If we know where the payload is, write the index
+		index := make([]byte, bundleIndexSize)
+		binary.BigEndian.PutUint64(index[0:8], uint64(payloadOffset))
+		binary.BigEndian.PutUint64(index[8:16], uint64(payloadSize))
+		copy(index[16:], bundleMagic)
+
+		_, bundleErr = bundle.Write(index)
+	}
+
+	if closeErr := bundle.Close(); bundleErr == nil {
+		bundleErr = closeErr
+	}
+
+	if bundleErr != nil { // If we failed at any point, don't leave a broken bundle behind
+		os.Remove(output)
+		bundleErr = errors.New("Failed to create bundle " + output + ": " + bundleErr.Error())
+	}
+
+	return bundleErr
+}
+
+// ExtractEmbeddedPayload will extract the payload appended to the running executable by CreateSelfExtractingBundle into destination
+func ExtractEmbeddedPayload(destination string) error {
+	executable, executableErr := os.Executable()
+
+	if executableErr != nil { // If we could not determine our own executable
+		return errors.New("Unable to locate the running executable: " + executableErr.Error())
+	}
+
+	bundle, openErr := os.Open(executable)
+
+	if openErr != nil { // If we failed to open ourselves
+		return openError(executable, openErr)
+	}
+
+	defer bundle.Close()
+
+	payload, payloadErr := embeddedPayload(bundle)
+
+	if payloadErr != nil {
+		return payloadErr
+	}
+
+	archive, archiveErr := NewArchiveReader(payload, ArchiveTarGz)
+
+	if archiveErr != nil { // If the payload is corrupt
+		return archiveErr
+	}
+
+	return archive.ExtractTo(destination)
+}
+
+// embeddedPayload locates the payload within a bundle using the index at the end of the file
+func embeddedPayload(bundle *os.File) (*io.SectionReader, error) {
+	bundleStats, statErr := bundle.Stat()
+
+	if statErr != nil || bundleStats.Size() < int64(bundleIndexSize) { // If the file is too small to have an index
+		return nil, errors.New(bundle.Name() + " does not contain an embedded payload.")
+	}
+
+	index := make([]byte, bundleIndexSize)
+
+	if _, readErr := bundle.ReadAt(index, bundleStats.Size()-int64(bundleIndexSize)); readErr != nil { // If we failed to read the index
+		return nil, readError(bundle.Name(), readErr)
+	}
+
+	if string(index[16:]) != bundleMagic { // If there is no index
+		return nil, errors.New(bundle.Name() + " does not contain an embedded payload.")
+	}
+
+	payloadOffset := int64(binary.BigEndian.Uint64(index[0:8]))
+	payloadSize := int64(binary.BigEndian.Uint64(index[8:16]))
+
+	if payloadOffset < 0 || payloadSize < 0 || payloadOffset+payloadSize > bundleStats.Size()-int64(bundleIndexSize) { // If the index is corrupt
+		return nil, errors.New(bundle.Name() + " has a corrupt payload index.")
+	}
+
+	return io.NewSectionReader(bundle, payloadOffset, payloadSize), nil
+}
@@ -0,0 +1,108 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrCaseCollision is returned by copies using WithCaseCollisionCheck when the source has names which differ only by case,
+// such as README and readme, and the destination can't tell them apart
+var ErrCaseCollision = errors.New("names differ only by case")
+
+// WithCaseCollisionCheck sets whether CopyDirectory checks the source for names which differ only by case before copying to a
+// case-insensitive destination, such as the default filesystems of macOS and Windows, where they would silently overwrite each
+// other. The copy fails with ErrCaseCollision rather than starting. Off by default, since it walks the source an extra time.
+func WithCaseCollisionCheck(check bool) Option {
+	return func(options *operationOptions) {
+		options.caseCollisionCheck = check
+	}
+}
+
+// FindCaseCollisions will return the groups of files and directories within path whose names differ only by case from others in
+// the same directory, such as README and readme. Each group is sorted, and the groups are sorted by their first path.
+func FindCaseCollisions(path string) ([][]string, error) {
+	return findCaseCollisions(path, newOperationOptions(nil))
+}
+
+// findCaseCollisions finds the case collisions within path, honoring the exclude patterns and limits of the options
+func findCaseCollisions(path string, options *operationOptions) ([][]string, error) {
+	if !IsDir(path) { // If this isn't a directory
+		return nil, notDirectoryError(path, nil)
+	}
+
+	names := make(map[string][]string) // Paths by their directory and case folded name
+
+	walkErr := walkTree(path, options, func(entryPath, relativePath string, info os.FileInfo) error {
+		if relativePath != "." { // The root has nothing to collide with
+			key := filepath.Dir(entryPath) + string(filepath.Separator) + strings.ToLower(info.Name())
+			names[key] = append(names[key], entryPath)
+		}
+
+		return nil
+	})
+
+	var collisions [][]string
+
+	for _, paths := range names { // For each name, keep those shared by more than one entry
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			collisions = append(collisions, paths)
+		}
+	}
+
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i][0] < collisions[j][0]
+	})
+
+	return collisions, walkErr
+}
+
+// checkCaseCollisions returns ErrCaseCollision if the source has names which differ only by case and the destination is case-insensitive,
+// when the operation checks for them
+func checkCaseCollisions(source, destination string, options *operationOptions) error {
+	if !options.caseCollisionCheck || !caseInsensitive(destination) {
+		return nil
+	}
+
+	collisions, findErr := findCaseCollisions(source, options)
+
+	if findErr != nil {
+		return findErr
+	}
+
+	if len(collisions) != 0 { // If anything would be overwritten
+		return fmt.Errorf("%d names in %s would collide in %s, such as %s: %w", len(collisions), source, destination, strings.Join(collisions[0], " and "), ErrCaseCollision)
+	}
+
+	return nil
+}
+
+// caseInsensitive checks if the filesystem holding path, or the nearest parent of it which exists, treats names which differ only
+// by case as the same, by creating a probe file there. Returns false if it can't tell, such as when the directory isn't writable.
+func caseInsensitive(path string) bool {
+	directory := path
+
+	for info, statErr := os.Stat(directory); statErr != nil || !info.IsDir(); info, statErr = os.Stat(directory) { // Find the nearest directory which exists
+		if parent := filepath.Dir(directory); parent != directory {
+			directory = parent
+		} else {
+			return false
+		}
+	}
+
+	probe, createErr := os.CreateTemp(directory, ".coreutils-case-probe-*")
+
+	if createErr != nil { // If we can't write here, we can't tell
+		return false
+	}
+
+	probe.Close()
+	defer os.Remove(probe.Name())
+
+	_, statErr := os.Stat(filepath.Join(directory, strings.ToUpper(filepath.Base(probe.Name()))))
+	return statErr == nil
+}
@@ -0,0 +1,160 @@
+package coreutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// certValidity is how long certificates made by GenerateSelfSignedCert are valid for
+const certValidity = 365 * 24 * time.Hour
+
+// certRenewBefore is how long before expiring LoadOrCreateCert replaces a certificate
+const certRenewBefore = 30 * 24 * time.Hour
+
+// CertPaths are the paths of a PEM encoded certificate and its private key
+type CertPaths struct {
+	Cert string // Path of the certificate
+	Key  string // Path of the private key, which is only readable by its owner
+}
+
+// GenerateSelfSignedCert will create a self-signed certificate valid for a year for the hosts, which may be names such as
+// "localhost" or IP addresses, writing it and its ECDSA private key to dst as PEM files. Both are written with WriteFileAtomic,
+// so replacing an existing pair never leaves a partial file, and the key is never readable by anyone but its owner.
+func GenerateSelfSignedCert(hosts []string, dst CertPaths) error {
+	if len(hosts) == 0 {
+		return errors.New("A certificate needs at least one host.")
+	}
+
+	der, key, createErr := selfSignedCertificate(hosts, certValidity)
+
+	if createErr != nil {
+		return createErr
+	}
+
+	keyDER, marshalErr := x509.MarshalPKCS8PrivateKey(key)
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	if writeErr := WriteFileAtomic(dst.Key, keyPEM, 0600, WithExactMode(true)); writeErr != nil { // Write the key first, so a certificate never exists without it
+		return writeErr
+	}
+
+	return WriteFileAtomic(dst.Cert, certPEM, 0644)
+}
+
+// LoadOrCreateCert will load the certificate and key at paths, first generating a self-signed certificate for the hosts
+// with GenerateSelfSignedCert if there isn't one, it expires within 30 days, or it doesn't cover every host
+func LoadOrCreateCert(hosts []string, paths CertPaths) (tls.Certificate, error) {
+	certificate, loadErr := tls.LoadX509KeyPair(paths.Cert, paths.Key)
+
+	if loadErr == nil && certificate.Leaf == nil { // Older versions of Go don't parse the certificate for us
+		certificate.Leaf, loadErr = x509.ParseCertificate(certificate.Certificate[0])
+	}
+
+	if loadErr == nil && certificateCovers(certificate.Leaf, hosts) && time.Until(certificate.Leaf.NotAfter) > certRenewBefore { // If the certificate is still good
+		return certificate, nil
+	}
+
+	if generateErr := GenerateSelfSignedCert(hosts, paths); generateErr != nil {
+		return tls.Certificate{}, generateErr
+	}
+
+	return tls.LoadX509KeyPair(paths.Cert, paths.Key)
+}
+
+// CertExpiry will return when the first certificate in the PEM file at path expires
+func CertExpiry(path string) (time.Time, error) {
+	content, readErr := os.ReadFile(path)
+
+	if readErr != nil {
+		return time.Time{}, openError(path, readErr)
+	}
+
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		certificate, parseErr := x509.ParseCertificate(block.Bytes)
+
+		if parseErr != nil {
+			return time.Time{}, errors.New(path + " has an invalid certificate: " + parseErr.Error())
+		}
+
+		return certificate.NotAfter, nil
+	}
+
+	return time.Time{}, errors.New(path + " does not contain a certificate.")
+}
+
+// CertExpiresWithin will check if the certificate in the PEM file at path expires, or has expired, within d
+func CertExpiresWithin(path string, d time.Duration) (bool, error) {
+	expiry, expiryErr := CertExpiry(path)
+	return (expiryErr == nil) && time.Until(expiry) < d, expiryErr
+}
+
+// selfSignedCertificate creates a self-signed certificate for the hosts, valid for validity, returning it in DER form along with its key
+func selfSignedCertificate(hosts []string, validity time.Duration) ([]byte, *ecdsa.PrivateKey, error) {
+	key, keyErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if keyErr != nil {
+		return nil, nil, keyErr
+	}
+
+	serial, serialErr := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+
+	if serialErr != nil {
+		return nil, nil, serialErr
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hosts[0]},
+		NotBefore:             time.Now().Add(-time.Hour), // Allow for clocks which are slightly behind
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, createErr := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	return der, key, createErr
+}
+
+// certificateCovers checks if the certificate is valid for every host
+func certificateCovers(certificate *x509.Certificate, hosts []string) bool {
+	if certificate == nil {
+		return false
+	}
+
+	for _, host := range hosts {
+		if certificate.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,33 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGenerateSelfSignedCertKeyMode(t *testing.T) {
+	directory := t.TempDir()
+	paths := CertPaths{Cert: filepath.Join(directory, "cert.pem"), Key: filepath.Join(directory, "key.pem")}
+
+	if writeErr := os.WriteFile(paths.Key, []byte("old key"), 0644); writeErr != nil { // A readable key being replaced must not stay readable
+		t.Fatal(writeErr)
+	}
+
+	if generateErr := GenerateSelfSignedCert([]string{"localhost", "127.0.0.1"}, paths); generateErr != nil {
+		t.Fatal(generateErr)
+	}
+
+	if info, statErr := os.Stat(paths.Key); runtime.GOOS != "windows" && (statErr != nil || info.Mode().Perm() != 0600) {
+		t.Errorf("expected the key to be 0600, got %v, %v", info.Mode(), statErr)
+	}
+
+	if _, loadErr := LoadOrCreateCert([]string{"localhost"}, paths); loadErr != nil {
+		t.Errorf("expected the generated pair to load, got %v", loadErr)
+	}
+
+	if entries, _ := os.ReadDir(directory); len(entries) != 2 {
+		t.Errorf("expected only the certificate and key, got %v", entries)
+	}
+}
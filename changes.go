@@ -0,0 +1,93 @@
+package coreutils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// HashIndex is a persistent index of file content hashes keyed by path, used to detect modifications independent of mtimes.
+// Its methods are safe for concurrent use, but Hashes must not be accessed directly while they run.
+type HashIndex struct {
+	// Hashes is the map of cleaned file paths to their last known content hash
+	Hashes map[string]string
+
+	indexFile string
+	lock      sync.Mutex
+}
+
+// FileChanged will hash the contents of the file and compare it against previousHash, returning whether it changed along with the new hash
+func FileChanged(path string, previousHash string) (changed bool, newHash string, err error) {
+	newHash, err = contentHash(path)
+	changed = (err == nil) && (newHash != previousHash)
+	return
+}
+
+// LoadHashIndex will load the hash index stored at indexFile, returning an empty index if the file does not exist yet
+func LoadHashIndex(indexFile string) (*HashIndex, error) {
+	var loadError error
+	index := &HashIndex{
+		Hashes:    make(map[string]string),
+		indexFile: indexFile,
+	}
+
+	if indexContent, readErr := os.ReadFile(indexFile); readErr == nil { // If we read the existing index
+		if decodeErr := json.Unmarshal(indexContent, &index.Hashes); decodeErr != nil { // If the index is not valid JSON
+			loadError = errors.New("Unable to parse hash index " + indexFile + ": " + decodeErr.Error())
+		}
+
+		if index.Hashes == nil { // If the index file contained null
+			index.Hashes = make(map[string]string)
+		}
+	} else if !os.IsNotExist(readErr) { // If the index exists but we failed to read it
+		loadError = readError(indexFile, readErr)
+	}
+
+	return index, loadError
+}
+
+// Changed checks if the file at path has changed since it was last recorded in the index, updating the recorded hash
+func (index *HashIndex) Changed(path string) (bool, error) {
+	key := filepath.Clean(path)
+
+	index.lock.Lock()
+	previousHash := index.Hashes[key] // An untracked path has an empty previous hash, so it is always reported as changed
+	index.lock.Unlock()
+
+	changed, newHash, changedErr := FileChanged(path, previousHash)
+
+	if changedErr == nil { // If we successfully hashed the file
+		index.lock.Lock()
+		index.Hashes[key] = newHash
+		index.lock.Unlock()
+	}
+
+	return changed, changedErr
+}
+
+// Forget removes the path from the index
+func (index *HashIndex) Forget(path string) {
+	index.lock.Lock()
+	delete(index.Hashes, filepath.Clean(path))
+	index.lock.Unlock()
+}
+
+// Save writes the index back to the file it was loaded from
+func (index *HashIndex) Save() error {
+	index.lock.Lock()
+	indexContent, encodeErr := json.MarshalIndent(index.Hashes, "", "\t")
+	index.lock.Unlock()
+
+	if encodeErr != nil { // If we failed to encode the index
+		return encodeErr
+	}
+
+	return WriteFileAtomic(index.indexFile, indexContent, GetDefaults().DefaultFileMode) // A crash mid-save must not leave an unparseable index
+}
+
+// contentHash will return the hex encoded sha256 sum of the file's content
+func contentHash(path string) (string, error) {
+	return HashFile(path, HashSHA256)
+}
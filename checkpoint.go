@@ -0,0 +1,189 @@
+package coreutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// checkpointHeader is the first line of every checkpoint file
+const checkpointHeader = "coreutils checkpoint 1"
+
+// checkpointFlushInterval is the number of completed entries buffered before they are written to the checkpoint file
+const checkpointFlushInterval = 256
+
+// ErrInvalidCheckpoint is returned by Resume when the file is not a checkpoint written by WithCheckpoint
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+
+// checkpoint records the files a copy has completed, so a later run can skip them
+type checkpoint struct {
+	lock      sync.Mutex
+	file      *os.File
+	writer    *bufio.Writer
+	completed map[string]bool // Slash separated paths, relative to the root of the copy, completed by previous runs
+	pending   int             // Entries written to writer since it was last flushed
+}
+
+// WithCheckpoint records each file CopyDirectory completes in the checkpoint file at path, so a copy which crashes or is
+// canceled can be continued with Resume rather than starting over. The file is removed once the copy succeeds.
+func WithCheckpoint(path string) Option {
+	return func(options *operationOptions) {
+		options.checkpointPath = path
+	}
+}
+
+// withResume continues the checkpoint at options.checkpointPath rather than starting a new one
+func withResume() Option {
+	return func(options *operationOptions) {
+		options.resumeCheckpoint = true
+	}
+}
+
+// Resume will continue the CopyDirectory recorded in the checkpoint file, skipping files which previous runs completed and
+// which still exist at the destination. The checkpoint is kept up to date, and removed once the copy succeeds.
+func Resume(checkpointPath string, opts ...Option) error {
+	start := time.Now()
+	source, destination, _, readErr := readCheckpoint(checkpointPath)
+
+	if readErr != nil {
+		trace(TraceEvent{Op: "Resume", Path: checkpointPath, Duration: time.Since(start), Err: readErr})
+		return readErr
+	}
+
+	_, copyErr := copyDirectoryStats("Resume", source, destination, newOperationOptions(append(opts[:len(opts):len(opts)], WithCheckpoint(checkpointPath), withResume())))
+	return copyErr
+}
+
+// readCheckpoint reads the source, destination, and completed entries of the checkpoint file. A partially written last
+// line, as left by a crash, is ignored.
+func readCheckpoint(path string) (source, destination string, completed map[string]bool, err error) {
+	file, openErr := os.Open(path)
+
+	if openErr != nil { // If the checkpoint doesn't exist
+		return "", "", nil, openError(path, openErr)
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var header []string
+
+	for len(header) < 3 && scanner.Scan() { // Read the header, source, and destination
+		header = append(header, scanner.Text())
+	}
+
+	if len(header) < 3 || header[0] != checkpointHeader {
+		return "", "", nil, fmt.Errorf("%s: %w", path, ErrInvalidCheckpoint)
+	}
+
+	source, sourceErr := strconv.Unquote(header[1])
+	destination, destinationErr := strconv.Unquote(header[2])
+
+	if sourceErr != nil || destinationErr != nil {
+		return "", "", nil, fmt.Errorf("%s: %w", path, ErrInvalidCheckpoint)
+	}
+
+	completed = make(map[string]bool)
+
+	for scanner.Scan() { // For each completed entry
+		if entry, unquoteErr := strconv.Unquote(scanner.Text()); unquoteErr == nil {
+			completed[entry] = true
+		}
+	}
+
+	if scanErr := scanner.Err(); scanErr != nil {
+		return "", "", nil, readError(path, scanErr)
+	}
+
+	return source, destination, completed, nil
+}
+
+// openCheckpoint starts the checkpoint of a copy from source to destination, or continues it when options.resumeCheckpoint is
+// set. Returns nil if the copy has no checkpoint.
+func openCheckpoint(source, destination string, options *operationOptions) (*checkpoint, error) {
+	if options.checkpointPath == "" {
+		return nil, nil
+	}
+
+	record := &checkpoint{}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	if options.resumeCheckpoint { // If we are continuing a previous run, keep what it completed
+		var readErr error
+
+		if _, _, record.completed, readErr = readCheckpoint(options.checkpointPath); readErr != nil {
+			return nil, readErr
+		}
+
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+
+	file, openErr := os.OpenFile(options.checkpointPath, flags, GetDefaults().DefaultFileMode)
+
+	if openErr != nil { // If we failed to create the checkpoint
+		return nil, writeError(options.checkpointPath, "Failed to open the checkpoint "+options.checkpointPath, openErr)
+	}
+
+	record.file = file
+	record.writer = bufio.NewWriter(file)
+
+	if !options.resumeCheckpoint { // A new checkpoint starts with what it is a checkpoint of
+		fmt.Fprintf(record.writer, "%s\n%s\n%s\n", checkpointHeader, strconv.Quote(source), strconv.Quote(destination))
+	}
+
+	return record, nil
+}
+
+// done checks if a previous run completed the file at relativePath, and it still exists at destinationPath
+func (record *checkpoint) done(relativePath, destinationPath string) bool {
+	if record == nil || !record.completed[filepath.ToSlash(relativePath)] {
+		return false
+	}
+
+	_, statErr := os.Lstat(destinationPath) // The file may have been removed since, such as by a canceled CopyDirectoryContext
+	return statErr == nil
+}
+
+// complete records that the file at relativePath has been copied
+func (record *checkpoint) complete(relativePath string) {
+	if record == nil {
+		return
+	}
+
+	record.lock.Lock()
+	defer record.lock.Unlock()
+
+	record.writer.WriteString(strconv.Quote(filepath.ToSlash(relativePath)) + "\n")
+	record.pending++
+
+	if record.pending >= checkpointFlushInterval { // Write in batches, since a lost batch only means recopying a few files
+		record.writer.Flush()
+		record.pending = 0
+	}
+}
+
+// close writes any buffered entries and closes the checkpoint, removing it if the copy succeeded
+func (record *checkpoint) close(copyErr error) error {
+	if record == nil {
+		return nil
+	}
+
+	flushErr := record.writer.Flush()
+
+	if closeErr := record.file.Close(); flushErr == nil {
+		flushErr = closeErr
+	}
+
+	if copyErr == nil { // If the copy finished, there is nothing left to resume
+		return os.Remove(record.file.Name())
+	}
+
+	return flushErr
+}
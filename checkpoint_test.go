@@ -0,0 +1,58 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestResumeTraced(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "source")
+	destination := filepath.Join(root, "destination")
+	checkpointPath := filepath.Join(root, "checkpoint")
+
+	if mkdirErr := os.Mkdir(source, 0755); mkdirErr != nil {
+		t.Fatal(mkdirErr)
+	}
+
+	if writeErr := os.WriteFile(filepath.Join(source, "file.txt"), []byte("resumed"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	checkpointContent := fmt.Sprintf("%s\n%s\n%s\n", checkpointHeader, strconv.Quote(source), strconv.Quote(destination))
+
+	if writeErr := os.WriteFile(checkpointPath, []byte(checkpointContent), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	var resumeErr error
+	ops := traceOps(source, func() { resumeErr = Resume(checkpointPath) })
+
+	if resumeErr != nil {
+		t.Fatal(resumeErr)
+	}
+
+	if len(ops) != 1 || ops[0] != "Resume" {
+		t.Errorf("expected a single Resume trace, got %v", ops)
+	}
+
+	if content, readErr := os.ReadFile(filepath.Join(destination, "file.txt")); readErr != nil || string(content) != "resumed" {
+		t.Errorf("expected the copy to complete, got %q, %v", content, readErr)
+	}
+
+	ops = traceOps(checkpointPath, func() { resumeErr = Resume(checkpointPath) }) // The checkpoint was removed by the successful copy
+
+	if resumeErr == nil || len(ops) != 1 || ops[0] != "Resume" {
+		t.Errorf("expected a traced failure to resume a missing checkpoint, got %v, %v", resumeErr, ops)
+	}
+
+	os.WriteFile(checkpointPath, []byte("not a checkpoint\n"), 0644)
+
+	if resumeErr = Resume(checkpointPath); !errors.Is(resumeErr, ErrInvalidCheckpoint) {
+		t.Errorf("expected ErrInvalidCheckpoint, got %v", resumeErr)
+	}
+}
@@ -0,0 +1,57 @@
+package coreutils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrClipboardUnavailable is returned when no clipboard tool is available on this system
+var ErrClipboardUnavailable = errors.New("no clipboard is available")
+
+// CopyToClipboard will place the text on the system clipboard
+func CopyToClipboard(text string) error {
+	command, findErr := clipboardCopyCommand()
+
+	if findErr != nil {
+		return findErr
+	}
+
+	runner, stderr := command.prepare()
+	runner.Stdin = strings.NewReader(text)
+
+	if runErr := runner.Run(); runErr != nil { // If we failed to copy
+		return command.commandError(runErr, stderr)
+	}
+
+	return nil
+}
+
+// ReadClipboard will return the text currently on the system clipboard
+func ReadClipboard() (string, error) {
+	command, findErr := clipboardPasteCommand()
+
+	if findErr != nil {
+		return "", findErr
+	}
+
+	runner, stderr := command.prepare()
+	runner.Stdout = nil // Output is returned by runner.Output instead
+	output, runErr := runner.Output()
+
+	if runErr != nil { // If we failed to read the clipboard
+		return "", command.commandError(runErr, stderr)
+	}
+
+	return string(output), nil
+}
+
+// firstAvailableCommand returns the first of the commands whose executable exists
+func firstAvailableCommand(commands ...Command) (Command, error) {
+	for _, command := range commands {
+		if ExecutableExists(command.Name) {
+			return command, nil
+		}
+	}
+
+	return Command{}, ErrClipboardUnavailable
+}
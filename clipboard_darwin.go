@@ -0,0 +1,11 @@
+package coreutils
+
+// clipboardCopyCommand returns the command used to copy to the clipboard
+func clipboardCopyCommand() (Command, error) {
+	return firstAvailableCommand(Command{Name: "pbcopy"})
+}
+
+// clipboardPasteCommand returns the command used to read the clipboard
+func clipboardPasteCommand() (Command, error) {
+	return firstAvailableCommand(Command{Name: "pbpaste"})
+}
@@ -0,0 +1,33 @@
+package coreutils
+
+import (
+	"os"
+)
+
+// clipboardCopyCommand returns the command used to copy to the clipboard, preferring Wayland when it is running
+func clipboardCopyCommand() (Command, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" { // If this is a Wayland session
+		if command, findErr := firstAvailableCommand(Command{Name: "wl-copy"}); findErr == nil {
+			return command, nil
+		}
+	}
+
+	return firstAvailableCommand(
+		Command{Name: "xclip", Args: []string{"-selection", "clipboard", "-in"}},
+		Command{Name: "xsel", Args: []string{"--clipboard", "--input"}},
+	)
+}
+
+// clipboardPasteCommand returns the command used to read the clipboard, preferring Wayland when it is running
+func clipboardPasteCommand() (Command, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" { // If this is a Wayland session
+		if command, findErr := firstAvailableCommand(Command{Name: "wl-paste", Args: []string{"--no-newline"}}); findErr == nil {
+			return command, nil
+		}
+	}
+
+	return firstAvailableCommand(
+		Command{Name: "xclip", Args: []string{"-selection", "clipboard", "-out"}},
+		Command{Name: "xsel", Args: []string{"--clipboard", "--output"}},
+	)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package coreutils
+
+// clipboardCopyCommand returns ErrClipboardUnavailable, since we don't know the clipboard tools of this platform
+func clipboardCopyCommand() (Command, error) {
+	return Command{}, ErrClipboardUnavailable
+}
+
+// clipboardPasteCommand returns ErrClipboardUnavailable, since we don't know the clipboard tools of this platform
+func clipboardPasteCommand() (Command, error) {
+	return Command{}, ErrClipboardUnavailable
+}
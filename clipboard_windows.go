@@ -0,0 +1,15 @@
+package coreutils
+
+// clipboardCopyCommand returns the command used to copy to the clipboard. PowerShell is preferred over clip.exe, which
+// can't handle text outside of the console code page.
+func clipboardCopyCommand() (Command, error) {
+	return firstAvailableCommand(
+		Command{Name: "powershell", Args: []string{"-NoProfile", "-Command", "$input | Set-Clipboard"}},
+		Command{Name: "clip"},
+	)
+}
+
+// clipboardPasteCommand returns the command used to read the clipboard
+func clipboardPasteCommand() (Command, error) {
+	return firstAvailableCommand(Command{Name: "powershell", Args: []string{"-NoProfile", "-Command", "Get-Clipboard -Raw"}})
+}
@@ -0,0 +1,76 @@
+package coreutils
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the time-dependent features of this package, such as heartbeats, share token expiry,
+// and file ages, so they can be driven by a fake clock in tests. See testutil.FakeClock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers the time on its channel at intervals, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// SystemClock is the Clock which uses the real time of the system
+type SystemClock struct{}
+
+// systemTicker is a Ticker backed by a time.Ticker
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+var (
+	clockLock    sync.RWMutex
+	packageClock Clock = SystemClock{}
+)
+
+// SetClock replaces the Clock used by this package. A nil Clock restores SystemClock.
+func SetClock(clock Clock) {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+
+	clockLock.Lock()
+	packageClock = clock
+	clockLock.Unlock()
+}
+
+// GetClock returns the Clock used by this package
+func GetClock() Clock {
+	clockLock.RLock()
+	defer clockLock.RUnlock()
+	return packageClock
+}
+
+// Now returns the current time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for d to pass, then sends the current time on the returned channel
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTicker returns a Ticker which sends the time every d
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+// C returns the channel the ticks are sent on
+func (ticker systemTicker) C() <-chan time.Time {
+	return ticker.ticker.C
+}
+
+// Stop turns off the ticker
+func (ticker systemTicker) Stop() {
+	ticker.ticker.Stop()
+}
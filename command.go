@@ -0,0 +1,159 @@
+package coreutils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command describes an external command to run
+type Command struct {
+	Name   string    // Name or path of the executable
+	Args   []string  // Arguments passed to the executable
+	Dir    string    // Working directory of the command, or the current directory if empty
+	Env    []string  // Environment of the command in "KEY=value" form, or the current environment if nil
+	Stdout io.Writer // Where the command's output goes when it isn't being piped, discarded if nil
+	Stderr io.Writer // Where the command's errors go, or included in the returned error if nil
+}
+
+// String returns the command line
+func (command Command) String() string {
+	return strings.Join(append([]string{command.Name}, command.Args...), " ")
+}
+
+// prepare creates the exec.Cmd for the command, along with the buffer its errors are captured in if no Stderr was provided.
+// Scripts are run through the interpreter in their shebang on Windows.
+func (command Command) prepare() (*exec.Cmd, *bytes.Buffer) {
+	name, args := scriptCommand(command.Name, command.Args)
+	runner := exec.Command(name, args...)
+	runner.Dir = command.Dir
+	runner.Env = command.Env
+	runner.Stdout = command.Stdout
+	runner.Stderr = command.Stderr
+
+	var stderr *bytes.Buffer
+
+	if command.Stderr == nil { // Capture the errors, so we can say why the command failed
+		stderr = &bytes.Buffer{}
+		runner.Stderr = stderr
+	}
+
+	return runner, stderr
+}
+
+// commandError describes why the command failed, including its captured errors
+func (command Command) commandError(runErr error, stderr *bytes.Buffer) error {
+	message := command.Name + " failed: " + runErr.Error()
+
+	if stderr != nil && stderr.Len() != 0 { // If the command told us why
+		message += ": " + strings.TrimSpace(stderr.String())
+	}
+
+	return errors.New(message)
+}
+
+// PipeCommandToFile will run the command, streaming its output into the dst file without a temp file, such as to save a
+// database dump. The file is removed if the command fails. Honors WithFileMode, WithDirMode, WithBufferSize, and WithExactMode.
+func PipeCommandToFile(cmd Command, dst string, opts ...Option) error {
+	start := time.Now()
+	options := newOperationOptions(opts)
+
+	if name, _ := scriptCommand(cmd.Name, nil); !ExecutableExists(name) { // If the executable, or the interpreter of the script, doesn't exist
+		return errors.New(cmd.Name + " is not an executable.")
+	}
+
+	if policyErr := checkPathPolicy(dst, true); policyErr != nil { // Check before running the command, rather than once it has output
+		return policyErr
+	}
+
+	if hookErr := preHooks("PipeCommandToFile", cmd.String(), dst, options.DefaultFileMode); hookErr != nil {
+		return hookErr
+	}
+
+	runner, stderr := cmd.prepare()
+	runner.Stdout = nil // The output is piped to the file instead
+	output, pipeErr := runner.StdoutPipe()
+
+	if pipeErr != nil {
+		return pipeErr
+	}
+
+	if startErr := runner.Start(); startErr != nil { // If we failed to start the command
+		return cmd.commandError(startErr, stderr)
+	}
+
+	written, pipeErr := copyFromReader(dst, output, options.DefaultFileMode, IOCategoryWrite, options)
+
+	if pipeErr != nil { // If we failed to write the output, stop the command rather than wait on a full pipe
+		runner.Process.Kill()
+	}
+
+	if waitErr := runner.Wait(); pipeErr == nil && waitErr != nil {
+		pipeErr = cmd.commandError(waitErr, stderr)
+	}
+
+	if pipeErr != nil { // Don't leave partial output behind
+		os.Remove(dst)
+	}
+
+	trace(TraceEvent{Op: "PipeCommandToFile", Path: cmd.String(), Destination: dst, Bytes: written, Duration: time.Since(start), Err: pipeErr})
+
+	return pipeErr
+}
+
+// PipeFileToCommand will run the command with the content of the src file streamed to its input, such as to restore a
+// database dump. The command's output goes to cmd.Stdout.
+func PipeFileToCommand(src string, cmd Command) error {
+	start := time.Now()
+
+	if name, _ := scriptCommand(cmd.Name, nil); !ExecutableExists(name) { // If the executable, or the interpreter of the script, doesn't exist
+		return errors.New(cmd.Name + " is not an executable.")
+	}
+
+	if policyErr := checkPathPolicy(src, false); policyErr != nil {
+		return policyErr
+	}
+
+	openFiles.acquire()
+	defer openFiles.release()
+
+	sourceFile, openErr := os.Open(src)
+
+	if openErr != nil { // If the file does not exist
+		return openError(src, openErr)
+	}
+
+	defer sourceFile.Close()
+
+	runner, stderr := cmd.prepare()
+	input := &countingReader{reader: sourceFile}
+	runner.Stdin = input
+
+	var pipeErr error
+
+	if runErr := runner.Run(); runErr != nil {
+		pipeErr = cmd.commandError(runErr, stderr)
+	}
+
+	accountRead(IOCategoryCopy, input.read)
+	trace(TraceEvent{Op: "PipeFileToCommand", Path: src, Destination: cmd.String(), Bytes: input.read, Duration: time.Since(start), Err: pipeErr})
+
+	return pipeErr
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+// Read reads from the underlying reader, counting the bytes read
+func (reader *countingReader) Read(p []byte) (int, error) {
+	readCount, readErr := reader.reader.Read(p)
+	reader.read += int64(readCount)
+	return readCount, readErr
+}
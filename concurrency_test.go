@@ -0,0 +1,122 @@
+package coreutils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSettingsChangedDuringOperations changes every package-level setting while copies run, for go test -race to check the
+// guarantees in the package documentation
+func TestSettingsChangedDuringOperations(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "source")
+
+	for index := 0; index < 16; index++ {
+		file := filepath.Join(source, fmt.Sprint(index%4), fmt.Sprint(index)+".txt")
+
+		if mkdirErr := os.MkdirAll(filepath.Dir(file), 0755); mkdirErr != nil {
+			t.Fatal(mkdirErr)
+		}
+
+		if writeErr := os.WriteFile(file, bytes.Repeat([]byte{byte(index)}, 64<<10), 0644); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+
+	defaults := GetDefaults()
+	defer SetDefaults(defaults)
+	defer SetPathPolicy(GetPathPolicy())
+	defer SetClock(GetClock())
+	defer SetIOAccountant(nil)
+
+	stop := make(chan struct{})
+	var settings, operations sync.WaitGroup
+
+	changeUntilStopped := func(change func(iteration int)) {
+		settings.Add(1)
+
+		go func() {
+			defer settings.Done()
+
+			for iteration := 0; ; iteration++ {
+				select {
+				case <-stop:
+					return
+				default:
+					change(iteration)
+				}
+			}
+		}()
+	}
+
+	changeUntilStopped(func(iteration int) {
+		changed := defaults
+		changed.BufferSize = 4096 << (iteration % 4)
+		changed.MaxOpenFiles = 2 + iteration%8
+		SetDefaults(changed)
+		GetDefaults()
+	})
+
+	changeUntilStopped(func(iteration int) {
+		SetPathPolicy(PathPolicy{AllowedRoots: []string{root}, DeniedGlobs: []string{fmt.Sprintf("*.denied%d", iteration)}})
+		GetPathPolicy()
+	})
+
+	changeUntilStopped(func(iteration int) {
+		SetClock(SystemClock{})
+		GetClock().Now()
+	})
+
+	changeUntilStopped(func(iteration int) {
+		SetIOAccountant(NewIOCounter())
+	})
+
+	changeUntilStopped(func(iteration int) {
+		removeHook := RegisterHook(OpCopy, PhasePre, func(HookEvent) error { return nil })
+		removeTrace := AddTraceHook(func(TraceEvent) {})
+		removeHook()
+		removeTrace()
+	})
+
+	copyErrs := make([]error, 4)
+
+	for index := range copyErrs {
+		operations.Add(1)
+
+		go func(index int) {
+			defer operations.Done()
+			destination := filepath.Join(root, "copies", fmt.Sprint(index))
+
+			if copyErrs[index] = CopyDirectory(source, destination, WithWorkers(4)); copyErrs[index] == nil {
+				_, copyErrs[index] = GetFiles(destination, true)
+			}
+		}(index)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		operations.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Error("copies did not finish while the settings changed")
+	}
+
+	close(stop)
+	settings.Wait()
+
+	for index, copyErr := range copyErrs {
+		if copyErr != nil {
+			t.Errorf("copy %d failed: %v", index, copyErr)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package coreutils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// createdPaths records the files and directories an operation created, so they can be removed if it is canceled
+type createdPaths struct {
+	lock  sync.Mutex
+	paths []string
+}
+
+// CopyFileContext will copy the file like CopyFile, stopping once ctx is canceled or times out. A destination the copy
+// created is removed, while one which already existed is left in place. The returned error wraps the error of ctx.
+func CopyFileContext(ctx context.Context, sourceFile, destinationFile string, opts ...Option) error {
+	return CopyFile(sourceFile, destinationFile, append(opts[:len(opts):len(opts)], withContext(ctx, nil))...)
+}
+
+// CopyDirectoryContext will copy the directory like CopyDirectory, stopping once ctx is canceled or times out. Every file
+// and directory the copy created is removed, while those which already existed at the destination are left in place.
+// The returned error wraps the error of ctx.
+func CopyDirectoryContext(ctx context.Context, sourceDirectory, destinationDirectory string, opts ...Option) error {
+	created := &createdPaths{}
+	_, copyError := CopyDirectoryStats(sourceDirectory, destinationDirectory, append(opts[:len(opts):len(opts)], withContext(ctx, created))...)
+
+	if copyError != nil && ctx.Err() != nil { // If the copy was cut short, clean up what it left behind
+		created.removeAll()
+	}
+
+	return copyError
+}
+
+// GetFilesContext will get the files like GetFiles, stopping once ctx is canceled or times out. The files found so far
+// are returned along with an error wrapping the error of ctx.
+func GetFilesContext(ctx context.Context, path string, recursive bool, opts ...Option) ([]string, error) {
+	return GetFiles(path, recursive, append(opts[:len(opts):len(opts)], withContext(ctx, nil))...)
+}
+
+// withContext sets the context which cancels the operation, and where to record the paths it creates
+func withContext(ctx context.Context, created *createdPaths) Option {
+	return func(options *operationOptions) {
+		options.ctx = ctx
+		options.created = created
+	}
+}
+
+// canceled returns an error wrapping the error of the context of the operation once it is done, naming the path being worked on
+func (options *operationOptions) canceled(path string) error {
+	if options.ctx == nil { // If the operation can't be canceled
+		return nil
+	}
+
+	if ctxErr := options.ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%s: %w", path, ctxErr)
+	}
+
+	return nil
+}
+
+// recordCreated records path as created by the operation if it doesn't exist yet and the operation is tracking what it creates.
+// Call it before creating the path.
+func (options *operationOptions) recordCreated(path string) {
+	if options.created == nil {
+		return
+	}
+
+	if _, statErr := os.Lstat(path); os.IsNotExist(statErr) {
+		options.created.lock.Lock()
+		options.created.paths = append(options.created.paths, path)
+		options.created.lock.Unlock()
+	}
+}
+
+// removeAll removes the recorded paths, newest first so files are removed before the directories containing them.
+// Directories which still have other content are left in place.
+func (created *createdPaths) removeAll() {
+	created.lock.Lock()
+	defer created.lock.Unlock()
+
+	for index := len(created.paths) - 1; index >= 0; index-- {
+		os.Remove(created.paths[index])
+	}
+
+	created.paths = nil
+}
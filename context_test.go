@@ -0,0 +1,75 @@
+package coreutils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyContextCanceledKeepsExistingDestination(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "src", "file.txt")
+	os.MkdirAll(filepath.Dir(source), 0755)
+	os.WriteFile(source, []byte(strings.Repeat("new content ", 64)), 0644)
+
+	copies := map[string]func(ctx context.Context, destination string, opts ...Option) error{
+		"CopyFileContext": func(ctx context.Context, destination string, opts ...Option) error {
+			return CopyFileContext(ctx, source, filepath.Join(destination, "file.txt"), opts...)
+		},
+		"CopyDirectoryContext": func(ctx context.Context, destination string, opts ...Option) error {
+			return CopyDirectoryContext(ctx, filepath.Dir(source), destination, opts...)
+		},
+	}
+
+	for name, copyFn := range copies {
+		t.Run(name, func(t *testing.T) {
+			destination := t.TempDir()
+			existing := filepath.Join(destination, "file.txt")
+			os.WriteFile(existing, []byte("original"), 0644)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cancelAfterFirstBuffer := WithProgress(func(path string, done, total int64) {
+				if done > 0 {
+					cancel()
+				}
+			})
+
+			if copyErr := copyFn(ctx, destination, WithBufferSize(16), cancelAfterFirstBuffer); !errors.Is(copyErr, context.Canceled) {
+				t.Fatalf("expected the copy to be canceled, got %v", copyErr)
+			}
+
+			if _, statErr := os.Stat(existing); statErr != nil {
+				t.Errorf("expected the existing destination to be left in place, got %v", statErr)
+			}
+		})
+	}
+}
+
+func TestCopyFileContextCanceledRemovesCreatedDestination(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "file.txt")
+	destination := filepath.Join(root, "copy.txt")
+	os.WriteFile(source, []byte(strings.Repeat("content ", 64)), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancelAfterFirstBuffer := WithProgress(func(path string, done, total int64) {
+		if done > 0 {
+			cancel()
+		}
+	})
+
+	if copyErr := CopyFileContext(ctx, source, destination, WithBufferSize(16), cancelAfterFirstBuffer); !errors.Is(copyErr, context.Canceled) {
+		t.Fatalf("expected the copy to be canceled, got %v", copyErr)
+	}
+
+	if _, statErr := os.Lstat(destination); !os.IsNotExist(statErr) {
+		t.Errorf("expected the partial destination to be removed, got %v", statErr)
+	}
+}
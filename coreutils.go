@@ -7,14 +7,19 @@ import (
 )
 
 // GlobalFileMode is a file mode we'll use for global IO operations.
+//
+// Deprecated: This is no longer used by the package. Use Defaults and SetDefaults instead.
 var GlobalFileMode os.FileMode
 
 // NonGlobalFileMode is the file mode we'll use for non-global IO operations.
+//
+// Deprecated: Use Defaults.DefaultDirMode and SetDefaults instead. A changed NonGlobalFileMode is still used as the
+// DefaultDirMode until SetDefaults is next called.
 var NonGlobalFileMode os.FileMode
 
 func init() {
 	GlobalFileMode = 0777 // Set to global read/write/executable
-	NonGlobalFileMode = 0744 // Only read/write/executable by owner, readable by group and others
+	NonGlobalFileMode = legacyDirMode // Only read/write/executable by owner, readable by group and others
 }
 
 // Sha512Sum will create a sha512sum of the string
@@ -0,0 +1,214 @@
+package coreutils
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// directoryBatchSize is the number of entries read from a directory at a time. This bounds the memory of reading a directory,
+// though callers which keep every entry, to order them or to close the directory before recursing, still hold them all.
+const directoryBatchSize = 1024
+
+// CountEntries will count the files and directories within path without calling stat on each entry, as a fast estimate of the
+// work a copy will involve. Symlinks are counted as files and never followed. Honors WithExclude and WithProgress (reporting
+// the number of entries counted so far).
+func CountEntries(path string, recursive bool, opts ...Option) (files, dirs int64, err error) {
+	options := newOperationOptions(opts)
+	err = countEntries(path, "", recursive, options, &files, &dirs)
+	return files, dirs, err
+}
+
+// countEntries adds the entries of path, which is relativePath within the root of the count, to files and dirs
+func countEntries(path, relativePath string, recursive bool, options *operationOptions, files, dirs *int64) error {
+	var subDirectories []string
+
+	readErr := readDirectory(path, func(entry os.DirEntry) error {
+		if options.excluded(filepath.Join(relativePath, entry.Name())) { // If this should be skipped
+			return nil
+		}
+
+		if entry.IsDir() {
+			*dirs++
+
+			if recursive { // Count the sub-directory once we've closed this one
+				subDirectories = append(subDirectories, entry.Name())
+			}
+		} else {
+			*files++
+		}
+
+		options.reportProgress(filepath.Join(path, entry.Name()), *files+*dirs, -1)
+		return nil
+	})
+
+	if readErr != nil {
+		return readErr
+	}
+
+	for _, name := range subDirectories { // For each sub-directory
+		if countErr := countEntries(filepath.Join(path, name), filepath.Join(relativePath, name), true, options, files, dirs); countErr != nil {
+			return countErr
+		}
+	}
+
+	return nil
+}
+
+// largestFilesCount is the number of files listed in TreeStats.LargestFiles
+const largestFilesCount = 10
+
+// TreeStats summarizes the contents of a directory tree
+type TreeStats struct {
+	Files        int64      // Number of files, symlinks, and other entries which aren't directories
+	Dirs         int64      // Number of directories, not counting the root
+	TotalBytes   int64      // Total size of the regular files
+	LargestFiles []FileSize // Up to the 10 largest regular files, largest first
+}
+
+// FileSize is the size of the file at Path
+type FileSize struct {
+	Path string
+	Size int64
+}
+
+// GetDirectorySize will total the size of the regular files within path, recursively. Symlinks are not followed.
+// Honors WithExclude and the WithMaxDepth, WithMaxEntries, and WithMaxPathLength limits.
+func GetDirectorySize(path string, opts ...Option) (int64, error) {
+	stats, statsErr := GetTreeStats(path, opts...)
+	return stats.TotalBytes, statsErr
+}
+
+// GetTreeStats will count the files, directories, and bytes within path, and find its largest files, with a single walk
+// which stats each entry once. Symlinks are counted as files and never followed. Honors WithExclude and the WithMaxDepth,
+// WithMaxEntries, and WithMaxPathLength limits.
+func GetTreeStats(path string, opts ...Option) (TreeStats, error) {
+	var stats TreeStats
+
+	if !IsDir(path) { // If this isn't a directory
+		return stats, notDirectoryError(path, nil)
+	}
+
+	walkErr := walkTree(path, newOperationOptions(opts), func(entryPath, relativePath string, info os.FileInfo) error {
+		switch {
+		case relativePath == ".": // The root itself isn't counted
+		case info.IsDir():
+			stats.Dirs++
+		default:
+			stats.Files++
+
+			if info.Mode().IsRegular() { // Only regular files have content to count
+				stats.TotalBytes += info.Size()
+				stats.addLargest(FileSize{Path: entryPath, Size: info.Size()})
+			}
+		}
+
+		return nil
+	})
+
+	return stats, walkErr
+}
+
+// addLargest adds the file to LargestFiles if it is among the largest found so far
+func (stats *TreeStats) addLargest(file FileSize) {
+	index := sort.Search(len(stats.LargestFiles), func(i int) bool {
+		return stats.LargestFiles[i].Size < file.Size
+	})
+
+	if index >= largestFilesCount { // If this is smaller than every file we're keeping
+		return
+	}
+
+	stats.LargestFiles = append(stats.LargestFiles, FileSize{})
+	copy(stats.LargestFiles[index+1:], stats.LargestFiles[index:])
+	stats.LargestFiles[index] = file
+
+	if len(stats.LargestFiles) > largestFilesCount {
+		stats.LargestFiles = stats.LargestFiles[:largestFilesCount]
+	}
+}
+
+// readDirectory calls fn with each entry of the directory at path, reading directoryBatchSize entries at a time. The directory
+// stays open while fn is called, so fn must not open files itself; collect sub-directories and recurse once readDirectory returns.
+func readDirectory(path string, fn func(entry os.DirEntry) error) error {
+	openFiles.acquire()
+	defer openFiles.release()
+
+	directory, openErr := os.Open(path)
+
+	if openErr != nil { // If we failed to open the directory
+		return notDirectoryError(path, openErr)
+	}
+
+	defer directory.Close()
+
+	for {
+		entries, readErr := directory.ReadDir(directoryBatchSize)
+
+		for _, entry := range entries { // For each entry in this batch
+			if fnErr := fn(entry); fnErr != nil {
+				return fnErr
+			}
+		}
+
+		if readErr == io.EOF { // If we've read every entry
+			return nil
+		} else if readErr != nil {
+			return notDirectoryError(path, readErr)
+		}
+	}
+}
+
+// EstimateCopySize will total the size and number of the files within src, as an estimate of the work copying it will involve.
+// Honors WithExclude, WithIgnoreFile, and the WithMaxDepth, WithMaxEntries, and WithMaxPathLength limits. The scan stops early if ctx is cancelled.
+func EstimateCopySize(ctx context.Context, src string, opts ...Option) (totalBytes, files int64, err error) {
+	options := newOperationOptions(opts)
+	err = estimateCopySize(ctx, src, "", options, nil, &totalBytes, &files)
+	return totalBytes, files, err
+}
+
+// estimateCopySize adds the sizes of the files within path, which is relativePath within the root of the scan, to totalBytes and files
+func estimateCopySize(ctx context.Context, path, relativePath string, options *operationOptions, ignores *ignoreRules, totalBytes, files *int64) error {
+	var subDirectories []string
+
+	ignores = ignores.load(path, relativePath, options)
+
+	readErr := readDirectory(path, func(entry os.DirEntry) error {
+		if ctxErr := ctx.Err(); ctxErr != nil { // If the scan was cancelled
+			return ctxErr
+		}
+
+		entryRelativePath := filepath.Join(relativePath, entry.Name())
+
+		if options.excluded(entryRelativePath) || ignores.ignored(entryRelativePath, entry.IsDir()) { // If this should be skipped
+			return nil
+		}
+
+		if limitErr := options.checkLimits(filepath.Join(path, entry.Name()), entryRelativePath, entry.IsDir()); limitErr != nil {
+			return limitErr
+		}
+
+		if entry.IsDir() { // Scan the sub-directory once we've closed this one
+			subDirectories = append(subDirectories, entry.Name())
+		} else if info, infoErr := entry.Info(); infoErr == nil && info.Mode().IsRegular() { // Only regular files have content to copy
+			*totalBytes += info.Size()
+			*files++
+		}
+
+		return nil
+	})
+
+	if readErr != nil {
+		return readErr
+	}
+
+	for _, name := range subDirectories { // For each sub-directory
+		if scanErr := estimateCopySize(ctx, filepath.Join(path, name), filepath.Join(relativePath, name), options, ignores, totalBytes, files); scanErr != nil {
+			return scanErr
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package coreutils
+
+import (
+	"os"
+	"sync"
+)
+
+// Defaults is the package-wide configuration used by operations unless overridden per call with an Option
+type Defaults struct {
+	// DefaultFileMode is the mode files are created with when a mode is not otherwise provided
+	DefaultFileMode os.FileMode
+
+	// DefaultDirMode is the mode directories are created with
+	DefaultDirMode os.FileMode
+
+	// BufferSize is the size in bytes of the buffers used when streaming file content
+	BufferSize int
+
+	// FollowSymlinks is whether operations act on the target of a symlink rather than the symlink itself
+	FollowSymlinks bool
+
+	// MaxOpenFiles is the maximum number of files the package will hold open at once, across all concurrent operations
+	MaxOpenFiles int
+
+	// AuditLog is the path of a JSON lines file every mutating operation is appended to, or empty to disable auditing
+	AuditLog string
+}
+
+// legacyDirMode is the value NonGlobalFileMode is initialized to
+const legacyDirMode os.FileMode = 0744
+
+var (
+	defaultsLock     sync.RWMutex
+	nonGlobalModeSet = legacyDirMode // NonGlobalFileMode as of the last SetDefaults, so a later change to it can be noticed
+	packageDefaults  = Defaults{
+		DefaultFileMode: 0644,          // Read/write by owner, readable by group and others
+		DefaultDirMode:  legacyDirMode, // Only read/write/executable by owner, readable by group and others
+		BufferSize:      32 * 1024,
+		FollowSymlinks:  false,
+		MaxOpenFiles:    256,
+	}
+)
+
+// GetDefaults returns the current package defaults. If NonGlobalFileMode was changed since SetDefaults was last called, or
+// since the package was initialized, it is used as the DefaultDirMode.
+func GetDefaults() Defaults {
+	defaultsLock.RLock()
+	defer defaultsLock.RUnlock()
+
+	defaults := packageDefaults
+
+	if NonGlobalFileMode != 0 && NonGlobalFileMode != nonGlobalModeSet { // If an application still sets the deprecated variable
+		defaults.DefaultDirMode = NonGlobalFileMode
+	}
+
+	return defaults
+}
+
+// SetDefaults replaces the package defaults. Zero values are replaced with the built-in default for that field, except FollowSymlinks.
+func SetDefaults(defaults Defaults) {
+	if defaults.DefaultFileMode == 0 {
+		defaults.DefaultFileMode = 0644
+	}
+
+	if defaults.DefaultDirMode == 0 {
+		defaults.DefaultDirMode = legacyDirMode
+	}
+
+	if defaults.BufferSize <= 0 {
+		defaults.BufferSize = 32 * 1024
+	}
+
+	if defaults.MaxOpenFiles <= 0 {
+		defaults.MaxOpenFiles = 256
+	}
+
+	defaultsLock.Lock()
+	packageDefaults = defaults
+	nonGlobalModeSet = NonGlobalFileMode
+	defaultsLock.Unlock()
+
+	openFiles.available.Broadcast() // Wake anything waiting on the open file budget, in case the limit was raised
+}
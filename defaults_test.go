@@ -0,0 +1,32 @@
+package coreutils
+
+import "testing"
+
+func TestNonGlobalFileModeStillApplies(t *testing.T) {
+	defaults, nonGlobalFileMode := GetDefaults(), NonGlobalFileMode
+
+	t.Cleanup(func() {
+		NonGlobalFileMode = nonGlobalFileMode
+		SetDefaults(defaults)
+	})
+
+	NonGlobalFileMode = 0700
+
+	if mode := GetDefaults().DefaultDirMode; mode != 0700 {
+		t.Errorf("expected a changed NonGlobalFileMode to be the DefaultDirMode, got %v", mode)
+	}
+
+	explicit := defaults
+	explicit.DefaultDirMode = 0755
+	SetDefaults(explicit)
+
+	if mode := GetDefaults().DefaultDirMode; mode != 0755 {
+		t.Errorf("expected the DefaultDirMode given to SetDefaults to take precedence over NonGlobalFileMode, got %v", mode)
+	}
+
+	NonGlobalFileMode = 0711
+
+	if mode := GetDefaults().DefaultDirMode; mode != 0711 {
+		t.Errorf("expected NonGlobalFileMode changed after SetDefaults to be the DefaultDirMode, got %v", mode)
+	}
+}
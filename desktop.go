@@ -0,0 +1,193 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Scope is whether desktop integration applies to the current user or to every user of the system
+type Scope int
+
+const (
+	// ScopeUser applies to the current user, under the XDG data and config homes
+	ScopeUser Scope = iota
+
+	// ScopeSystem applies to every user, under /usr/local/share and /etc/xdg. Writing requires root.
+	ScopeSystem
+)
+
+// DesktopEntry is a freedesktop.org .desktop file describing an application
+type DesktopEntry struct {
+	ID         string   // Desktop file ID, such as "com.example.App", used as the file name. Derived from Name if empty.
+	Type       string   // Type of the entry, "Application" if empty
+	Name       string   // Name of the application, required
+	Comment    string   // Tooltip describing the application
+	Exec       string   // Command line used to launch the application, required for applications
+	Icon       string   // Icon name or absolute path
+	Terminal   bool     // Whether the application runs in a terminal
+	NoDisplay  bool     // Whether to hide the application from menus
+	Categories []string // Menu categories, such as "Utility"
+	MimeTypes  []string // MIME types the application can open
+}
+
+// WriteDesktopEntry will validate the entry and write it to the applications directory of the scope
+func WriteDesktopEntry(entry DesktopEntry, scope Scope) error {
+	directory := filepath.Join(xdgDataDirectory(scope), "applications")
+	return writeDesktopEntry(entry, directory)
+}
+
+// EnableAutostart will start the application with the execLine when the current user logs in to their desktop
+func EnableAutostart(appName, execLine string) error {
+	return writeDesktopEntry(DesktopEntry{Name: appName, Exec: execLine}, filepath.Join(xdgConfigDirectory(ScopeUser), "autostart"))
+}
+
+// DisableAutostart will stop the application from starting when the current user logs in, undoing EnableAutostart
+func DisableAutostart(appName string) error {
+	autostartFile := filepath.Join(xdgConfigDirectory(ScopeUser), "autostart", desktopEntryID(DesktopEntry{Name: appName})+".desktop")
+
+	if policyErr := checkPathPolicy(autostartFile, true); policyErr != nil {
+		return policyErr
+	}
+
+	if removeErr := os.Remove(autostartFile); removeErr != nil && !os.IsNotExist(removeErr) {
+		return errors.New("Failed to remove " + autostartFile + ": " + removeErr.Error())
+	}
+
+	return nil
+}
+
+// Validate checks the entry has everything required by the Desktop Entry Specification
+func (entry DesktopEntry) Validate() error {
+	entryType := entry.Type
+
+	if entryType == "" {
+		entryType = "Application"
+	}
+
+	switch {
+	case entryType != "Application" && entryType != "Link" && entryType != "Directory":
+		return errors.New(entryType + " is not a valid desktop entry type.")
+	case entry.Name == "":
+		return errors.New("Desktop entries require a Name.")
+	case entryType == "Application" && entry.Exec == "":
+		return errors.New("Desktop entry " + entry.Name + " requires an Exec line.")
+	case strings.ContainsAny(entry.ID, "/\\"):
+		return errors.New(entry.ID + " is not a valid desktop file ID.")
+	}
+
+	for _, value := range append(append([]string{}, entry.Categories...), entry.MimeTypes...) { // Lists can't contain their separator
+		if strings.ContainsAny(value, ";\n") || value == "" {
+			return errors.New(value + " is not a valid desktop entry list value.")
+		}
+	}
+
+	return nil
+}
+
+// String returns the entry in the .desktop file format
+func (entry DesktopEntry) String() string {
+	entryType := entry.Type
+
+	if entryType == "" {
+		entryType = "Application"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("[Desktop Entry]\n")
+	builder.WriteString("Type=" + entryType + "\n")
+	builder.WriteString("Name=" + desktopEntryEscape(entry.Name) + "\n")
+
+	if entry.Comment != "" {
+		builder.WriteString("Comment=" + desktopEntryEscape(entry.Comment) + "\n")
+	}
+
+	if entry.Exec != "" {
+		builder.WriteString("Exec=" + desktopEntryEscape(entry.Exec) + "\n")
+	}
+
+	if entry.Icon != "" {
+		builder.WriteString("Icon=" + desktopEntryEscape(entry.Icon) + "\n")
+	}
+
+	if entryType == "Application" {
+		builder.WriteString("Terminal=" + strconv.FormatBool(entry.Terminal) + "\n")
+	}
+
+	if entry.NoDisplay {
+		builder.WriteString("NoDisplay=true\n")
+	}
+
+	if len(entry.Categories) != 0 {
+		builder.WriteString("Categories=" + strings.Join(entry.Categories, ";") + ";\n")
+	}
+
+	if len(entry.MimeTypes) != 0 {
+		builder.WriteString("MimeType=" + strings.Join(entry.MimeTypes, ";") + ";\n")
+	}
+
+	return builder.String()
+}
+
+// writeDesktopEntry validates the entry and writes it into the directory
+func writeDesktopEntry(entry DesktopEntry, directory string) error {
+	if validateErr := entry.Validate(); validateErr != nil {
+		return validateErr
+	}
+
+	if mkdirErr := os.MkdirAll(directory, 0755); mkdirErr != nil { // Desktop environments must be able to read the directory
+		return writeError(directory, "Failed to create "+directory, mkdirErr)
+	}
+
+	return WriteOrUpdateFile(filepath.Join(directory, desktopEntryID(entry)+".desktop"), []byte(entry.String()), 0644)
+}
+
+// desktopEntryID returns the desktop file ID of the entry, deriving it from the name if one wasn't provided
+func desktopEntryID(entry DesktopEntry) string {
+	if entry.ID != "" {
+		return strings.TrimSuffix(entry.ID, ".desktop")
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '/' || r == '\\' {
+			return '-'
+		}
+
+		return r
+	}, strings.ToLower(entry.Name))
+}
+
+// desktopEntryEscape escapes the characters the Desktop Entry Specification requires in string values
+func desktopEntryEscape(value string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\t", "\\t", "\r", "\\r").Replace(value)
+}
+
+// xdgDataDirectory returns the directory desktop data, such as applications, is written to for the scope
+func xdgDataDirectory(scope Scope) string {
+	if scope == ScopeSystem {
+		return "/usr/local/share"
+	}
+
+	if dataHome := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dataHome) { // The spec requires relative paths to be ignored
+		return dataHome
+	}
+
+	homeDirectory, _ := os.UserHomeDir()
+	return filepath.Join(homeDirectory, ".local", "share")
+}
+
+// xdgConfigDirectory returns the directory desktop configuration, such as autostart entries, is written to for the scope
+func xdgConfigDirectory(scope Scope) string {
+	if scope == ScopeSystem {
+		return "/etc/xdg"
+	}
+
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(configHome) { // The spec requires relative paths to be ignored
+		return configHome
+	}
+
+	homeDirectory, _ := os.UserHomeDir()
+	return filepath.Join(homeDirectory, ".config")
+}
@@ -0,0 +1,40 @@
+package coreutils
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// deterministicEpoch is the modification time given to archive entries in deterministic mode when SOURCE_DATE_EPOCH isn't
+// set. It is the earliest time a zip can represent, so tar and zip archives agree.
+var deterministicEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// WithDeterministic sets whether the output of the operation is reproducible, so the same input always produces byte-identical
+// output, such as for build pipelines. Archive entries are given a fixed modification time, taken from the SOURCE_DATE_EPOCH
+// environment variable if set and otherwise 1980-01-01, and directories are walked in sorted order rather than the order the
+// filesystem returns them in. Off by default.
+func WithDeterministic(deterministic bool) Option {
+	return func(options *operationOptions) {
+		options.deterministic = deterministic
+	}
+}
+
+// deterministicModTime returns the modification time archive entries are given in deterministic mode
+func deterministicModTime() time.Time {
+	if epoch, parseErr := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); parseErr == nil { // If the build asks for a specific time
+		return time.Unix(epoch, 0).UTC()
+	}
+
+	return deterministicEpoch
+}
+
+// orderEntries sorts the directory entries by name if the operation is deterministic
+func (options *operationOptions) orderEntries(entries []os.DirEntry) {
+	if options.deterministic {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+}
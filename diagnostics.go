@@ -0,0 +1,172 @@
+package coreutils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	diagnosticsMaxEntries = 100000             // Most entries listed per path, so huge trees don't produce huge bundles
+	diagnosticsMaxLogs    = 20                 // Most log files included per path
+	diagnosticsLogTail    = 64 * 1024          // Bytes included from the end of each log file
+	diagnosticsLogMaxAge  = 7 * 24 * time.Hour // Log files modified longer ago than this are not included
+)
+
+// CollectDiagnostics will gather the state of each of the paths into a tar.gz at output for support requests. For each path
+// it records the tree with modes, owners, sizes, and modification times, the usage of the disk it is on, and the end of any
+// recently modified .log files within it. The most recent operations traced by this package are included as well.
+func CollectDiagnostics(paths []string, output string) error {
+	start := time.Now()
+	collectErr := collectDiagnostics(paths, output)
+	trace(TraceEvent{Op: "CollectDiagnostics", Path: output, Duration: time.Since(start), Err: collectErr})
+	return collectErr
+}
+
+// collectDiagnostics writes the diagnostics of the paths to a tar.gz at output
+func collectDiagnostics(paths []string, output string) error {
+	if policyErr := checkPathPolicy(output, true); policyErr != nil {
+		return policyErr
+	}
+
+	now := time.Now()
+	outputFile, createErr := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) // Diagnostics describe the system in detail, so keep them private
+
+	if createErr != nil {
+		return writeError(output, "Failed to create "+output, createErr)
+	}
+
+	archive := NewArchiveWriter(outputFile, ArchiveTarGz)
+	addSection := func(name string, content []byte) error {
+		return archive.AddEntry(ArchiveEntry{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: now}, bytes.NewReader(content))
+	}
+
+	var disk strings.Builder
+	collectErr := addSection("trace.txt", crashReportTrace())
+
+	for index, path := range paths { // For each path we were asked about
+		if collectErr != nil {
+			break
+		}
+
+		sectionName := fmt.Sprintf("%d-%s", index, strings.Trim(strings.Map(diagnosticsNameRune, path), "_"))
+		tree, logs := diagnosticsTree(path, now)
+
+		if total, available, usageErr := diskUsage(path); usageErr == nil {
+			fmt.Fprintf(&disk, "%s: %d bytes total, %d bytes available, %.1f%% used\n", path, total, available, 100-float64(available)/float64(max(total, 1))*100)
+		} else {
+			fmt.Fprintf(&disk, "%s: %s\n", path, usageErr)
+		}
+
+		if collectErr = addSection(sectionName+"/tree.txt", tree); collectErr != nil {
+			break
+		}
+
+		for _, logPath := range logs { // Include the end of each recent log
+			relativeLog, _ := filepath.Rel(path, logPath)
+
+			if collectErr = addSection(sectionName+"/logs/"+filepath.ToSlash(relativeLog), diagnosticsLogTailOf(logPath)); collectErr != nil {
+				break
+			}
+		}
+	}
+
+	if collectErr == nil {
+		collectErr = addSection("disk.txt", []byte(disk.String()))
+	}
+
+	if closeErr := archive.Close(); collectErr == nil {
+		collectErr = closeErr
+	}
+
+	if closeErr := outputFile.Close(); collectErr == nil {
+		collectErr = closeErr
+	}
+
+	if collectErr != nil { // Don't leave a corrupt bundle behind
+		os.Remove(output)
+	}
+
+	return collectErr
+}
+
+// diagnosticsTree lists the tree at path, one entry per line, returning the listing and any recently modified log files
+func diagnosticsTree(path string, now time.Time) ([]byte, []string) {
+	var tree bytes.Buffer
+	var logs []string
+	var entries int
+
+	walkErr := filepath.WalkDir(path, func(entryPath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil { // Record what we couldn't read, such as directories we lack permission to, and carry on
+			fmt.Fprintf(&tree, "%s: %s\n", entryPath, walkErr)
+			return nil
+		}
+
+		if entries++; entries > diagnosticsMaxEntries {
+			fmt.Fprintf(&tree, "... stopped after %d entries\n", diagnosticsMaxEntries)
+			return filepath.SkipAll
+		}
+
+		info, infoErr := entry.Info()
+
+		if infoErr != nil {
+			fmt.Fprintf(&tree, "%s: %s\n", entryPath, infoErr)
+			return nil
+		}
+
+		owner := "-"
+
+		if uid, gid, ok := fileOwner(info); ok {
+			owner = fmt.Sprintf("%d:%d", uid, gid)
+		}
+
+		fmt.Fprintf(&tree, "%s %s %12d %s %s\n", info.Mode(), owner, info.Size(), info.ModTime().Format(time.RFC3339), entryPath)
+
+		if info.Mode().IsRegular() && strings.HasSuffix(entry.Name(), ".log") && now.Sub(info.ModTime()) < diagnosticsLogMaxAge && len(logs) < diagnosticsMaxLogs {
+			logs = append(logs, entryPath)
+		}
+
+		return nil
+	})
+
+	if walkErr != nil {
+		fmt.Fprintf(&tree, "%s: %s\n", path, walkErr)
+	}
+
+	return tree.Bytes(), logs
+}
+
+// diagnosticsLogTailOf returns the end of the log file, or why it couldn't be read
+func diagnosticsLogTailOf(logPath string) []byte {
+	openFiles.acquire()
+	defer openFiles.release()
+
+	logFile, openErr := os.Open(logPath)
+
+	if openErr != nil {
+		return []byte(openErr.Error() + "\n")
+	}
+
+	defer logFile.Close()
+
+	if info, statErr := logFile.Stat(); statErr == nil && info.Size() > diagnosticsLogTail { // Only keep the end of large logs
+		logFile.Seek(-diagnosticsLogTail, io.SeekEnd)
+	}
+
+	tail, _ := io.ReadAll(logFile)
+	return tail
+}
+
+// diagnosticsNameRune replaces characters which would make awkward archive directory names
+func diagnosticsNameRune(r rune) rune {
+	if r == '/' || r == '\\' || r == ':' || r == ' ' {
+		return '_'
+	}
+
+	return r
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package coreutils
+
+import (
+	"errors"
+)
+
+// diskUsage returns an error, as we don't know how to query disk usage on this platform
+func diskUsage(path string) (total, available uint64, err error) {
+	return 0, 0, errors.New("Disk usage is not available on this platform.")
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+
+package coreutils
+
+import (
+	"syscall"
+)
+
+// diskUsage returns the total size of the filesystem containing path, and the space available to unprivileged users
+func diskUsage(path string) (total, available uint64, err error) {
+	var stat syscall.Statfs_t
+
+	if statErr := syscall.Statfs(path, &stat); statErr != nil {
+		return 0, 0, statErr
+	}
+
+	return uint64(stat.Blocks) * uint64(stat.Bsize), uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
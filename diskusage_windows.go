@@ -0,0 +1,26 @@
+package coreutils
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// getDiskFreeSpaceEx is GetDiskFreeSpaceExW from kernel32
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// diskUsage returns the total size of the volume containing path, and the space available to the current user
+func diskUsage(path string) (total, available uint64, err error) {
+	pathPointer, pathErr := syscall.UTF16PtrFromString(path)
+
+	if pathErr != nil {
+		return 0, 0, pathErr
+	}
+
+	result, _, callErr := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(pathPointer)), uintptr(unsafe.Pointer(&available)), uintptr(unsafe.Pointer(&total)), 0)
+
+	if result == 0 { // If the call failed
+		return 0, 0, callErr
+	}
+
+	return total, available, nil
+}
@@ -0,0 +1,260 @@
+package coreutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// DNS record types and classes used by mDNS
+const (
+	dnsTypeA    uint16 = 1
+	dnsTypePTR  uint16 = 12
+	dnsTypeTXT  uint16 = 16
+	dnsTypeAAAA uint16 = 28
+	dnsTypeSRV  uint16 = 33
+	dnsTypeANY  uint16 = 255
+
+	dnsClassIN         uint16 = 1
+	dnsClassCacheFlush uint16 = 0x8000 // Set on records only we answer for, so caches replace rather than add to them
+	dnsFlagResponse    uint16 = 0x8400 // Authoritative answer
+)
+
+// errMalformedDNS is returned when a DNS message can't be parsed
+var errMalformedDNS = errors.New("malformed DNS message")
+
+// dnsQuestion is a question of a DNS message
+type dnsQuestion struct {
+	name  string
+	qtype uint16
+}
+
+// dnsRecord is a resource record of a DNS message. Only the fields of its type are used.
+type dnsRecord struct {
+	name   string
+	rtype  uint16
+	class  uint16
+	ttl    uint32
+	target string   // Target of PTR and SRV records
+	port   uint16   // Port of SRV records
+	txt    []string // Strings of TXT records
+	ip     net.IP   // Address of A and AAAA records
+}
+
+// dnsMessage is a DNS message. Answers, authority, and additional records are all kept in answers.
+type dnsMessage struct {
+	id        uint16
+	flags     uint16
+	questions []dnsQuestion
+	answers   []dnsRecord
+}
+
+// pack encodes the message, without name compression
+func (message *dnsMessage) pack() []byte {
+	packed := binary.BigEndian.AppendUint16(nil, message.id)
+	packed = binary.BigEndian.AppendUint16(packed, message.flags)
+	packed = binary.BigEndian.AppendUint16(packed, uint16(len(message.questions)))
+	packed = binary.BigEndian.AppendUint16(packed, uint16(len(message.answers)))
+	packed = append(packed, 0, 0, 0, 0) // No authority or additional records
+
+	for _, question := range message.questions {
+		packed = appendDNSName(packed, question.name)
+		packed = binary.BigEndian.AppendUint16(packed, question.qtype)
+		packed = binary.BigEndian.AppendUint16(packed, dnsClassIN)
+	}
+
+	for _, record := range message.answers {
+		var data []byte
+
+		switch record.rtype {
+		case dnsTypePTR:
+			data = appendDNSName(nil, record.target)
+		case dnsTypeSRV:
+			data = append(make([]byte, 4), byte(record.port>>8), byte(record.port)) // Priority and weight of 0
+			data = appendDNSName(data, record.target)
+		case dnsTypeTXT:
+			for _, text := range record.txt {
+				data = append(append(data, byte(len(text))), text...)
+			}
+		case dnsTypeA:
+			data = record.ip.To4()
+		case dnsTypeAAAA:
+			data = record.ip.To16()
+		}
+
+		packed = appendDNSName(packed, record.name)
+		packed = binary.BigEndian.AppendUint16(packed, record.rtype)
+		packed = binary.BigEndian.AppendUint16(packed, record.class)
+		packed = binary.BigEndian.AppendUint32(packed, record.ttl)
+		packed = binary.BigEndian.AppendUint16(packed, uint16(len(data)))
+		packed = append(packed, data...)
+	}
+
+	return packed
+}
+
+// parseDNSMessage decodes a DNS message, skipping the data of record types we don't use
+func parseDNSMessage(packed []byte) (*dnsMessage, error) {
+	if len(packed) < 12 {
+		return nil, errMalformedDNS
+	}
+
+	message := &dnsMessage{id: binary.BigEndian.Uint16(packed), flags: binary.BigEndian.Uint16(packed[2:])}
+	questionCount := int(binary.BigEndian.Uint16(packed[4:]))
+	recordCount := int(binary.BigEndian.Uint16(packed[6:])) + int(binary.BigEndian.Uint16(packed[8:])) + int(binary.BigEndian.Uint16(packed[10:]))
+	offset := 12
+
+	for question := 0; question < questionCount; question++ {
+		name, next, nameErr := readDNSName(packed, offset)
+
+		if nameErr != nil || next+4 > len(packed) {
+			return nil, errMalformedDNS
+		}
+
+		message.questions = append(message.questions, dnsQuestion{name: name, qtype: binary.BigEndian.Uint16(packed[next:])})
+		offset = next + 4
+	}
+
+	for record := 0; record < recordCount; record++ {
+		name, next, nameErr := readDNSName(packed, offset)
+
+		if nameErr != nil || next+10 > len(packed) {
+			return nil, errMalformedDNS
+		}
+
+		record := dnsRecord{
+			name:  name,
+			rtype: binary.BigEndian.Uint16(packed[next:]),
+			class: binary.BigEndian.Uint16(packed[next+2:]),
+			ttl:   binary.BigEndian.Uint32(packed[next+4:]),
+		}
+
+		dataStart := next + 10
+		dataEnd := dataStart + int(binary.BigEndian.Uint16(packed[next+8:]))
+
+		if dataEnd > len(packed) {
+			return nil, errMalformedDNS
+		}
+
+		data := packed[dataStart:dataEnd]
+		var dataErr error
+
+		switch record.rtype {
+		case dnsTypePTR:
+			record.target, _, dataErr = readDNSName(packed, dataStart)
+		case dnsTypeSRV:
+			if len(data) < 7 {
+				return nil, errMalformedDNS
+			}
+
+			record.port = binary.BigEndian.Uint16(data[4:])
+			record.target, _, dataErr = readDNSName(packed, dataStart+6)
+		case dnsTypeTXT:
+			for position := 0; position < len(data); position += int(data[position]) + 1 {
+				if position+1+int(data[position]) > len(data) {
+					return nil, errMalformedDNS
+				}
+
+				record.txt = append(record.txt, string(data[position+1:position+1+int(data[position])]))
+			}
+		case dnsTypeA, dnsTypeAAAA:
+			record.ip = append(net.IP{}, data...)
+		}
+
+		if dataErr != nil {
+			return nil, dataErr
+		}
+
+		message.answers = append(message.answers, record)
+		offset = dataEnd
+	}
+
+	return message, nil
+}
+
+// appendDNSName appends the name, in which dots within labels are escaped as "\.", in DNS wire format
+func appendDNSName(packed []byte, name string) []byte {
+	for _, label := range splitDNSName(name) {
+		packed = append(append(packed, byte(len(label))), label...)
+	}
+
+	return append(packed, 0)
+}
+
+// splitDNSName splits the name into its labels, unescaping dots within them
+func splitDNSName(name string) []string {
+	var labels []string
+	var label strings.Builder
+
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '\\' && i+1 < len(name):
+			i++
+			label.WriteByte(name[i])
+		case name[i] == '.':
+			labels = append(labels, label.String())
+			label.Reset()
+		default:
+			label.WriteByte(name[i])
+		}
+	}
+
+	if label.Len() != 0 {
+		labels = append(labels, label.String())
+	}
+
+	return labels
+}
+
+// readDNSName reads the name at offset, following compression pointers, and returns the offset after it. Dots and
+// backslashes within labels are escaped.
+func readDNSName(packed []byte, offset int) (string, int, error) {
+	var name strings.Builder
+	next := -1 // Offset after the name, once we've followed a pointer
+
+	for jumps := 0; ; {
+		if offset >= len(packed) {
+			return "", 0, errMalformedDNS
+		}
+
+		length := int(packed[offset])
+
+		switch {
+		case length == 0:
+			if next == -1 {
+				next = offset + 1
+			}
+
+			if name.Len() == 0 { // The root name
+				name.WriteByte('.')
+			}
+
+			return name.String(), next, nil
+		case length&0xC0 == 0xC0: // A pointer to a name earlier in the message
+			if offset+1 >= len(packed) || jumps > 32 { // If the message is truncated or loops
+				return "", 0, errMalformedDNS
+			}
+
+			if next == -1 {
+				next = offset + 2
+			}
+
+			offset = int(binary.BigEndian.Uint16(packed[offset:]) & 0x3FFF)
+			jumps++
+		default:
+			if offset+1+length > len(packed) {
+				return "", 0, errMalformedDNS
+			}
+
+			name.WriteString(strings.NewReplacer(`\`, `\\`, `.`, `\.`).Replace(string(packed[offset+1 : offset+1+length])))
+			name.WriteByte('.')
+			offset += 1 + length
+		}
+	}
+}
+
+// sameDNSName compares two names without regard to case, as DNS does
+func sameDNSName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
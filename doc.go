@@ -0,0 +1,25 @@
+// Package coreutils provides file, directory, archive, and system utilities shared by Strobl Industries projects.
+//
+// # Concurrency
+//
+// Every exported function is safe to call from multiple goroutines at once. Operations never change the working directory,
+// and package-level settings such as those of SetDefaults, SetPathPolicy, SetClock, SetIOAccountant, and SetShareKey are
+// guarded, so changing them while operations run is safe; an operation already running may use either the old or the new
+// setting. Hooks registered with RegisterHook and AddTraceHook, and callbacks such as WithProgress, may be called from several
+// goroutines at once when WithWorkers is above 1, so they must be safe for concurrent use themselves.
+//
+// Values are safe for concurrent use where documented, such as HashIndex and IOCounter. Others, such as ArchiveWriter and
+// ArchiveReader, must only be used by one goroutine at a time. The deprecated GlobalFileMode and NonGlobalFileMode variables
+// are not guarded and must not be modified while other goroutines use the package.
+//
+// Concurrent operations on the same paths are safe for the package, but the resulting files are whatever the filesystem
+// makes of the interleaved writes. Functions which change the process itself, such as DropPrivileges and PrependToPath,
+// affect every goroutine.
+//
+// # Layout
+//
+// The package is deliberately one flat package rather than sub-packages such as fsutil, netutil, and archive under a /v2
+// import path. A /v2 path needs the repository to adopt Go modules first, since it is still imported by its GOPATH path, and
+// forwarding wrappers for every exported function would double the API to maintain without changing what it does.
+// Sub-packages are kept for optional pieces with their own dependencies or audience: bench, faultfs, metrics, and testutil.
+package coreutils
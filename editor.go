@@ -0,0 +1,88 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// ErrNoEditor is returned when $VISUAL and $EDITOR are unset and none of the fallback editors are installed
+var ErrNoEditor = errors.New("no text editor found")
+
+// EditInEditor will write the initial content to a temporary file with the extension, such as ".md", open it in the user's
+// editor, and return the content once the editor exits, like git does for commit messages. The editor is $VISUAL, then
+// $EDITOR, falling back to nano or vi, or notepad on Windows. The editor may include arguments, such as "code --wait".
+func EditInEditor(initialContent []byte, extension string) ([]byte, error) {
+	command, commandErr := editorCommand()
+
+	if commandErr != nil {
+		return nil, commandErr
+	}
+
+	file, createErr := os.CreateTemp("", "edit-*"+extension)
+
+	if createErr != nil {
+		return nil, errors.New("Failed to create a temporary file: " + createErr.Error())
+	}
+
+	path := file.Name()
+	defer os.Remove(path)
+
+	_, writeErr := file.Write(initialContent)
+
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	if writeErr != nil {
+		return nil, writeError(path, "Failed to write "+path, writeErr)
+	}
+
+	command.Args = append(command.Args, path)
+	command.Stdout, command.Stderr = os.Stdout, os.Stderr // The editor needs the terminal
+	runner, _ := command.prepare()
+	runner.Stdin = os.Stdin
+
+	if runErr := runner.Run(); runErr != nil { // If the editor failed, or the user aborted it
+		return nil, errors.New("The editor failed: " + runErr.Error())
+	}
+
+	content, readErr := os.ReadFile(path)
+
+	if readErr != nil {
+		return nil, readError(path, readErr)
+	}
+
+	return content, nil
+}
+
+// editorCommand returns the command running the user's editor, to which the file to edit is appended
+func editorCommand() (Command, error) {
+	for _, variable := range []string{"VISUAL", "EDITOR"} {
+		editor := strings.TrimSpace(os.Getenv(variable))
+
+		if editor == "" {
+			continue
+		}
+
+		if runtime.GOOS == "windows" {
+			fields := strings.Fields(editor)
+			return Command{Name: fields[0], Args: fields[1:]}, nil
+		}
+
+		return Command{Name: "sh", Args: []string{"-c", editor + ` "$1"`, "sh"}}, nil // Let the shell handle any quoting in the editor, as git does
+	}
+
+	fallbacks := []Command{{Name: "nano"}, {Name: "vi"}}
+
+	if runtime.GOOS == "windows" {
+		fallbacks = []Command{{Name: "notepad"}}
+	}
+
+	if command, findErr := firstAvailableCommand(fallbacks...); findErr == nil {
+		return command, nil
+	}
+
+	return Command{}, ErrNoEditor
+}
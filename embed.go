@@ -0,0 +1,110 @@
+package coreutils
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ExtractFSOptions are the options used by ExtractEmbeddedFS
+type ExtractFSOptions struct {
+	// FileMode is the mode files are written with. Defaults to Defaults.DefaultFileMode.
+	FileMode os.FileMode
+
+	// DirMode is the mode directories are created with. Defaults to Defaults.DefaultDirMode.
+	DirMode os.FileMode
+
+	// Modes maps glob patterns, matched against the file name, to the mode files matching the pattern are written with. Ex: "*.sh" to 0755.
+	// When several patterns match, the first in sorted order is used, so the result doesn't vary between runs.
+	Modes map[string]os.FileMode
+
+	// OnlyIfChanged will skip writing files whose content on disk is already identical
+	OnlyIfChanged bool
+}
+
+// ExtractEmbeddedFS will write the contents of root within fsys, such as an embed.FS, to the dst directory
+func ExtractEmbeddedFS(fsys fs.FS, root, dst string, opts ExtractFSOptions) error {
+	start := time.Now()
+	extractErr := extractEmbeddedFS(fsys, root, dst, opts)
+	trace(TraceEvent{Op: "ExtractEmbeddedFS", Path: root, Destination: dst, Duration: time.Since(start), Err: extractErr})
+	return extractErr
+}
+
+// extractEmbeddedFS writes the contents of root within fsys to the dst directory
+func extractEmbeddedFS(fsys fs.FS, root, dst string, opts ExtractFSOptions) error {
+	defaults := GetDefaults()
+
+	if opts.FileMode == 0 { // If no file mode was provided
+		opts.FileMode = defaults.DefaultFileMode
+	}
+
+	if opts.DirMode == 0 { // If no directory mode was provided
+		opts.DirMode = defaults.DefaultDirMode
+	}
+
+	if root == "" {
+		root = "."
+	}
+
+	patterns := make([]string, 0, len(opts.Modes))
+
+	for pattern := range opts.Modes {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Strings(patterns) // Map iteration is random, so patterns are matched in sorted order
+
+	return fs.WalkDir(fsys, root, func(name string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil { // If we failed to read part of the embedded filesystem
+			return walkErr
+		}
+
+		relativeName := name
+
+		if root != "." { // If we are extracting a sub-tree, strip the root from the name
+			relativeName = name[len(root):]
+		}
+
+		destinationPath := filepath.Join(dst, filepath.FromSlash(relativeName))
+
+		if entry.IsDir() { // If this is a directory
+			if policyErr := checkPathPolicy(destinationPath, true); policyErr != nil {
+				return policyErr
+			}
+
+			if mkdirErr := os.MkdirAll(destinationPath, opts.DirMode); mkdirErr != nil {
+				return writeError(destinationPath, "Failed to create "+destinationPath, mkdirErr)
+			}
+
+			return nil
+		}
+
+		content, readErr := fs.ReadFile(fsys, name)
+
+		if readErr != nil { // If we failed to read the embedded file
+			return readError(name, readErr)
+		}
+
+		fileMode := opts.FileMode
+
+		for _, pattern := range patterns { // For each mode mapping
+			if matched, _ := path.Match(pattern, path.Base(name)); matched {
+				fileMode = opts.Modes[pattern]
+				break
+			}
+		}
+
+		if opts.OnlyIfChanged { // If we should only write files that differ
+			if existingContent, existingErr := os.ReadFile(destinationPath); existingErr == nil && bytes.Equal(existingContent, content) {
+				os.Chmod(destinationPath, fileMode) // Still ensure the mode matches what was requested
+				return nil
+			}
+		}
+
+		return WriteOrUpdateFile(destinationPath, content, fileMode)
+	})
+}
@@ -0,0 +1,36 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExtractEmbeddedFSModesAreDeterministic(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't keep execute permissions")
+	}
+
+	fsys := fstest.MapFS{"run.sh": {Data: []byte("#!/bin/sh\n")}}
+	modes := map[string]os.FileMode{"run*": 0700, "*.sh": 0755, "r*": 0750} // All match, "*.sh" sorts first
+
+	for attempt := 0; attempt < 20; attempt++ { // Map iteration order changes between ranges, so repeat to catch it
+		dst := t.TempDir()
+
+		if extractErr := ExtractEmbeddedFS(fsys, "", dst, ExtractFSOptions{Modes: modes}); extractErr != nil {
+			t.Fatalf("ExtractEmbeddedFS failed: %v", extractErr)
+		}
+
+		info, statErr := os.Stat(filepath.Join(dst, "run.sh"))
+
+		if statErr != nil {
+			t.Fatalf("run.sh wasn't extracted: %v", statErr)
+		}
+
+		if info.Mode().Perm() != 0755 {
+			t.Fatalf("expected run.sh to have the mode of the first sorted pattern, 0755, got %v", info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// pathLock serializes changes to PATH, so concurrent calls to PrependToPath don't lose or duplicate a directory
+var pathLock sync.Mutex
+
+// Shell is a shell, or other source of the login environment, whose configuration PersistPathChange can edit
+type Shell int
+
+const (
+	// ShellPOSIX edits ~/.profile, which is read by sh, dash, and login shells
+	ShellPOSIX Shell = iota
+
+	// ShellBash edits ~/.bashrc
+	ShellBash
+
+	// ShellZsh edits .zshrc in $ZDOTDIR, or the home directory
+	ShellZsh
+
+	// ShellFish edits config.fish in the fish configuration directory
+	ShellFish
+
+	// ShellWindows edits the PATH of the user's environment in the Windows registry
+	ShellWindows
+)
+
+// DetectShell will return the Shell of the current user, based on $SHELL, or ShellWindows on Windows
+func DetectShell() Shell {
+	if runtime.GOOS == "windows" {
+		return ShellWindows
+	}
+
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "bash":
+		return ShellBash
+	case "zsh":
+		return ShellZsh
+	case "fish":
+		return ShellFish
+	default:
+		return ShellPOSIX
+	}
+}
+
+// PathContains will check if the directory is one of the entries of PATH. Entries are compared as cleaned paths, and
+// without regard to case on Windows.
+func PathContains(dir string) bool {
+	dir = filepath.Clean(dir)
+
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if entry == "" {
+			continue
+		}
+
+		if entry = filepath.Clean(entry); entry == dir || (runtime.GOOS == "windows" && strings.EqualFold(entry, dir)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrependToPath will put the directory at the front of PATH for this process and the commands it runs, unless it is already in PATH.
+// Use PersistPathChange to keep it in PATH for future sessions.
+func PrependToPath(dir string) error {
+	pathLock.Lock()
+	defer pathLock.Unlock()
+
+	if PathContains(dir) {
+		return nil
+	}
+
+	return os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// PersistPathChange will add the directory to the front of PATH in future sessions of the shell, by appending to its
+// configuration file or, for ShellWindows, editing the user's environment in the registry. Nothing is changed if the
+// directory was already added. The current process is unaffected, see PrependToPath.
+func PersistPathChange(dir string, shell Shell) error {
+	if shell == ShellWindows {
+		return persistWindowsPath(dir)
+	}
+
+	homeDirectory, homeErr := os.UserHomeDir()
+
+	if homeErr != nil {
+		return homeErr
+	}
+
+	var profile, line string
+	exportLine := `export PATH="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`").Replace(dir) + `:$PATH"`
+
+	switch shell {
+	case ShellPOSIX:
+		profile, line = filepath.Join(homeDirectory, ".profile"), exportLine
+	case ShellBash:
+		profile, line = filepath.Join(homeDirectory, ".bashrc"), exportLine
+	case ShellZsh:
+		zshDirectory := os.Getenv("ZDOTDIR")
+
+		if zshDirectory == "" {
+			zshDirectory = homeDirectory
+		}
+
+		profile, line = filepath.Join(zshDirectory, ".zshrc"), exportLine
+	case ShellFish:
+		profile = filepath.Join(xdgConfigDirectory(ScopeUser), "fish", "config.fish")
+		line = "fish_add_path --prepend '" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(dir) + "'"
+	default:
+		return errors.New("Unknown shell " + fmt.Sprint(int(shell)))
+	}
+
+	return appendLineOnce(profile, line)
+}
+
+// appendLineOnce appends the line to the file, creating it if needed, unless the file already has the line
+func appendLineOnce(path, line string) error {
+	if policyErr := checkPathPolicy(path, true); policyErr != nil {
+		return policyErr
+	}
+
+	content, readErr := os.ReadFile(path)
+
+	if readErr != nil && !errors.Is(readErr, os.ErrNotExist) { // If the file exists but we can't read it
+		return readError(path, readErr)
+	}
+
+	for _, existing := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(existing) == line { // If we've already added it
+			return nil
+		}
+	}
+
+	if len(content) != 0 && content[len(content)-1] != '\n' { // Don't join our line onto the last one
+		line = "\n" + line
+	}
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(path), GetDefaults().DefaultDirMode); mkdirErr != nil {
+		return writeError(path, "Failed to create the path leading up to "+path, mkdirErr)
+	}
+
+	openFiles.acquire()
+	defer openFiles.release()
+
+	file, openErr := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+
+	if openErr != nil {
+		return writeError(path, "Failed to create "+path, openErr)
+	}
+
+	_, writeErr := file.WriteString(line + "\n")
+
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	return writeErr
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package coreutils
+
+import "errors"
+
+// persistWindowsPath returns an error, since there is no Windows registry on this platform
+func persistWindowsPath(dir string) error {
+	return errors.New("ShellWindows is only supported on Windows.")
+}
@@ -0,0 +1,70 @@
+package coreutils
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// sendMessageTimeout is SendMessageTimeoutW from user32
+var sendMessageTimeout = syscall.NewLazyDLL("user32.dll").NewProc("SendMessageTimeoutW")
+
+const (
+	hwndBroadcast   = 0xffff // HWND_BROADCAST, every top-level window
+	wmSettingChange = 0x001a // WM_SETTINGCHANGE
+	smtoAbortIfHung = 0x0002 // SMTO_ABORTIFHUNG, don't wait on windows which aren't responding
+)
+
+// persistWindowsPath prepends the directory to the PATH of the user's environment in the registry, then tells running programs
+// such as Explorer that the environment changed, so programs they start get the new PATH
+func persistWindowsPath(dir string) error {
+	key, openErr := registry.OpenKey(registry.CURRENT_USER, "Environment", registry.QUERY_VALUE|registry.SET_VALUE)
+
+	if openErr != nil { // If we can't edit the user's environment
+		return errors.New("Failed to open the user's environment: " + openErr.Error())
+	}
+
+	defer key.Close()
+
+	if prependErr := prependToRegistryPath(key, dir); prependErr != nil {
+		return prependErr
+	}
+
+	environment, _ := syscall.UTF16PtrFromString("Environment")
+	sendMessageTimeout.Call(hwndBroadcast, wmSettingChange, 0, uintptr(unsafe.Pointer(environment)), smtoAbortIfHung, 5000, 0) // Programs which miss it get the new PATH on the next login
+
+	return nil
+}
+
+// prependToRegistryPath prepends the directory to the Path value of the key, unless it is already an entry. The value is
+// written as REG_EXPAND_SZ, as Windows writes it, so entries such as %USERPROFILE%\go\bin keep being expanded.
+func prependToRegistryPath(key registry.Key, dir string) error {
+	current, _, readErr := key.GetStringValue("Path") // Returned unexpanded, whether REG_SZ or REG_EXPAND_SZ
+
+	if readErr != nil && !errors.Is(readErr, registry.ErrNotExist) { // If there is a PATH we can't read
+		return errors.New("Failed to read the user's PATH: " + readErr.Error())
+	}
+
+	entries := []string{dir}
+
+	for _, entry := range strings.Split(current, ";") {
+		if entry == "" {
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimRight(entry, `\`), strings.TrimRight(dir, `\`)) { // If we've already added it
+			return nil
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if setErr := key.SetExpandStringValue("Path", strings.Join(entries, ";")); setErr != nil {
+		return errors.New("Failed to change the user's PATH: " + setErr.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package coreutils
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestPrependToRegistryPathKeepsExpandableEntries(t *testing.T) {
+	keyPath := `Software\coreutils-test-` + t.Name()
+	key, _, createErr := registry.CreateKey(registry.CURRENT_USER, keyPath, registry.QUERY_VALUE|registry.SET_VALUE)
+
+	if createErr != nil {
+		t.Fatal(createErr)
+	}
+
+	defer registry.DeleteKey(registry.CURRENT_USER, keyPath)
+	defer key.Close()
+
+	if setErr := key.SetStringValue("Path", `%USERPROFILE%\go\bin;C:\Tools`); setErr != nil { // Written as REG_SZ, as some installers do
+		t.Fatal(setErr)
+	}
+
+	for _, dir := range []string{`C:\coreutils`, `c:\COREUTILS\`} { // The second is the same directory, so nothing changes
+		if prependErr := prependToRegistryPath(key, dir); prependErr != nil {
+			t.Fatal(prependErr)
+		}
+	}
+
+	value, valueType, readErr := key.GetStringValue("Path")
+
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+
+	if valueType != registry.EXPAND_SZ {
+		t.Errorf("expected PATH to be written as REG_EXPAND_SZ, got type %d", valueType)
+	}
+
+	if expected := `C:\coreutils;%USERPROFILE%\go\bin;C:\Tools`; value != expected {
+		t.Errorf("expected PATH to be %s, got %s", expected, value)
+	}
+}
@@ -0,0 +1,94 @@
+package coreutils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+var (
+	// ErrNotExist is the Kind of an IOError for a path which does not exist. Such errors also match fs.ErrNotExist.
+	ErrNotExist = errors.New("does not exist")
+
+	// ErrNotDirectory is the Kind of an IOError for a path which should be a directory but isn't
+	ErrNotDirectory = errors.New("not a directory")
+
+	// ErrReadFailed is the Kind of an IOError for a path which exists but could not be opened or read, such as for lack of permission
+	ErrReadFailed = errors.New("read failed")
+
+	// ErrWriteFailed is the Kind of an IOError for a path which could not be created or written
+	ErrWriteFailed = errors.New("write failed")
+)
+
+// IOError describes an I/O failure on a path. It matches both its Kind and the underlying error with errors.Is, so callers
+// can tell a missing source (ErrNotExist) from a permission problem (fs.ErrPermission) or a failed write (ErrWriteFailed).
+type IOError struct {
+	Kind    error  // ErrNotExist, ErrNotDirectory, ErrReadFailed, or ErrWriteFailed
+	Path    string // Path the operation failed on
+	Err     error  // Underlying error, such as an *os.PathError, if there is one
+	message string
+}
+
+// Error returns the description of the failure
+func (ioError *IOError) Error() string {
+	return ioError.message
+}
+
+// Unwrap returns the Kind and the underlying error, so errors.Is and errors.As match either
+func (ioError *IOError) Unwrap() []error {
+	if ioError.Err == nil {
+		return []error{ioError.Kind}
+	}
+
+	return []error{ioError.Kind, ioError.Err}
+}
+
+// openError describes a failure to open, stat, or read the path as an input. A path which doesn't exist is ErrNotExist, while
+// anything else, such as a lack of permission, is ErrReadFailed.
+func openError(path string, err error) error {
+	if err == nil || errors.Is(err, fs.ErrNotExist) { // If the path is missing, rather than unreadable
+		if err == nil {
+			err = fs.ErrNotExist
+		}
+
+		return &IOError{Kind: ErrNotExist, Path: path, Err: err, message: path + " does not exist."}
+	}
+
+	return readError(path, err)
+}
+
+// notDirectoryError describes the path not being a directory. If err says the path is actually missing or unreadable, or
+// err is nil and the path turns out to be, that is described instead.
+func notDirectoryError(path string, err error) error {
+	if err == nil { // Find out whether there's anything there
+		_, err = os.Stat(path)
+	}
+
+	if err != nil && !errors.Is(err, syscall.ENOTDIR) { // If the path is missing or unreadable, rather than not a directory
+		return openError(path, err)
+	}
+
+	return &IOError{Kind: ErrNotDirectory, Path: path, Err: err, message: path + " is not a directory."}
+}
+
+// readError describes a failure to read the path
+func readError(path string, err error) error {
+	message := "Unable to read: " + path
+
+	if err != nil {
+		message += ": " + err.Error()
+	}
+
+	return &IOError{Kind: ErrReadFailed, Path: path, Err: err, message: message}
+}
+
+// writeError describes a failure to create or write the path, with message saying what was being done, such as "Failed to create "
+// and the path
+func writeError(path, message string, err error) error {
+	if err != nil {
+		message += ": " + err.Error()
+	}
+
+	return &IOError{Kind: ErrWriteFailed, Path: path, Err: err, message: message}
+}
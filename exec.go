@@ -5,11 +5,13 @@ import (
 	"os/exec"
 )
 
-// ExecCommand executes a command with args and returning the stringified output
+// ExecCommand executes a command with args and returning the stringified output. Scripts are run through the interpreter in their shebang on Windows.
 func ExecCommand(command string, args []string, redirect bool) string {
-	if ExecutableExists(command) { // If the executable exists
+	name, args := scriptCommand(command, args)
+
+	if ExecutableExists(name) { // If the executable exists
 		var output []byte
-		runner := exec.Command(command, args...)
+		runner := exec.Command(name, args...)
 
 		if redirect { // If we should redirect output to var
 			output, _ = runner.CombinedOutput() // Combine the output of stderr and stdout
@@ -0,0 +1,383 @@
+// Package faultfs wraps a coreutils.Filesystem to inject failures deterministically, so consumers can test how their code
+// handles permission errors, full disks, and short reads without needing a broken system to reproduce them.
+//
+// Faults are described up front and fire on exact calls, so a test fails the same way every run:
+//
+//	fs := faultfs.New(coreutils.OSFilesystem{}, faultfs.FailNth(faultfs.OpWrite, 3, syscall.EACCES), faultfs.DiskFull(1<<20))
+package faultfs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/StroblIndustries/coreutils"
+)
+
+// Op is a kind of Filesystem call faults can be injected into
+type Op int
+
+const (
+	// OpOpen is Open and OpenFile
+	OpOpen Op = iota + 1
+
+	// OpRead is Read on an open File, ReadFile, and ReadDir
+	OpRead
+
+	// OpWrite is Write on an open File, and WriteFile
+	OpWrite
+
+	// OpStat is Stat and Lstat
+	OpStat
+
+	// OpMkdir is Mkdir and MkdirAll
+	OpMkdir
+
+	// OpRemove is Remove and RemoveAll
+	OpRemove
+
+	// OpRename is Rename
+	OpRename
+
+	// OpSymlink is Symlink and Readlink
+	OpSymlink
+
+	// OpClose is Close and Sync on an open File
+	OpClose
+)
+
+// String returns the name of the operation, as used in the Op of the errors returned
+func (op Op) String() string {
+	switch op {
+	case OpOpen:
+		return "open"
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	case OpStat:
+		return "stat"
+	case OpMkdir:
+		return "mkdir"
+	case OpRemove:
+		return "remove"
+	case OpRename:
+		return "rename"
+	case OpSymlink:
+		return "symlink"
+	case OpClose:
+		return "close"
+	default:
+		return "unknown"
+	}
+}
+
+// Fault configures a failure for New to inject
+type Fault func(fs *FS)
+
+// rule fails calls of an operation, optionally only on matching paths
+type rule struct {
+	op      Op
+	nth     int    // Matching call to fail, counting from 1, or 0 to fail every matching call
+	pattern string // filepath.Match pattern the path or its base name must match, or empty for any path
+	err     error
+	calls   int // Number of matching calls so far
+}
+
+// FS is a coreutils.Filesystem which passes calls through to another Filesystem, failing those its faults describe
+type FS struct {
+	fs        coreutils.Filesystem
+	lock      sync.Mutex
+	rules     []*rule
+	shortRead int   // Most bytes a single Read returns, or 0 for no limit
+	spaceLeft int64 // Bytes which can still be written before writes fail with ENOSPC, or -1 for no limit
+}
+
+// FailNth fails the nth call of the operation, counting from 1, with err. The error is wrapped in an *os.PathError, so
+// errors.Is(err, fs.ErrPermission) and the like work as they would for a real failure.
+func FailNth(op Op, n int, err error) Fault {
+	return func(fs *FS) {
+		fs.rules = append(fs.rules, &rule{op: op, nth: n, err: err})
+	}
+}
+
+// FailPath fails every call of the operation on a path matching the filepath.Match pattern, compared against both the
+// whole path and its base name, with err
+func FailPath(op Op, pattern string, err error) Fault {
+	return func(fs *FS) {
+		fs.rules = append(fs.rules, &rule{op: op, pattern: pattern, err: err})
+	}
+}
+
+// ShortReads limits each Read on an open File to at most max bytes, as pipes and network filesystems may, to catch code
+// which assumes a single Read fills the buffer
+func ShortReads(max int) Fault {
+	return func(fs *FS) {
+		fs.shortRead = max
+	}
+}
+
+// DiskFull lets capacity bytes be written in total across every file, after which writes write what fits and fail with ENOSPC
+func DiskFull(capacity int64) Fault {
+	return func(fs *FS) {
+		fs.spaceLeft = capacity
+	}
+}
+
+// New will wrap fs, injecting the faults provided into the calls made through it
+func New(fs coreutils.Filesystem, faults ...Fault) *FS {
+	faultFS := &FS{fs: fs, spaceLeft: -1}
+
+	for _, fault := range faults {
+		fault(faultFS)
+	}
+
+	return faultFS
+}
+
+// fault counts the call against each rule for the operation, returning the error of the first rule which fails it
+func (fs *FS) fault(op Op, name string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	var faultErr error
+
+	for _, rule := range fs.rules {
+		if rule.op != op || !rule.matches(name) {
+			continue
+		}
+
+		rule.calls++
+
+		if faultErr == nil && (rule.nth == 0 || rule.calls == rule.nth) { // If this is a call the rule fails
+			faultErr = &os.PathError{Op: op.String(), Path: name, Err: rule.err}
+		}
+	}
+
+	return faultErr
+}
+
+// reserve takes up to size bytes of the remaining space, returning how many can be written
+func (fs *FS) reserve(size int) int {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	if fs.spaceLeft < 0 { // If there's no limit
+		return size
+	}
+
+	if int64(size) > fs.spaceLeft {
+		size = int(fs.spaceLeft)
+	}
+
+	fs.spaceLeft -= int64(size)
+
+	return size
+}
+
+// matches checks if the rule applies to the path
+func (rule *rule) matches(name string) bool {
+	if rule.pattern == "" {
+		return true
+	}
+
+	wholeMatch, _ := filepath.Match(rule.pattern, name)
+	baseMatch, _ := filepath.Match(rule.pattern, filepath.Base(name))
+
+	return wholeMatch || baseMatch
+}
+
+// Open opens the named file for reading
+func (fs *FS) Open(name string) (coreutils.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file with the flags and permissions provided
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (coreutils.File, error) {
+	if faultErr := fs.fault(OpOpen, name); faultErr != nil {
+		return nil, faultErr
+	}
+
+	file, openErr := fs.fs.OpenFile(name, flag, perm)
+
+	if openErr != nil {
+		return nil, openErr
+	}
+
+	return &faultFile{File: file, fs: fs}, nil
+}
+
+// Stat returns the FileInfo of the named file, following symlinks
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	if faultErr := fs.fault(OpStat, name); faultErr != nil {
+		return nil, faultErr
+	}
+
+	return fs.fs.Stat(name)
+}
+
+// Lstat returns the FileInfo of the named file, without following symlinks
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	if faultErr := fs.fault(OpStat, name); faultErr != nil {
+		return nil, faultErr
+	}
+
+	return fs.fs.Lstat(name)
+}
+
+// ReadDir reads the entries of the named directory
+func (fs *FS) ReadDir(name string) ([]os.DirEntry, error) {
+	if faultErr := fs.fault(OpRead, name); faultErr != nil {
+		return nil, faultErr
+	}
+
+	return fs.fs.ReadDir(name)
+}
+
+// ReadFile reads the content of the named file
+func (fs *FS) ReadFile(name string) ([]byte, error) {
+	if faultErr := fs.fault(OpRead, name); faultErr != nil {
+		return nil, faultErr
+	}
+
+	return fs.fs.ReadFile(name)
+}
+
+// WriteFile writes data to the named file, creating it if necessary. When the disk is full, only what fits is written.
+func (fs *FS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if faultErr := fs.fault(OpWrite, name); faultErr != nil {
+		return faultErr
+	}
+
+	fits := fs.reserve(len(data))
+
+	if writeErr := fs.fs.WriteFile(name, data[:fits], perm); writeErr != nil {
+		return writeErr
+	}
+
+	if fits < len(data) { // If the disk filled up
+		return &os.PathError{Op: OpWrite.String(), Path: name, Err: syscall.ENOSPC}
+	}
+
+	return nil
+}
+
+// Mkdir creates the named directory
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	if faultErr := fs.fault(OpMkdir, name); faultErr != nil {
+		return faultErr
+	}
+
+	return fs.fs.Mkdir(name, perm)
+}
+
+// MkdirAll creates the named directory along with any parents
+func (fs *FS) MkdirAll(name string, perm os.FileMode) error {
+	if faultErr := fs.fault(OpMkdir, name); faultErr != nil {
+		return faultErr
+	}
+
+	return fs.fs.MkdirAll(name, perm)
+}
+
+// Remove removes the named file or empty directory
+func (fs *FS) Remove(name string) error {
+	if faultErr := fs.fault(OpRemove, name); faultErr != nil {
+		return faultErr
+	}
+
+	return fs.fs.Remove(name)
+}
+
+// RemoveAll removes the named path and any children it contains
+func (fs *FS) RemoveAll(name string) error {
+	if faultErr := fs.fault(OpRemove, name); faultErr != nil {
+		return faultErr
+	}
+
+	return fs.fs.RemoveAll(name)
+}
+
+// Rename renames oldName to newName. Path patterns are matched against oldName.
+func (fs *FS) Rename(oldName, newName string) error {
+	if faultErr := fs.fault(OpRename, oldName); faultErr != nil {
+		return faultErr
+	}
+
+	return fs.fs.Rename(oldName, newName)
+}
+
+// Symlink creates name as a symlink to target
+func (fs *FS) Symlink(target, name string) error {
+	if faultErr := fs.fault(OpSymlink, name); faultErr != nil {
+		return faultErr
+	}
+
+	return fs.fs.Symlink(target, name)
+}
+
+// Readlink returns the target of the named symlink
+func (fs *FS) Readlink(name string) (string, error) {
+	if faultErr := fs.fault(OpSymlink, name); faultErr != nil {
+		return "", faultErr
+	}
+
+	return fs.fs.Readlink(name)
+}
+
+// faultFile is a File opened through an FS, injecting its faults into reads, writes, and closes
+type faultFile struct {
+	coreutils.File
+	fs *FS
+}
+
+// Read reads from the file, returning at most the short read limit
+func (file *faultFile) Read(p []byte) (int, error) {
+	if faultErr := file.fs.fault(OpRead, file.Name()); faultErr != nil {
+		return 0, faultErr
+	}
+
+	if file.fs.shortRead > 0 && len(p) > file.fs.shortRead { // If reads should come up short
+		p = p[:file.fs.shortRead]
+	}
+
+	return file.File.Read(p)
+}
+
+// Write writes to the file. When the disk is full, only what fits is written.
+func (file *faultFile) Write(p []byte) (int, error) {
+	if faultErr := file.fs.fault(OpWrite, file.Name()); faultErr != nil {
+		return 0, faultErr
+	}
+
+	fits := file.fs.reserve(len(p))
+	written, writeErr := file.File.Write(p[:fits])
+
+	if writeErr == nil && fits < len(p) { // If the disk filled up
+		writeErr = &os.PathError{Op: OpWrite.String(), Path: file.Name(), Err: syscall.ENOSPC}
+	}
+
+	return written, writeErr
+}
+
+// Sync commits the content of the file to storage
+func (file *faultFile) Sync() error {
+	if faultErr := file.fs.fault(OpClose, file.Name()); faultErr != nil {
+		return faultErr
+	}
+
+	return file.File.Sync()
+}
+
+// Close closes the file. The underlying file is closed even when a fault is injected, so tests don't leak descriptors.
+func (file *faultFile) Close() error {
+	faultErr := file.fs.fault(OpClose, file.Name())
+	closeErr := file.File.Close()
+
+	if faultErr != nil {
+		return faultErr
+	}
+
+	return closeErr
+}
@@ -0,0 +1,54 @@
+package coreutils
+
+import (
+	"sync"
+)
+
+// fileBudget limits how many files the package holds open at once, across every goroutine
+type fileBudget struct {
+	lock      sync.Mutex
+	available *sync.Cond
+	open      int
+}
+
+// openFiles is the package-wide budget of open files, bounded by Defaults.MaxOpenFiles
+var openFiles = newFileBudget()
+
+// newFileBudget creates an empty fileBudget
+func newFileBudget() *fileBudget {
+	budget := &fileBudget{}
+	budget.available = sync.NewCond(&budget.lock)
+	return budget
+}
+
+// acquire waits until opening another file would stay within Defaults.MaxOpenFiles. Each goroutine must only hold one
+// acquisition at a time, releasing it before acquiring again, or it may wait forever.
+func (budget *fileBudget) acquire() {
+	budget.acquireMany(1)
+}
+
+// acquireMany waits until opening count more files at once would stay within Defaults.MaxOpenFiles. If count alone exceeds
+// the limit, it waits until no other files are open instead. The same rules as acquire apply.
+func (budget *fileBudget) acquireMany(count int) {
+	budget.lock.Lock()
+
+	for budget.open > 0 && budget.open+count > GetDefaults().MaxOpenFiles { // Wait for other goroutines to release files, or the limit to be raised
+		budget.available.Wait()
+	}
+
+	budget.open += count
+	budget.lock.Unlock()
+}
+
+// release returns a file to the budget once it has been closed
+func (budget *fileBudget) release() {
+	budget.releaseMany(1)
+}
+
+// releaseMany returns count files to the budget once they have been closed
+func (budget *fileBudget) releaseMany(count int) {
+	budget.lock.Lock()
+	budget.open -= count
+	budget.lock.Unlock()
+	budget.available.Broadcast() // Wake every waiter, since those acquiring many files may not fit where a single file would
+}
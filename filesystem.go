@@ -0,0 +1,150 @@
+package coreutils
+
+import (
+	"io"
+	"os"
+)
+
+// File is an open file returned by a Filesystem
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	Sync() error
+}
+
+// Filesystem is the set of file operations used by this package, allowing them to be confined to a root or substituted in tests
+type Filesystem interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(name string, perm os.FileMode) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	Rename(oldName, newName string) error
+	Symlink(target, name string) error
+	Readlink(name string) (string, error)
+}
+
+// OSFilesystem is the Filesystem which performs operations directly on the host filesystem
+type OSFilesystem struct{}
+
+// Open opens the named file for reading
+func (OSFilesystem) Open(name string) (File, error) {
+	return OSFilesystem{}.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file with the flags and permissions provided
+func (OSFilesystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if isWriteFlag(flag) { // If the open could modify the file, check the policy allows it
+		if policyErr := checkPathPolicy(name, true); policyErr != nil {
+			return nil, policyErr
+		}
+	}
+
+	file, openErr := os.OpenFile(name, flag, perm)
+
+	if openErr != nil { // Avoid returning a typed nil File
+		return nil, openErr
+	}
+
+	return file, nil
+}
+
+// Stat returns the FileInfo of the named file, following symlinks
+func (OSFilesystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Lstat returns the FileInfo of the named file, without following symlinks
+func (OSFilesystem) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// ReadDir reads the entries of the named directory
+func (OSFilesystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// ReadFile reads the content of the named file
+func (OSFilesystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// WriteFile writes data to the named file, creating it if necessary
+func (OSFilesystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if policyErr := checkPathPolicy(name, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.WriteFile(name, data, perm)
+}
+
+// Mkdir creates the named directory
+func (OSFilesystem) Mkdir(name string, perm os.FileMode) error {
+	if policyErr := checkPathPolicy(name, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Mkdir(name, perm)
+}
+
+// MkdirAll creates the named directory along with any parents
+func (OSFilesystem) MkdirAll(name string, perm os.FileMode) error {
+	if policyErr := checkPathPolicy(name, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.MkdirAll(name, perm)
+}
+
+// Remove removes the named file or empty directory
+func (OSFilesystem) Remove(name string) error {
+	if policyErr := checkPathPolicy(name, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Remove(name)
+}
+
+// RemoveAll removes the named path and any children it contains, if the policy allows every one of them to be removed
+func (OSFilesystem) RemoveAll(name string) error {
+	if policyErr := checkTreePolicy(name); policyErr != nil {
+		return policyErr
+	}
+
+	return os.RemoveAll(name)
+}
+
+// Rename renames oldName to newName
+func (OSFilesystem) Rename(oldName, newName string) error {
+	if policyErr := checkPathPolicy(oldName, true); policyErr != nil {
+		return policyErr
+	} else if policyErr = checkPathPolicy(newName, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Rename(oldName, newName)
+}
+
+// Symlink creates name as a symlink to target
+func (OSFilesystem) Symlink(target, name string) error {
+	if policyErr := checkPathPolicy(name, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Symlink(target, name)
+}
+
+// Readlink returns the target of the named symlink
+func (OSFilesystem) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
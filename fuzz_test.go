@@ -0,0 +1,25 @@
+package coreutils_test
+
+import (
+	"testing"
+
+	"github.com/StroblIndustries/coreutils/testutil"
+)
+
+// Run these with go test -fuzz=FuzzName. Plain go test runs just their seeds.
+
+func FuzzAbsPath(f *testing.F) {
+	testutil.FuzzAbsPath(f)
+}
+
+func FuzzSecureJoin(f *testing.F) {
+	testutil.FuzzSecureJoin(f)
+}
+
+func FuzzIgnoreMatcher(f *testing.F) {
+	testutil.FuzzIgnoreMatcher(f)
+}
+
+func FuzzArchiveExtract(f *testing.F) {
+	testutil.FuzzArchiveExtract(f)
+}
@@ -0,0 +1,187 @@
+package coreutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotGitRepo is returned when a path is not within a git working tree
+var ErrNotGitRepo = errors.New("not a git repository")
+
+// IsGitRepo will check if the path is within a git working tree
+func IsGitRepo(path string) bool {
+	_, rootErr := GitRoot(path)
+	return rootErr == nil
+}
+
+// GitRoot will return the root of the git working tree the path is within, by looking for .git in the path and each
+// directory above it. Worktrees and submodules, whose .git is a file pointing elsewhere, are supported.
+func GitRoot(path string) (string, error) {
+	directory, absErr := filepath.Abs(path)
+
+	if absErr != nil {
+		return "", fmt.Errorf("%s: %w", path, ErrNotGitRepo)
+	}
+
+	if !IsDir(directory) { // If this is a file, start from the directory it is in
+		directory = filepath.Dir(directory)
+	}
+
+	for {
+		if _, statErr := os.Stat(filepath.Join(directory, ".git")); statErr == nil { // If this directory has a repository or a pointer to one
+			return directory, nil
+		}
+
+		parent := filepath.Dir(directory)
+
+		if parent == directory { // If we've reached the root of the filesystem
+			return "", fmt.Errorf("%s: %w", path, ErrNotGitRepo)
+		}
+
+		directory = parent
+	}
+}
+
+// GitCurrentBranch will return the name of the branch checked out in the git working tree the path is within, such as
+// "main". Returns an empty string if HEAD is detached rather than on a branch.
+func GitCurrentBranch(path string) (string, error) {
+	gitDir, _, dirErr := gitDirectories(path)
+
+	if dirErr != nil {
+		return "", dirErr
+	}
+
+	head, readErr := readGitFile(filepath.Join(gitDir, "HEAD"))
+
+	if readErr != nil {
+		return "", readErr
+	}
+
+	if ref, isRef := strings.CutPrefix(head, "ref: "); isRef { // If HEAD points at a branch
+		return strings.TrimPrefix(ref, "refs/heads/"), nil
+	}
+
+	return "", nil
+}
+
+// GitHeadCommit will return the full hash of the commit checked out in the git working tree the path is within. Returns
+// an error if the current branch has no commits yet.
+func GitHeadCommit(path string) (string, error) {
+	gitDir, commonDir, dirErr := gitDirectories(path)
+
+	if dirErr != nil {
+		return "", dirErr
+	}
+
+	ref := "HEAD"
+
+	for depth := 0; depth < 10; depth++ { // Follow symbolic refs, giving up on a cycle
+		refDir := commonDir
+
+		if ref == "HEAD" { // HEAD belongs to the worktree, other refs are shared
+			refDir = gitDir
+		}
+
+		content, readErr := readGitFile(filepath.Join(refDir, filepath.FromSlash(ref)))
+
+		if errors.Is(readErr, os.ErrNotExist) { // If the ref isn't a loose file, it may be packed
+			content, readErr = packedGitRef(commonDir, ref)
+		}
+
+		if readErr != nil {
+			return "", readErr
+		}
+
+		target, isRef := strings.CutPrefix(content, "ref: ")
+
+		if !isRef { // If this is a commit hash
+			return content, nil
+		}
+
+		ref = target
+	}
+
+	return "", errors.New("Too many levels of symbolic refs in " + gitDir)
+}
+
+// gitDirectories returns the git directory of the working tree the path is within, and the common directory holding the
+// refs shared by every worktree of the repository
+func gitDirectories(path string) (gitDir, commonDir string, err error) {
+	root, rootErr := GitRoot(path)
+
+	if rootErr != nil {
+		return "", "", rootErr
+	}
+
+	gitDir = filepath.Join(root, ".git")
+
+	if !IsDir(gitDir) { // If .git is a file pointing at the git directory, as in worktrees and submodules
+		pointer, readErr := readGitFile(gitDir)
+
+		if readErr != nil {
+			return "", "", readErr
+		}
+
+		target, isPointer := strings.CutPrefix(pointer, "gitdir: ")
+
+		if !isPointer {
+			return "", "", errors.New(gitDir + " is not a valid gitdir file.")
+		}
+
+		if !filepath.IsAbs(target) { // Relative targets are relative to the working tree
+			target = filepath.Join(root, target)
+		}
+
+		gitDir = filepath.Clean(target)
+	}
+
+	commonDir = gitDir
+
+	if common, readErr := readGitFile(filepath.Join(gitDir, "commondir")); readErr == nil { // If this is a linked worktree
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+
+		commonDir = filepath.Clean(common)
+	}
+
+	return gitDir, commonDir, nil
+}
+
+// readGitFile reads a small file from a git directory, trimming surrounding whitespace
+func readGitFile(path string) (string, error) {
+	content, readErr := os.ReadFile(path)
+
+	if readErr != nil {
+		return "", readErr
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// packedGitRef looks up the ref in the packed-refs file of the git directory
+func packedGitRef(gitDir, ref string) (string, error) {
+	file, openErr := os.Open(filepath.Join(gitDir, "packed-refs"))
+
+	if openErr != nil { // If the ref doesn't exist at all
+		return "", errors.New(ref + " does not exist in " + gitDir)
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		hash, name, found := strings.Cut(scanner.Text(), " ")
+
+		if found && name == ref { // Comments start with # and peeled tags with ^, so neither has a name after a space
+			return hash, nil
+		}
+	}
+
+	return "", errors.New(ref + " does not exist in " + gitDir)
+}
@@ -0,0 +1,189 @@
+package coreutils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// HashAlgo is a hash algorithm used to compute digests of content as it streams
+type HashAlgo int
+
+const (
+	// HashSHA256 is SHA-256, and the zero value of HashAlgo
+	HashSHA256 HashAlgo = iota
+
+	// HashSHA512 is SHA-512
+	HashSHA512
+
+	// HashSHA1 is SHA-1, only for verifying content against legacy checksums
+	HashSHA1
+
+	// HashMD5 is MD5, only for verifying content against legacy checksums
+	HashMD5
+
+	// HashCRC32 is the IEEE CRC-32 checksum, which detects accidental corruption but not tampering
+	HashCRC32
+)
+
+// String returns the name of the algorithm
+func (algo HashAlgo) String() string {
+	switch algo {
+	case HashSHA512:
+		return "sha512"
+	case HashSHA1:
+		return "sha1"
+	case HashMD5:
+		return "md5"
+	case HashCRC32:
+		return "crc32"
+	default:
+		return "sha256"
+	}
+}
+
+// New creates a hash.Hash for the algorithm. Unknown algorithms use SHA-256.
+func (algo HashAlgo) New() hash.Hash {
+	switch algo {
+	case HashSHA512:
+		return sha512.New()
+	case HashSHA1:
+		return sha1.New()
+	case HashMD5:
+		return md5.New()
+	case HashCRC32:
+		return crc32.NewIEEE()
+	default:
+		return sha256.New()
+	}
+}
+
+// HashingWriter is an io.Writer which computes a digest of everything written through it
+type HashingWriter struct {
+	writer io.Writer
+	hasher hash.Hash
+}
+
+// HashingReader is an io.Reader which computes a digest of everything read through it
+type HashingReader struct {
+	reader io.Reader
+	hasher hash.Hash
+}
+
+// NewHashingWriter creates a HashingWriter which writes to w, hashing the content with algo
+func NewHashingWriter(w io.Writer, algo HashAlgo) *HashingWriter {
+	return &HashingWriter{writer: w, hasher: algo.New()}
+}
+
+// Write writes p to the underlying writer, hashing the bytes that were written
+func (writer *HashingWriter) Write(p []byte) (int, error) {
+	writeCount, writeErr := writer.writer.Write(p)
+	writer.hasher.Write(p[:writeCount])
+	return writeCount, writeErr
+}
+
+// Sum returns the digest of everything written so far
+func (writer *HashingWriter) Sum() []byte {
+	return writer.hasher.Sum(nil)
+}
+
+// HexSum returns the hex encoded digest of everything written so far
+func (writer *HashingWriter) HexSum() string {
+	return hex.EncodeToString(writer.Sum())
+}
+
+// NewHashingReader creates a HashingReader which reads from r, hashing the content with algo
+func NewHashingReader(r io.Reader, algo HashAlgo) *HashingReader {
+	return &HashingReader{reader: r, hasher: algo.New()}
+}
+
+// Read reads from the underlying reader into p, hashing the bytes that were read
+func (reader *HashingReader) Read(p []byte) (int, error) {
+	readCount, readErr := reader.reader.Read(p)
+	reader.hasher.Write(p[:readCount])
+	return readCount, readErr
+}
+
+// Sum returns the digest of everything read so far
+func (reader *HashingReader) Sum() []byte {
+	return reader.hasher.Sum(nil)
+}
+
+// HexSum returns the hex encoded digest of everything read so far
+func (reader *HashingReader) HexSum() string {
+	return hex.EncodeToString(reader.Sum())
+}
+
+// HashFile will return the hex encoded digest of the content of the file at path, reading it a buffer at a time so files of
+// any size can be hashed
+func HashFile(path string, algo HashAlgo) (string, error) {
+	openFiles.acquire()
+	defer openFiles.release()
+
+	return hashFile(path, algo)
+}
+
+// hashFile hashes the file like HashFile, for callers which already hold a slot from the open file budget
+func hashFile(path string, algo HashAlgo) (string, error) {
+	file, openErr := os.Open(path)
+
+	if openErr != nil { // If we failed to open the file
+		return "", openError(path, openErr)
+	}
+
+	defer file.Close()
+
+	hasher := algo.New()
+
+	hashedBytes, copyErr := io.CopyBuffer(hasher, file, make([]byte, GetDefaults().BufferSize))
+	accountRead(IOCategoryHash, hashedBytes)
+
+	if copyErr != nil { // If we failed to read the file
+		return "", readError(path, copyErr)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// HashDirectory will return a hex encoded digest of the files within the directory, combining the slash separated path of each
+// file relative to path with the digest of its content, in sorted order. Two trees have the same digest when they hold the same
+// files with the same content, regardless of where they are or the order they were created in, so it can verify a CopyDirectory.
+// Empty directories, modes, and times are not part of the digest. Honors the Options of GetFiles, such as WithExclude.
+func HashDirectory(path string, algo HashAlgo, opts ...Option) (string, error) {
+	files, listErr := GetFiles(path, true, opts...)
+
+	if listErr != nil { // If we failed to list every file
+		return "", listErr
+	}
+
+	relativePaths := make([]string, 0, len(files))
+
+	for _, file := range files {
+		relativePath, _ := filepath.Rel(path, file)
+		relativePaths = append(relativePaths, filepath.ToSlash(relativePath))
+	}
+
+	sort.Strings(relativePaths) // GetFiles lists each directory's files before its sub-directories, so sort for a stable order
+
+	hasher := algo.New()
+
+	for _, relativePath := range relativePaths { // For each file, combine its path and content digest
+		fileDigest, hashErr := HashFile(filepath.Join(path, filepath.FromSlash(relativePath)), algo)
+
+		if hashErr != nil {
+			return "", hashErr
+		}
+
+		io.WriteString(hasher, relativePath+"\x00"+fileDigest+"\n") // NUL can't appear in a path, so entries can't be confused with each other
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
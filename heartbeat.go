@@ -0,0 +1,79 @@
+package coreutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// Heartbeat is the payload written to a heartbeat file
+type Heartbeat struct {
+	Timestamp time.Time `json:"timestamp"`
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+}
+
+// StartHeartbeat writes a heartbeat to path immediately and then every interval until ctx is canceled.
+// An error is returned if the first heartbeat can not be written.
+func StartHeartbeat(ctx context.Context, path string, interval time.Duration) error {
+	if interval <= 0 { // If the interval would spin
+		return errors.New("The heartbeat interval must be greater than zero.")
+	}
+
+	if writeErr := writeHeartbeat(path); writeErr != nil { // If we can't write the heartbeat at all
+		return writeErr
+	}
+
+	ticker := GetClock().NewTicker(interval) // Created before returning, so a fake clock advanced right after still ticks
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				writeHeartbeat(path) // A failed beat will be noticed by CheckHeartbeat as a stale heartbeat
+			}
+		}
+	}()
+
+	return nil
+}
+
+// CheckHeartbeat reads the heartbeat at path, returning an error if it is missing, invalid, or older than maxAge
+func CheckHeartbeat(path string, maxAge time.Duration) (Heartbeat, error) {
+	var heartbeat Heartbeat
+
+	content, readErr := os.ReadFile(path)
+
+	if readErr != nil { // If there is no heartbeat
+		return heartbeat, openError(path, readErr)
+	}
+
+	if decodeErr := json.Unmarshal(content, &heartbeat); decodeErr != nil { // If the heartbeat is not valid
+		return heartbeat, errors.New(path + " is not a valid heartbeat: " + decodeErr.Error())
+	}
+
+	if age := GetClock().Now().Sub(heartbeat.Timestamp); age > maxAge { // If the heartbeat is too old
+		return heartbeat, errors.New(path + " is stale, the last heartbeat was " + age.Round(time.Second).String() + " ago.")
+	}
+
+	return heartbeat, nil
+}
+
+// writeHeartbeat writes a heartbeat with the current time to path. It is written atomically, so CheckHeartbeat never reads
+// a half-written heartbeat.
+func writeHeartbeat(path string) error {
+	hostname, _ := os.Hostname()
+	content, _ := json.Marshal(Heartbeat{
+		Timestamp: GetClock().Now(),
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+	})
+
+	return WriteFileAtomic(path, content, GetDefaults().DefaultFileMode)
+}
@@ -0,0 +1,33 @@
+package coreutils
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStartHeartbeatWritesAtomically(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "heartbeat.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var startErr error
+
+	ops := traceOps(file, func() {
+		startErr = StartHeartbeat(ctx, file, time.Hour)
+	})
+
+	if startErr != nil {
+		t.Fatalf("StartHeartbeat failed: %v", startErr)
+	}
+
+	if !reflect.DeepEqual(ops, []string{"WriteFileAtomic"}) {
+		t.Errorf("expected the heartbeat to be written with WriteFileAtomic, traced %v", ops)
+	}
+
+	if _, checkErr := CheckHeartbeat(file, time.Minute); checkErr != nil {
+		t.Errorf("CheckHeartbeat failed: %v", checkErr)
+	}
+}
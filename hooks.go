@@ -0,0 +1,139 @@
+package coreutils
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// OpType is a kind of operation hooks can be registered for
+type OpType int
+
+const (
+	// OpCopy is any copy, such as CopyFile, CopyDirectory, Resume, CopyFileMulti, or CopyFromReader
+	OpCopy OpType = iota + 1
+
+	// OpWrite is any write of new content, such as WriteOrUpdateFile, WriteFileAtomic, WriteFromReader, PipeCommandToFile, or ExtractArchive
+	OpWrite
+
+	// OpDelete is any removal, such as RemoveTree, RemoveDirectoryContents, RemoveIfEmpty, or MoveToTrash
+	OpDelete
+)
+
+// Phase is when a hook runs relative to its operation
+type Phase int
+
+const (
+	// PhasePre hooks run before the operation touches the filesystem, and may reject it by returning an error
+	PhasePre Phase = iota
+
+	// PhasePost hooks run once the operation has finished, whether it succeeded or not. Their errors are ignored.
+	PhasePost
+)
+
+// HookEvent describes the operation a hook is run for
+type HookEvent struct {
+	Op          OpType      // Kind of operation
+	Phase       Phase       // Whether the operation is about to run or has finished
+	Name        string      // Name of the function performing the operation, such as CopyFile
+	Path        string      // Path the operation acts on, or its source
+	Destination string      // Destination of the operation, if it has one
+	Mode        os.FileMode // Mode files are written with, if the operation was given one
+	Bytes       int64       // Number of bytes written, in PhasePost
+	Err         error       // Error the operation returned, in PhasePost
+}
+
+// HookFunc is called with each HookEvent of the operations and phase it was registered for. It is called synchronously,
+// so it should return quickly.
+type HookFunc func(event HookEvent) error
+
+// hookKey identifies the operation and phase hooks are registered for
+type hookKey struct {
+	op    OpType
+	phase Phase
+}
+
+// hookedOperations maps the name of each traced operation to its OpType, so post hooks can be run from trace
+var hookedOperations = map[string]OpType{
+	"CopyDirectory":           OpCopy,
+	"CopyFile":                OpCopy,
+	"CopyFileMulti":           OpCopy,
+	"CopyFromReader":          OpCopy,
+	"ExtractArchive":          OpWrite,
+	"MoveToTrash":             OpDelete,
+	"PipeCommandToFile":       OpWrite,
+	"RemoveDirectoryContents": OpDelete,
+	"RemoveIfEmpty":           OpDelete,
+	"RemoveTree":              OpDelete,
+	"Resume":                  OpCopy,
+	"WriteFileAtomic":         OpWrite,
+	"WriteFromReader":         OpWrite,
+	"WriteOrUpdateFile":       OpWrite,
+}
+
+var (
+	hookLock       sync.RWMutex
+	operationHooks = make(map[hookKey]map[int]HookFunc)
+	hookId         int
+)
+
+// RegisterHook registers fn to be called in the phase of every operation of the type, returning a function which removes
+// it. A PhasePre hook which returns an error stops the operation, which returns that error.
+func RegisterHook(op OpType, phase Phase, fn HookFunc) (remove func()) {
+	key := hookKey{op, phase}
+
+	hookLock.Lock()
+	hookId++
+	id := hookId
+
+	if operationHooks[key] == nil {
+		operationHooks[key] = make(map[int]HookFunc)
+	}
+
+	operationHooks[key][id] = fn
+	hookLock.Unlock()
+
+	return func() {
+		hookLock.Lock()
+		delete(operationHooks[key], id)
+		hookLock.Unlock()
+	}
+}
+
+// runHooks calls the hooks registered for the operation and phase of the event, stopping at the first error
+func runHooks(event HookEvent) error {
+	hookLock.RLock()
+	registered := make([]HookFunc, 0, len(operationHooks[hookKey{event.Op, event.Phase}]))
+
+	for _, hook := range operationHooks[hookKey{event.Op, event.Phase}] {
+		registered = append(registered, hook)
+	}
+
+	hookLock.RUnlock()
+
+	for _, hook := range registered {
+		if hookErr := hook(event); hookErr != nil {
+			return hookErr
+		}
+	}
+
+	return nil
+}
+
+// preHooks runs the PhasePre hooks of the named operation, returning why it was rejected if a hook rejects it
+func preHooks(name, path, destination string, mode os.FileMode) error {
+	event := HookEvent{Op: hookedOperations[name], Phase: PhasePre, Name: name, Path: path, Destination: destination, Mode: mode}
+
+	if hookErr := runHooks(event); hookErr != nil {
+		return fmt.Errorf("%s of %s rejected by a hook: %w", name, path, hookErr)
+	}
+
+	return nil
+}
+
+// postHooks runs the PhasePost hooks of the traced operation, if it is one hooks can be registered for
+func postHooks(event TraceEvent) {
+	if op, hooked := hookedOperations[event.Op]; hooked {
+		runHooks(HookEvent{Op: op, Phase: PhasePost, Name: event.Op, Path: event.Path, Destination: event.Destination, Mode: event.Mode, Bytes: event.Bytes, Err: event.Err})
+	}
+}
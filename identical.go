@@ -0,0 +1,45 @@
+package coreutils
+
+import (
+	"os"
+)
+
+// CompareMode is how files are compared to decide if they are identical
+type CompareMode int
+
+const (
+	// CompareSizeAndModTime considers files identical when their size and modification time match. This is fast, and copies made
+	// with WithSkipIdentical are given the modification time of their source so later runs can skip them.
+	CompareSizeAndModTime CompareMode = iota + 1
+
+	// CompareContent considers files identical when their size and SHA256 hash match. This is slower but ignores modification times.
+	CompareContent
+)
+
+// WithSkipIdentical skips copying files which are already identical at the destination, as determined by the CompareMode.
+// Skipped files are counted in Stats.FilesSkipped.
+func WithSkipIdentical(compare CompareMode) Option {
+	return func(options *operationOptions) {
+		options.skipIdentical = compare
+	}
+}
+
+// identical checks if the destination file is already identical to the source file
+func identical(sourceFile string, sourceInfo os.FileInfo, destinationFile string, compare CompareMode) bool {
+	destinationInfo, statErr := os.Stat(destinationFile)
+
+	if statErr != nil || !destinationInfo.Mode().IsRegular() || destinationInfo.Size() != sourceInfo.Size() { // If the destination doesn't exist or differs in size
+		return false
+	}
+
+	switch compare {
+	case CompareSizeAndModTime:
+		return destinationInfo.ModTime().Equal(sourceInfo.ModTime())
+	case CompareContent:
+		sourceHash, sourceErr := contentHash(sourceFile)
+		destinationHash, destinationErr := contentHash(destinationFile)
+		return (sourceErr == nil) && (destinationErr == nil) && (sourceHash == destinationHash)
+	}
+
+	return false
+}
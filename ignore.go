@@ -0,0 +1,233 @@
+package coreutils
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultIgnoreFile is the name of the ignore file recursive copies and listings honor unless WithIgnoreFile says otherwise
+const DefaultIgnoreFile = ".copyignore"
+
+// ignorePattern is a single compiled line of an ignore file
+type ignorePattern struct {
+	segments []string // Slash separated glob segments, relative to the directory of the ignore file, where "**" matches any number of segments
+	negate   bool     // Whether matching paths are re-included rather than ignored
+	dirOnly  bool     // Whether the pattern only matches directories
+}
+
+// IgnoreMatcher matches paths against the patterns of a single ignore file, using the syntax and semantics of .gitignore
+type IgnoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// ignoreRules is the chain of ignore files from the root of a recursive operation down to the directory currently being walked
+type ignoreRules struct {
+	directory string // Slash separated path of the directory holding the ignore file, relative to the root
+	matcher   *IgnoreMatcher
+	parent    *ignoreRules
+}
+
+// WithIgnoreFile sets the name of the ignore file honored in each directory of a recursive copy or listing. Defaults to
+// DefaultIgnoreFile. An empty name disables ignore files.
+func WithIgnoreFile(name string) Option {
+	return func(options *operationOptions) {
+		options.ignoreFile = name
+	}
+}
+
+// NewIgnoreMatcher will compile the patterns, each being a line of an ignore file. Blank lines and comments are skipped.
+func NewIgnoreMatcher(patterns []string) *IgnoreMatcher {
+	matcher := &IgnoreMatcher{}
+
+	for _, line := range patterns {
+		if pattern, ok := compileIgnorePattern(line); ok {
+			matcher.patterns = append(matcher.patterns, pattern)
+		}
+	}
+
+	return matcher
+}
+
+// LoadIgnoreFile will read and compile the ignore file at path
+func LoadIgnoreFile(path string) (*IgnoreMatcher, error) {
+	file, openErr := os.Open(path)
+
+	if openErr != nil {
+		return nil, openErr
+	}
+
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return NewIgnoreMatcher(lines), scanner.Err()
+}
+
+// compileIgnorePattern compiles a line of an ignore file, returning false if it has no pattern
+func compileIgnorePattern(line string) (ignorePattern, bool) {
+	var pattern ignorePattern
+
+	line = strings.TrimSuffix(line, "\r")
+
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") { // Trailing spaces are ignored unless escaped
+		line = line[:len(line)-1]
+	}
+
+	if line == "" || line[0] == '#' { // If this is a blank line or a comment
+		return pattern, false
+	}
+
+	if line[0] == '!' {
+		pattern.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") { // If a leading ! or # is escaped
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+
+	anchored := strings.Contains(line, "/") // Patterns with a slash before the end are relative to the ignore file, others match at any depth
+
+	for _, segment := range strings.Split(line, "/") {
+		if segment != "" {
+			pattern.segments = append(pattern.segments, fnmatchClass(segment))
+		}
+	}
+
+	if len(pattern.segments) == 0 { // If the line was only slashes
+		return pattern, false
+	}
+
+	if !anchored {
+		pattern.segments = append([]string{"**"}, pattern.segments...)
+	} else if pattern.segments[len(pattern.segments)-1] == "**" { // A trailing "/**" matches everything inside, but not the directory itself
+		pattern.segments = append(pattern.segments[:len(pattern.segments)-1], "*", "**")
+	}
+
+	return pattern, true
+}
+
+// fnmatchClass converts the "[!...]" negated character classes of gitignore into the "[^...]" of path.Match
+func fnmatchClass(segment string) string {
+	var converted strings.Builder
+
+	for i := 0; i < len(segment); i++ {
+		converted.WriteByte(segment[i])
+
+		if segment[i] == '\\' && i+1 < len(segment) { // Keep escaped characters as they are
+			i++
+			converted.WriteByte(segment[i])
+		} else if segment[i] == '[' && i+1 < len(segment) && segment[i+1] == '!' {
+			converted.WriteByte('^')
+			i++
+		}
+	}
+
+	return converted.String()
+}
+
+// Match checks if the path, relative to the directory of the ignore file, is ignored. Like git, a path inside an ignored
+// directory is ignored too, and cannot be re-included by a negated pattern. isDir says whether the path is a directory.
+func (matcher *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	relPath = strings.Trim(path.Clean(filepath.ToSlash(relPath)), "/")
+	parents := strings.Split(relPath, "/")
+
+	for depth := 1; depth < len(parents); depth++ { // For each directory leading up to the path
+		if ignored, _ := matcher.match(strings.Join(parents[:depth], "/"), true); ignored {
+			return true
+		}
+	}
+
+	ignored, _ := matcher.match(relPath, isDir)
+	return ignored
+}
+
+// match checks the slash separated path, relative to the directory of the ignore file, against each pattern. The last
+// matching pattern decides whether it is ignored, and matched is false if no pattern matches at all.
+func (matcher *IgnoreMatcher) match(relativePath string, isDir bool) (ignored, matched bool) {
+	names := strings.Split(relativePath, "/")
+
+	for i := len(matcher.patterns) - 1; i >= 0; i-- { // Later patterns override earlier ones, so check them first
+		pattern := matcher.patterns[i]
+
+		if (!pattern.dirOnly || isDir) && matchSegments(pattern.segments, names) {
+			return !pattern.negate, true
+		}
+	}
+
+	return false, false
+}
+
+// matchSegments checks if the names of a path match the glob segments, where "**" matches any number of names
+func matchSegments(segments, names []string) bool {
+	for len(segments) > 0 {
+		if segments[0] == "**" {
+			for skip := 0; skip <= len(names); skip++ {
+				if matchSegments(segments[1:], names[skip:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+
+		if nameMatch, _ := path.Match(segments[0], names[0]); !nameMatch {
+			return false
+		}
+
+		segments, names = segments[1:], names[1:]
+	}
+
+	return len(names) == 0
+}
+
+// load extends the rules with the ignore file in directory, which is relativeDirectory within the root, if it has one.
+// Safe to call on nil rules, as at the root.
+func (rules *ignoreRules) load(directory, relativeDirectory string, options *operationOptions) *ignoreRules {
+	if options.ignoreFile == "" { // If ignore files are disabled
+		return rules
+	}
+
+	openFiles.acquire()
+	matcher, loadErr := LoadIgnoreFile(filepath.Join(directory, options.ignoreFile))
+	openFiles.release()
+
+	if loadErr != nil || len(matcher.patterns) == 0 { // If this directory has no ignore file, or it is empty
+		return rules
+	}
+
+	return &ignoreRules{directory: filepath.ToSlash(relativeDirectory), matcher: matcher, parent: rules}
+}
+
+// ignored checks if the path, relative to the root of the operation, is ignored. Ignore files in deeper directories take
+// precedence over those above them.
+func (rules *ignoreRules) ignored(relativePath string, isDir bool) bool {
+	relativePath = filepath.ToSlash(relativePath)
+
+	for current := rules; current != nil; current = current.parent {
+		pathInDirectory := relativePath
+
+		if current.directory != "" {
+			pathInDirectory = strings.TrimPrefix(relativePath, current.directory+"/")
+		}
+
+		if ignored, matched := current.matcher.match(pathInDirectory, isDir); matched {
+			return ignored
+		}
+	}
+
+	return false
+}
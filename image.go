@@ -0,0 +1,107 @@
+package coreutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// imageWalkFunc is called for each entry in a disk image, with a function to open the content of regular files
+type imageWalkFunc func(entry ArchiveEntry, open func() (io.Reader, error)) error
+
+// diskImage is a read-only disk image format we can walk
+type diskImage interface {
+	walk(fn imageWalkFunc) error
+}
+
+// ListImage will list the entries of an ISO9660 or squashfs image without mounting it
+func ListImage(image string) ([]ArchiveEntry, error) {
+	var entries []ArchiveEntry
+
+	listErr := walkImage(image, func(entry ArchiveEntry, open func() (io.Reader, error)) error {
+		entries = append(entries, entry)
+		return nil
+	})
+
+	return entries, listErr
+}
+
+// ExtractImage will extract the contents of an ISO9660 or squashfs image into the destination directory without mounting it
+func ExtractImage(image, destination string) error {
+	start := time.Now()
+	extractErr := extractImage(image, destination)
+	trace(TraceEvent{Op: "ExtractImage", Path: image, Destination: destination, Duration: time.Since(start), Err: extractErr})
+	return extractErr
+}
+
+// extractImage extracts the contents of the image into the destination directory
+func extractImage(image, destination string) error {
+	if policyErr := checkPathPolicy(destination, true); policyErr != nil {
+		return policyErr
+	}
+
+	if mkdirErr := os.MkdirAll(destination, GetDefaults().DefaultDirMode); mkdirErr != nil { // If we failed to create the destination
+		return writeError(destination, "Failed to create "+destination, mkdirErr)
+	}
+
+	return walkImage(image, func(entry ArchiveEntry, open func() (io.Reader, error)) error {
+		entryPath, pathErr := archiveEntryPath(destination, entry.Name)
+
+		if pathErr != nil { // If this entry would escape the destination
+			return pathErr
+		}
+
+		var content io.Reader
+
+		if entry.Mode.IsRegular() { // If this is a file, open its content
+			var openErr error
+
+			if content, openErr = open(); openErr != nil {
+				return openErr
+			}
+		}
+
+		return extractEntry(destination, entryPath, &entry, content, newOperationOptions(nil))
+	})
+}
+
+// walkImage opens the image, detects its format, and walks each of its entries
+func walkImage(image string, fn imageWalkFunc) error {
+	imageFile, openErr := os.Open(image)
+
+	if openErr != nil { // If the image doesn't exist
+		return openError(image, openErr)
+	}
+
+	defer imageFile.Close()
+
+	var imageReader diskImage
+	var imageErr error
+
+	magic := make([]byte, 5)
+
+	if _, readErr := imageFile.ReadAt(magic[:4], 0); readErr == nil && string(magic[:4]) == squashfsMagic { // If this is a squashfs image
+		imageReader, imageErr = newSquashfsImage(imageFile)
+	} else if _, readErr := imageFile.ReadAt(magic, isoDescriptorStart*isoSectorSize+1); readErr == nil && string(magic) == isoIdentifier { // If this is an ISO9660 image
+		imageReader, imageErr = newISOImage(imageFile)
+	} else {
+		imageErr = errors.New(image + " is not a supported image format.")
+	}
+
+	if imageErr != nil { // If we failed to read the image
+		return imageErr
+	}
+
+	return imageReader.walk(fn)
+}
+
+// joinImagePath joins a directory path and name within an image
+func joinImagePath(directory, name string) string {
+	if directory == "" {
+		return name
+	}
+
+	return path.Join(directory, name)
+}
@@ -0,0 +1,78 @@
+package coreutils
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// squashfsSuperblock returns a version 4, gzip compressed superblock with the block size and table positions provided
+func squashfsSuperblock(blockSize uint32, inodeTableStart, directoryTableStart uint64) []byte {
+	superblock := make([]byte, 96)
+	copy(superblock, squashfsMagic)
+	binary.LittleEndian.PutUint32(superblock[12:16], blockSize)
+	binary.LittleEndian.PutUint16(superblock[20:22], squashfsCompressionGzip)
+	binary.LittleEndian.PutUint16(superblock[28:30], 4)
+	binary.LittleEndian.PutUint64(superblock[64:72], inodeTableStart)
+	binary.LittleEndian.PutUint64(superblock[72:80], directoryTableStart)
+	return superblock
+}
+
+// writeImage writes the image content to a file in a temporary directory, returning its path
+func writeImage(t *testing.T, content []byte) string {
+	t.Helper()
+	imagePath := filepath.Join(t.TempDir(), "image")
+
+	if writeErr := os.WriteFile(imagePath, content, 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	return imagePath
+}
+
+func TestSquashfsRejectsInvalidBlockSize(t *testing.T) {
+	for _, blockSize := range []uint32{0, 1, 3000, 2 << 10, 2 << 20} {
+		imagePath := writeImage(t, squashfsSuperblock(blockSize, 96, 96))
+
+		if _, listErr := ListImage(imagePath); listErr == nil || !strings.Contains(listErr.Error(), "block size") {
+			t.Errorf("block size %d: expected an invalid block size error, got %v", blockSize, listErr)
+		}
+	}
+}
+
+func TestSquashfsRejectsFileLargerThanImage(t *testing.T) {
+	inode := make([]byte, 16+40) // An extended file inode claiming to be 4 EiB without a fragment
+	binary.LittleEndian.PutUint16(inode[0:2], 9)
+	binary.LittleEndian.PutUint64(inode[16+8:16+16], 1<<62)
+	binary.LittleEndian.PutUint32(inode[16+28:16+32], squashfsNoFragment)
+
+	metadata := binary.LittleEndian.AppendUint16(nil, 0x8000|uint16(len(inode))) // Stored uncompressed
+	metadata = append(metadata, inode...)
+
+	image := append(squashfsSuperblock(128<<10, 96, uint64(96+len(metadata))), metadata...)
+
+	if _, listErr := ListImage(writeImage(t, image)); listErr == nil || !strings.Contains(listErr.Error(), "larger than the image") {
+		t.Errorf("expected a file larger than the image error, got %v", listErr)
+	}
+}
+
+func TestISORejectsDirectoryLargerThanImage(t *testing.T) {
+	image := make([]byte, (isoDescriptorStart+2)*isoSectorSize)
+
+	primary := image[isoDescriptorStart*isoSectorSize:]
+	primary[0] = 1
+	copy(primary[1:6], isoIdentifier)
+	primary[156] = 34                                              // Length of the root directory record
+	binary.LittleEndian.PutUint32(primary[156+2:156+6], 18)        // Extent of the root directory, just past the descriptors
+	binary.LittleEndian.PutUint32(primary[156+10:156+14], 1<<32-1) // Size of the root directory, far larger than the image
+
+	terminator := image[(isoDescriptorStart+1)*isoSectorSize:]
+	terminator[0] = 255
+	copy(terminator[1:6], isoIdentifier)
+
+	if _, listErr := ListImage(writeImage(t, image)); listErr == nil || !strings.Contains(listErr.Error(), "larger than the image") {
+		t.Errorf("expected a directory larger than the image error, got %v", listErr)
+	}
+}
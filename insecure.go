@@ -0,0 +1,116 @@
+package coreutils
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// FindingKind is the kind of problem a Finding describes
+type FindingKind int
+
+const (
+	FindingSetuid        FindingKind = iota + 1 // File runs as its owner
+	FindingSetgid                               // File runs as its group
+	FindingWorldWritable                        // File, or directory without the sticky bit, which anyone may modify
+	FindingUnknownOwner                         // File whose owner or group does not exist on this system, as left behind by removed users
+)
+
+// Finding is a file or directory FindInsecureFiles flagged
+type Finding struct {
+	Path string      // Path of the file or directory
+	Kind FindingKind // What is insecure about it
+	Mode os.FileMode // Mode the file or directory has
+	UID  int         // ID of the user owning it, or -1 on platforms without ownership
+	GID  int         // ID of the group owning it, or -1 on platforms without ownership
+}
+
+// String returns a readable name of the kind of finding
+func (kind FindingKind) String() string {
+	switch kind {
+	case FindingSetuid:
+		return "setuid"
+	case FindingSetgid:
+		return "setgid"
+	case FindingWorldWritable:
+		return "world-writable"
+	case FindingUnknownOwner:
+		return "unknown owner"
+	default:
+		return "FindingKind(" + strconv.Itoa(int(kind)) + ")"
+	}
+}
+
+// FindInsecureFiles will walk the tree at path and return a Finding for each setuid or setgid file, each world-writable file
+// and directory, and each entry owned by a user or group which doesn't exist. World-writable directories with the sticky
+// bit set, such as /tmp, are not flagged. An entry may have several Findings. Symlinks are not checked.
+func FindInsecureFiles(path string) ([]Finding, error) {
+	var findings []Finding
+
+	knownUsers := make(map[int]bool)  // Whether each UID we've looked up exists
+	knownGroups := make(map[int]bool) // Whether each GID we've looked up exists
+
+	walkErr := walkTree(path, newOperationOptions(nil), func(entryPath, relativePath string, info os.FileInfo) error {
+		mode := info.Mode()
+
+		if mode&os.ModeSymlink != 0 { // Symlinks are always 0777, and their targets are checked on their own
+			return nil
+		}
+
+		uid, gid, hasOwner := fileOwner(info)
+
+		if !hasOwner {
+			uid, gid = -1, -1
+		}
+
+		finding := Finding{Path: entryPath, Mode: mode, UID: uid, GID: gid}
+		add := func(kind FindingKind) {
+			finding.Kind = kind
+			findings = append(findings, finding)
+		}
+
+		if mode&os.ModeSetuid != 0 {
+			add(FindingSetuid)
+		}
+
+		if mode&os.ModeSetgid != 0 && !info.IsDir() { // Setgid directories only make new entries inherit their group
+			add(FindingSetgid)
+		}
+
+		if mode&0002 != 0 && !(info.IsDir() && mode&os.ModeSticky != 0) { // If anyone may modify it, or remove others' entries from it
+			add(FindingWorldWritable)
+		}
+
+		if hasOwner && (!ownerExists(uid, knownUsers, lookupUserID) || !ownerExists(gid, knownGroups, lookupGroupID)) {
+			add(FindingUnknownOwner)
+		}
+
+		return nil
+	})
+
+	return findings, walkErr
+}
+
+// ownerExists returns whether the user or group ID exists, caching the result of lookup in known
+func ownerExists(id int, known map[int]bool, lookup func(id string) error) bool {
+	exists, cached := known[id]
+
+	if !cached {
+		exists = (lookup(strconv.Itoa(id)) == nil)
+		known[id] = exists
+	}
+
+	return exists
+}
+
+// lookupUserID returns an error if the user ID doesn't exist
+func lookupUserID(id string) error {
+	_, lookupErr := user.LookupId(id)
+	return lookupErr
+}
+
+// lookupGroupID returns an error if the group ID doesn't exist
+func lookupGroupID(id string) error {
+	_, lookupErr := user.LookupGroupId(id)
+	return lookupErr
+}
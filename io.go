@@ -1,13 +1,19 @@
 package coreutils
 
 import (
+	"bytes"
 	"fmt"
 	"errors"
-	"io/ioutil"
+	"io"
+	"io/fs"
 	"os"
 	"os/user"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // AbsPath get the absolute directory path, cleaning out any file names, home directory references, etc.
@@ -25,6 +31,7 @@ func AbsPath(path string) string {
 
 		if file, openErr := os.Open(path); openErr == nil { // Attempt to open the path, to validate if it is a file or directory
 			stat, statErr := file.Stat()
+			file.Close()
 			stripLastElement = (statErr == nil) && !stat.IsDir() // Sets stripLastElement to true if stat.IsDir is not true
 		} else { // If we failed to open the directory or file
 			lastElement := filepath.Base(path)
@@ -39,100 +46,650 @@ func AbsPath(path string) string {
 	return path
 }
 
-// CopyDirectory will the directory specified and its contents into the destination directory
-func CopyDirectory(sourceDirectory, destinationDirectory string) error {
+// CopyDirectory will copy the directory specified and its contents into the destination directory, refusing to copy it onto
+// itself or into its own subtree. Options which don't apply to copies are ignored.
+func CopyDirectory(sourceDirectory, destinationDirectory string, opts ...Option) error {
+	_, copyError := CopyDirectoryStats(sourceDirectory, destinationDirectory, opts...)
+	return copyError
+}
+
+// CopyDirectoryStats will copy the directory like CopyDirectory, returning Stats summarizing what was copied
+func CopyDirectoryStats(sourceDirectory, destinationDirectory string, opts ...Option) (Stats, error) {
+	return copyDirectoryStats("CopyDirectory", sourceDirectory, destinationDirectory, newOperationOptions(opts))
+}
+
+// copyDirectoryStats copies the directory like CopyDirectoryStats, running the hooks and trace of the named operation
+func copyDirectoryStats(name, sourceDirectory, destinationDirectory string, options *operationOptions) (Stats, error) {
+	start := time.Now()
+	options.stats = &statsCollector{}
+
+	copyError := checkOverlap(sourceDirectory, destinationDirectory)
+
+	if copyError == nil {
+		copyError = checkCaseCollisions(sourceDirectory, destinationDirectory, options)
+	}
+
+	if copyError == nil {
+		copyError = preHooks(name, sourceDirectory, destinationDirectory, 0)
+	}
+
+	if copyError == nil {
+		options.checkpoint, copyError = openCheckpoint(sourceDirectory, destinationDirectory, options)
+	}
+
+	if copyError == nil { // If we aren't copying the directory onto itself or into its own subtree, and no hook objects
+		var priorityError error
+
+		if len(options.priority) != 0 { // Copy the files with priority across the whole tree first
+			priorityError = copyPriorityFiles(sourceDirectory, destinationDirectory, options)
+		}
+
+		if !isFatalWalkError(priorityError) {
+			options.copies = newCopyPool(options.workers)
+			copyError = copyDirectory(sourceDirectory, destinationDirectory, "", options, newDirectoryChain(sourceDirectory, options), nil)
+		}
+
+		if copyError == nil {
+			copyError = priorityError
+		}
+
+		copyError = options.validationError(copyError) // Files which failed validation keep the checkpoint, so rolled back files are copied again by Resume
+
+		if closeErr := options.checkpoint.close(copyError); copyError == nil {
+			copyError = closeErr
+		}
+	}
+
+	stats := options.stats.finish(start)
+
+	trace(TraceEvent{Op: name, Path: sourceDirectory, Destination: destinationDirectory, Bytes: stats.BytesTransferred, Duration: stats.Duration, Err: copyError})
+
+	return stats, copyError
+}
+
+// copyDirectory copies the sourceDirectory, which is relativeDirectory within the root of the copy, into destinationDirectory.
+// The ancestors are only tracked when following symlinks, and ignores are the rules of the ignore files above sourceDirectory.
+func copyDirectory(sourceDirectory, destinationDirectory, relativeDirectory string, options *operationOptions, ancestors *directoryChain, ignores *ignoreRules) error {
 	if !IsDir(sourceDirectory) { // If this isn't a source directory
-		return errors.New(sourceDirectory + " is not a directory.")
+		return notDirectoryError(sourceDirectory, nil)
+	}
+
+	if policyErr := checkPathPolicy(sourceDirectory, false); policyErr != nil {
+		return policyErr
+	} else if policyErr = checkPathPolicy(destinationDirectory, true); policyErr != nil {
+		return policyErr
 	}
 
+	ignores = ignores.load(sourceDirectory, relativeDirectory, options)
+
 	var copyError error
-	currentDirectory, _ := os.Getwd()            // Get the working directory
-	currentDirectory = AbsPath(currentDirectory) // Get the absolute path of the current working directory
 
-	os.MkdirAll(destinationDirectory, NonGlobalFileMode) // Ensure destinationDirectory exists
+	options.recordCreated(destinationDirectory)
+	os.MkdirAll(destinationDirectory, options.DefaultDirMode) // Ensure destinationDirectory exists
 
-	finalSourceDir := filepath.Base(sourceDirectory)                              // Determine what our final source directory is. For instance, we should only copy child from test/parent/child
-	parentOfFinalSourceDir := strings.TrimSuffix(sourceDirectory, finalSourceDir) // Get the parent directories we need to change to. Ex: test/parent
+	var directoryContents []os.DirEntry // Every entry is held at once, so it can be ordered, but as a DirEntry which isn't stat-ed until needed
 
-	os.Chdir(parentOfFinalSourceDir)
+	directoryReadError := readDirectory(sourceDirectory, func(entry os.DirEntry) error {
+		directoryContents = append(directoryContents, entry)
+		return nil
+	}) // The directory is closed before recursing, so deep trees don't hold a file open per level
 
-	if sourceDirectoryFile, sourceDirOpenErr := os.Open(finalSourceDir); sourceDirOpenErr == nil { // If we did not fail to open finalSourceDir
-		if directoryContents, directoryReadError := sourceDirectoryFile.Readdir(-1); directoryReadError == nil { // Read the directory contents
-			if len(directoryContents) != 0 { // If the directory has contents
-				for _, contentItemFileInfo := range directoryContents { // For each FileInfo struct in directoryContents
-					contentItemName := contentItemFileInfo.Name() // Get the name of the item
-					sourceItemPath := finalSourceDir + "/" + contentItemName
-					destinationItemPath := destinationDirectory + "/" + contentItemName
+	options.orderEntries(directoryContents)
+	options.orderCopies(directoryContents)
 
-					if contentItemFileInfo.IsDir() { // If this is a directory
-						copyError = CopyDirectory(sourceItemPath, destinationItemPath) // Copy this sub-directory and its contents
-					} else { // If this is a file
-						copyError = CopyFile(sourceItemPath, destinationItemPath) // Copy the directory
+	files := options.copies.directory()
+
+	if directoryReadError == nil { // Read the directory contents
+		for _, contentItem := range directoryContents { // For each entry in directoryContents
+			if copyError = options.canceled(sourceDirectory); copyError != nil { // If the copy has been canceled, stop before the next item
+				break
+			}
+
+			contentItemName := contentItem.Name() // Get the name of the item
+			sourceItemPath := filepath.Join(sourceDirectory, contentItemName) // Paths are always joined rather than changing directory, so concurrent copies don't interfere
+			destinationItemPath := filepath.Join(destinationDirectory, contentItemName)
+			relativeItemPath := filepath.Join(relativeDirectory, contentItemName)
+
+			if options.excluded(relativeItemPath) { // If this item should be skipped
+				options.stats.skipped()
+				continue
+			}
+
+			isDir := contentItem.IsDir()
+			var contentItemInfo os.FileInfo // Only needed when following symlinks, to detect loops
+
+			if options.FollowSymlinks && (isDir || contentItem.Type()&os.ModeSymlink != 0) { // If we should copy whatever this symlink points to
+				if targetInfo, statErr := os.Stat(sourceItemPath); statErr == nil {
+					contentItemInfo, isDir = targetInfo, targetInfo.IsDir()
+				}
+			} else if isDir && ancestors != nil { // If only junctions are followed, directories are still tracked to catch junctions leading back into them
+				contentItemInfo, _ = os.Stat(sourceItemPath)
+			}
+
+			junction := contentItem.Type()&os.ModeIrregular != 0 && isJunction(sourceItemPath)
+
+			if junction && options.junctions == JunctionSkip { // If junctions should be left out
+				options.stats.skipped()
+				continue
+			} else if junction && options.junctions == JunctionFollow { // If we should copy the directory this junction points to
+				if targetInfo, statErr := os.Stat(sourceItemPath); statErr == nil {
+					contentItemInfo, isDir = targetInfo, targetInfo.IsDir()
+				}
+			}
+
+			if ignores.ignored(relativeItemPath, isDir) { // If an ignore file says to skip this item
+				options.stats.skipped()
+				continue
+			}
+
+			if !isDir && !options.included(relativeItemPath) { // If this file doesn't match the include patterns
+				options.stats.skipped()
+				continue
+			}
+
+			if !isDir && options.prioritized[relativeItemPath] { // If this was already copied for having priority
+				continue
+			}
+
+			if !isDir && options.checkpoint.done(relativeItemPath, destinationItemPath) { // If a previous run already copied this file
+				options.stats.skipped()
+				continue
+			}
+
+			if limitErr := options.checkLimits(sourceItemPath, relativeItemPath, isDir); limitErr != nil { // If we've gone too far, stop the whole copy
+				copyError = limitErr
+				break
+			}
+
+			if isDir && junction && ancestors.checkLoop(sourceItemPath, contentItemInfo) != nil { // If a followed junction led back into a directory we're already copying, recreate it instead
+				copyError = copyJunction(sourceItemPath, destinationItemPath, options)
+			} else if isDir && ancestors.checkLoop(sourceItemPath, contentItemInfo) != nil { // If a followed symlink led back into a directory we're already copying, break the cycle by copying the link itself
+				copyError = copySymlink(sourceItemPath, destinationItemPath, options)
+			} else if isDir { // If this is a directory
+				copyError = copyDirectory(sourceItemPath, destinationItemPath, relativeItemPath, options, ancestors.child(contentItemInfo), ignores) // Copy this sub-directory and its contents
+
+				if isFatalWalkError(copyError) { // If the sub-directory exceeded a limit, looped, or was canceled, stop the whole copy
+					break
+				}
+			} else if junction { // If this is a junction we shouldn't follow, recreate it
+				copyError = copyJunction(sourceItemPath, destinationItemPath, options)
+
+				if copyError == nil {
+					options.checkpoint.complete(relativeItemPath)
+				}
+			} else if contentItem.Type()&os.ModeSymlink != 0 && !options.FollowSymlinks { // If this is a symlink we shouldn't follow, recreate it
+				copyError = copySymlink(sourceItemPath, destinationItemPath, options)
+
+				if copyError == nil {
+					copyError = options.applyMaps(destinationItemPath, relativeItemPath, false)
+				}
+
+				if copyError == nil {
+					options.checkpoint.complete(relativeItemPath)
+				}
+			} else { // If this is a file
+				copyError = files.run(func() error { // Copy the file, on a worker if the copy is parallel
+					fileError := copyFile(sourceItemPath, destinationItemPath, options)
+
+					if fileError == nil {
+						fileError = options.applyMaps(destinationItemPath, relativeItemPath, false)
+					}
+
+					if fileError == nil {
+						options.checkpoint.complete(relativeItemPath)
 					}
+
+					return fileError
+				})
+
+				if isFatalWalkError(copyError) { // If OverwriteError found an existing file, or the copy was canceled, stop the whole copy
+					break
 				}
 			}
-		} else { // If there was a read error on the directory
-			copyError = errors.New("Unable to read: " + sourceDirectory)
 		}
-	} else {
-		copyError = errors.New("Unsable to open: " + sourceDirectory)
+	} else { // If there was a read error on the directory
+		copyError = readError(sourceDirectory, directoryReadError)
+	}
+
+	if filesError := files.finish(); copyError == nil { // Wait for the files of this directory, so its mode and times are set last
+		copyError = filesError
+	}
+
+	if copyError == nil {
+		copyError = options.copySecurityAttributes(sourceDirectory, destinationDirectory)
+	}
+
+	if copyError == nil {
+		copyError = options.copyMacMetadata(sourceDirectory, destinationDirectory)
+	}
+
+	if copyError == nil { // Set the mapped mode and owner once the contents are copied, since a read-only mode would stop them being written
+		copyError = options.applyMaps(destinationDirectory, relativeDirectory, true)
 	}
 
-	os.Chdir(currentDirectory)
+	if options.preserveTimes && copyError == nil { // Set the modification time last, since copying the contents changes it
+		if sourceInfo, statErr := os.Stat(sourceDirectory); statErr == nil {
+			copyError = os.Chtimes(destinationDirectory, sourceInfo.ModTime(), sourceInfo.ModTime())
+		}
+	}
 
 	return copyError
 }
 
-// CopyFile will copy a file and its relevant permissions
-func CopyFile(sourceFile, destinationFile string) error {
+// CopyFile will copy a file and its relevant permissions, refusing to copy it onto itself. The content is streamed rather than
+// read into memory. Options which don't apply to copying a file are ignored.
+func CopyFile(sourceFile, destinationFile string, opts ...Option) error {
+	start := time.Now()
+	options := newOperationOptions(opts)
+	options.stats = &statsCollector{}
+
+	copyError := checkOverlap(sourceFile, destinationFile)
+
+	if copyError == nil {
+		copyError = preHooks("CopyFile", sourceFile, destinationFile, 0)
+	}
+
+	if copyError == nil { // If we aren't copying the file onto itself, and no hook objects
+		copyError = copyFile(sourceFile, destinationFile, options)
+
+		if copyError == nil {
+			copyError = options.applyMaps(destinationFile, filepath.Base(destinationFile), false)
+		}
+
+		copyError = options.validationError(copyError)
+	}
+
+	stats := options.stats.finish(start)
+
+	trace(TraceEvent{Op: "CopyFile", Path: sourceFile, Destination: destinationFile, Bytes: stats.BytesTransferred, Duration: stats.Duration, Err: copyError})
+
+	return copyError
+}
+
+// CopyFileWithProgress will copy the file like CopyFile, calling progress with the number of bytes copied so far and the size of the
+// file as each buffer is written, such as to render a progress bar. A nil progress copies without reporting progress.
+func CopyFileWithProgress(sourceFile, destinationFile string, progress func(copied, total int64), opts ...Option) error {
+	var reporter ProgressFunc
+
+	if progress != nil {
+		reporter = func(_ string, copied, total int64) {
+			progress(copied, total)
+		}
+	}
+
+	return CopyFile(sourceFile, destinationFile, append(opts[:len(opts):len(opts)], WithProgress(reporter))...)
+}
+
+// copyFile copies the sourceFile to destinationFile
+func copyFile(sourceFile, destinationFile string, options *operationOptions) error {
 	var copyError error
 
+	if copyError = checkPathPolicy(sourceFile, false); copyError == nil {
+		copyError = checkPathPolicy(destinationFile, true)
+	}
+
+	if copyError != nil { // If the policy doesn't allow this copy
+		options.stats.failed()
+		return copyError
+	}
+
+	if options.skipIdentical != 0 { // If we should skip files already at the destination. Checked before taking a slot from the budget, since hashing takes its own.
+		if sourceInfo, statErr := os.Stat(sourceFile); statErr == nil && sourceInfo.Mode().IsRegular() && identical(sourceFile, sourceInfo, destinationFile, options.skipIdentical) {
+			options.stats.skipped()
+			return nil
+		}
+	}
+
+	if skip, overwriteErr := options.checkOverwrite(sourceFile, destinationFile); overwriteErr != nil { // If the file exists and we shouldn't replace it
+		options.stats.failed()
+		return overwriteErr
+	} else if skip {
+		options.stats.skipped()
+		return nil
+	}
+
+	openFiles.acquireMany(2) // The content is streamed, so the source and destination are open at the same time
+	defer openFiles.releaseMany(2)
+
 	sourceFileStruct, sourceFileError := os.Open(sourceFile) // Attempt to open the sourceFile
 
 	if sourceFileError == nil { // If there was not an error opening the source file
+		defer sourceFileStruct.Close()
 		sourceFileStats, _ := sourceFileStruct.Stat() // Get the stats of the file
 
 		if sourceFileStats.IsDir() { // If this is actually a directory
 			copyError = errors.New(sourceFile + " is a directory. Please use CopyDirectory instead.")
+			options.stats.failed()
 		} else { // If it is indeed a file
-			var fileContent []byte
+			var copiedBytes int64
 			sourceFileMode := sourceFileStats.Mode() // Get the FileMode of this file
-			sourceFileStruct.Close()                 // Close the file
 
-			fileContent, copyError = ioutil.ReadFile(sourceFile) // Read the source file
-			copyError = WriteOrUpdateFile(destinationFile, fileContent, sourceFileMode)
+			if copyError = options.canceled(sourceFile); copyError != nil { // If the copy was canceled before it started
+				options.stats.failed()
+				return copyError
+			}
+
+			var sourceDigest string
+			source, size, transformErr := options.transformSource(sourceFile, sourceFileStruct, sourceFileStats.Size())
+
+			if transformErr != nil { // If the TransformFunc rejected the file
+				options.stats.failed()
+				return transformErr
+			}
+
+			if closer, isCloser := source.(io.Closer); isCloser && options.transform != nil { // If the TransformFunc returned something to close once the copy is done
+				defer closer.Close()
+			}
+
+			if options.transform == nil && options.staging.reserve(sourceFileStats.Size()) { // If we should stage the copy and it fits in the staging area. Transformed copies aren't, since staging copies the source as is.
+				sourceFileStruct.Close() // The staged copy reads the source itself
+				copiedBytes, copyError = stagedCopy(sourceFile, destinationFile, sourceFileMode, options)
+				options.staging.release(sourceFileStats.Size())
+
+				if copyError == nil && options.verify { // The staged copy doesn't hash as it reads, so hash the source separately
+					sourceDigest, copyError = hashFile(sourceFile, verifyAlgo)
+				}
+
+				if copyError == nil {
+					options.reportProgress(destinationFile, copiedBytes, copiedBytes)
+				}
+			} else if options.verify { // Hash the source as it is copied, so it is only read once
+				sourceHasher := NewHashingReader(source, verifyAlgo)
+				copiedBytes, copyError = streamCopy(sourceHasher, destinationFile, sourceFileMode, size, options)
+				sourceDigest = sourceHasher.HexSum()
+			} else {
+				copiedBytes, copyError = streamCopy(source, destinationFile, sourceFileMode, size, options)
+			}
+
+			accountRead(IOCategoryCopy, copiedBytes)
+
+			if copyError == nil && options.verify { // If the copy should be read back and compared against the source
+				copyError = verifyCopy(destinationFile, sourceDigest)
+			}
+
+			if copyError == nil && (options.preserveTimes || options.skipIdentical == CompareSizeAndModTime) { // Carry over the modification time, so the copy is recognized as identical next time
+				copyError = os.Chtimes(destinationFile, sourceFileStats.ModTime(), sourceFileStats.ModTime())
+			}
+
+			if copyError == nil {
+				copyError = options.copySecurityAttributes(sourceFile, destinationFile)
+			}
+
+			if copyError == nil {
+				sourceFileStruct.Close() // Done reading, so the alternate streams can be copied within this copy's slots of the budget
+				copyError = options.copyAlternateStreams(sourceFile, destinationFile)
+			}
+
+			if copyError == nil {
+				copyError = options.copyMacMetadata(sourceFile, destinationFile)
+			}
+
+			if copyError == nil { // If we copied the file
+				accountWritten(IOCategoryCopy, copiedBytes)
+
+				if options.validateCopy(sourceFile, destinationFile) { // If the copy is valid, or is kept despite being invalid
+					options.stats.copied(copiedBytes)
+				} else {
+					options.stats.failed()
+				}
+			} else {
+				options.stats.failed()
+			}
 		}
 	} else { // If the file does not exist
-		copyError = errors.New(sourceFile + " does not exist.")
+		copyError = openError(sourceFile, sourceFileError)
+		options.stats.failed()
 	}
 
 	return copyError
 }
 
-// GetFiles will get all the files from a directory.
-func GetFiles(path string, recursive bool) ([]string, error) {
+// streamCopy streams the content of source into destinationFile a buffer at a time, reporting progress after each buffer.
+// size is the expected size of the content. Returns the number of bytes copied.
+func streamCopy(source io.Reader, destinationFile string, mode os.FileMode, size int64, options *operationOptions) (int64, error) {
+	if mkdirErr := os.MkdirAll(filepath.Dir(destinationFile), options.DefaultDirMode); mkdirErr != nil { // If we failed to make the directories leading up to the destination
+		return 0, writeError(destinationFile, "Failed to create the path leading up to "+destinationFile, mkdirErr)
+	}
+
+	options.recordCreated(destinationFile)
+	_, existsErr := os.Lstat(destinationFile)
+	created := os.IsNotExist(existsErr) // Only a destination we create is removed if the copy is canceled
+	destination, openErr := os.OpenFile(destinationFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+
+	if openErr != nil { // If we failed to create the destination
+		return 0, writeError(destinationFile, "Failed to create "+destinationFile, openErr)
+	}
+
+	buffer := make([]byte, options.BufferSize)
+	var copied int64
+	var copyErr error
+
+	options.reportProgress(destinationFile, 0, size)
+
+	for copyErr == nil {
+		if canceledErr := options.canceled(destinationFile); canceledErr != nil { // If the copy was canceled, a partial file we created is of no use
+			destination.Close()
+
+			if created && options.created == nil { // If nothing else will clean it up
+				os.Remove(destinationFile)
+			}
+
+			return copied, canceledErr
+		}
+
+		readCount, readErr := source.Read(buffer)
+
+		if readCount > 0 {
+			if _, writeErr := destination.Write(buffer[:readCount]); writeErr != nil {
+				copyErr = writeErr
+				break
+			}
+
+			copied += int64(readCount)
+			options.reportProgress(destinationFile, copied, size)
+		}
+
+		if readErr == io.EOF { // If we have copied everything
+			break
+		} else if readErr != nil {
+			copyErr = readErr
+		}
+	}
+
+	if closeErr := destination.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+
+	if copyErr == nil && options.exactMode { // If the file should have exactly the mode of the source, regardless of umask or a previous mode
+		copyErr = os.Chmod(destinationFile, mode)
+	}
+
+	if copyErr != nil {
+		return copied, writeError(destinationFile, "Failed to write "+destinationFile, copyErr)
+	}
+
+	return copied, nil
+}
+
+// CopyFromReader will copy the content of r into the dst file, creating or truncating it with the provided mode along with
+// any directories leading up to it. Honors WithBufferSize, WithDirMode, and WithExactMode.
+func CopyFromReader(dst string, r io.Reader, mode os.FileMode, opts ...Option) error {
+	start := time.Now()
+	var written int64
+	copyError := preHooks("CopyFromReader", dst, "", mode)
+
+	if copyError == nil {
+		written, copyError = copyFromReader(dst, r, mode, IOCategoryCopy, newOperationOptions(opts))
+	}
+
+	trace(TraceEvent{Op: "CopyFromReader", Path: dst, Bytes: written, Mode: mode, Duration: time.Since(start), Err: copyError})
+
+	return copyError
+}
+
+// copyFromReader streams r into dst, accounting the bytes written to category, and returns the number of bytes written
+func copyFromReader(dst string, r io.Reader, mode os.FileMode, category IOCategory, options *operationOptions) (int64, error) {
+	if policyErr := checkPathPolicy(dst, true); policyErr != nil {
+		return 0, policyErr
+	}
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(dst), options.DefaultDirMode); mkdirErr != nil { // If we failed to make the directories leading up to dst
+		return 0, writeError(dst, "Failed to create the path leading up to "+dst, mkdirErr)
+	}
+
+	if options.atomicWrite { // If readers should never see a partially written file
+		written, writeErr := atomicWrite(dst, r, mode, options)
+
+		if writeErr == nil {
+			accountWritten(category, written)
+		}
+
+		return written, writeErr
+	}
+
+	openFiles.acquire()
+	defer openFiles.release()
+
+	file, openErr := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+
+	if openErr != nil { // If we failed to create the file
+		return 0, writeError(dst, "Failed to create "+dst, openErr)
+	}
+
+	written, copyErr := io.CopyBuffer(file, r, make([]byte, options.BufferSize))
+
+	if closeErr := file.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+
+	if copyErr == nil && options.exactMode { // If the file should have exactly the mode requested, regardless of umask or a previous mode
+		copyErr = os.Chmod(dst, mode)
+	}
+
+	if copyErr != nil {
+		return written, writeError(dst, "Failed to write "+dst, copyErr)
+	}
+
+	accountWritten(category, written)
+
+	return written, nil
+}
+
+// GetFiles will get all the files from a directory. Symlinks are listed as files unless following them, in which case a symlink
+// leading back into a directory already being listed is listed rather than followed. Honors WithExclude, WithIgnoreFile, WithFollowSymlinks, WithProgress (reporting the
+// number of files found so far), WithWorkers (listing sub-directories concurrently when recursive), WithDeterministic (listing in sorted order), and the
+// WithMaxDepth, WithMaxEntries, and WithMaxPathLength limits.
+func GetFiles(path string, recursive bool, opts ...Option) ([]string, error) {
+	options := newOperationOptions(opts)
+	var foundCount int64
+	var countLock sync.Mutex
+
+	if options.progress != nil { // Wrap the callback so concurrent workers report a single running count
+		progress := options.progress
+		options.progress = func(filePath string, done, total int64) {
+			countLock.Lock()
+			foundCount += done
+			progress(filePath, foundCount, -1)
+			countLock.Unlock()
+		}
+	}
+
+	return getFiles(path, "", recursive, options, make(chan struct{}, options.workers-1), newDirectoryChain(path, options), nil)
+}
+
+// getFilesResult is the result of listing a sub-directory
+type getFilesResult struct {
+	files []string
+	err   error
+}
+
+// getFiles lists the files of path, which is relativePath within the root of the listing. Sub-directories are listed
+// concurrently whenever a slot in workerSlots is free. The ancestors are only tracked when following symlinks, and ignores
+// are the rules of the ignore files above path.
+func getFiles(path, relativePath string, recursive bool, options *operationOptions, workerSlots chan struct{}, ancestors *directoryChain, ignores *ignoreRules) ([]string, error) {
 	var files []string      // Define files as a []string
 	var getFilesError error // Define getFilesError as an error
 
-	if directory, openErr := os.Open(path); openErr == nil {
-		directoryContents, directoryReadError := directory.Readdir(-1)
+	ignores = ignores.load(path, relativePath, options)
 
-		if directoryReadError == nil { // If there was no issue reading the directory contents
-			for _, fileInfoStruct := range directoryContents { // For each FileInfo struct in directoryContents
-				name := fileInfoStruct.Name()
+	var directoryContents []os.DirEntry // Every entry is held at once, so it can be ordered, but as a DirEntry which isn't stat-ed until needed
 
-				if recursive && fileInfoStruct.IsDir() { // If the FileInfo indicates the object is a directory and we're doing recursive file fetching
-					additionalFiles, _ := GetFiles(filepath.Join(path,name), true)
-					files = append(files, additionalFiles...)
-				} else if !fileInfoStruct.IsDir() { // FileInfo is not a directory
-					files = append(files, filepath.Join(path, name)) // Add to files the file's name
+	getFilesError = readDirectory(path, func(entry os.DirEntry) error {
+		directoryContents = append(directoryContents, entry)
+		return nil
+	})
+
+	options.orderEntries(directoryContents)
+
+	if getFilesError == nil { // If there was no issue reading the directory contents
+		var subDirectoryFiles []*getFilesResult // Results of each sub-directory, kept in order
+		var waitGroup sync.WaitGroup
+
+		for _, entry := range directoryContents { // For each entry in directoryContents
+			if getFilesError = options.canceled(path); getFilesError != nil { // If the listing has been canceled, stop before the next entry
+				break
+			}
+
+			name := entry.Name()
+
+			if options.excluded(filepath.Join(relativePath, name)) { // If this should be skipped
+				continue
+			}
+
+			isDir := entry.IsDir()
+			var entryInfo os.FileInfo // Only needed when following symlinks, to detect loops
+
+			if options.FollowSymlinks && (isDir || entry.Type()&os.ModeSymlink != 0) { // If we should treat this symlink as whatever it points to
+				if targetInfo, statErr := os.Stat(filepath.Join(path, name)); statErr == nil {
+					entryInfo, isDir = targetInfo, targetInfo.IsDir()
 				}
 			}
-		} else { // If there was ano issue reading the directory content
-			getFilesError = errors.New("Cannot read the contents of " + path)
+
+			if ignores.ignored(filepath.Join(relativePath, name), isDir) { // If an ignore file says to skip this
+				continue
+			}
+
+			if limitErr := options.checkLimits(filepath.Join(path, name), filepath.Join(relativePath, name), recursive && isDir); limitErr != nil { // If we've gone too far, stop listing
+				getFilesError = limitErr
+				break
+			}
+
+			if recursive && isDir && ancestors.checkLoop(filepath.Join(path, name), entryInfo) != nil { // If a followed symlink led back into a directory we're already listing, list the link rather than looping
+				isDir = false
+			}
+
+			if recursive && isDir { // If the entry is a directory and we're doing recursive file fetching
+				slot := new(getFilesResult)
+				subDirectoryFiles = append(subDirectoryFiles, slot)
+				subPath, subRelativePath, subAncestors := filepath.Join(path, name), filepath.Join(relativePath, name), ancestors.child(entryInfo)
+
+				select {
+				case workerSlots <- struct{}{}: // If a worker is free, list this sub-directory concurrently
+					waitGroup.Add(1)
+
+					go func() {
+						defer waitGroup.Done()
+						slot.files, slot.err = getFiles(subPath, subRelativePath, true, options, workerSlots, subAncestors, ignores)
+						<-workerSlots
+					}()
+				default:
+					slot.files, slot.err = getFiles(subPath, subRelativePath, true, options, workerSlots, subAncestors, ignores)
+				}
+			} else if !isDir { // The entry is not a directory
+				files = append(files, filepath.Join(path, name)) // Add to files the file's name
+				options.reportProgress(filepath.Join(path, name), 1, -1)
+			}
+		}
+
+		waitGroup.Wait()
+
+		for _, subDirectory := range subDirectoryFiles { // Add the files of each sub-directory
+			files = append(files, subDirectory.files...)
+
+			if getFilesError == nil && isFatalWalkError(subDirectory.err) { // Unreadable sub-directories are skipped, but exceeding a limit, looping, or being canceled stops the listing
+				getFilesError = subDirectory.err
+			}
 		}
-	} else { // If path is not a directory
-		getFilesError = errors.New(path + " is not a directory.")
 	}
 
 	return files, getFilesError
@@ -140,35 +697,56 @@ func GetFiles(path string, recursive bool) ([]string, error) {
 
 // GetFilesContains will return any files from a directory containing a particular string
 func GetFilesContains(path, substring string) ([]string, error) {
-	var files []string                // Define files as the parsed files
-	var getFilesError error           // Define getFilesError as an error
-	var allDirectoryContents []string // Define allDirectoryContents as the contents returned (if any) from GetFiles
+	return getFilesFiltered(path, false, func(relativePath string) bool {
+		return strings.Contains(filepath.Base(relativePath), substring)
+	})
+}
 
-	allDirectoryContents, getFilesError = GetFiles(path, false) // Get all the files from the path
+// GetFilesContainsRecursive will return any files from a directory containing a particular string, recursively
+func GetFilesContainsRecursive(path, substring string) ([]string, error) {
+	return getFilesFiltered(path, true, func(relativePath string) bool {
+		return strings.Contains(filepath.Base(relativePath), substring)
+	})
+}
 
-	if getFilesError == nil { // If there was no issue getting the directory contents
-		for _, fileName := range allDirectoryContents { // For each file name in directory contents
-			if strings.Contains(filepath.Base(fileName), substring) { // If the file name contains our substring
-				files = append(files, fileName) // Append to files
-			}
+// GetFilesMatching will return any files from a directory whose slash separated path relative to the directory matches the glob
+// pattern, where "**" matches any number of directories, such as "**/*.go" for every Go file in the tree
+func GetFilesMatching(path, pattern string, recursive bool) ([]string, error) {
+	segments := strings.Split(pattern, "/")
+
+	for _, segment := range segments { // Check the pattern up front, since a bad pattern would otherwise just match nothing
+		if _, matchErr := filepath.Match(segment, ""); matchErr != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, matchErr)
 		}
 	}
 
-	return files, getFilesError
+	return getFilesFiltered(path, recursive, func(relativePath string) bool {
+		return matchSegments(segments, strings.Split(relativePath, "/"))
+	})
 }
 
-// GetFilesContainsRecursive will return any files from a directory containing a particular string, recursively
-func GetFilesContainsRecursive(path, substring string) ([]string, error) {
-	var files []string                // Define files as the parsed files
-	var getFilesError error           // Define getFilesError as an error
-	var allDirectoryContents []string // Define allDirectoryContents as the contents returned (if any) from GetFiles
+// GetFilesMatchingRegex will return any files from a directory whose slash separated path relative to the directory matches the
+// regular expression. The expression is unanchored, so use ^ and $ to match the whole path.
+func GetFilesMatchingRegex(path, expression string, recursive bool) ([]string, error) {
+	matcher, compileErr := regexp.Compile(expression)
+
+	if compileErr != nil { // If this isn't a valid regular expression
+		return nil, compileErr
+	}
+
+	return getFilesFiltered(path, recursive, matcher.MatchString)
+}
 
-	allDirectoryContents, getFilesError = GetFiles(path, true) // Get all the files from the path
+// getFilesFiltered returns the files from a directory whose slash separated path relative to the directory is kept by keep
+func getFilesFiltered(path string, recursive bool, keep func(relativePath string) bool) ([]string, error) {
+	var files []string
+
+	allDirectoryContents, getFilesError := GetFiles(path, recursive) // Get all the files from the path
 
 	if getFilesError == nil { // If there was no issue getting the directory contents
 		for _, fileName := range allDirectoryContents { // For each file name in directory contents
-			if strings.Contains(filepath.Base(fileName), substring) { // If the file name contains our substring
-				files = append(files, fileName) // Append to files
+			if relativePath, relErr := filepath.Rel(path, fileName); relErr == nil && keep(filepath.ToSlash(relativePath)) {
+				files = append(files, fileName)
 			}
 		}
 	}
@@ -176,12 +754,41 @@ func GetFilesContainsRecursive(path, substring string) ([]string, error) {
 	return files, getFilesError
 }
 
+// GetEntries will get the entries of a directory, sorted by name, without calling stat on each of them. Use the Info method
+// of an entry when its size, modification time, or full mode is needed.
+func GetEntries(path string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+
+	readErr := readDirectory(path, func(entry os.DirEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, readErr
+}
+
+// OpenReader will open the file at path for reading and seeking, such as to pass it to WriteFromReader or an ArchiveWriter
+func OpenReader(path string) (io.ReadSeekCloser, error) {
+	file, openErr := os.Open(path)
+
+	if openErr != nil { // If the file does not exist
+		return nil, openError(path, openErr)
+	}
+
+	return file, nil
+}
+
 // IsDir checks if the path provided is a directory or not
 func IsDir(path string) bool {
 	var isDir bool
 	fileObject, fileOpenError := os.Open(path) // Open currentDirectory + path
 
 	if fileOpenError == nil { // If there was no error opening the file object
+		defer fileObject.Close()
 		stat, filePathError := fileObject.Stat() // Get any stats
 
 		if filePathError == nil { // If we got the statistics properly
@@ -192,8 +799,59 @@ func IsDir(path string) bool {
 	return isDir
 }
 
-// WriteOrUpdateFile writes or updates the file contents of the passed file under the leading filepath with the specified sourceFileMode
-func WriteOrUpdateFile(file string, fileContent []byte, sourceFileMode os.FileMode) error {
+// WriteOrUpdateFile writes or updates the file contents of the passed file under the leading filepath with the specified sourceFileMode.
+// Honors WithExactMode and WithAtomicWrite.
+func WriteOrUpdateFile(file string, fileContent []byte, sourceFileMode os.FileMode, opts ...Option) error {
+	return tracedWriteOrUpdateFile("WriteOrUpdateFile", file, fileContent, sourceFileMode, newOperationOptions(opts))
+}
+
+// tracedWriteOrUpdateFile writes the fileContent to file like writeOrUpdateFile, running the hooks and trace of the named operation
+func tracedWriteOrUpdateFile(name, file string, fileContent []byte, sourceFileMode os.FileMode, options *operationOptions) error {
+	start := time.Now()
+	writeErr := preHooks(name, file, "", sourceFileMode)
+	var written int64
+
+	if writeErr == nil {
+		writeErr = writeOrUpdateFile(file, fileContent, sourceFileMode, options)
+	}
+
+	if writeErr == nil { // If we wrote the file
+		written = int64(len(fileContent))
+		accountWritten(IOCategoryWrite, written)
+	}
+
+	trace(TraceEvent{Op: name, Path: file, Bytes: written, Mode: sourceFileMode, Duration: time.Since(start), Err: writeErr})
+
+	return writeErr
+}
+
+// WriteFromReader writes the content of r to the file at path with the provided mode, creating any directories leading up to it,
+// without buffering the whole content in memory. Returns the number of bytes written. Honors WithBufferSize, WithDirMode, WithExactMode, and WithAtomicWrite.
+func WriteFromReader(path string, r io.Reader, mode os.FileMode, opts ...Option) (int64, error) {
+	start := time.Now()
+	var written int64
+	writeErr := preHooks("WriteFromReader", path, "", mode)
+
+	if writeErr == nil {
+		written, writeErr = copyFromReader(path, r, mode, IOCategoryWrite, newOperationOptions(opts))
+	}
+
+	trace(TraceEvent{Op: "WriteFromReader", Path: path, Bytes: written, Mode: mode, Duration: time.Since(start), Err: writeErr})
+
+	return written, writeErr
+}
+
+// writeOrUpdateFile writes the fileContent to file with the sourceFileMode
+func writeOrUpdateFile(file string, fileContent []byte, sourceFileMode os.FileMode, options *operationOptions) error {
+	if policyErr := checkPathPolicy(file, true); policyErr != nil {
+		return policyErr
+	}
+
+	if options.atomicWrite { // If readers should never see a partially written file
+		_, writeErr := atomicWrite(file, bytes.NewReader(fileContent), sourceFileMode, options)
+		return writeErr
+	}
+
 	var writeDirectory string // Directory to write file
 
 	currentDirectory, _ := os.Getwd()            // Get the working directory
@@ -208,14 +866,18 @@ func WriteOrUpdateFile(file string, fileContent []byte, sourceFileMode os.FileMo
 
 	if currentDirectory != writeDirectory { // If the currentDirectory is not the same directory as the writeDirectory
 		if createDirsErr := os.MkdirAll(writeDirectory, sourceFileMode); createDirsErr != nil { // If we failed to make all the directories needed
-			return errors.New(fmt.Sprintf("Failed to create the path leading up to %s: %s", fileName + ": ", writeDirectory))
+			return writeError(file, fmt.Sprintf("Failed to create the path leading up to %s: %s", fileName+": ", writeDirectory), createDirsErr)
 		}
 	}
 
-	writeErr := ioutil.WriteFile(filepath.Join(writeDirectory,fileName), fileContent, sourceFileMode)
+	writeErr := os.WriteFile(filepath.Join(writeDirectory,fileName), fileContent, sourceFileMode)
+
+	if writeErr == nil && options.exactMode { // If the file should have exactly the mode requested, regardless of umask or a previous mode
+		writeErr = os.Chmod(filepath.Join(writeDirectory, fileName), sourceFileMode)
+	}
 
 	if writeErr != nil {
-		writeErr = errors.New(fmt.Sprintf("Failed to write %s in directory %s: %s", fileName , writeDirectory, writeErr.Error()))
+		writeErr = writeError(file, fmt.Sprintf("Failed to write %s in directory %s", fileName, writeDirectory), writeErr)
 	}
 
 	return writeErr
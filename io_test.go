@@ -0,0 +1,71 @@
+package coreutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCopyDirectoryParallel(t *testing.T) {
+	source := t.TempDir()
+
+	for _, name := range []string{"a/1.txt", "a/b/2.txt", "c/3.txt", "4.txt"} {
+		file := filepath.Join(source, name)
+
+		if mkdirErr := os.MkdirAll(filepath.Dir(file), 0755); mkdirErr != nil {
+			t.Fatal(mkdirErr)
+		}
+
+		if writeErr := os.WriteFile(file, []byte(name), 0644); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+
+	workingDirectory, _ := os.Getwd()
+	destinationRoot := t.TempDir()
+	copyErrs := make([]error, 8)
+	var group sync.WaitGroup
+
+	for index := range copyErrs {
+		group.Add(1)
+
+		go func(index int) {
+			defer group.Done()
+			copyErrs[index] = CopyDirectory(source, filepath.Join(destinationRoot, fmt.Sprint(index)), WithWorkers(2))
+		}(index)
+	}
+
+	group.Wait()
+
+	for index, copyErr := range copyErrs {
+		if copyErr != nil {
+			t.Fatalf("copy %d failed: %v", index, copyErr)
+		}
+
+		for _, name := range []string{"a/1.txt", "a/b/2.txt", "c/3.txt", "4.txt"} {
+			if content, readErr := os.ReadFile(filepath.Join(destinationRoot, fmt.Sprint(index), name)); readErr != nil || string(content) != name {
+				t.Errorf("copy %d has %q for %s, %v", index, content, name, readErr)
+			}
+		}
+	}
+
+	if currentDirectory, _ := os.Getwd(); currentDirectory != workingDirectory { // If a copy changed the working directory out from under the others
+		t.Errorf("expected the working directory to stay %s, got %s", workingDirectory, currentDirectory)
+	}
+}
+
+func TestCopyFileWithProgressNil(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "file.txt")
+	os.WriteFile(source, []byte("content"), 0644)
+
+	if copyErr := CopyFileWithProgress(source, filepath.Join(root, "copy.txt"), nil); copyErr != nil {
+		t.Fatal(copyErr)
+	}
+
+	if content, readErr := os.ReadFile(filepath.Join(root, "copy.txt")); readErr != nil || string(content) != "content" {
+		t.Errorf("expected the file to be copied, got %q, %v", content, readErr)
+	}
+}
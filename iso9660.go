@@ -0,0 +1,191 @@
+package coreutils
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	isoSectorSize      = 2048    // Size of a logical sector
+	isoDescriptorStart = 16      // Sector the volume descriptors begin at
+	isoIdentifier      = "CD001" // Standard identifier of every volume descriptor
+)
+
+// isoImage is a read-only ISO9660 image, with Joliet names used when available
+type isoImage struct {
+	file   *os.File
+	size   int64  // Size of the image, which every extent must be within
+	root   []byte // Directory record of the root directory
+	joliet bool   // Whether the root is from a Joliet supplementary descriptor
+}
+
+// newISOImage reads the volume descriptors of the ISO9660 image
+func newISOImage(file *os.File) (*isoImage, error) {
+	info, statErr := file.Stat()
+
+	if statErr != nil {
+		return nil, openError(file.Name(), statErr)
+	}
+
+	image := &isoImage{file: file, size: info.Size()}
+	descriptor := make([]byte, isoSectorSize)
+
+	for sector := int64(isoDescriptorStart); ; sector++ { // For each volume descriptor
+		if _, readErr := file.ReadAt(descriptor, sector*isoSectorSize); readErr != nil {
+			return nil, errors.New(file.Name() + " has a truncated volume descriptor set.")
+		}
+
+		if string(descriptor[1:6]) != isoIdentifier { // If this isn't a valid descriptor
+			return nil, errors.New(file.Name() + " has an invalid volume descriptor.")
+		}
+
+		descriptorType := descriptor[0]
+
+		if descriptorType == 255 { // If this is the set terminator
+			break
+		}
+
+		isJoliet := (descriptorType == 2) && (descriptor[88] == '%') && (descriptor[89] == '/') && strings.ContainsRune("@CE", rune(descriptor[90]))
+
+		if (descriptorType == 1 && image.root == nil) || isJoliet { // Prefer a Joliet root over the primary one, since it has proper names
+			image.root = append([]byte(nil), descriptor[156:156+34]...)
+			image.joliet = isJoliet
+		}
+	}
+
+	if image.root == nil { // If there was no primary volume descriptor
+		return nil, errors.New(file.Name() + " does not have a primary volume descriptor.")
+	}
+
+	return image, nil
+}
+
+// walk walks every entry of the image, starting from the root directory
+func (image *isoImage) walk(fn imageWalkFunc) error {
+	return image.walkDirectory("", image.root, fn, 0)
+}
+
+// walkDirectory walks the directory described by the directory record, recursing into sub-directories
+func (image *isoImage) walkDirectory(directoryPath string, record []byte, fn imageWalkFunc, depth int) error {
+	if depth > 255 { // ISO9660 limits nesting far below this, so this image is corrupt or malicious
+		return errors.New(image.file.Name() + " has directories nested too deeply.")
+	}
+
+	extent := int64(binary.LittleEndian.Uint32(record[2:6])) * isoSectorSize
+	directorySize := int64(binary.LittleEndian.Uint32(record[10:14]))
+
+	if extent+directorySize > image.size { // If the directory claims to extend past the end of the image
+		return errors.New(image.file.Name() + " has a directory larger than the image in " + directoryPath)
+	}
+
+	directoryContent := make([]byte, directorySize)
+
+	if _, readErr := image.file.ReadAt(directoryContent, extent); readErr != nil { // If we failed to read the directory
+		return errors.New("Unable to read directory " + directoryPath + " from " + image.file.Name())
+	}
+
+	for offset := int64(0); offset < directorySize; {
+		recordLength := int64(directoryContent[offset])
+
+		if recordLength == 0 { // Records do not cross sectors, so skip the padding to the next sector
+			offset = (offset/isoSectorSize + 1) * isoSectorSize
+			continue
+		}
+
+		if recordLength < 34 || offset+recordLength > directorySize { // If the record is corrupt
+			return errors.New(image.file.Name() + " has a corrupt directory record in " + directoryPath)
+		}
+
+		childRecord := directoryContent[offset : offset+recordLength]
+		offset += recordLength
+
+		nameLength := int(childRecord[32])
+
+		if 33+nameLength > len(childRecord) { // If the name would overrun the record
+			return errors.New(image.file.Name() + " has a corrupt directory record in " + directoryPath)
+		}
+
+		rawName := childRecord[33 : 33+nameLength]
+
+		if nameLength == 1 && (rawName[0] == 0 || rawName[0] == 1) { // Skip the . and .. entries
+			continue
+		}
+
+		name := image.decodeName(rawName)
+
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") { // If the name is not a valid single path element
+			return errors.New(image.file.Name() + " has an invalid file name in " + directoryPath)
+		}
+
+		entry := ArchiveEntry{
+			Name:    joinImagePath(directoryPath, name),
+			Size:    int64(binary.LittleEndian.Uint32(childRecord[10:14])),
+			ModTime: isoRecordTime(childRecord[18:25]),
+		}
+
+		if childRecord[25]&0x02 != 0 { // If this is a directory
+			entry.Mode = os.ModeDir | 0755
+			entry.Size = 0
+
+			if walkErr := fn(entry, nil); walkErr != nil {
+				return walkErr
+			}
+
+			if walkErr := image.walkDirectory(entry.Name, childRecord, fn, depth+1); walkErr != nil {
+				return walkErr
+			}
+		} else { // If this is a file
+			entry.Mode = 0644
+			fileOffset := int64(binary.LittleEndian.Uint32(childRecord[2:6])) * isoSectorSize
+			fileSize := entry.Size
+
+			walkErr := fn(entry, func() (io.Reader, error) {
+				return io.NewSectionReader(image.file, fileOffset, fileSize), nil
+			})
+
+			if walkErr != nil {
+				return walkErr
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeName converts the raw file identifier into a usable file name
+func (image *isoImage) decodeName(rawName []byte) string {
+	var name string
+
+	if image.joliet { // Joliet names are big endian UCS-2
+		characters := make([]uint16, len(rawName)/2)
+
+		for index := range characters {
+			characters[index] = binary.BigEndian.Uint16(rawName[index*2:])
+		}
+
+		name = string(utf16.Decode(characters))
+	} else {
+		name = string(rawName)
+	}
+
+	if versionIndex := strings.LastIndex(name, ";"); versionIndex != -1 { // Strip the file version, such as ;1
+		name = name[:versionIndex]
+	}
+
+	if !image.joliet { // Primary names without an extension end in a dot
+		name = strings.TrimSuffix(name, ".")
+	}
+
+	return name
+}
+
+// isoRecordTime converts the 7 byte recording date of a directory record into a time
+func isoRecordTime(recordTime []byte) time.Time {
+	offset := time.FixedZone("", int(int8(recordTime[6]))*15*60) // Offset from GMT in 15 minute intervals
+	return time.Date(1900+int(recordTime[0]), time.Month(recordTime[1]), int(recordTime[2]), int(recordTime[3]), int(recordTime[4]), int(recordTime[5]), 0, offset)
+}
@@ -0,0 +1,127 @@
+package coreutils
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// JunctionPolicy is what CopyDirectory does with NTFS junctions, the directory links of Windows
+type JunctionPolicy int
+
+const (
+	// JunctionRecreate creates a junction at the destination pointing at the same target, as CopyDirectory does with symlinks.
+	// This is the default.
+	JunctionRecreate JunctionPolicy = iota
+
+	// JunctionSkip leaves junctions out of the copy, counting them in Stats.FilesSkipped
+	JunctionSkip
+
+	// JunctionFollow copies the contents of the directory a junction points to. A junction leading back into a directory
+	// already being copied is recreated rather than followed, as with WithFollowSymlinks.
+	JunctionFollow
+)
+
+// WithJunctions sets what CopyDirectory does with NTFS junctions. Defaults to JunctionRecreate. Junctions only exist on Windows,
+// so this does nothing elsewhere.
+func WithJunctions(policy JunctionPolicy) Option {
+	return func(options *operationOptions) {
+		options.junctions = policy
+	}
+}
+
+// WithAlternateStreams sets whether copies carry over the alternate data streams of NTFS files, such as the Zone.Identifier
+// which marks downloaded files, rather than only copying their main content. Alternate data streams only exist on Windows,
+// so this does nothing elsewhere.
+func WithAlternateStreams(copyStreams bool) Option {
+	return func(options *operationOptions) {
+		options.alternateStreams = copyStreams
+	}
+}
+
+// copyJunction recreates the junction sourceJunction at destinationJunction, pointing at the same target
+func copyJunction(sourceJunction, destinationJunction string, options *operationOptions) error {
+	if policyErr := checkPathPolicy(destinationJunction, true); policyErr != nil {
+		options.stats.failed()
+		return policyErr
+	}
+
+	if skip, overwriteErr := options.checkOverwrite(sourceJunction, destinationJunction); overwriteErr != nil { // If something exists there and we shouldn't replace it
+		options.stats.failed()
+		return overwriteErr
+	} else if skip {
+		options.stats.skipped()
+		return nil
+	}
+
+	target, readErr := os.Readlink(sourceJunction)
+
+	if readErr != nil { // If we failed to read where the junction points
+		options.stats.failed()
+		return readError(sourceJunction, readErr)
+	}
+
+	options.recordCreated(destinationJunction)
+
+	if junctionErr := createJunction(target, destinationJunction); junctionErr != nil {
+		options.stats.failed()
+		return writeError(destinationJunction, "Failed to create the junction "+destinationJunction, junctionErr)
+	}
+
+	options.stats.copied(0)
+	return nil
+}
+
+// copyAlternateStreams copies the alternate data streams of sourceFile onto destinationFile, if the operation keeps them.
+// Like copyStream, it must be called with two slots of the budget held and no other files open.
+func (options *operationOptions) copyAlternateStreams(sourceFile, destinationFile string) error {
+	if !options.alternateStreams {
+		return nil
+	}
+
+	streams, listErr := alternateStreams(sourceFile)
+
+	if listErr != nil { // If we failed to list the streams
+		return readError(sourceFile, listErr)
+	}
+
+	for _, stream := range streams { // For each stream, named like ":Zone.Identifier:$DATA"
+		streamName := strings.TrimSuffix(stream, ":$DATA")
+
+		if copyErr := copyStream(sourceFile+streamName, destinationFile+streamName, options); copyErr != nil {
+			return copyErr
+		}
+	}
+
+	return nil
+}
+
+// copyStream copies the content of a single alternate data stream. It runs within the two slots of the budget its caller holds
+// for copying the file, which must have closed the file itself first, since a goroutine can only hold one acquisition at a time.
+func copyStream(sourceStream, destinationStream string, options *operationOptions) error {
+	source, openErr := os.Open(sourceStream)
+
+	if openErr != nil { // If we failed to open the stream
+		return openError(sourceStream, openErr)
+	}
+
+	defer source.Close()
+
+	destination, createErr := os.OpenFile(destinationStream, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, options.DefaultFileMode)
+
+	if createErr != nil { // If we failed to create the stream
+		return writeError(destinationStream, "Failed to create "+destinationStream, createErr)
+	}
+
+	_, copyErr := io.CopyBuffer(destination, source, make([]byte, options.BufferSize))
+
+	if closeErr := destination.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+
+	if copyErr != nil {
+		return writeError(destinationStream, "Failed to write "+destinationStream, copyErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package coreutils
+
+import (
+	"errors"
+)
+
+// isJunction returns false, as junctions only exist on Windows
+func isJunction(path string) bool {
+	return false
+}
+
+// createJunction fails, as junctions only exist on Windows
+func createJunction(target, link string) error {
+	return errors.New("Junctions are only supported on Windows.")
+}
+
+// alternateStreams returns no streams, as alternate data streams only exist on Windows
+func alternateStreams(path string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,88 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// findFirstStream and findNextStream are FindFirstStreamW and FindNextStreamW from kernel32
+var (
+	findFirstStream = syscall.NewLazyDLL("kernel32.dll").NewProc("FindFirstStreamW")
+	findNextStream  = syscall.NewLazyDLL("kernel32.dll").NewProc("FindNextStreamW")
+)
+
+// errorHandleEOF is ERROR_HANDLE_EOF, returned once there are no more streams
+const errorHandleEOF = syscall.Errno(38)
+
+// win32FindStreamData is WIN32_FIND_STREAM_DATA
+type win32FindStreamData struct {
+	streamSize int64
+	streamName [syscall.MAX_PATH + 36]uint16
+}
+
+// isJunction checks if path is an NTFS junction. Go reports junctions as irregular files rather than symlinks, which
+// are told apart from other reparse points by also being directories.
+func isJunction(path string) bool {
+	info, statErr := os.Lstat(path)
+
+	if statErr != nil || info.Mode()&os.ModeIrregular == 0 {
+		return false
+	}
+
+	attributes, isAttributes := info.Sys().(*syscall.Win32FileAttributeData)
+
+	return isAttributes && attributes.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 && attributes.FileAttributes&syscall.FILE_ATTRIBUTE_DIRECTORY != 0
+}
+
+// createJunction creates a junction at link pointing at the target directory
+func createJunction(target, link string) error {
+	if output, mklinkErr := exec.Command("cmd", "/c", "mklink", "/J", link, target).CombinedOutput(); mklinkErr != nil {
+		return errors.New(strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// alternateStreams returns the names of the alternate data streams of the file at path, such as ":Zone.Identifier:$DATA"
+func alternateStreams(path string) ([]string, error) {
+	pathPointer, pathErr := syscall.UTF16PtrFromString(path)
+
+	if pathErr != nil {
+		return nil, pathErr
+	}
+
+	var data win32FindStreamData
+	handle, _, callErr := findFirstStream.Call(uintptr(unsafe.Pointer(pathPointer)), 0, uintptr(unsafe.Pointer(&data)), 0) // 0 is FindStreamInfoStandard
+
+	if syscall.Handle(handle) == syscall.InvalidHandle { // If the file has no streams we can list, such as on a filesystem other than NTFS
+		if callErr == errorHandleEOF {
+			return nil, nil
+		}
+
+		return nil, callErr
+	}
+
+	defer syscall.FindClose(syscall.Handle(handle))
+
+	var streams []string
+
+	for {
+		if name := syscall.UTF16ToString(data.streamName[:]); name != "::$DATA" { // Skip the main content, which is copied already
+			streams = append(streams, name)
+		}
+
+		if result, _, nextErr := findNextStream.Call(handle, uintptr(unsafe.Pointer(&data))); result == 0 { // If there are no more streams
+			if nextErr != errorHandleEOF {
+				return streams, nextErr
+			}
+
+			break
+		}
+	}
+
+	return streams, nil
+}
@@ -0,0 +1,147 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newJunctionTree creates src holding a file and a junction to a directory outside of it, returning src and the junction's target
+func newJunctionTree(t *testing.T) (source, target string) {
+	t.Helper()
+	root := t.TempDir()
+	source = filepath.Join(root, "src")
+	target = filepath.Join(root, "target")
+
+	for _, directory := range []string{source, target} {
+		if mkdirErr := os.Mkdir(directory, 0755); mkdirErr != nil {
+			t.Fatal(mkdirErr)
+		}
+	}
+
+	if writeErr := os.WriteFile(filepath.Join(target, "inside.txt"), []byte("inside"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if junctionErr := createJunction(target, filepath.Join(source, "link")); junctionErr != nil {
+		t.Fatal(junctionErr)
+	}
+
+	if !isJunction(filepath.Join(source, "link")) {
+		t.Fatal("mklink /J did not create a junction")
+	}
+
+	return source, target
+}
+
+func TestCopyDirectoryJunctionRecreate(t *testing.T) {
+	source, _ := newJunctionTree(t)
+	destination := filepath.Join(t.TempDir(), "dst")
+
+	if copyErr := CopyDirectory(source, destination); copyErr != nil {
+		t.Fatal(copyErr)
+	}
+
+	if !isJunction(filepath.Join(destination, "link")) {
+		t.Error("expected the junction to be recreated")
+	}
+}
+
+func TestCopyDirectoryJunctionSkip(t *testing.T) {
+	source, _ := newJunctionTree(t)
+	destination := filepath.Join(t.TempDir(), "dst")
+	stats, copyErr := CopyDirectoryStats(source, destination, WithJunctions(JunctionSkip))
+
+	if copyErr != nil {
+		t.Fatal(copyErr)
+	}
+
+	if _, statErr := os.Lstat(filepath.Join(destination, "link")); !os.IsNotExist(statErr) {
+		t.Errorf("expected the junction to be skipped, got %v", statErr)
+	}
+
+	if stats.FilesSkipped != 1 {
+		t.Errorf("expected 1 skipped entry, got %d", stats.FilesSkipped)
+	}
+}
+
+func TestCopyDirectoryJunctionFollow(t *testing.T) {
+	source, _ := newJunctionTree(t)
+	destination := filepath.Join(t.TempDir(), "dst")
+
+	if copyErr := CopyDirectory(source, destination, WithJunctions(JunctionFollow)); copyErr != nil {
+		t.Fatal(copyErr)
+	}
+
+	if isJunction(filepath.Join(destination, "link")) {
+		t.Error("expected the junction to be followed, not recreated")
+	}
+
+	if content, readErr := os.ReadFile(filepath.Join(destination, "link", "inside.txt")); readErr != nil || string(content) != "inside" {
+		t.Errorf("expected the junction's contents to be copied, got %q, %v", content, readErr)
+	}
+}
+
+func TestCopyAlternateStreams(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "src.txt")
+	destination := filepath.Join(root, "dst.txt")
+
+	if writeErr := os.WriteFile(source, []byte("main"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if writeErr := os.WriteFile(source+":Zone.Identifier", []byte("[ZoneTransfer]\r\nZoneId=3\r\n"), 0644); writeErr != nil {
+		t.Skip("the temporary directory doesn't support alternate data streams:", writeErr)
+	}
+
+	streams, listErr := alternateStreams(source)
+
+	if listErr != nil || len(streams) != 1 || streams[0] != ":Zone.Identifier:$DATA" {
+		t.Fatalf("expected the Zone.Identifier stream, got %v, %v", streams, listErr)
+	}
+
+	if copyErr := CopyFile(source, destination, WithAlternateStreams(true)); copyErr != nil {
+		t.Fatal(copyErr)
+	}
+
+	if content, readErr := os.ReadFile(destination + ":Zone.Identifier"); readErr != nil || string(content) != "[ZoneTransfer]\r\nZoneId=3\r\n" {
+		t.Errorf("expected the stream to be copied, got %q, %v", content, readErr)
+	}
+}
+
+func TestCopyAlternateStreamsWithinBudget(t *testing.T) {
+	defaults := GetDefaults()
+	defer SetDefaults(defaults)
+
+	limited := defaults
+	limited.MaxOpenFiles = 2 // Only enough for the copy itself, so copying the streams mustn't take slots of its own
+	SetDefaults(limited)
+
+	root := t.TempDir()
+	source := filepath.Join(root, "src.txt")
+
+	if writeErr := os.WriteFile(source, []byte("main"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if writeErr := os.WriteFile(source+":extra", []byte("extra"), 0644); writeErr != nil {
+		t.Skip("the temporary directory doesn't support alternate data streams:", writeErr)
+	}
+
+	copied := make(chan error, 1)
+
+	go func() {
+		copied <- CopyDirectory(root, filepath.Join(t.TempDir(), "dst"), WithAlternateStreams(true), WithWorkers(4))
+	}()
+
+	select {
+	case copyErr := <-copied:
+		if copyErr != nil {
+			t.Fatal(copyErr)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("copying alternate streams waited on the open file budget forever")
+	}
+}
@@ -0,0 +1,26 @@
+package coreutils_test
+
+import (
+	"testing"
+
+	"github.com/StroblIndustries/coreutils/testutil"
+)
+
+// largeFileSize is past 4GiB, so offsets which are truncated to 32 bits land on the wrong marker
+const largeFileSize = 1<<32 + 1<<20
+
+func TestCopyFileSparseMarkers(t *testing.T) {
+	if tortureErr := testutil.LargeFileTorture(t.TempDir(), 1<<20); tortureErr != nil {
+		t.Fatal(tortureErr)
+	}
+}
+
+func TestCopyFileLarge(t *testing.T) {
+	if testing.Short() { // The copy isn't sparse, so this writes over 4GiB
+		t.Skip("skipping the multi-GB copy in short mode")
+	}
+
+	if tortureErr := testutil.LargeFileTorture(t.TempDir(), largeFileSize); tortureErr != nil {
+		t.Fatal(tortureErr)
+	}
+}
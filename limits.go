@@ -0,0 +1,66 @@
+package coreutils
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrLimitExceeded is matched by every LimitError using errors.Is
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// LimitError is returned when a recursive operation exceeds one of its configured limits
+type LimitError struct {
+	Limit string // Which limit was exceeded: "depth", "entries", or "path length"
+	Max   int    // The configured maximum
+	Path  string // The path at which the limit was exceeded
+}
+
+// Error returns a description of the exceeded limit
+func (limitErr *LimitError) Error() string {
+	return "Exceeded the maximum " + limitErr.Limit + " of " + strconv.Itoa(limitErr.Max) + " at " + limitErr.Path
+}
+
+// Is allows matching any LimitError against ErrLimitExceeded
+func (limitErr *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// WithMaxDepth limits how many directories deep recursive operations will descend. There is no limit by default.
+func WithMaxDepth(depth int) Option {
+	return func(options *operationOptions) {
+		options.maxDepth = depth
+	}
+}
+
+// WithMaxEntries limits the total number of files and directories a recursive operation will visit. There is no limit by default.
+func WithMaxEntries(entries int) Option {
+	return func(options *operationOptions) {
+		options.maxEntries = entries
+	}
+}
+
+// WithMaxPathLength limits the length in bytes of the paths a recursive operation will visit. There is no limit by default.
+func WithMaxPathLength(length int) Option {
+	return func(options *operationOptions) {
+		options.maxPathLength = length
+	}
+}
+
+// checkLimits checks if visiting the entry at path, which is relativePath within the root of the operation, exceeds any limit
+func (options *operationOptions) checkLimits(path, relativePath string, isDir bool) error {
+	if options.maxPathLength > 0 && len(path) > options.maxPathLength { // If the path is too long
+		return &LimitError{Limit: "path length", Max: options.maxPathLength, Path: path}
+	}
+
+	if options.maxEntries > 0 && options.entriesVisited.Add(1) > int64(options.maxEntries) { // If we have visited too many entries
+		return &LimitError{Limit: "entries", Max: options.maxEntries, Path: path}
+	}
+
+	if isDir && options.maxDepth > 0 && strings.Count(filepath.ToSlash(relativePath), "/")+1 > options.maxDepth { // If descending into this would be too deep
+		return &LimitError{Limit: "depth", Max: options.maxDepth, Path: path}
+	}
+
+	return nil
+}
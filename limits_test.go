@@ -0,0 +1,28 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxDepthIsOptIn(t *testing.T) {
+	root := t.TempDir()
+	deepest := filepath.Join(root, strings.Repeat("d"+string(filepath.Separator), 1100))
+
+	if mkdirErr := os.MkdirAll(deepest, 0755); mkdirErr != nil {
+		t.Skipf("the filesystem can't hold a tree this deep: %v", mkdirErr)
+	}
+
+	os.WriteFile(filepath.Join(deepest, "file.txt"), nil, 0644)
+
+	if files, getErr := GetFiles(root, true); getErr != nil || len(files) != 1 {
+		t.Errorf("expected the deep file to be found without a depth limit, got %v, %v", files, getErr)
+	}
+
+	if _, getErr := GetFiles(root, true, WithMaxDepth(10)); !errors.Is(getErr, ErrLimitExceeded) {
+		t.Errorf("expected WithMaxDepth to limit the walk, got %v", getErr)
+	}
+}
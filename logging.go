@@ -0,0 +1,131 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the severity of a logged message
+type LogLevel int
+
+const (
+	LogDebug   LogLevel = iota // LogDebug is detail only useful when debugging
+	LogInfo                    // LogInfo is routine information, such as an operation completing
+	LogWarning                 // LogWarning is something unexpected which didn't cause a failure
+	LogError                   // LogError is a failed operation
+)
+
+// String returns the name of the level
+func (level LogLevel) String() string {
+	switch level {
+	case LogDebug:
+		return "debug"
+	case LogWarning:
+		return "warning"
+	case LogError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// LogSink is a destination for log messages, such as stderr, syslog, or the systemd journal. Fields are structured data
+// attached to the message, with upper case keys such as "PATH", which sinks without structured logging append to the message.
+type LogSink interface {
+	Log(level LogLevel, message string, fields map[string]string) error
+	Close() error
+}
+
+// writerSink is a LogSink which writes a line of text per message
+type writerSink struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterSink creates a LogSink which writes each message as a line of text to w, such as os.Stderr
+func NewWriterSink(w io.Writer) LogSink {
+	return &writerSink{writer: w}
+}
+
+// Log writes the message as a line of text
+func (sink *writerSink) Log(level LogLevel, message string, fields map[string]string) error {
+	line := time.Now().Format(time.RFC3339) + " " + strings.ToUpper(level.String()) + " " + formatLogMessage(message, fields) + "\n"
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	_, writeErr := io.WriteString(sink.writer, line)
+	return writeErr
+}
+
+// Close does nothing, as the underlying writer belongs to the caller
+func (sink *writerSink) Close() error {
+	return nil
+}
+
+// NewLogSink creates a LogSink by name, so the sink can be chosen at runtime such as from a flag or configuration file.
+// The names are "stderr", "syslog", and "journald". The identifier names the program in syslog and the journal.
+func NewLogSink(name, identifier string) (LogSink, error) {
+	switch strings.ToLower(name) {
+	case "stderr", "":
+		return NewWriterSink(os.Stderr), nil
+	case "syslog":
+		return NewSyslogSink(identifier)
+	case "journald", "journal":
+		return NewJournaldSink(identifier)
+	default:
+		return nil, errors.New(name + " is not a supported log sink.")
+	}
+}
+
+// LogOperations will log every traced operation of the package to the sink, at LogError for failures and LogInfo otherwise.
+// Returns a function which stops logging to the sink, without closing it.
+func LogOperations(sink LogSink) (remove func()) {
+	return AddTraceHook(func(event TraceEvent) {
+		level := LogInfo
+		fields := map[string]string{
+			"OPERATION":   event.Op,
+			"PATH":        event.Path,
+			"BYTES":       strconv.FormatInt(event.Bytes, 10),
+			"DURATION_MS": strconv.FormatInt(event.Duration.Milliseconds(), 10),
+		}
+
+		if event.Destination != "" {
+			fields["DESTINATION"] = event.Destination
+		}
+
+		message := event.Op + " " + event.Path
+
+		if event.Err != nil { // If the operation failed
+			level = LogError
+			fields["ERROR"] = event.Err.Error()
+			message += " failed: " + event.Err.Error()
+		}
+
+		sink.Log(level, message, fields)
+	})
+}
+
+// formatLogMessage appends the fields to the message in sorted KEY=value form, for sinks without structured logging
+func formatLogMessage(message string, fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		message += " " + key + "=" + fmt.Sprintf("%q", fields[key])
+	}
+
+	return message
+}
@@ -0,0 +1,104 @@
+package coreutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// journaldSocket is the socket journald receives native protocol messages on
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journaldSink is a LogSink which sends messages to the systemd journal with their fields
+type journaldSink struct {
+	connection *net.UnixConn
+	identifier string
+}
+
+// NewJournaldSink creates a LogSink which sends messages to the systemd journal, with fields stored as journal fields so
+// they can be filtered with journalctl, such as "journalctl OPERATION=CopyFile"
+func NewJournaldSink(identifier string) (LogSink, error) {
+	connection, dialErr := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+
+	if dialErr != nil { // If journald isn't running
+		return nil, errors.New("Unable to connect to journald: " + dialErr.Error())
+	}
+
+	return &journaldSink{connection: connection, identifier: identifier}, nil
+}
+
+// Log sends the message and its fields to the journal
+func (sink *journaldSink) Log(level LogLevel, message string, fields map[string]string) error {
+	var datagram bytes.Buffer
+
+	writeJournalField(&datagram, "MESSAGE", message)
+	writeJournalField(&datagram, "PRIORITY", strconv.Itoa(syslogPriority(level)))
+
+	if sink.identifier != "" {
+		writeJournalField(&datagram, "SYSLOG_IDENTIFIER", sink.identifier)
+	}
+
+	for key, value := range fields {
+		writeJournalField(&datagram, journalFieldName(key), value)
+	}
+
+	_, writeErr := sink.connection.Write(datagram.Bytes())
+	return writeErr
+}
+
+// Close disconnects from the journal
+func (sink *journaldSink) Close() error {
+	return sink.connection.Close()
+}
+
+// writeJournalField writes the field in the journal native protocol, using the binary form for values with new lines
+func writeJournalField(datagram *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		datagram.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	datagram.WriteString(name + "\n")
+	binary.Write(datagram, binary.LittleEndian, uint64(len(value)))
+	datagram.WriteString(value + "\n")
+}
+
+// journalFieldName converts the key into a valid journal field name, which may only contain upper case letters, digits,
+// and underscores, and can't start with an underscore, which is reserved for journald, or a digit
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsUpper(r) || unicode.IsDigit(r)) {
+			return r
+		} else if r < unicode.MaxASCII && unicode.IsLower(r) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "FIELD_" + name
+	}
+
+	return name
+}
+
+// syslogPriority converts the level into its syslog priority
+func syslogPriority(level LogLevel) int {
+	switch level {
+	case LogDebug:
+		return 7
+	case LogWarning:
+		return 4
+	case LogError:
+		return 3
+	default:
+		return 6
+	}
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package coreutils
+
+import (
+	"errors"
+)
+
+// NewSyslogSink returns an error, as syslog is not available on this platform
+func NewSyslogSink(identifier string) (LogSink, error) {
+	return nil, errors.New("syslog is not available on this platform.")
+}
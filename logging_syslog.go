@@ -0,0 +1,46 @@
+//go:build !windows && !plan9
+
+package coreutils
+
+import (
+	"errors"
+	"log/syslog"
+)
+
+// syslogSink is a LogSink which sends messages to the local syslog daemon
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink creates a LogSink which sends messages to the local syslog daemon, tagged with the identifier.
+// Fields are appended to the message in KEY="value" form.
+func NewSyslogSink(identifier string) (LogSink, error) {
+	writer, dialErr := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, identifier)
+
+	if dialErr != nil { // If syslog isn't running
+		return nil, errors.New("Unable to connect to syslog: " + dialErr.Error())
+	}
+
+	return &syslogSink{writer: writer}, nil
+}
+
+// Log sends the message to syslog at the priority of the level
+func (sink *syslogSink) Log(level LogLevel, message string, fields map[string]string) error {
+	message = formatLogMessage(message, fields)
+
+	switch level {
+	case LogDebug:
+		return sink.writer.Debug(message)
+	case LogWarning:
+		return sink.writer.Warning(message)
+	case LogError:
+		return sink.writer.Err(message)
+	default:
+		return sink.writer.Info(message)
+	}
+}
+
+// Close disconnects from syslog
+func (sink *syslogSink) Close() error {
+	return sink.writer.Close()
+}
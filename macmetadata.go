@@ -0,0 +1,96 @@
+package coreutils
+
+import (
+	"os"
+)
+
+// quarantineAttribute is the extended attribute macOS marks downloaded files with, making Gatekeeper check them before they run
+const quarantineAttribute = "com.apple.quarantine"
+
+// QuarantinePolicy is what copies do with the com.apple.quarantine attribute macOS marks downloaded files with
+type QuarantinePolicy int
+
+const (
+	// QuarantineDefault copies the attribute along with the rest of the metadata when WithMacMetadata is set. This is the default.
+	QuarantineDefault QuarantinePolicy = iota
+
+	// QuarantineStrip removes the attribute from every copy, so downloaded tools run without Gatekeeper prompting
+	QuarantineStrip
+
+	// QuarantinePreserve copies the attribute even without WithMacMetadata, so copies of downloads are still checked
+	QuarantinePreserve
+)
+
+// WithMacMetadata sets whether copies keep the extended attributes of each file and directory on macOS, such as the Finder
+// flags, color labels, and resource forks, which are otherwise dropped. Does nothing on other platforms.
+func WithMacMetadata(preserve bool) Option {
+	return func(options *operationOptions) {
+		options.macMetadata = preserve
+	}
+}
+
+// WithQuarantine sets what copies do with the com.apple.quarantine attribute on macOS. Defaults to QuarantineDefault.
+// Does nothing on other platforms.
+func WithQuarantine(policy QuarantinePolicy) Option {
+	return func(options *operationOptions) {
+		options.quarantine = policy
+	}
+}
+
+// StripQuarantine will remove the com.apple.quarantine attribute from the file at path, or from everything within it if it is a
+// directory, so a downloaded tool runs without Gatekeeper prompting. Does nothing on other platforms.
+func StripQuarantine(path string) error {
+	info, statErr := os.Lstat(path)
+
+	if statErr != nil { // If the path doesn't exist
+		return openError(path, statErr)
+	}
+
+	if !info.IsDir() {
+		return removeExtendedAttribute(path, quarantineAttribute)
+	}
+
+	return walkTree(path, newOperationOptions(nil), func(entryPath, relativePath string, info os.FileInfo) error {
+		if info.Mode()&os.ModeSymlink != 0 { // The attribute would be removed from the target rather than the symlink
+			return nil
+		}
+
+		return removeExtendedAttribute(entryPath, quarantineAttribute)
+	})
+}
+
+// copyMacMetadata copies the extended attributes of sourcePath onto destinationPath under the WithMacMetadata and WithQuarantine
+// options of the operation
+func (options *operationOptions) copyMacMetadata(sourcePath, destinationPath string) error {
+	if options.macMetadata || options.quarantine == QuarantinePreserve { // If anything should be copied
+		names, listErr := listExtendedAttributes(sourcePath)
+
+		if listErr != nil {
+			return readError(sourcePath, listErr)
+		}
+
+		for _, name := range names { // For each attribute, copy it if the options ask for it
+			if name == quarantineAttribute && !(options.quarantine == QuarantinePreserve || (options.macMetadata && options.quarantine != QuarantineStrip)) {
+				continue
+			} else if name != quarantineAttribute && !options.macMetadata {
+				continue
+			}
+
+			value, getErr := getExtendedAttribute(sourcePath, name)
+
+			if getErr != nil {
+				return readError(sourcePath, getErr)
+			}
+
+			if setErr := setExtendedAttribute(destinationPath, name, value); setErr != nil {
+				return writeError(destinationPath, "Failed to set "+name+" on "+destinationPath, setErr)
+			}
+		}
+	}
+
+	if options.quarantine == QuarantineStrip { // Apps with quarantine enabled mark every file they create, so strip it even if it wasn't copied
+		return removeExtendedAttribute(destinationPath, quarantineAttribute)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package coreutils
+
+import (
+	"encoding/hex"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// listExtendedAttributes returns the names of the extended attributes of the file at path
+func listExtendedAttributes(path string) ([]string, error) {
+	output, listErr := exec.Command("xattr", path).Output()
+
+	if listErr != nil {
+		return nil, xattrError(listErr)
+	}
+
+	return strings.Fields(string(output)), nil
+}
+
+// getExtendedAttribute returns the value of the named extended attribute of the file at path
+func getExtendedAttribute(path, name string) ([]byte, error) {
+	output, getErr := exec.Command("xattr", "-px", name, path).Output()
+
+	if getErr != nil {
+		return nil, xattrError(getErr)
+	}
+
+	return hex.DecodeString(strings.Join(strings.Fields(string(output)), "")) // The value is printed as hex, split across lines
+}
+
+// setExtendedAttribute sets the named extended attribute of the file at path
+func setExtendedAttribute(path, name string, value []byte) error {
+	if _, setErr := exec.Command("xattr", "-wx", name, hex.EncodeToString(value), path).Output(); setErr != nil {
+		return xattrError(setErr)
+	}
+
+	return nil
+}
+
+// removeExtendedAttribute removes the named extended attribute from the file at path, if it has it
+func removeExtendedAttribute(path, name string) error {
+	if _, removeErr := exec.Command("xattr", "-d", name, path).Output(); removeErr != nil {
+		if exitErr, isExit := removeErr.(*exec.ExitError); isExit && strings.Contains(string(exitErr.Stderr), "No such xattr") { // If it didn't have the attribute
+			return nil
+		}
+
+		return xattrError(removeErr)
+	}
+
+	return nil
+}
+
+// xattrError converts a failed run of the xattr tool into an error describing why it failed
+func xattrError(runErr error) error {
+	if exitErr, isExit := runErr.(*exec.ExitError); isExit && len(exitErr.Stderr) != 0 {
+		return errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return runErr
+}
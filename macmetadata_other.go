@@ -0,0 +1,23 @@
+//go:build !darwin
+
+package coreutils
+
+// listExtendedAttributes returns no attributes, as Mac metadata only exists on macOS
+func listExtendedAttributes(path string) ([]string, error) {
+	return nil, nil
+}
+
+// getExtendedAttribute returns no value, as Mac metadata only exists on macOS
+func getExtendedAttribute(path, name string) ([]byte, error) {
+	return nil, nil
+}
+
+// setExtendedAttribute does nothing, as Mac metadata only exists on macOS
+func setExtendedAttribute(path, name string, value []byte) error {
+	return nil
+}
+
+// removeExtendedAttribute does nothing, as Mac metadata only exists on macOS
+func removeExtendedAttribute(path, name string) error {
+	return nil
+}
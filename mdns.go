@@ -0,0 +1,342 @@
+package coreutils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mdnsGroup is the multicast address mDNS queries and announcements are sent to
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+const (
+	mdnsTTL            = 120                             // Seconds peers may cache our records for
+	mdnsLegacyTTL      = 10                              // Seconds one-shot queriers, which aren't full mDNS peers, may cache our records for
+	mdnsServicesName   = "_services._dns-sd._udp.local." // Name listing every service type, for browsers
+	mdnsMaxMessageSize = 9000
+)
+
+// ServiceInfo is a service found by DiscoverServices
+type ServiceInfo struct {
+	Instance string            // Name of this instance of the service, such as "Living Room"
+	Type     string            // Type of the service, such as "_myapp._tcp"
+	Host     string            // Host name of the machine offering the service, such as "laptop.local."
+	Port     int               // Port the service listens on
+	Addrs    []net.IP          // Addresses of the host
+	TXT      map[string]string // Metadata published with the service
+}
+
+// mdnsService is a service we announce
+type mdnsService struct {
+	instance    string // Fully qualified name of the instance, such as "Living Room._myapp._tcp.local."
+	serviceType string // Fully qualified type, such as "_myapp._tcp.local."
+	host        string // Fully qualified host name, such as "laptop.local."
+	port        int
+	txt         []string
+	addrs       []net.IP
+}
+
+// AnnounceService will announce a service on the local network over mDNS (zeroconf), answering queries for it until stop is
+// called. The name is the instance name followed by the service type, such as "Living Room._myapp._tcp", and the txt
+// entries are published alongside it as metadata. Only IPv4 is used.
+func AnnounceService(name string, port int, txt map[string]string) (stop func(), err error) {
+	service, serviceErr := newMDNSService(name, port, txt)
+
+	if serviceErr != nil {
+		return nil, serviceErr
+	}
+
+	conn, listenErr := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+
+	if listenErr != nil {
+		return nil, errors.New("Failed to listen for mDNS queries: " + listenErr.Error())
+	}
+
+	var waitGroup sync.WaitGroup
+	stopped := make(chan struct{})
+	waitGroup.Add(2)
+
+	go func() {
+		defer waitGroup.Done()
+		service.respond(conn)
+	}()
+
+	go func() {
+		defer waitGroup.Done()
+		announcement := (&dnsMessage{flags: dnsFlagResponse, answers: service.records(mdnsTTL, true)}).pack()
+		conn.WriteToUDP(announcement, mdnsGroup) // Announce ourselves so peers learn about us without asking
+
+		select {
+		case <-time.After(time.Second): // Repeat in case the first was lost
+			conn.WriteToUDP(announcement, mdnsGroup)
+		case <-stopped:
+		}
+	}()
+
+	var stopOnce sync.Once
+
+	return func() {
+		stopOnce.Do(func() {
+			close(stopped)
+			goodbye := &dnsMessage{flags: dnsFlagResponse, answers: service.records(0, false)} // A TTL of 0 tells peers to forget us
+			conn.WriteToUDP(goodbye.pack(), mdnsGroup)
+			conn.Close()
+			waitGroup.Wait()
+		})
+	}, nil
+}
+
+// DiscoverServices will find instances of the service type, such as "_myapp._tcp", on the local network over mDNS, querying
+// repeatedly until ctx is done and returning every instance which answered, sorted by instance name. Only IPv4 is used.
+func DiscoverServices(ctx context.Context, serviceType string) ([]ServiceInfo, error) {
+	serviceType = strings.TrimSuffix(strings.TrimSuffix(serviceType, "."), ".local") + ".local."
+	conn, listenErr := net.ListenUDP("udp4", nil) // Querying from a port other than 5353 asks responders to answer us directly
+
+	if listenErr != nil {
+		return nil, errors.New("Failed to listen for mDNS answers: " + listenErr.Error())
+	}
+
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now()) // Unblock the read below
+	}()
+
+	query := (&dnsMessage{questions: []dnsQuestion{{name: serviceType, qtype: dnsTypePTR}}}).pack()
+	var records []dnsRecord
+	buffer := make([]byte, mdnsMaxMessageSize)
+
+	for nextQuery := time.Now(); ctx.Err() == nil; {
+		if time.Now().After(nextQuery) { // Ask again every second, in case queries or answers are lost
+			if _, writeErr := conn.WriteToUDP(query, mdnsGroup); writeErr != nil {
+				return nil, errors.New("Failed to send the mDNS query: " + writeErr.Error())
+			}
+
+			nextQuery = time.Now().Add(time.Second)
+		}
+
+		conn.SetReadDeadline(nextQuery)
+		readCount, _, readErr := conn.ReadFromUDP(buffer)
+
+		if readErr != nil { // If it's time to query again, or we're done
+			continue
+		}
+
+		if message, parseErr := parseDNSMessage(buffer[:readCount]); parseErr == nil && message.flags&0x8000 != 0 { // If this is an answer
+			records = append(records, message.answers...)
+		}
+	}
+
+	return assembleServices(serviceType, records), nil
+}
+
+// newMDNSService describes the service to announce, with the host name and addresses of this machine
+func newMDNSService(name string, port int, txt map[string]string) (*mdnsService, error) {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "."), ".local")
+	protocolIndex := max(strings.LastIndex(name, "._tcp"), strings.LastIndex(name, "._udp"))
+
+	if protocolIndex == -1 || protocolIndex+5 != len(name) { // If the name doesn't end with a service type
+		return nil, errors.New(name + " is not an instance name followed by a service type such as _myapp._tcp.")
+	}
+
+	typeIndex := strings.LastIndex(name[:protocolIndex], "._")
+
+	if typeIndex <= 0 {
+		return nil, errors.New(name + " is not an instance name followed by a service type such as _myapp._tcp.")
+	}
+
+	hostName, hostErr := os.Hostname()
+
+	if hostErr != nil {
+		return nil, hostErr
+	}
+
+	service := &mdnsService{
+		instance:    strings.ReplaceAll(name[:typeIndex], ".", `\.`) + "." + name[typeIndex+1:] + ".local.",
+		serviceType: name[typeIndex+1:] + ".local.",
+		host:        strings.Split(hostName, ".")[0] + ".local.",
+		port:        port,
+	}
+
+	for key, value := range txt {
+		service.txt = append(service.txt, key+"="+value)
+	}
+
+	sort.Strings(service.txt)
+
+	if len(service.txt) == 0 { // TXT records must have at least one string
+		service.txt = []string{""}
+	}
+
+	interfaceAddrs, _ := net.InterfaceAddrs()
+
+	for _, addr := range interfaceAddrs {
+		if ipNet, isIP := addr.(*net.IPNet); isIP && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			service.addrs = append(service.addrs, ipNet.IP.To4())
+		}
+	}
+
+	return service, nil
+}
+
+// respond answers queries for the service until conn is closed
+func (service *mdnsService) respond(conn *net.UDPConn) {
+	buffer := make([]byte, mdnsMaxMessageSize)
+
+	for {
+		readCount, source, readErr := conn.ReadFromUDP(buffer)
+
+		if errors.Is(readErr, net.ErrClosed) {
+			return
+		} else if readErr != nil {
+			continue
+		}
+
+		query, parseErr := parseDNSMessage(buffer[:readCount])
+
+		if parseErr != nil || query.flags&0x8000 != 0 { // If this isn't a query
+			continue
+		}
+
+		legacy := source.Port != mdnsGroup.Port // One-shot queriers expect a direct, conventional DNS answer
+		ttl := uint32(mdnsTTL)
+
+		if legacy {
+			ttl = mdnsLegacyTTL
+		}
+
+		var answers []dnsRecord
+
+		for _, question := range query.questions {
+			answers = append(answers, service.answer(question, ttl)...)
+		}
+
+		if len(answers) == 0 {
+			continue
+		}
+
+		response := &dnsMessage{flags: dnsFlagResponse, answers: answers}
+		destination := mdnsGroup
+
+		if legacy {
+			response.id, response.questions, destination = query.id, query.questions, source
+		}
+
+		conn.WriteToUDP(response.pack(), destination)
+	}
+}
+
+// answer returns the records answering the question, if it is about us
+func (service *mdnsService) answer(question dnsQuestion, ttl uint32) []dnsRecord {
+	matches := func(qtype uint16) bool { return question.qtype == qtype || question.qtype == dnsTypeANY }
+
+	switch {
+	case sameDNSName(question.name, service.serviceType) && matches(dnsTypePTR):
+		return service.records(ttl, true)
+	case sameDNSName(question.name, mdnsServicesName) && matches(dnsTypePTR):
+		return []dnsRecord{{name: mdnsServicesName, rtype: dnsTypePTR, class: dnsClassIN, ttl: ttl, target: service.serviceType}}
+	case sameDNSName(question.name, service.instance) && (matches(dnsTypeSRV) || matches(dnsTypeTXT)):
+		return service.records(ttl, true)[1:]
+	case sameDNSName(question.name, service.host) && matches(dnsTypeA):
+		return service.records(ttl, true)[3:]
+	}
+
+	return nil
+}
+
+// records returns the PTR, SRV, and TXT records of the service, followed by the addresses of the host if requested
+func (service *mdnsService) records(ttl uint32, withAddrs bool) []dnsRecord {
+	uniqueClass := dnsClassIN | dnsClassCacheFlush
+
+	records := []dnsRecord{
+		{name: service.serviceType, rtype: dnsTypePTR, class: dnsClassIN, ttl: ttl, target: service.instance},
+		{name: service.instance, rtype: dnsTypeSRV, class: uniqueClass, ttl: ttl, target: service.host, port: uint16(service.port)},
+		{name: service.instance, rtype: dnsTypeTXT, class: uniqueClass, ttl: ttl, txt: service.txt},
+	}
+
+	for _, addr := range service.addrs {
+		if !withAddrs {
+			break
+		}
+
+		records = append(records, dnsRecord{name: service.host, rtype: dnsTypeA, class: uniqueClass, ttl: ttl, ip: addr})
+	}
+
+	return records
+}
+
+// assembleServices combines the records answering a query for the service type into the instances they describe
+func assembleServices(serviceType string, records []dnsRecord) []ServiceInfo {
+	instances := make(map[string]*ServiceInfo)
+	addrs := make(map[string][]net.IP) // Addresses of each host, by lowercase name
+
+	for _, record := range records {
+		if record.rtype == dnsTypePTR && sameDNSName(record.name, serviceType) && record.ttl != 0 {
+			instances[strings.ToLower(record.target)] = &ServiceInfo{Type: strings.TrimSuffix(serviceType, ".local."), TXT: make(map[string]string)}
+		} else if record.rtype == dnsTypeA || record.rtype == dnsTypeAAAA {
+			host := strings.ToLower(record.name)
+
+			if !containsIP(addrs[host], record.ip) {
+				addrs[host] = append(addrs[host], record.ip)
+			}
+		}
+	}
+
+	for _, record := range records {
+		instance, found := instances[strings.ToLower(record.name)]
+
+		if !found {
+			continue
+		}
+
+		switch record.rtype {
+		case dnsTypeSRV:
+			instance.Host, instance.Port = record.target, int(record.port)
+		case dnsTypeTXT:
+			for _, text := range record.txt {
+				if key, value, _ := strings.Cut(text, "="); key != "" {
+					instance.TXT[key] = value
+				}
+			}
+		}
+	}
+
+	var services []ServiceInfo
+
+	for name, instance := range instances {
+		instanceLabel := splitDNSName(name)[0]
+
+		for _, record := range records { // Take the instance name with its original case
+			if record.rtype == dnsTypePTR && strings.EqualFold(record.target, name) {
+				instanceLabel = splitDNSName(record.target)[0]
+			}
+		}
+
+		instance.Instance = instanceLabel
+		instance.Addrs = addrs[strings.ToLower(instance.Host)]
+		services = append(services, *instance)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Instance < services[j].Instance
+	})
+
+	return services
+}
+
+// containsIP checks if the address is in the list
+func containsIP(list []net.IP, ip net.IP) bool {
+	for _, listed := range list {
+		if listed.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,200 @@
+// Package metrics exposes Prometheus metrics for the IO operations of coreutils.
+//
+// Instrumenting a consumer only requires serving the Handler:
+//
+//	http.Handle("/metrics", metrics.Handler())
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/StroblIndustries/coreutils"
+)
+
+// DurationBuckets are the upper bounds, in seconds, of the operation duration histogram buckets
+var DurationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60}
+
+// Collector accumulates metrics from coreutils trace events
+type Collector struct {
+	lock       sync.Mutex
+	bytes      map[string]float64    // Bytes written, by operation
+	operations map[string]float64    // Completed operations, by operation
+	errors     map[[2]string]float64 // Errors, by operation and error type
+	durations  map[string]*histogram // Durations, by operation
+	remove     func()
+}
+
+// histogram is a cumulative Prometheus histogram
+type histogram struct {
+	counts []float64 // Count of observations less than or equal to each bucket
+	sum    float64
+	count  float64
+}
+
+var (
+	defaultCollector *Collector
+	defaultOnce      sync.Once
+)
+
+// Handler returns an http.Handler serving the metrics of the default Collector, registering it with coreutils on first use
+func Handler() http.Handler {
+	defaultOnce.Do(func() {
+		defaultCollector = NewCollector()
+	})
+
+	return defaultCollector
+}
+
+// NewCollector creates a Collector and registers it as a coreutils trace hook
+func NewCollector() *Collector {
+	collector := &Collector{
+		bytes:      make(map[string]float64),
+		operations: make(map[string]float64),
+		errors:     make(map[[2]string]float64),
+		durations:  make(map[string]*histogram),
+	}
+
+	collector.remove = coreutils.AddTraceHook(collector.Observe)
+
+	return collector
+}
+
+// Close unregisters the Collector from coreutils. Metrics collected so far remain available.
+func (collector *Collector) Close() {
+	collector.remove()
+}
+
+// Observe records a trace event
+func (collector *Collector) Observe(event coreutils.TraceEvent) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+
+	collector.operations[event.Op]++
+	collector.bytes[event.Op] += float64(event.Bytes)
+
+	if event.Err != nil { // If the operation failed, record it by the type of error
+		collector.errors[[2]string{event.Op, errorType(event.Err)}]++
+	}
+
+	durationHistogram, exists := collector.durations[event.Op]
+
+	if !exists {
+		durationHistogram = &histogram{counts: make([]float64, len(DurationBuckets))}
+		collector.durations[event.Op] = durationHistogram
+	}
+
+	seconds := event.Duration.Seconds()
+
+	for index, bound := range DurationBuckets { // Buckets are cumulative, so count every bucket this fits in
+		if seconds <= bound {
+			durationHistogram.counts[index]++
+		}
+	}
+
+	durationHistogram.sum += seconds
+	durationHistogram.count++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (collector *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	collector.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format to w
+func (collector *Collector) WriteTo(w io.Writer) (int64, error) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+
+	var output strings.Builder
+
+	output.WriteString("# HELP coreutils_bytes_total Bytes written by coreutils operations.\n# TYPE coreutils_bytes_total counter\n")
+
+	for _, op := range sortedKeys(collector.bytes) {
+		fmt.Fprintf(&output, "coreutils_bytes_total{op=%q} %s\n", op, formatValue(collector.bytes[op]))
+	}
+
+	output.WriteString("# HELP coreutils_operations_total Completed coreutils operations.\n# TYPE coreutils_operations_total counter\n")
+
+	for _, op := range sortedKeys(collector.operations) {
+		fmt.Fprintf(&output, "coreutils_operations_total{op=%q} %s\n", op, formatValue(collector.operations[op]))
+	}
+
+	output.WriteString("# HELP coreutils_errors_total Failed coreutils operations by type of error.\n# TYPE coreutils_errors_total counter\n")
+
+	errorKeys := make([][2]string, 0, len(collector.errors))
+
+	for key := range collector.errors {
+		errorKeys = append(errorKeys, key)
+	}
+
+	sort.Slice(errorKeys, func(i, j int) bool {
+		return errorKeys[i][0] < errorKeys[j][0] || (errorKeys[i][0] == errorKeys[j][0] && errorKeys[i][1] < errorKeys[j][1])
+	})
+
+	for _, key := range errorKeys {
+		fmt.Fprintf(&output, "coreutils_errors_total{op=%q,type=%q} %s\n", key[0], key[1], formatValue(collector.errors[key]))
+	}
+
+	output.WriteString("# HELP coreutils_operation_duration_seconds Duration of coreutils operations.\n# TYPE coreutils_operation_duration_seconds histogram\n")
+
+	for _, op := range sortedKeys(collector.durations) {
+		durationHistogram := collector.durations[op]
+
+		for index, bound := range DurationBuckets {
+			fmt.Fprintf(&output, "coreutils_operation_duration_seconds_bucket{op=%q,le=%q} %s\n", op, formatValue(bound), formatValue(durationHistogram.counts[index]))
+		}
+
+		fmt.Fprintf(&output, "coreutils_operation_duration_seconds_bucket{op=%q,le=\"+Inf\"} %s\n", op, formatValue(durationHistogram.count))
+		fmt.Fprintf(&output, "coreutils_operation_duration_seconds_sum{op=%q} %s\n", op, formatValue(durationHistogram.sum))
+		fmt.Fprintf(&output, "coreutils_operation_duration_seconds_count{op=%q} %s\n", op, formatValue(durationHistogram.count))
+	}
+
+	written, writeErr := io.WriteString(w, output.String())
+	return int64(written), writeErr
+}
+
+// errorType classifies an error into a small set of label values
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return "not_exist"
+	case errors.Is(err, os.ErrPermission):
+		return "permission"
+	case errors.Is(err, os.ErrExist):
+		return "exist"
+	default:
+		return "other"
+	}
+}
+
+// formatValue formats a sample value the way Prometheus expects
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of the map in sorted order, so output is stable
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
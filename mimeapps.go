@@ -0,0 +1,190 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mimeAppsDefaultSection is the section of mimeapps.list holding the default application of each MIME type
+const mimeAppsDefaultSection = "[Default Applications]"
+
+// SetDefaultApplication will make the desktop file, such as "org.gnome.gedit.desktop", the current user's default application
+// for the MIME type by updating their mimeapps.list. Other associations in the file are preserved.
+func SetDefaultApplication(mimeType, desktopFile string) error {
+	if !strings.Contains(mimeType, "/") || strings.ContainsAny(mimeType, "=\n") { // If this isn't a MIME type
+		return errors.New(mimeType + " is not a valid MIME type.")
+	}
+
+	if !strings.HasSuffix(desktopFile, ".desktop") || strings.ContainsAny(desktopFile, "/;\n") { // If this isn't a desktop file ID
+		return errors.New(desktopFile + " is not a valid desktop file ID.")
+	}
+
+	configDirectory := xdgConfigDirectory(ScopeUser)
+	listFile := filepath.Join(configDirectory, "mimeapps.list")
+
+	var lines []string
+
+	if content, readErr := os.ReadFile(listFile); readErr == nil { // If the user already has associations, keep them
+		lines = strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	} else if !os.IsNotExist(readErr) {
+		return readError(listFile, readErr)
+	}
+
+	lines = setMimeAppsDefault(lines, mimeType, desktopFile)
+
+	if mkdirErr := os.MkdirAll(configDirectory, GetDefaults().DefaultDirMode); mkdirErr != nil {
+		return writeError(configDirectory, "Failed to create "+configDirectory, mkdirErr)
+	}
+
+	return WriteOrUpdateFile(listFile, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// GetDefaultApplication will return the desktop file ID of the default application for the MIME type, searching the
+// mimeapps.list files in the order of the XDG specification and skipping applications which aren't installed
+func GetDefaultApplication(mimeType string) (string, error) {
+	for _, listFile := range mimeAppsListFiles() { // For each mimeapps.list, in order of precedence
+		content, readErr := os.ReadFile(listFile)
+
+		if readErr != nil { // Most of these won't exist
+			continue
+		}
+
+		for _, desktopFile := range mimeAppsDefaults(strings.Split(string(content), "\n"), mimeType) {
+			if desktopEntryInstalled(desktopFile) {
+				return desktopFile, nil
+			}
+		}
+	}
+
+	return "", errors.New("No default application is set for " + mimeType)
+}
+
+// setMimeAppsDefault sets the default of the MIME type in the lines of a mimeapps.list, adding the section if needed
+func setMimeAppsDefault(lines []string, mimeType, desktopFile string) []string {
+	entry := mimeType + "=" + desktopFile + ";"
+	inSection := false
+	sectionEnd := -1 // Index after the last line of the section, where a new entry is added
+
+	for index, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmedLine, "[") { // If this starts a section
+			inSection = (trimmedLine == mimeAppsDefaultSection)
+
+			if inSection {
+				sectionEnd = index + 1
+			}
+
+			continue
+		}
+
+		if !inSection {
+			continue
+		}
+
+		if key, _, found := strings.Cut(trimmedLine, "="); found && strings.TrimSpace(key) == mimeType { // If the MIME type already has a default, replace it
+			lines[index] = entry
+			return lines
+		}
+
+		if trimmedLine != "" { // Add after the last entry of the section rather than after trailing blank lines
+			sectionEnd = index + 1
+		}
+	}
+
+	if sectionEnd == -1 { // If there is no section of defaults yet
+		if len(lines) != 0 {
+			lines = append(lines, "")
+		}
+
+		return append(lines, mimeAppsDefaultSection, entry)
+	}
+
+	return append(lines[:sectionEnd], append([]string{entry}, lines[sectionEnd:]...)...)
+}
+
+// mimeAppsDefaults returns the desktop file IDs listed as the default of the MIME type in the lines of a mimeapps.list
+func mimeAppsDefaults(lines []string, mimeType string) []string {
+	inSection := false
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmedLine, "[") { // If this starts a section
+			inSection = (trimmedLine == mimeAppsDefaultSection)
+		} else if key, value, found := strings.Cut(trimmedLine, "="); inSection && found && strings.TrimSpace(key) == mimeType {
+			return strings.FieldsFunc(value, func(r rune) bool { return r == ';' })
+		}
+	}
+
+	return nil
+}
+
+// mimeAppsListFiles returns the mimeapps.list files in order of precedence, including desktop specific files such as gnome-mimeapps.list
+func mimeAppsListFiles() []string {
+	var desktops []string
+
+	for _, desktop := range strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":") {
+		if desktop != "" {
+			desktops = append(desktops, strings.ToLower(desktop))
+		}
+	}
+
+	var listFiles []string
+
+	addDirectory := func(directory string) {
+		for _, desktop := range desktops {
+			listFiles = append(listFiles, filepath.Join(directory, desktop+"-mimeapps.list"))
+		}
+
+		listFiles = append(listFiles, filepath.Join(directory, "mimeapps.list"))
+	}
+
+	addDirectory(xdgConfigDirectory(ScopeUser))
+
+	for _, configDirectory := range xdgDirectories("XDG_CONFIG_DIRS", "/etc/xdg") {
+		addDirectory(configDirectory)
+	}
+
+	addDirectory(filepath.Join(xdgDataDirectory(ScopeUser), "applications"))
+
+	for _, dataDirectory := range xdgDirectories("XDG_DATA_DIRS", "/usr/local/share:/usr/share") {
+		addDirectory(filepath.Join(dataDirectory, "applications"))
+	}
+
+	return listFiles
+}
+
+// desktopEntryInstalled checks if the desktop file exists in any of the applications directories
+func desktopEntryInstalled(desktopFile string) bool {
+	dataDirectories := append([]string{xdgDataDirectory(ScopeUser)}, xdgDirectories("XDG_DATA_DIRS", "/usr/local/share:/usr/share")...)
+
+	for _, dataDirectory := range dataDirectories {
+		if _, statErr := os.Stat(filepath.Join(dataDirectory, "applications", desktopFile)); statErr == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// xdgDirectories returns the absolute directories of the colon separated environment variable, or of the fallback if it is unset
+func xdgDirectories(variable, fallback string) []string {
+	value := os.Getenv(variable)
+
+	if value == "" {
+		value = fallback
+	}
+
+	var directories []string
+
+	for _, directory := range strings.Split(value, ":") {
+		if filepath.IsAbs(directory) { // The spec requires relative paths to be ignored
+			directories = append(directories, directory)
+		}
+	}
+
+	return directories
+}
@@ -0,0 +1,74 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ModeMap sets the permissions of copied and extracted files by glob pattern, so deployments don't inherit whatever modes the
+// machine they were built on happened to have
+type ModeMap struct {
+	Patterns map[string]os.FileMode // Glob patterns, such as "*.sh", to the mode of the files matching them. Patterns are matched like WithExclude, and the longest matching pattern wins.
+	File     os.FileMode            // Mode of files matching no pattern. Defaults to 0644.
+	Dir      os.FileMode            // Mode of directories. Defaults to 0755.
+}
+
+// WithModeMap gives each copied or extracted file and directory its mode under the ModeMap, rather than the mode of its source.
+// Modes are set exactly, so the umask does not apply. Symlinks are left as they are.
+func WithModeMap(modes ModeMap) Option {
+	return func(options *operationOptions) {
+		options.modeMap = &modes
+	}
+}
+
+// mode returns the mode of the file or directory at relativePath, relative to the root of the operation, under the ModeMap
+func (modes *ModeMap) mode(relativePath string, isDir bool) os.FileMode {
+	if isDir {
+		if modes.Dir == 0 {
+			return 0755
+		}
+
+		return modes.Dir
+	}
+
+	relativePath = filepath.ToSlash(relativePath)
+	baseName := filepath.Base(relativePath)
+	mode, matchedPattern := modes.File, ""
+
+	if mode == 0 {
+		mode = 0644
+	}
+
+	for pattern, patternMode := range modes.Patterns { // For each pattern, keep the most specific which matches. Ties go to the first in sort order, so the result doesn't depend on map order.
+		if len(pattern) < len(matchedPattern) || (len(pattern) == len(matchedPattern) && pattern > matchedPattern) {
+			continue
+		}
+
+		baseMatch, _ := filepath.Match(pattern, baseName)
+		pathMatch, _ := filepath.Match(pattern, relativePath)
+
+		if baseMatch || pathMatch {
+			mode, matchedPattern = patternMode, pattern
+		}
+	}
+
+	return mode
+}
+
+// applyModeMap sets the mode of path, which is relativePath within the root of the operation, under the ModeMap of the
+// operation, if it has one. Paths which no longer exist, such as files rolled back by WithValidateRollback, are ignored.
+func (options *operationOptions) applyModeMap(path, relativePath string, isDir bool) error {
+	if options.modeMap == nil {
+		return nil
+	}
+
+	if info, statErr := os.Lstat(path); statErr != nil || info.Mode()&os.ModeSymlink != 0 { // If the path is gone, or is a symlink whose target isn't ours to change
+		return nil
+	}
+
+	if chmodErr := os.Chmod(path, options.modeMap.mode(relativePath, isDir)); chmodErr != nil {
+		return writeError(path, "Failed to set the mode of "+path, chmodErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package coreutils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// MountImage will mount the disk image read-only on the mountpoint using a loopback device. An empty fsType lets mount detect it.
+func MountImage(image, mountpoint string, fsType string) error {
+	if os.Geteuid() != 0 { // If we are not running as root
+		return errors.New("Mounting " + image + " requires root privileges.")
+	}
+
+	if _, statErr := os.Stat(image); statErr != nil { // If the image doesn't exist
+		return openError(image, statErr)
+	}
+
+	if !IsDir(mountpoint) { // If the mountpoint isn't a directory
+		return notDirectoryError(mountpoint, nil)
+	}
+
+	if !ExecutableExists("mount") { // If mount isn't available
+		return errors.New("mount is not an executable.")
+	}
+
+	args := []string{"-o", "loop,ro"}
+
+	if fsType != "" { // If a filesystem type was provided
+		args = append(args, "-t", fsType)
+	}
+
+	args = append(args, image, mountpoint)
+
+	if output, mountErr := exec.Command("mount", args...).CombinedOutput(); mountErr != nil { // If we failed to mount the image
+		return errors.New("Failed to mount " + image + " on " + mountpoint + ": " + strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// MountImageContext will mount the disk image like MountImage, unmounting it once ctx is canceled
+func MountImageContext(ctx context.Context, image, mountpoint string, fsType string) error {
+	if mountErr := MountImage(image, mountpoint, fsType); mountErr != nil {
+		return mountErr
+	}
+
+	go func() {
+		<-ctx.Done()
+		Unmount(mountpoint)
+	}()
+
+	return nil
+}
+
+// Unmount will unmount whatever is mounted on the mountpoint, detaching any loopback device used by it
+func Unmount(mountpoint string) error {
+	if os.Geteuid() != 0 { // If we are not running as root
+		return errors.New("Unmounting " + mountpoint + " requires root privileges.")
+	}
+
+	if !ExecutableExists("umount") { // If umount isn't available
+		return errors.New("umount is not an executable.")
+	}
+
+	if output, unmountErr := exec.Command("umount", "-d", mountpoint).CombinedOutput(); unmountErr != nil { // If we failed to unmount
+		return errors.New("Failed to unmount " + mountpoint + ": " + strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package coreutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// multiCopyDestination is a single destination of CopyFileMulti
+type multiCopyDestination struct {
+	path string
+	file *os.File
+	err  error
+}
+
+// CopyFileMulti will copy the src file to every destination in dsts, reading the source only once and writing to the
+// destinations concurrently. A destination which fails is removed and does not stop the others; the returned error joins
+// the errors of every failed destination. Honors WithBufferSize, WithDirMode, WithExactMode, and WithProgress.
+func CopyFileMulti(src string, dsts []string, opts ...Option) error {
+	start := time.Now()
+	options := newOperationOptions(opts)
+
+	var read int64
+	copyError := preHooks("CopyFileMulti", src, strings.Join(dsts, ", "), 0)
+
+	if copyError == nil {
+		read, copyError = copyFileMulti(src, dsts, options)
+	}
+
+	trace(TraceEvent{Op: "CopyFileMulti", Path: src, Destination: strings.Join(dsts, ", "), Bytes: read, Duration: time.Since(start), Err: copyError})
+
+	return copyError
+}
+
+// copyFileMulti fans the content of src out to dsts, returning the number of bytes read from src
+func copyFileMulti(src string, dsts []string, options *operationOptions) (int64, error) {
+	sourceStat, statErr := os.Stat(src)
+
+	if statErr != nil { // If the source does not exist
+		return 0, openError(src, statErr)
+	} else if sourceStat.IsDir() {
+		return 0, errors.New(src + " is a directory. Please use CopyDirectory instead.")
+	} else if policyErr := checkPathPolicy(src, false); policyErr != nil {
+		return 0, policyErr
+	}
+
+	destinations := make([]*multiCopyDestination, len(dsts))
+
+	for index, dst := range dsts { // Refuse any destination which is the source itself, or which the policy doesn't allow
+		destinations[index] = &multiCopyDestination{path: dst, err: checkOverlap(src, dst)}
+
+		if destinations[index].err == nil {
+			destinations[index].err = checkPathPolicy(dst, true)
+		}
+	}
+
+	openFiles.acquireMany(len(dsts) + 1) // The source and every destination are open at once
+	defer openFiles.releaseMany(len(dsts) + 1)
+
+	sourceFile, openErr := os.Open(src)
+
+	if openErr != nil { // If we failed to open the source
+		return 0, openError(src, openErr)
+	}
+
+	defer sourceFile.Close()
+
+	for _, destination := range destinations { // Create each destination
+		if destination.err != nil {
+			continue
+		}
+
+		if mkdirErr := os.MkdirAll(filepath.Dir(destination.path), options.DefaultDirMode); mkdirErr != nil {
+			destination.err = writeError(destination.path, "Failed to create the path leading up to "+destination.path, mkdirErr)
+		} else if destination.file, openErr = os.OpenFile(destination.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, sourceStat.Mode()); openErr != nil {
+			destination.err = writeError(destination.path, "Failed to create "+destination.path, openErr)
+		}
+	}
+
+	buffer := make([]byte, options.BufferSize)
+	var read int64
+	var readErr error
+
+	for readErr == nil {
+		var readCount int
+		readCount, readErr = sourceFile.Read(buffer)
+
+		if readCount > 0 { // Write this chunk to every destination which hasn't failed
+			var waitGroup sync.WaitGroup
+
+			for _, destination := range destinations {
+				if destination.file == nil || destination.err != nil {
+					continue
+				}
+
+				waitGroup.Add(1)
+
+				go func(destination *multiCopyDestination) {
+					defer waitGroup.Done()
+
+					if _, writeErr := destination.file.Write(buffer[:readCount]); writeErr != nil {
+						destination.err = writeError(destination.path, "Failed to write "+destination.path, writeErr)
+					}
+				}(destination)
+			}
+
+			waitGroup.Wait()
+
+			read += int64(readCount)
+			options.reportProgress(src, read, sourceStat.Size())
+		}
+	}
+
+	accountRead(IOCategoryCopy, read)
+
+	if readErr != io.EOF { // If we failed partway through reading the source, every destination is incomplete
+		readErr = readError(src, readErr)
+	} else {
+		readErr = nil
+	}
+
+	var destinationErrors []error
+
+	for _, destination := range destinations { // Finish each destination, removing any which failed
+		if destination.file != nil {
+			if closeErr := destination.file.Close(); destination.err == nil && closeErr != nil {
+				destination.err = writeError(destination.path, "Failed to write "+destination.path, closeErr)
+			}
+
+			if destination.err == nil && readErr != nil {
+				destination.err = readErr
+			}
+
+			if destination.err == nil && options.exactMode { // If the file should have exactly the mode of the source, regardless of umask
+				destination.err = os.Chmod(destination.path, sourceStat.Mode())
+			}
+
+			if destination.err != nil { // Don't leave a partial copy behind
+				os.Remove(destination.path)
+			}
+		}
+
+		if destination.err != nil {
+			destinationErrors = append(destinationErrors, destination.err)
+		} else {
+			accountWritten(IOCategoryCopy, read)
+		}
+	}
+
+	return read, errors.Join(destinationErrors...)
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package coreutils
+
+// openNoFollow is unavailable outside of Unix, where callers rely on checking the path with Lstat before opening it
+const openNoFollow = 0
@@ -0,0 +1,8 @@
+//go:build unix
+
+package coreutils
+
+import "syscall"
+
+// openNoFollow makes an open fail rather than follow a symlink in place of the final component of the path
+const openNoFollow = syscall.O_NOFOLLOW
@@ -0,0 +1,97 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrNormalizationCollision is returned by NormalizeFilenames when names in the same directory are the same once normalized,
+// so one can't be renamed without replacing the other
+var ErrNormalizationCollision = errors.New("names are the same once normalized")
+
+// NormalizedRename is a rename made by NormalizeFilenames, or which would be made in a dry run
+type NormalizedRename struct {
+	From string // Path before the rename
+	To   string // Path after the rename, within the original parent directory
+}
+
+// NormalizeFilenames will rename the files and directories within path whose names aren't in the Unicode normalization form,
+// such as norm.NFC, which Linux tools usually produce, or norm.NFD, which older macOS filesystems store. Mismatched forms make
+// the same name look different between platforms, causing syncing tools to copy files back and forth. If dryRun is true,
+// nothing is renamed. The renames are returned in walk order, with paths as they were before any of them, and are made deepest
+// first so each parent's path stays valid. Names which would be the same as another in their directory once normalized are
+// left alone and reported in an error wrapping ErrNormalizationCollision, alongside the renames which could be made.
+func NormalizeFilenames(path string, form norm.Form, dryRun bool) ([]NormalizedRename, error) {
+	if dryRun { // A dry run doesn't touch the filesystem, so like AuditPermissions it isn't traced
+		return normalizeFilenames(path, form, true)
+	}
+
+	start := time.Now()
+	renames, normalizeErr := normalizeFilenames(path, form, false)
+	trace(TraceEvent{Op: "NormalizeFilenames", Path: path, Duration: time.Since(start), Err: normalizeErr})
+	return renames, normalizeErr
+}
+
+// normalizeFilenames renames the entries within path whose names aren't in the normalization form, unless dryRun is true
+func normalizeFilenames(path string, form norm.Form, dryRun bool) ([]NormalizedRename, error) {
+	if !IsDir(path) { // If this isn't a directory
+		return nil, notDirectoryError(path, nil)
+	}
+
+	var entries []string                // Paths of every entry, in walk order
+	groups := make(map[string][]string) // Paths by their directory and normalized name
+
+	walkErr := walkTree(path, newOperationOptions(nil), func(entryPath, relativePath string, info os.FileInfo) error {
+		if relativePath != "." { // The root's name is left alone
+			key := filepath.Join(filepath.Dir(entryPath), form.String(info.Name()))
+			groups[key] = append(groups[key], entryPath)
+			entries = append(entries, entryPath)
+		}
+
+		return nil
+	})
+
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	var renames []NormalizedRename
+	var collisions [][]string
+
+	for _, entryPath := range entries {
+		normalizedPath := filepath.Join(filepath.Dir(entryPath), form.String(filepath.Base(entryPath)))
+		group := groups[normalizedPath]
+
+		if len(group) > 1 { // If this would collide, report the group once
+			if group[0] == entryPath {
+				collisions = append(collisions, group)
+			}
+		} else if normalizedPath != entryPath {
+			renames = append(renames, NormalizedRename{From: entryPath, To: normalizedPath})
+		}
+	}
+
+	var renameErr error
+
+	if !dryRun {
+		for index := len(renames) - 1; index >= 0; index-- { // Rename children before the directories holding them
+			if renameErr = (OSFilesystem{}).Rename(renames[index].From, renames[index].To); renameErr != nil {
+				renameErr = writeError(renames[index].From, "Failed to rename "+renames[index].From+" to "+renames[index].To, renameErr)
+				renames = renames[index+1:] // Only report the renames which were made
+				break
+			}
+		}
+	}
+
+	if renameErr == nil && len(collisions) != 0 { // If some names had to be left alone
+		renameErr = fmt.Errorf("%d names in %s would collide once normalized, such as %s: %w", len(collisions), path, strings.Join(collisions[0], " and "), ErrNormalizationCollision)
+	}
+
+	return renames, renameErr
+}
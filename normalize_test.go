@@ -0,0 +1,40 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestNormalizeFilenamesTraced(t *testing.T) {
+	root := t.TempDir()
+	decomposed := norm.NFD.String("café.txt")
+
+	if writeErr := os.WriteFile(filepath.Join(root, decomposed), nil, 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	var renames []NormalizedRename
+	var normalizeErr error
+	ops := traceOps(root, func() { renames, normalizeErr = NormalizeFilenames(root, norm.NFC, true) })
+
+	if normalizeErr != nil || len(renames) != 1 || len(ops) != 0 {
+		t.Fatalf("expected an untraced dry run with one rename, got %v, %v, %v", renames, normalizeErr, ops)
+	}
+
+	ops = traceOps(root, func() { renames, normalizeErr = NormalizeFilenames(root, norm.NFC, false) })
+
+	if normalizeErr != nil || len(renames) != 1 {
+		t.Fatalf("expected one rename, got %v, %v", renames, normalizeErr)
+	}
+
+	if len(ops) != 1 || ops[0] != "NormalizeFilenames" {
+		t.Errorf("expected a single NormalizeFilenames trace, got %v", ops)
+	}
+
+	if _, statErr := os.Lstat(filepath.Join(root, norm.NFC.String("café.txt"))); statErr != nil {
+		t.Errorf("expected the name to be normalized, got %v", statErr)
+	}
+}
@@ -0,0 +1,99 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Urgency is how urgently a desktop notification should be brought to the user's attention
+type Urgency int
+
+const (
+	// UrgencyLow is for informational notifications the user may ignore
+	UrgencyLow Urgency = iota
+
+	// UrgencyNormal is for most notifications, such as a job completing
+	UrgencyNormal
+
+	// UrgencyCritical is for notifications which need attention, such as a backup failing. These stay on screen until dismissed where supported.
+	UrgencyCritical
+)
+
+// ErrNoNotifier is returned when there is no way to show desktop notifications, such as on a headless server
+var ErrNoNotifier = errors.New("desktop notifications are not available")
+
+// windowsToastScript shows a toast notification with the title and body from the environment, so they never need to be quoted
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$title = [Security.SecurityElement]::Escape($env:COREUTILS_NOTIFY_TITLE)
+$body = [Security.SecurityElement]::Escape($env:COREUTILS_NOTIFY_BODY)
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml("<toast scenario='$env:COREUTILS_NOTIFY_SCENARIO'><visual><binding template='ToastGeneric'><text>$title</text><text>$body</text></binding></visual></toast>")
+$notifier = [Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe')
+$notifier.Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+// Notify will show a desktop notification, using notify-send or D-Bus on Linux, osascript on macOS, and a toast on Windows
+func Notify(title, body string, urgency Urgency) error {
+	var command Command
+
+	if urgency < UrgencyLow || urgency > UrgencyCritical { // If this isn't an Urgency we know
+		return errors.New("Unknown urgency " + fmt.Sprint(int(urgency)))
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		command = Command{Name: "osascript", Args: []string{"-e", "display notification " + appleScriptString(body) + " with title " + appleScriptString(title)}}
+	case "windows":
+		scenario := "default"
+
+		if urgency == UrgencyCritical { // Reminders stay on screen until dismissed
+			scenario = "reminder"
+		}
+
+		command = Command{
+			Name: "powershell",
+			Args: []string{"-NoProfile", "-NonInteractive", "-Command", windowsToastScript},
+			Env:  append(os.Environ(), "COREUTILS_NOTIFY_TITLE="+title, "COREUTILS_NOTIFY_BODY="+body, "COREUTILS_NOTIFY_SCENARIO="+scenario),
+		}
+	default:
+		urgencyNames := []string{"low", "normal", "critical"}
+
+		if ExecutableExists("notify-send") { // Prefer libnotify's tool
+			command = Command{Name: "notify-send", Args: []string{"--urgency=" + urgencyNames[urgency], "--", title, body}}
+		} else { // Otherwise talk to the notification daemon over D-Bus directly
+			command = Command{Name: "gdbus", Args: []string{
+				"call", "--session",
+				"--dest", "org.freedesktop.Notifications",
+				"--object-path", "/org/freedesktop/Notifications",
+				"--method", "org.freedesktop.Notifications.Notify",
+				"''", "0", "''", gvariantString(title), gvariantString(body), "[]",
+				fmt.Sprintf("{'urgency': <byte %d>}", urgency), "-1",
+			}}
+		}
+	}
+
+	if !ExecutableExists(command.Name) { // If we have no way to show the notification
+		return fmt.Errorf("%s: %w", command.Name, ErrNoNotifier)
+	}
+
+	runner, stderr := command.prepare()
+
+	if runErr := runner.Run(); runErr != nil { // If we failed to show the notification
+		return command.commandError(runErr, stderr)
+	}
+
+	return nil
+}
+
+// appleScriptString quotes the text as an AppleScript string literal
+func appleScriptString(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// gvariantString quotes the text as a GVariant string literal, as parsed by gdbus
+func gvariantString(text string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(text) + "'"
+}
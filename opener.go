@@ -0,0 +1,85 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrNoOpener is returned when the tool used to open files in their default application is not available, such as on a headless server
+var ErrNoOpener = errors.New("no tool to open files with is available")
+
+// ErrNoDefaultApp is returned when no application is associated with the file or URL
+var ErrNoDefaultApp = errors.New("no default application")
+
+// OpenInDefaultApp will open the file, directory, or URL in the user's default application for it, such as a browser for
+// a generated HTML report. Files which don't exist return an error matching os.ErrNotExist, a missing opener matches
+// ErrNoOpener, and nothing being associated with the file matches ErrNoDefaultApp.
+func OpenInDefaultApp(pathOrURL string) error {
+	target := pathOrURL
+
+	if !isURL(pathOrURL) { // If this is a path, make sure it exists
+		absolutePath, absErr := filepath.Abs(pathOrURL)
+
+		if absErr != nil {
+			return absErr
+		}
+
+		if _, statErr := os.Stat(absolutePath); statErr != nil { // If it does not exist
+			return fmt.Errorf("%s does not exist: %w", pathOrURL, os.ErrNotExist)
+		}
+
+		target = absolutePath
+	}
+
+	var command Command
+
+	switch runtime.GOOS {
+	case "darwin":
+		command = Command{Name: "open", Args: []string{target}}
+	case "windows":
+		command = Command{Name: "rundll32", Args: []string{"url.dll,FileProtocolHandler", target}} // Avoids cmd's start, which mangles quoting
+	default:
+		command = Command{Name: "xdg-open", Args: []string{target}}
+	}
+
+	if !ExecutableExists(command.Name) { // If the opener isn't installed
+		return fmt.Errorf("%s: %w", command.Name, ErrNoOpener)
+	}
+
+	runner, stderr := command.prepare()
+	runErr := runner.Run()
+
+	var exitErr *exec.ExitError
+
+	if runErr == nil {
+		return nil
+	} else if command.Name == "xdg-open" && errors.As(runErr, &exitErr) { // xdg-open documents its exit codes, so classify them
+		switch exitErr.ExitCode() {
+		case 2:
+			return fmt.Errorf("%s does not exist: %w", pathOrURL, os.ErrNotExist)
+		case 3:
+			return fmt.Errorf("%s: %w", pathOrURL, ErrNoDefaultApp)
+		}
+	} else if command.Name == "open" && strings.Contains(stderr.String(), "No application knows how to open") {
+		return fmt.Errorf("%s: %w", pathOrURL, ErrNoDefaultApp)
+	}
+
+	return command.commandError(runErr, stderr)
+}
+
+// isURL checks if the string is a URL with a scheme, rather than a path
+func isURL(pathOrURL string) bool {
+	if schemeIndex := strings.Index(pathOrURL, ":"); schemeIndex > 1 { // Longer than a single character, so Windows drive letters aren't mistaken for schemes
+		scheme := pathOrURL[:schemeIndex]
+		return strings.IndexFunc(scheme, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.')
+		}) == -1
+	}
+
+	return false
+}
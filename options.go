@@ -0,0 +1,204 @@
+package coreutils
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// Option configures an individual call to a function in this package. The same Options are shared by the
+// copy, listing, and archive functions, each With function documents what it affects, and Options a function has no
+// use for are ignored.
+type Option func(*operationOptions)
+
+// ProgressFunc is called as an operation progresses on path. done and total are in the unit of the operation,
+// such as bytes copied or files found, with a total of -1 when it is not known ahead of time.
+type ProgressFunc func(path string, done, total int64)
+
+// operationOptions is the combined configuration of an operation, built from the Options passed to it
+type operationOptions struct {
+	Defaults
+
+	exactMode  bool         // Whether to chmod created files and directories so the umask does not apply
+	workers    int          // Number of concurrent workers, where supported
+	exclude    []string     // Glob patterns of paths to skip
+	include    []string     // Glob patterns of the only files to copy, empty for every file
+	ignoreFile string       // Name of the ignore file honored in each directory, empty to disable
+	progress   ProgressFunc // Called as the operation progresses
+
+	stats         *statsCollector // Collects the Stats of the operation, if requested
+	skipIdentical CompareMode     // How to detect files already identical at the destination, 0 to always copy
+	staging       *stagingArea    // Local temp space to stage copies in, if requested
+	overwrite     OverwritePolicy // What copies do when a file already exists at the destination
+	preserveTimes bool            // Whether copies keep the modification time of their source
+	atomicWrite   bool            // Whether writes go to a temporary file which is renamed into place
+	transform     TransformFunc   // Changes the content of each file as it is copied, nil to copy it as is
+	verify        bool            // Whether copies are read back and compared against the digest of their source
+	modeMap       *ModeMap        // Modes of copied and extracted files by pattern, nil to keep the mode of the source
+	ownerMap      *OwnerMap       // Owners of copied and extracted files by pattern, nil to leave them owned by the caller
+
+	securityAttributes bool             // Whether the SELinux context and capabilities of files are kept
+	junctions          JunctionPolicy   // What CopyDirectory does with NTFS junctions
+	alternateStreams   bool             // Whether the alternate data streams of NTFS files are copied
+	macMetadata        bool             // Whether the extended attributes of files are copied on macOS
+	quarantine         QuarantinePolicy // What copies do with the quarantine attribute of macOS
+	caseCollisionCheck bool             // Whether copies check for names which would collide at a case-insensitive destination
+	force              bool             // Whether removals may act on a filesystem root or home directory
+
+	copies *copyPool // Runs the file copies of CopyDirectory concurrently, nil to copy them one at a time
+
+	validate        ValidateFunc   // Called on each copied file, nil to not validate
+	rollbackInvalid bool           // Whether files which fail validation are removed
+	invalid         *invalidCopies // Errors of the files which failed validation
+
+	bandwidthLimit  int64         // Bytes per second each transfer is limited to, 0 for no limit
+	serveAuth       ServeAuthFunc // Decides whether ServeDirectory answers a request, nil to answer every request
+	uploadOverwrite bool          // Whether ServeDirectory uploads may replace existing files
+	maxUploadSize   int64         // Largest file ServeDirectory accepts an upload of, 0 for defaultMaxUploadSize
+
+	deterministic bool // Whether output should be reproducible, with fixed timestamps and sorted walks
+
+	ctx     context.Context // Cancels the operation once done, nil if it can't be canceled
+	created *createdPaths   // Where the paths created by the operation are recorded, nil if they aren't tracked
+
+	copyOrder   CopyOrderFunc   // Order the entries of each directory are copied in, nil for the order they are read in
+	priority    []string        // Glob patterns of files copied before the rest of the tree
+	prioritized map[string]bool // Relative paths of the files already copied for having priority
+
+	checkpointPath   string      // Where completed files are recorded for Resume, empty for no checkpoint
+	resumeCheckpoint bool        // Whether to continue the checkpoint rather than starting a new one
+	checkpoint       *checkpoint // The open checkpoint of the operation, nil if there is none
+
+	maxDepth       int          // Maximum directory depth of recursive operations, 0 for no limit
+	maxEntries     int          // Maximum entries visited by recursive operations, 0 for no limit
+	maxPathLength  int          // Maximum length of paths visited by recursive operations, 0 for no limit
+	entriesVisited atomic.Int64 // Entries visited so far, shared by concurrent workers
+}
+
+// newOperationOptions applies the Options to a default configuration
+func newOperationOptions(opts []Option) *operationOptions {
+	options := &operationOptions{Defaults: GetDefaults(), workers: 1, ignoreFile: DefaultIgnoreFile}
+
+	for _, opt := range opts { // For each Option provided
+		if opt != nil {
+			opt(options)
+		}
+	}
+
+	return options
+}
+
+// excluded checks if the path, relative to the root of the operation, matches any of the exclude patterns.
+// Patterns are matched against both the base name and the full relative path.
+func (options *operationOptions) excluded(relativePath string) bool {
+	relativePath = filepath.ToSlash(relativePath)
+	baseName := filepath.Base(relativePath)
+
+	for _, pattern := range options.exclude { // For each exclude pattern
+		if baseMatch, _ := filepath.Match(pattern, baseName); baseMatch {
+			return true
+		}
+
+		if pathMatch, _ := filepath.Match(pattern, relativePath); pathMatch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reportProgress calls the progress callback, if one was provided
+func (options *operationOptions) reportProgress(path string, done, total int64) {
+	if options.progress != nil {
+		options.progress(path, done, total)
+	}
+}
+
+// WithExactMode sets whether files and directories are chmod-ed after creation, so they end up with exactly the mode
+// requested rather than the mode with the process umask applied (see EffectiveMode). The umask is respected by default.
+func WithExactMode(exact bool) Option {
+	return func(options *operationOptions) {
+		options.exactMode = exact
+	}
+}
+
+// WithDefaults overrides all of the package Defaults for this call
+func WithDefaults(defaults Defaults) Option {
+	return func(options *operationOptions) {
+		options.Defaults = defaults
+	}
+}
+
+// WithFileMode overrides Defaults.DefaultFileMode for this call
+func WithFileMode(mode os.FileMode) Option {
+	return func(options *operationOptions) {
+		options.DefaultFileMode = mode
+	}
+}
+
+// WithDirMode overrides Defaults.DefaultDirMode for this call
+func WithDirMode(mode os.FileMode) Option {
+	return func(options *operationOptions) {
+		options.DefaultDirMode = mode
+	}
+}
+
+// WithBufferSize overrides Defaults.BufferSize for this call
+func WithBufferSize(size int) Option {
+	return func(options *operationOptions) {
+		if size > 0 {
+			options.BufferSize = size
+		}
+	}
+}
+
+// WithFollowSymlinks overrides Defaults.FollowSymlinks for this call
+func WithFollowSymlinks(follow bool) Option {
+	return func(options *operationOptions) {
+		options.FollowSymlinks = follow
+	}
+}
+
+// progressWriter reports the progress of writes to the underlying writer
+type progressWriter struct {
+	writer  io.Writer
+	path    string
+	written int64
+	total   int64
+	report  ProgressFunc
+}
+
+// Write writes to the underlying writer, reporting the total written so far
+func (writer *progressWriter) Write(p []byte) (int, error) {
+	writeCount, writeErr := writer.writer.Write(p)
+	writer.written += int64(writeCount)
+	writer.report(writer.path, writer.written, writer.total)
+	return writeCount, writeErr
+}
+
+// WithWorkers sets the number of concurrent workers used by operations which support parallelism. Defaults to 1.
+// With more than one, CopyDirectory copies files concurrently, so a ProgressFunc or ValidateFunc may be called concurrently.
+func WithWorkers(workers int) Option {
+	return func(options *operationOptions) {
+		if workers > 0 {
+			options.workers = workers
+		}
+	}
+}
+
+// WithExclude skips any paths matching the glob patterns, such as "*.tmp". Patterns are matched against both the
+// name of each file or directory and its slash separated path relative to the root of the operation.
+func WithExclude(patterns ...string) Option {
+	return func(options *operationOptions) {
+		options.exclude = append(options.exclude, patterns...)
+	}
+}
+
+// WithProgress sets a callback which is called as the operation progresses
+func WithProgress(fn ProgressFunc) Option {
+	return func(options *operationOptions) {
+		options.progress = fn
+	}
+}
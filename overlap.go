@@ -0,0 +1,71 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrOverlappingPaths is returned when asked to copy a path onto itself or into its own subtree
+var ErrOverlappingPaths = errors.New("source and destination overlap")
+
+// SameFile checks if the paths provided refer to the same file, comparing by device and inode rather than by name.
+// Returns false if either path does not exist.
+func SameFile(a, b string) bool {
+	aInfo, aErr := os.Stat(a)
+
+	if aErr != nil {
+		return false
+	}
+
+	bInfo, bErr := os.Stat(b)
+
+	return (bErr == nil) && os.SameFile(aInfo, bInfo)
+}
+
+// checkOverlap returns ErrOverlappingPaths if destination is source itself, or is within source when source is a directory
+func checkOverlap(source, destination string) error {
+	if SameFile(source, destination) { // If these are the same file, even by way of a link or mount
+		return fmt.Errorf("%s and %s: %w", source, destination, ErrOverlappingPaths)
+	}
+
+	if !IsDir(source) { // Only directories have a subtree to copy into
+		return nil
+	}
+
+	resolvedSource, sourceErr := resolveExisting(source)
+	resolvedDestination, destinationErr := resolveExisting(destination)
+
+	if sourceErr == nil && destinationErr == nil && isWithin(resolvedSource, resolvedDestination) { // If the destination is inside the source
+		return fmt.Errorf("%s is within %s: %w", destination, source, ErrOverlappingPaths)
+	}
+
+	return nil
+}
+
+// resolveExisting returns the absolute path with any symlinks resolved, for as much of the path as exists
+func resolveExisting(path string) (string, error) {
+	absolutePath, absErr := filepath.Abs(path)
+
+	if absErr != nil {
+		return "", absErr
+	}
+
+	var missing string // Trailing components which don't exist yet
+
+	for {
+		if resolvedPath, evalErr := filepath.EvalSymlinks(absolutePath); evalErr == nil { // If this part of the path exists
+			return filepath.Join(resolvedPath, missing), nil
+		}
+
+		parent := filepath.Dir(absolutePath)
+
+		if parent == absolutePath { // If we've run out of path to resolve
+			return filepath.Join(absolutePath, missing), nil
+		}
+
+		missing = filepath.Join(filepath.Base(absolutePath), missing)
+		absolutePath = parent
+	}
+}
@@ -0,0 +1,124 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrDestinationExists is returned when a file already exists at the destination and OverwriteError is in effect
+var ErrDestinationExists = errors.New("destination already exists")
+
+// OverwritePolicy is what a copy does when a file already exists at the destination
+type OverwritePolicy int
+
+const (
+	// OverwriteAlways replaces any existing file. This is the default.
+	OverwriteAlways OverwritePolicy = iota
+
+	// OverwriteSkip leaves existing files untouched, counting them in Stats.FilesSkipped
+	OverwriteSkip
+
+	// OverwriteError stops the copy with ErrDestinationExists at the first existing file
+	OverwriteError
+
+	// OverwriteNewer only replaces existing files when the source has a newer modification time
+	OverwriteNewer
+)
+
+// CopyOptions are the options of CopyDirectoryWithOptions
+type CopyOptions struct {
+	Exclude        []string        // Glob patterns of files and directories to skip, as with WithExclude
+	Include        []string        // Glob patterns of the only files to copy, as with WithInclude. Empty copies every file.
+	Overwrite      OverwritePolicy // What to do when a file already exists at the destination
+	FollowSymlinks bool            // Whether to copy what symlinks point to rather than recreating them, overriding Defaults.FollowSymlinks
+	PreserveTimes  bool            // Whether copied files and directories keep the modification time of their source
+}
+
+// CopyDirectoryWithOptions will copy the directory like CopyDirectory, configured by CopyOptions rather than a list of Options
+func CopyDirectoryWithOptions(src, dst string, opts CopyOptions) error {
+	return CopyDirectory(src, dst, opts.options()...)
+}
+
+// options converts the CopyOptions to their equivalent Options
+func (copyOptions CopyOptions) options() []Option {
+	return []Option{
+		WithExclude(copyOptions.Exclude...),
+		WithInclude(copyOptions.Include...),
+		WithOverwrite(copyOptions.Overwrite),
+		WithFollowSymlinks(copyOptions.FollowSymlinks),
+		WithPreserveTimes(copyOptions.PreserveTimes),
+	}
+}
+
+// WithInclude only copies files matching the glob patterns, such as "*.go". Patterns are matched like WithExclude, against both
+// the name of each file and its slash separated path relative to the root of the operation. Directories are always descended into.
+func WithInclude(patterns ...string) Option {
+	return func(options *operationOptions) {
+		options.include = append(options.include, patterns...)
+	}
+}
+
+// WithOverwrite sets what copies do when a file already exists at the destination. Defaults to OverwriteAlways.
+func WithOverwrite(policy OverwritePolicy) Option {
+	return func(options *operationOptions) {
+		options.overwrite = policy
+	}
+}
+
+// WithPreserveTimes sets whether copied files and directories are given the modification time of their source
+func WithPreserveTimes(preserve bool) Option {
+	return func(options *operationOptions) {
+		options.preserveTimes = preserve
+	}
+}
+
+// included checks if the file, relative to the root of the operation, matches any of the include patterns.
+// Every file is included when there are no patterns.
+func (options *operationOptions) included(relativePath string) bool {
+	if len(options.include) == 0 {
+		return true
+	}
+
+	relativePath = filepath.ToSlash(relativePath)
+	baseName := filepath.Base(relativePath)
+
+	for _, pattern := range options.include { // For each include pattern
+		if baseMatch, _ := filepath.Match(pattern, baseName); baseMatch {
+			return true
+		}
+
+		if pathMatch, _ := filepath.Match(pattern, relativePath); pathMatch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkOverwrite checks if the sourceFile may replace destinationFile under the OverwritePolicy, returning whether the file
+// should be skipped instead, or ErrDestinationExists if the copy should stop
+func (options *operationOptions) checkOverwrite(sourceFile, destinationFile string) (skip bool, err error) {
+	if options.overwrite == OverwriteAlways {
+		return false, nil
+	}
+
+	destinationInfo, statErr := os.Lstat(destinationFile)
+
+	if statErr != nil { // If there is nothing to overwrite
+		return false, nil
+	}
+
+	switch options.overwrite {
+	case OverwriteSkip:
+		return true, nil
+	case OverwriteError:
+		return false, fmt.Errorf("%s: %w", destinationFile, ErrDestinationExists)
+	case OverwriteNewer:
+		sourceInfo, sourceErr := os.Stat(sourceFile)
+		return sourceErr == nil && !sourceInfo.ModTime().After(destinationInfo.ModTime()), nil // A missing source is left for the copy to report
+	}
+
+	return false, nil
+}
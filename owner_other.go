@@ -0,0 +1,12 @@
+//go:build !unix
+
+package coreutils
+
+import (
+	"os"
+)
+
+// fileOwner returns false, as files on this platform aren't owned by numeric user and group IDs
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
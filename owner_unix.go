@@ -0,0 +1,17 @@
+//go:build unix
+
+package coreutils
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the user and group IDs which own the file described by info
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	if stat, isStat := info.Sys().(*syscall.Stat_t); isStat {
+		return int(stat.Uid), int(stat.Gid), true
+	}
+
+	return 0, 0, false
+}
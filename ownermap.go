@@ -0,0 +1,95 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Owner is the user and group IDs a file is owned by. An ID of -1 leaves that part of the ownership as it is.
+type Owner struct {
+	UID int
+	GID int
+}
+
+// OwnerMap sets the ownership of copied and extracted files by glob pattern, so provisioning tools can install files with the right
+// ownership in a single pass
+type OwnerMap struct {
+	Patterns map[string]Owner // Glob patterns, such as "*.key", to the owner of the files and directories matching them. Patterns are matched like WithExclude, and the longest matching pattern wins.
+	Default  *Owner           // Owner of files and directories matching no pattern, nil to leave them owned by the caller
+}
+
+// WithOwner gives every copied or extracted file and directory the same owner, like WithOwnerMap with only a Default
+func WithOwner(uid, gid int) Option {
+	return WithOwnerMap(OwnerMap{Default: &Owner{UID: uid, GID: gid}})
+}
+
+// WithOwnerMap gives each copied or extracted file, directory, and symlink its owner under the OwnerMap. Changing ownership requires
+// privileges, so this only applies when running as root, and is ignored otherwise, such as on Windows.
+func WithOwnerMap(owners OwnerMap) Option {
+	return func(options *operationOptions) {
+		options.ownerMap = &owners
+	}
+}
+
+// owner returns the owner of the file or directory at relativePath, relative to the root of the operation, under the OwnerMap,
+// and whether it has one
+func (owners *OwnerMap) owner(relativePath string) (Owner, bool) {
+	relativePath = filepath.ToSlash(relativePath)
+	baseName := filepath.Base(relativePath)
+	matchedPattern := ""
+	var owner Owner
+	found := owners.Default != nil
+
+	if found {
+		owner = *owners.Default
+	}
+
+	for pattern, patternOwner := range owners.Patterns { // For each pattern, keep the most specific which matches, as with ModeMap
+		if len(pattern) < len(matchedPattern) || (len(pattern) == len(matchedPattern) && pattern > matchedPattern) {
+			continue
+		}
+
+		baseMatch, _ := filepath.Match(pattern, baseName)
+		pathMatch, _ := filepath.Match(pattern, relativePath)
+
+		if baseMatch || pathMatch {
+			owner, matchedPattern, found = patternOwner, pattern, true
+		}
+	}
+
+	return owner, found
+}
+
+// applyOwnerMap sets the owner of path, which is relativePath within the root of the operation, under the OwnerMap of the operation,
+// if it has one and we are running as root. Symlinks are changed themselves rather than what they point to.
+func (options *operationOptions) applyOwnerMap(path, relativePath string) error {
+	if options.ownerMap == nil || os.Geteuid() != 0 {
+		return nil
+	}
+
+	owner, found := options.ownerMap.owner(relativePath)
+
+	if !found {
+		return nil
+	}
+
+	chownErr := options.keepSecurityAttributes(path, func() error {
+		return os.Lchown(path, owner.UID, owner.GID)
+	})
+
+	if chownErr != nil && !os.IsNotExist(chownErr) { // Paths which are gone, such as files rolled back by WithValidateRollback, are ignored
+		return writeError(path, "Failed to set the owner of "+path, chownErr)
+	}
+
+	return nil
+}
+
+// applyMaps applies the OwnerMap and ModeMap of the operation to path, which is relativePath within the root of the operation.
+// The owner is set first, since changing it can clear the setuid and setgid bits of the mode.
+func (options *operationOptions) applyMaps(path, relativePath string, isDir bool) error {
+	if ownerErr := options.applyOwnerMap(path, relativePath); ownerErr != nil {
+		return ownerErr
+	}
+
+	return options.applyModeMap(path, relativePath, isDir)
+}
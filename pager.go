@@ -0,0 +1,65 @@
+package coreutils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// PageOutput will show the content through the user's pager, so long output can be scrolled. The pager is $PAGER, falling
+// back to less, or more where less isn't installed. Like git, less is given LESS=FRX unless LESS is set, so colors are shown,
+// output fitting on one screen is printed without waiting, and the screen isn't cleared. The content is printed directly
+// when stdout isn't a terminal, PAGER is "cat" or empty, or no pager can be run.
+func PageOutput(content string) error {
+	command, usePager := pagerCommand()
+
+	if !usePager || !isTerminal(os.Stdout) { // If the output is being piped or redirected, or paging is disabled
+		_, writeErr := fmt.Fprint(os.Stdout, content)
+		return writeErr
+	}
+
+	command.Stdout, command.Stderr = os.Stdout, os.Stderr
+	runner, _ := command.prepare()
+	runner.Stdin = strings.NewReader(content)
+
+	if _, lessSet := os.LookupEnv("LESS"); !lessSet {
+		runner.Env = append(os.Environ(), "LESS=FRX")
+	}
+
+	if startErr := runner.Start(); startErr != nil { // If the pager can't be run, print the content ourselves
+		_, writeErr := fmt.Fprint(os.Stdout, content)
+		return writeErr
+	}
+
+	runner.Wait() // The pager exiting early, such as the user quitting less, isn't an error
+
+	return nil
+}
+
+// pagerCommand returns the command running the user's pager, or false if paging is disabled or there is no pager
+func pagerCommand() (Command, bool) {
+	if pager, pagerSet := os.LookupEnv("PAGER"); pagerSet {
+		pager = strings.TrimSpace(pager)
+
+		if pager == "" || pager == "cat" { // If paging was turned off
+			return Command{}, false
+		}
+
+		if runtime.GOOS == "windows" {
+			fields := strings.Fields(pager)
+			return Command{Name: fields[0], Args: fields[1:]}, true
+		}
+
+		return Command{Name: "sh", Args: []string{"-c", pager}}, true // Let the shell handle any quoting in the pager
+	}
+
+	command, findErr := firstAvailableCommand(Command{Name: "less"}, Command{Name: "more"})
+	return command, findErr == nil
+}
+
+// isTerminal checks if the file is a terminal rather than a pipe or regular file
+func isTerminal(file *os.File) bool {
+	info, statErr := file.Stat()
+	return statErr == nil && info.Mode()&os.ModeCharDevice != 0
+}
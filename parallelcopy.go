@@ -0,0 +1,95 @@
+package coreutils
+
+import (
+	"sync"
+)
+
+// copyPool runs the file copies of a CopyDirectory concurrently, with at most WithWorkers copies in flight. Directories are
+// still created by the walk itself, in order, so a file is only copied once the directory it goes in exists.
+type copyPool struct {
+	slots chan struct{}
+	lock  sync.Mutex
+	fatal error // First error which stops the whole copy, such as cancellation or OverwriteError
+}
+
+// directoryCopies tracks the file copies started for the entries of a single directory
+type directoryCopies struct {
+	pool *copyPool
+	wait sync.WaitGroup
+	lock sync.Mutex
+	err  error // Error of the last copy which failed
+}
+
+// newCopyPool creates a copyPool of the number of workers, or returns nil if the copy should be sequential
+func newCopyPool(workers int) *copyPool {
+	if workers <= 1 {
+		return nil
+	}
+
+	return &copyPool{slots: make(chan struct{}, workers)}
+}
+
+// directory starts tracking the file copies of a directory. Safe to call on a nil copyPool, in which case copies run sequentially.
+func (pool *copyPool) directory() *directoryCopies {
+	if pool == nil {
+		return nil
+	}
+
+	return &directoryCopies{pool: pool}
+}
+
+// run starts copyItem on a worker once one is free, returning the error which stopped the whole copy, if any has. When copies
+// are sequential, copyItem is run right away and its error returned.
+func (copies *directoryCopies) run(copyItem func() error) error {
+	if copies == nil {
+		return copyItem()
+	}
+
+	copies.pool.lock.Lock()
+	fatal := copies.pool.fatal
+	copies.pool.lock.Unlock()
+
+	if fatal != nil { // If another copy has stopped the whole copy, don't start any more
+		return fatal
+	}
+
+	copies.pool.slots <- struct{}{}
+	copies.wait.Add(1)
+
+	go func() {
+		defer copies.wait.Done()
+		defer func() { <-copies.pool.slots }()
+
+		if copyErr := copyItem(); copyErr != nil {
+			copies.lock.Lock()
+			copies.err = copyErr
+			copies.lock.Unlock()
+
+			if isFatalWalkError(copyErr) { // If this copy should stop the rest
+				copies.pool.lock.Lock()
+
+				if copies.pool.fatal == nil {
+					copies.pool.fatal = copyErr
+				}
+
+				copies.pool.lock.Unlock()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// finish waits for the copies of the directory to complete, returning the error of the last one which failed
+func (copies *directoryCopies) finish() error {
+	if copies == nil {
+		return nil
+	}
+
+	copies.wait.Wait()
+
+	copies.lock.Lock()
+	defer copies.lock.Unlock()
+
+	return copies.err
+}
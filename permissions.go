@@ -0,0 +1,145 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// PermPolicy declares the permissions files and directories in a tree are expected to have. Zero fields are not checked.
+type PermPolicy struct {
+	ForbiddenBits os.FileMode // Permission bits nothing may have, such as 0002 for no world-writable files
+	MaxFileMode   os.FileMode // Permission bits files may have at most, such as 0644
+	MaxDirMode    os.FileMode // Permission bits directories may have at most, such as 0755
+	Owner         string      // Name or numeric ID of the user everything must be owned by
+	Group         string      // Name or numeric ID of the group everything must be owned by
+}
+
+// Violation is a file or directory whose permissions deviate from a PermPolicy
+type Violation struct {
+	Path     string      // Path of the file or directory
+	Problem  string      // Description of how it deviates, such as "mode -rw-rw-rw- has forbidden bits --------w-"
+	Mode     os.FileMode // Mode the file or directory had
+	Repaired bool        // Whether RepairPermissions fixed it
+	Err      error       // Why RepairPermissions failed to fix it, if it did
+}
+
+// AuditPermissions will walk the tree at path and return every file and directory whose permissions or ownership deviate from
+// the policy. Symlinks are not checked, since their own permissions are not used. Honors WithExclude and the WithMaxDepth,
+// WithMaxEntries, and WithMaxPathLength limits.
+func AuditPermissions(path string, policy PermPolicy, opts ...Option) ([]Violation, error) {
+	return walkPermissions(path, policy, false, newOperationOptions(opts))
+}
+
+// RepairPermissions will audit the tree at path like AuditPermissions, fixing each Violation by removing the offending mode
+// bits and changing its owner. Returns every Violation found, with whether it was repaired.
+func RepairPermissions(path string, policy PermPolicy, opts ...Option) ([]Violation, error) {
+	start := time.Now()
+	violations, repairErr := walkPermissions(path, policy, true, newOperationOptions(opts))
+	trace(TraceEvent{Op: "RepairPermissions", Path: path, Duration: time.Since(start), Err: repairErr})
+	return violations, repairErr
+}
+
+// walkPermissions audits, and optionally repairs, every entry of the tree at path
+func walkPermissions(path string, policy PermPolicy, repair bool, options *operationOptions) ([]Violation, error) {
+	uid, gid, lookupErr := policyOwner(policy)
+
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+
+	var violations []Violation
+
+	walkErr := walkTree(path, options, func(entryPath, relativePath string, info os.FileInfo) error {
+		checkPermissions(entryPath, info, policy, uid, gid, repair, &violations)
+		return nil
+	})
+
+	return violations, walkErr
+}
+
+// checkPermissions adds a Violation for each way the entry deviates from the policy, repairing it if requested
+func checkPermissions(path string, info os.FileInfo, policy PermPolicy, uid, gid int, repair bool, violations *[]Violation) {
+	if info.Mode()&os.ModeSymlink != 0 { // Symlink permissions aren't used, and chmod would change the target
+		return
+	}
+
+	mode := info.Mode()
+	allowed := os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+	if info.IsDir() && policy.MaxDirMode != 0 {
+		allowed = policy.MaxDirMode
+	} else if !info.IsDir() && policy.MaxFileMode != 0 {
+		allowed = policy.MaxFileMode
+	}
+
+	allowed &^= policy.ForbiddenBits
+	permissionBits := mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+
+	if extraBits := permissionBits &^ allowed; extraBits != 0 { // If the entry has bits it shouldn't
+		violation := Violation{Path: path, Problem: "mode " + mode.String() + " has disallowed bits " + extraBits.String(), Mode: mode}
+
+		if repair {
+			if violation.Err = checkPathPolicy(path, true); violation.Err == nil {
+				violation.Err = os.Chmod(path, permissionBits&allowed)
+			}
+
+			violation.Repaired = (violation.Err == nil)
+		}
+
+		*violations = append(*violations, violation)
+	}
+
+	fileUID, fileGID, hasOwner := fileOwner(info)
+
+	if !hasOwner || (uid == -1 && gid == -1) { // If ownership can't be or doesn't need to be checked
+		return
+	}
+
+	if (uid != -1 && fileUID != uid) || (gid != -1 && fileGID != gid) { // If the entry is owned by someone else
+		violation := Violation{Path: path, Problem: "owned by " + strconv.Itoa(fileUID) + ":" + strconv.Itoa(fileGID), Mode: mode}
+
+		if repair {
+			if violation.Err = checkPathPolicy(path, true); violation.Err == nil {
+				violation.Err = os.Lchown(path, uid, gid) // -1 leaves the owner or group unchanged
+			}
+
+			violation.Repaired = (violation.Err == nil)
+		}
+
+		*violations = append(*violations, violation)
+	}
+}
+
+// policyOwner resolves the owner and group of the policy to numeric IDs, with -1 for those not being checked
+func policyOwner(policy PermPolicy) (uid, gid int, err error) {
+	uid, gid = -1, -1
+
+	if policy.Owner != "" {
+		if uid, err = strconv.Atoi(policy.Owner); err != nil { // If this is a name rather than an ID
+			owner, lookupErr := user.Lookup(policy.Owner)
+
+			if lookupErr != nil {
+				return -1, -1, errors.New(policy.Owner + " is not a user.")
+			}
+
+			uid, _ = strconv.Atoi(owner.Uid)
+		}
+	}
+
+	if policy.Group != "" {
+		if gid, err = strconv.Atoi(policy.Group); err != nil { // If this is a name rather than an ID
+			group, lookupErr := user.LookupGroup(policy.Group)
+
+			if lookupErr != nil {
+				return -1, -1, errors.New(policy.Group + " is not a group.")
+			}
+
+			gid, _ = strconv.Atoi(group.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
@@ -0,0 +1,52 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepairPermissionsAudited(t *testing.T) {
+	root := t.TempDir()
+	tree := filepath.Join(root, "tree")
+	file := filepath.Join(tree, "open.txt")
+	auditLog := filepath.Join(root, "audit.jsonl")
+
+	if mkdirErr := os.Mkdir(tree, 0755); mkdirErr != nil {
+		t.Fatal(mkdirErr)
+	}
+
+	if writeErr := os.WriteFile(file, nil, 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if chmodErr := os.Chmod(file, 0666); chmodErr != nil {
+		t.Fatal(chmodErr)
+	}
+
+	defaults := GetDefaults()
+	defer SetDefaults(defaults)
+
+	audited := defaults
+	audited.AuditLog = auditLog
+	SetDefaults(audited)
+
+	violations, repairErr := RepairPermissions(tree, PermPolicy{ForbiddenBits: 0002})
+
+	if repairErr != nil {
+		t.Fatal(repairErr)
+	}
+
+	if len(violations) != 1 || !violations[0].Repaired {
+		t.Fatalf("expected open.txt to be repaired, got %+v", violations)
+	}
+
+	if info, statErr := os.Stat(file); statErr != nil || info.Mode().Perm() != 0664 {
+		t.Errorf("expected open.txt to be 0664, got %v, %v", info.Mode(), statErr)
+	}
+
+	if records, readErr := os.ReadFile(auditLog); readErr != nil || !strings.Contains(string(records), `"op":"RepairPermissions"`) {
+		t.Errorf("expected RepairPermissions to be audited, got %s, %v", records, readErr)
+	}
+}
@@ -0,0 +1,415 @@
+package coreutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// ErrPickCancelled is returned when the user cancels PickFile or PickDirectory
+var ErrPickCancelled = errors.New("pick cancelled")
+
+// pickerEntry is an entry listed by the picker
+type pickerEntry struct {
+	name  string
+	isDir bool
+}
+
+// pickerKey is a key the picker responds to
+type pickerKey int
+
+const (
+	keyNone pickerKey = iota // A key the picker ignores
+	keyRune
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyLeft
+	keyRight
+	keyEnter
+	keyBackspace
+	keyCancel
+)
+
+// pickHere is the name of the row PickDirectory lists first, to pick the directory currently shown
+const pickHere = "."
+
+// PickFile will let the user pick a file within root in the terminal. The list is navigated with the up and down arrow keys,
+// Enter or the right arrow opens a directory or picks a file, and the left arrow or Backspace goes back up. Typing fuzzy
+// filters the list. Only files for which filter returns true are listed, or every file if filter is nil. Returns the path of
+// the file, or ErrPickCancelled if the user pressed Escape or Ctrl+C.
+//
+// If stdin or stdout isn't a terminal, such as when piped, the list is numbered and read line by line instead.
+func PickFile(root string, filter func(string) bool) (string, error) {
+	return pick(root, false, filter)
+}
+
+// PickDirectory will let the user pick a directory within root in the terminal like PickFile. The first row of the list,
+// ".", picks the directory currently shown.
+func PickDirectory(root string) (string, error) {
+	return pick(root, true, nil)
+}
+
+// pick runs the picker in the terminal, or falls back to pickLines when stdin or stdout isn't one
+func pick(root string, directories bool, filter func(string) bool) (string, error) {
+	inputFd, outputFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+
+	if !term.IsTerminal(inputFd) || !term.IsTerminal(outputFd) { // If we can't read keys or draw the list
+		return pickLines(root, directories, filter, os.Stdin, os.Stdout)
+	}
+
+	state, rawErr := term.MakeRaw(inputFd)
+
+	if rawErr != nil { // If the terminal can't read single keys
+		return pickLines(root, directories, filter, os.Stdin, os.Stdout)
+	}
+
+	defer term.Restore(inputFd, state)
+
+	_, height, sizeErr := term.GetSize(outputFd)
+
+	if sizeErr != nil {
+		height = 24
+	}
+
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")       // Switch to the alternate screen and hide the cursor
+	defer fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l") // Restore the cursor and what was on screen before
+
+	return browse(root, directories, filter, os.Stdin, os.Stdout, height)
+}
+
+// browse draws the list of the picker to output and navigates it with the keys read from input, until the user picks a file,
+// or a directory if directories is set. height is the number of lines of the terminal.
+func browse(root string, directories bool, filter func(string) bool, input io.Reader, output io.Writer, height int) (string, error) {
+	reader := bufio.NewReader(input)
+	root = filepath.Clean(root)
+	current, query := root, ""
+	cursor, offset := 0, 0
+	visible := max(height-3, 1) // Lines left for the list after the directory, filter, and help lines
+
+	for {
+		entries, listErr := pickerEntries(current, directories, filter)
+
+		if listErr != nil {
+			return "", listErr
+		}
+
+		rows := fuzzyFilter(entries, query)
+
+		if directories {
+			rows = append([]pickerEntry{{pickHere, true}}, rows...)
+		}
+
+		cursor = max(min(cursor, len(rows)-1), 0)
+		offset = max(min(offset, cursor), cursor-visible+1) // Scroll just enough to keep the cursor on screen
+		drawPicker(output, current, query, rows, cursor, offset, visible, directories)
+
+		key, letter, keyErr := readPickerKey(reader)
+
+		if keyErr != nil { // If the input was closed
+			return "", ErrPickCancelled
+		}
+
+		goUp := func() {
+			if current == root { // Never leave the root
+				return
+			}
+
+			left := filepath.Base(current)
+			current, query, cursor = filepath.Dir(current), "", 0
+
+			if siblings, siblingsErr := pickerEntries(current, directories, filter); siblingsErr == nil { // Put the cursor back on the directory we left
+				for index, sibling := range siblings {
+					if sibling.name == left {
+						cursor = index
+
+						if directories {
+							cursor++ // Past the pickHere row
+						}
+					}
+				}
+			}
+		}
+
+		switch key {
+		case keyCancel:
+			return "", ErrPickCancelled
+		case keyUp:
+			cursor--
+		case keyDown:
+			cursor++
+		case keyPageUp:
+			cursor -= visible
+		case keyPageDown:
+			cursor += visible
+		case keyLeft:
+			goUp()
+		case keyBackspace:
+			if query == "" {
+				goUp()
+			} else {
+				runes := []rune(query)
+				query, cursor = string(runes[:len(runes)-1]), 0
+			}
+		case keyRune:
+			query, cursor = query+string(letter), 0
+		case keyEnter, keyRight:
+			if len(rows) == 0 { // If nothing matches the filter
+				continue
+			}
+
+			row := rows[cursor]
+
+			switch {
+			case directories && cursor == 0: // If this is the pickHere row
+				if key == keyEnter {
+					return current, nil
+				}
+			case row.isDir:
+				current, query, cursor = filepath.Join(current, row.name), "", 0
+			case key == keyEnter:
+				return filepath.Join(current, row.name), nil
+			}
+		}
+	}
+}
+
+// drawPicker clears the screen and draws the directory, the filter, the rows from offset with the cursor, and the help line.
+// Lines end with a carriage return, as the terminal is raw.
+func drawPicker(output io.Writer, current, query string, rows []pickerEntry, cursor, offset, visible int, directories bool) {
+	var screen strings.Builder
+
+	screen.WriteString("\x1b[H\x1b[2J") // Move to the top left and clear the screen
+	fmt.Fprintf(&screen, "%s\r\n", current)
+	fmt.Fprintf(&screen, "Filter: %s\r\n", query)
+
+	for index := offset; index < len(rows) && index < offset+visible; index++ {
+		marker, suffix := "  ", ""
+
+		if index == cursor {
+			marker = "> "
+		}
+
+		if rows[index].isDir && !(directories && index == 0) {
+			suffix = string(filepath.Separator)
+		}
+
+		fmt.Fprintf(&screen, "%s%s%s\r\n", marker, rows[index].name, suffix)
+	}
+
+	if len(rows) == 0 {
+		screen.WriteString("  (nothing matches)\r\n")
+	}
+
+	if directories {
+		screen.WriteString("Up/Down to move, Enter on . to pick this directory, Enter to open, Left to go up, type to filter, Esc to cancel")
+	} else {
+		screen.WriteString("Up/Down to move, Enter to open or pick, Left to go up, type to filter, Esc to cancel")
+	}
+
+	io.WriteString(output, screen.String())
+}
+
+// readPickerKey reads the next key from the raw terminal, decoding the escape sequences of the arrow and page keys
+func readPickerKey(reader *bufio.Reader) (pickerKey, rune, error) {
+	letter, _, readErr := reader.ReadRune()
+
+	if readErr != nil {
+		return keyNone, 0, readErr
+	}
+
+	switch letter {
+	case '\r', '\n':
+		return keyEnter, 0, nil
+	case 0x7f, 0x08: // Delete or Ctrl+H, depending on the terminal
+		return keyBackspace, 0, nil
+	case 0x03: // Ctrl+C, which raw mode delivers as a key rather than a signal
+		return keyCancel, 0, nil
+	case 0x10: // Ctrl+P
+		return keyUp, 0, nil
+	case 0x0e: // Ctrl+N
+		return keyDown, 0, nil
+	case 0x1b:
+		if reader.Buffered() == 0 { // If Escape was pressed on its own, rather than starting a sequence
+			return keyCancel, 0, nil
+		}
+
+		if introducer, _ := reader.ReadByte(); introducer != '[' && introducer != 'O' { // If this isn't a sequence we know
+			return keyNone, 0, nil
+		}
+
+		var sequence []byte
+
+		for { // Read up to the final byte of the sequence
+			next, sequenceErr := reader.ReadByte()
+
+			if sequenceErr != nil {
+				return keyNone, 0, sequenceErr
+			}
+
+			sequence = append(sequence, next)
+
+			if next >= 0x40 && next <= 0x7e {
+				break
+			}
+		}
+
+		switch string(sequence) {
+		case "A":
+			return keyUp, 0, nil
+		case "B":
+			return keyDown, 0, nil
+		case "C":
+			return keyRight, 0, nil
+		case "D":
+			return keyLeft, 0, nil
+		case "5~":
+			return keyPageUp, 0, nil
+		case "6~":
+			return keyPageDown, 0, nil
+		}
+
+		return keyNone, 0, nil
+	}
+
+	if unicode.IsPrint(letter) {
+		return keyRune, letter, nil
+	}
+
+	return keyNone, 0, nil
+}
+
+// pickLines runs the picker line by line over input and output, for when they aren't a terminal, until the user picks a file,
+// or a directory if directories is set. Entries are chosen by number, text filters the list, ".." goes up, "." picks the
+// directory shown, and "q" cancels.
+func pickLines(root string, directories bool, filter func(string) bool, input io.Reader, output io.Writer) (string, error) {
+	reader := bufio.NewReader(input) // Shared between prompts, so nothing buffered is lost between them
+	current := filepath.Clean(root)
+	query := ""
+
+	for {
+		entries, listErr := pickerEntries(current, directories, filter)
+
+		if listErr != nil {
+			return "", listErr
+		}
+
+		shown := fuzzyFilter(entries, query)
+		fmt.Fprintf(output, "\n%s\n", current)
+
+		for i, entry := range shown {
+			suffix := ""
+
+			if entry.isDir {
+				suffix = string(filepath.Separator)
+			}
+
+			fmt.Fprintf(output, "%4d  %s%s\n", i+1, entry.name, suffix)
+		}
+
+		if len(shown) == 0 {
+			fmt.Fprintln(output, "      (nothing matches)")
+		}
+
+		help := "Number to choose, text to filter, .. to go up, q to cancel"
+
+		if directories {
+			help = "Number to open, . to pick this directory, text to filter, .. to go up, q to cancel"
+		}
+
+		fmt.Fprint(output, help+": ")
+		line, readErr := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+
+		if readErr != nil && line == "" { // If the input was closed
+			return "", ErrPickCancelled
+		}
+
+		choice, numberErr := strconv.Atoi(line)
+
+		switch {
+		case line == "q":
+			return "", ErrPickCancelled
+		case line == "." && directories:
+			return current, nil
+		case line == "..":
+			if current != filepath.Clean(root) { // Never leave the root
+				current, query = filepath.Dir(current), ""
+			}
+		case line == "":
+			query = "" // Clear the filter
+		case numberErr == nil && choice >= 1 && choice <= len(shown):
+			chosen := filepath.Join(current, shown[choice-1].name)
+
+			if !shown[choice-1].isDir { // If this is a file, we're done
+				return chosen, nil
+			}
+
+			current, query = chosen, ""
+		default:
+			query = line
+		}
+	}
+}
+
+// pickerEntries lists the directories of the directory, followed by the files accepted by filter unless only listing directories
+func pickerEntries(directory string, directoriesOnly bool, filter func(string) bool) ([]pickerEntry, error) {
+	dirEntries, readErr := GetEntries(directory)
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var directories, files []pickerEntry
+
+	for _, entry := range dirEntries {
+		isDir := entry.IsDir()
+
+		if entry.Type()&os.ModeSymlink != 0 { // Browse into symlinked directories too
+			isDir = IsDir(filepath.Join(directory, entry.Name()))
+		}
+
+		if isDir {
+			directories = append(directories, pickerEntry{entry.Name(), true})
+		} else if !directoriesOnly && (filter == nil || filter(filepath.Join(directory, entry.Name()))) {
+			files = append(files, pickerEntry{entry.Name(), false})
+		}
+	}
+
+	return append(directories, files...), nil
+}
+
+// fuzzyFilter returns the entries whose names contain the letters of the query in order, ignoring case
+func fuzzyFilter(entries []pickerEntry, query string) []pickerEntry {
+	if query == "" {
+		return entries
+	}
+
+	var matches []pickerEntry
+	query = strings.ToLower(query)
+
+	for _, entry := range entries {
+		remaining := query
+
+		for _, letter := range strings.ToLower(entry.name) {
+			if remaining != "" && strings.HasPrefix(remaining, string(letter)) {
+				remaining = remaining[len(string(letter)):]
+			}
+		}
+
+		if remaining == "" {
+			matches = append(matches, entry)
+		}
+	}
+
+	return matches
+}
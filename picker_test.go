@@ -0,0 +1,86 @@
+package coreutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrowseNavigation(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "sub"), 0755)
+
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/c.go"} {
+		os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), nil, 0644)
+	}
+
+	tests := []struct {
+		name        string
+		directories bool
+		keys        string
+		expected    string
+		err         error
+	}{
+		{"arrow down and pick", false, "\x1b[B\r", "a.txt", nil},
+		{"open and filter", false, "\rcg\r", "sub/c.go", nil},
+		{"go up returns to the directory", false, "\r\x1b[D\x1b[B\r", "a.txt", nil},
+		{"backspace edits the filter then goes up", false, "\rc\x7f\x7f\x1b[B\r", "a.txt", nil},
+		{"left stays within the root", false, "\x1b[D\x1b[B\r", "a.txt", nil},
+		{"right doesn't pick files", false, "\x1b[B\x1b[C\x1b[A\x1b[C\x1b[B\x1b[B\r", "sub/c.go", nil},
+		{"pick a directory", true, "\x1b[B\r\r", "sub", nil},
+		{"pick the root", true, "\r", "", nil},
+		{"escape cancels", false, "\x1b[B\x1b", "", ErrPickCancelled},
+		{"closed input cancels", false, "\x1b[B", "", ErrPickCancelled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			picked, pickErr := browse(root, test.directories, nil, strings.NewReader(test.keys), io.Discard, 10)
+
+			if !errors.Is(pickErr, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, pickErr)
+			}
+
+			if expected := filepath.Join(root, filepath.FromSlash(test.expected)); test.err == nil && picked != expected {
+				t.Errorf("expected %s to be picked, got %s", expected, picked)
+			}
+		})
+	}
+}
+
+// TestBrowseScrollsToCursor pages and moves down past the two rows a five line terminal has room for
+func TestBrowseScrollsToCursor(t *testing.T) {
+	root := t.TempDir()
+
+	for _, name := range []string{"1", "2", "3", "4", "5", "6"} {
+		os.WriteFile(filepath.Join(root, name), nil, 0644)
+	}
+
+	var screen strings.Builder
+
+	if _, pickErr := browse(root, false, nil, strings.NewReader("\x1b[6~\x1b[B\x1b[B"), &screen, 5); pickErr != ErrPickCancelled {
+		t.Fatalf("expected the closed input to cancel, got %v", pickErr)
+	}
+
+	frames := strings.Split(screen.String(), "\x1b[H\x1b[2J")
+	last := frames[len(frames)-1]
+
+	if !strings.Contains(last, "  4\r\n> 5\r\n") || strings.Contains(last, "  3\r\n") {
+		t.Errorf("expected the list to scroll to the cursor on 5, got %q", last)
+	}
+}
+
+func TestPickLines(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "sub"), 0755)
+	os.WriteFile(filepath.Join(root, "sub", "b.txt"), nil, 0644)
+
+	picked, pickErr := pickLines(root, false, nil, strings.NewReader("1\n1\n"), io.Discard)
+
+	if pickErr != nil || picked != filepath.Join(root, "sub", "b.txt") {
+		t.Errorf("expected sub/b.txt to be picked, got %s (%v)", picked, pickErr)
+	}
+}
@@ -0,0 +1,162 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrPolicyDenied is returned when the PathPolicy does not allow an operation to touch a path
+var ErrPolicyDenied = errors.New("denied by the path policy")
+
+// PathPolicy restricts which paths the package may touch, so applications embedding it can sandbox what it does on their behalf.
+// Paths are compared after being made absolute and having any symlinks resolved, so a symlink can't be used to get around the policy.
+type PathPolicy struct {
+	// AllowedRoots are the directories paths must be within, or empty to allow any path
+	AllowedRoots []string
+
+	// DeniedGlobs are patterns of paths which may never be touched, using the syntax of .gitignore relative to the root of
+	// the filesystem, such as "*.pem" for any file ending in .pem, or "/etc" for /etc and everything within it
+	DeniedGlobs []string
+
+	// ReadOnlyPrefixes are the directories whose contents may be read but not created, modified, or removed
+	ReadOnlyPrefixes []string
+}
+
+// compiledPathPolicy is a PathPolicy with its roots resolved and its patterns compiled
+type compiledPathPolicy struct {
+	policy           PathPolicy
+	allowedRoots     []string
+	denied           *IgnoreMatcher
+	readOnlyPrefixes []string
+}
+
+var (
+	policyLock sync.RWMutex
+	pathPolicy *compiledPathPolicy // nil when no policy is set
+)
+
+// SetPathPolicy replaces the policy consulted by every function which creates, modifies, or removes files, and by the
+// copies reading them. Those functions return an error wrapping ErrPolicyDenied rather than touching a path the policy
+// does not allow. The zero PathPolicy allows everything.
+func SetPathPolicy(policy PathPolicy) {
+	var compiled *compiledPathPolicy
+
+	if len(policy.AllowedRoots) != 0 || len(policy.DeniedGlobs) != 0 || len(policy.ReadOnlyPrefixes) != 0 { // If the policy restricts anything
+		compiled = &compiledPathPolicy{
+			policy:           policy,
+			allowedRoots:     resolvePolicyPaths(policy.AllowedRoots),
+			denied:           NewIgnoreMatcher(policy.DeniedGlobs),
+			readOnlyPrefixes: resolvePolicyPaths(policy.ReadOnlyPrefixes),
+		}
+	}
+
+	policyLock.Lock()
+	pathPolicy = compiled
+	policyLock.Unlock()
+}
+
+// GetPathPolicy returns the current PathPolicy
+func GetPathPolicy() PathPolicy {
+	policyLock.RLock()
+	defer policyLock.RUnlock()
+
+	if pathPolicy == nil {
+		return PathPolicy{}
+	}
+
+	return pathPolicy.policy
+}
+
+// resolvePolicyPaths resolves each of the paths of a PathPolicy the same way the paths being checked are
+func resolvePolicyPaths(paths []string) []string {
+	resolvedPaths := make([]string, 0, len(paths))
+
+	for _, policyPath := range paths {
+		if resolvedPath, resolveErr := resolveExisting(policyPath); resolveErr == nil {
+			resolvedPaths = append(resolvedPaths, resolvedPath)
+		}
+	}
+
+	return resolvedPaths
+}
+
+// checkPathPolicy returns an error wrapping ErrPolicyDenied if the PathPolicy does not allow the path to be touched, or to
+// be modified if write is true
+func checkPathPolicy(path string, write bool) error {
+	policyLock.RLock()
+	policy := pathPolicy
+	policyLock.RUnlock()
+
+	if policy == nil { // If there's no policy, everything is allowed
+		return nil
+	}
+
+	resolvedPath, resolveErr := resolveExisting(path)
+
+	if resolveErr != nil {
+		return fmt.Errorf("%s: %w", path, ErrPolicyDenied)
+	}
+
+	if len(policy.allowedRoots) != 0 && !withinAny(policy.allowedRoots, resolvedPath) { // If the path is outside of every allowed root
+		return fmt.Errorf("%s is outside of the allowed roots: %w", path, ErrPolicyDenied)
+	}
+
+	absolutePath, _ := filepath.Abs(path)
+	isDir := IsDir(resolvedPath)
+
+	if policy.denied.Match(filesystemRelative(absolutePath), isDir) || policy.denied.Match(filesystemRelative(resolvedPath), isDir) { // If the path, or where it leads, matches a denied glob
+		return fmt.Errorf("%s matches a denied glob: %w", path, ErrPolicyDenied)
+	}
+
+	if write && withinAny(policy.readOnlyPrefixes, resolvedPath) { // If the path may only be read
+		return fmt.Errorf("%s is read-only: %w", path, ErrPolicyDenied)
+	}
+
+	return nil
+}
+
+// checkTreePolicy returns an error wrapping ErrPolicyDenied if the PathPolicy does not allow path, or anything within it, to be
+// removed. Removing a tree would otherwise take files under a ReadOnlyPrefixes directory, or matching a DeniedGlobs pattern, along with it.
+func checkTreePolicy(path string) error {
+	policyLock.RLock()
+	policy := pathPolicy
+	policyLock.RUnlock()
+
+	if policy == nil { // If there's no policy, everything is allowed
+		return nil
+	}
+
+	if _, statErr := os.Lstat(path); statErr != nil { // If there's no tree to check, only the path itself
+		return checkPathPolicy(path, true)
+	}
+
+	return walkTree(path, newOperationOptions(nil), func(entryPath, relativePath string, info os.FileInfo) error {
+		return checkPathPolicy(entryPath, true)
+	})
+}
+
+// withinAny checks if the path is any of the directories or within one of them
+func withinAny(directories []string, path string) bool {
+	for _, directory := range directories {
+		if isWithin(directory, path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filesystemRelative converts an absolute path into a slash separated path relative to the root of its filesystem, as
+// DeniedGlobs are matched against
+func filesystemRelative(absolutePath string) string {
+	return strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(absolutePath, filepath.VolumeName(absolutePath))), "/")
+}
+
+// isWriteFlag checks if the flags of an open would create, modify, or truncate the file
+func isWriteFlag(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+}
@@ -0,0 +1,84 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newPolicyTree creates a tree holding a read-only directory and a denied file, returning its root
+func newPolicyTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	if mkdirErr := os.MkdirAll(filepath.Join(root, "tree", "readonly"), 0755); mkdirErr != nil {
+		t.Fatal(mkdirErr)
+	}
+
+	for _, file := range []string{filepath.Join("tree", "readonly", "kept.txt"), filepath.Join("tree", "secret.pem"), filepath.Join("tree", "plain.txt")} {
+		if writeErr := os.WriteFile(filepath.Join(root, file), nil, 0644); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+
+	return root
+}
+
+func TestRemoveTreeHonorsReadOnlyDescendants(t *testing.T) {
+	root := newPolicyTree(t)
+	SetPathPolicy(PathPolicy{ReadOnlyPrefixes: []string{filepath.Join(root, "tree", "readonly")}})
+	defer SetPathPolicy(PathPolicy{})
+
+	if removeErr := RemoveTree(filepath.Join(root, "tree")); !errors.Is(removeErr, ErrPolicyDenied) {
+		t.Fatalf("expected ErrPolicyDenied, got %v", removeErr)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(root, "tree", "readonly", "kept.txt")); statErr != nil {
+		t.Errorf("expected the read-only file to be kept, got %v", statErr)
+	}
+}
+
+func TestMoveToTrashHonorsReadOnlyDescendants(t *testing.T) {
+	root := newPolicyTree(t)
+	SetTrashDirectory(filepath.Join(root, "trash"))
+	defer SetTrashDirectory("")
+	SetPathPolicy(PathPolicy{ReadOnlyPrefixes: []string{filepath.Join(root, "tree", "readonly")}})
+	defer SetPathPolicy(PathPolicy{})
+
+	if trashErr := MoveToTrash(filepath.Join(root, "tree")); !errors.Is(trashErr, ErrPolicyDenied) {
+		t.Fatalf("expected ErrPolicyDenied, got %v", trashErr)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(root, "tree", "readonly", "kept.txt")); statErr != nil {
+		t.Errorf("expected the read-only file to be kept, got %v", statErr)
+	}
+}
+
+func TestRemoveDirectoryContentsHonorsDeniedGlobs(t *testing.T) {
+	root := newPolicyTree(t)
+	SetPathPolicy(PathPolicy{DeniedGlobs: []string{"*.pem"}})
+	defer SetPathPolicy(PathPolicy{})
+
+	if removeErr := RemoveDirectoryContents(root); !errors.Is(removeErr, ErrPolicyDenied) {
+		t.Fatalf("expected ErrPolicyDenied, got %v", removeErr)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(root, "tree", "secret.pem")); statErr != nil {
+		t.Errorf("expected the denied file to be kept, got %v", statErr)
+	}
+}
+
+func TestRemoveTreeAllowedByPolicy(t *testing.T) {
+	root := newPolicyTree(t)
+	SetPathPolicy(PathPolicy{AllowedRoots: []string{root}})
+	defer SetPathPolicy(PathPolicy{})
+
+	if removeErr := RemoveTree(filepath.Join(root, "tree")); removeErr != nil {
+		t.Fatal(removeErr)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(root, "tree")); !os.IsNotExist(statErr) {
+		t.Errorf("expected the tree to be removed, got %v", statErr)
+	}
+}
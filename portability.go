@@ -0,0 +1,140 @@
+package coreutils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Platform is an operating system a tree can be checked for with CheckTreePortability
+type Platform int
+
+const (
+	// PlatformLinux is Linux, which only limits the length of names
+	PlatformLinux Platform = iota
+
+	// PlatformMacOS is macOS, which also reserves the colon and is case-insensitive by default
+	PlatformMacOS
+
+	// PlatformWindows is Windows, which also reserves device names, a set of characters, and trailing dots and spaces, limits the length
+	// of paths, and is case-insensitive
+	PlatformWindows
+)
+
+// Issue is a problem with a path found by CheckTreePortability
+type Issue struct {
+	Path    string // Path with the problem
+	Problem string // Description of the problem
+}
+
+// maxNameLength is the longest a single name can be, in bytes, on the filesystems of every Platform
+const maxNameLength = 255
+
+// maxWindowsPath is the longest a path can be on Windows without long path support, MAX_PATH less the terminating NUL
+const maxWindowsPath = 259
+
+// windowsReservedNames are the device names Windows reserves, with or without an extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// String returns the name of the Platform
+func (platform Platform) String() string {
+	switch platform {
+	case PlatformLinux:
+		return "Linux"
+	case PlatformMacOS:
+		return "macOS"
+	case PlatformWindows:
+		return "Windows"
+	default:
+		return "unknown"
+	}
+}
+
+// CheckTreePortability will check the names of everything within path for problems on the target Platform, such as reserved names,
+// trailing dots and spaces, characters which aren't allowed, names longer than 255 bytes, relative paths too long for Windows, and
+// names which differ only by case on a case-insensitive Platform. Issues are returned in the order they are found, with a path
+// having an Issue for each of its problems.
+func CheckTreePortability(path string, target Platform) ([]Issue, error) {
+	if !IsDir(path) { // If this isn't a directory
+		return nil, notDirectoryError(path, nil)
+	}
+
+	var issues []Issue
+	options := newOperationOptions(nil)
+
+	walkErr := walkTree(path, options, func(entryPath, relativePath string, info os.FileInfo) error {
+		if relativePath == "." { // The root's name isn't part of the tree
+			return nil
+		}
+
+		for _, problem := range namePortabilityProblems(info.Name(), target) {
+			issues = append(issues, Issue{Path: entryPath, Problem: problem})
+		}
+
+		if target == PlatformWindows && len(relativePath) > maxWindowsPath { // If this is too long wherever the tree is put
+			issues = append(issues, Issue{Path: entryPath, Problem: fmt.Sprintf("relative path is %d characters, longer than the %d Windows allows", len(relativePath), maxWindowsPath)})
+		}
+
+		return nil
+	})
+
+	if walkErr != nil {
+		return issues, walkErr
+	}
+
+	if target == PlatformMacOS || target == PlatformWindows { // If the Platform is case-insensitive
+		collisions, findErr := findCaseCollisions(path, options)
+
+		for _, collision := range collisions {
+			for _, collidingPath := range collision {
+				issues = append(issues, Issue{Path: collidingPath, Problem: "name differs only by case from another in the same directory"})
+			}
+		}
+
+		walkErr = findErr
+	}
+
+	return issues, walkErr
+}
+
+// namePortabilityProblems returns the problems with a single name on the target Platform
+func namePortabilityProblems(name string, target Platform) []string {
+	var problems []string
+
+	if len(name) > maxNameLength {
+		problems = append(problems, fmt.Sprintf("name is %d bytes, longer than %d", len(name), maxNameLength))
+	}
+
+	switch target {
+	case PlatformMacOS:
+		if strings.Contains(name, ":") { // Shown as a slash by Finder, and a separator to older APIs
+			problems = append(problems, "name contains a colon")
+		}
+	case PlatformWindows:
+		base := strings.ToUpper(name)
+
+		if dot := strings.Index(base, "."); dot != -1 { // Reserved names are reserved with any extension
+			base = base[:dot]
+		}
+
+		if windowsReservedNames[strings.TrimRight(base, " ")] {
+			problems = append(problems, "name is reserved for a device")
+		}
+
+		if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+			problems = append(problems, "name ends with a dot or space")
+		}
+
+		if invalid := strings.IndexFunc(name, func(r rune) bool {
+			return r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r)
+		}); invalid != -1 {
+			problems = append(problems, fmt.Sprintf("name contains %q, which isn't allowed", name[invalid]))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,147 @@
+package coreutils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CopyOrderFunc reports whether the entry a should be copied before the entry b
+type CopyOrderFunc func(a, b fs.FileInfo) bool
+
+// SmallestFirst is a CopyOrderFunc which copies smaller files first, so many files become available early in a long copy
+func SmallestFirst(a, b fs.FileInfo) bool {
+	return a.Size() < b.Size()
+}
+
+// WithCopyOrder sets the order the entries of each directory are copied in, such as SmallestFirst. Entries which compare
+// equal keep the order they would otherwise be copied in.
+func WithCopyOrder(less CopyOrderFunc) Option {
+	return func(options *operationOptions) {
+		options.copyOrder = less
+	}
+}
+
+// WithPriority copies the files matching the glob patterns, such as "*.service", before any other file in the tree, so
+// processes depending on them can start while the rest of the copy continues. Patterns are matched like WithExclude, and
+// files matching an earlier pattern are copied before those matching a later one.
+func WithPriority(patterns ...string) Option {
+	return func(options *operationOptions) {
+		options.priority = append(options.priority, patterns...)
+	}
+}
+
+// priorityRank returns the index of the first priority pattern the path, relative to the root of the operation, matches,
+// or -1 if it doesn't match any
+func (options *operationOptions) priorityRank(relativePath string) int {
+	relativePath = filepath.ToSlash(relativePath)
+	baseName := filepath.Base(relativePath)
+
+	for index, pattern := range options.priority { // For each priority pattern, in order
+		if baseMatch, _ := filepath.Match(pattern, baseName); baseMatch {
+			return index
+		}
+
+		if pathMatch, _ := filepath.Match(pattern, relativePath); pathMatch {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// orderCopies sorts the directory entries with the CopyOrderFunc of the operation, if it has one
+func (options *operationOptions) orderCopies(entries []os.DirEntry) {
+	if options.copyOrder == nil {
+		return
+	}
+
+	infos := make(map[string]fs.FileInfo, len(entries)) // Stat each entry once rather than on every comparison
+
+	for _, entry := range entries {
+		if info, infoErr := entry.Info(); infoErr == nil {
+			infos[entry.Name()] = info
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		first, second := infos[entries[i].Name()], infos[entries[j].Name()]
+
+		if first == nil || second == nil { // Entries which vanished are left for the copy to report, after the rest
+			return second == nil && first != nil
+		}
+
+		return options.copyOrder(first, second)
+	})
+}
+
+// copyPriorityFiles copies the files within sourceDirectory matching the priority patterns into destinationDirectory,
+// recording them so the main copy skips them
+func copyPriorityFiles(sourceDirectory, destinationDirectory string, options *operationOptions) error {
+	progress := options.progress
+	options.progress = nil // Listing reports each file found, which isn't progress of the copy
+
+	files, listErr := getFiles(sourceDirectory, "", true, options, make(chan struct{}, options.workers-1), newDirectoryChain(sourceDirectory, options), nil)
+
+	options.progress = progress
+	options.entriesVisited.Store(0) // The main copy visits every entry again, so it shouldn't count them twice
+
+	if isFatalWalkError(listErr) { // If the tree is too large or the copy was canceled
+		return listErr
+	}
+
+	type priorityFile struct {
+		relativePath string
+		rank         int
+	}
+
+	var priorityFiles []priorityFile
+
+	for _, file := range files { // For each file, keep those with priority
+		relativePath, _ := filepath.Rel(sourceDirectory, file)
+
+		if rank := options.priorityRank(relativePath); rank != -1 && options.included(relativePath) {
+			priorityFiles = append(priorityFiles, priorityFile{relativePath, rank})
+		}
+	}
+
+	sort.SliceStable(priorityFiles, func(i, j int) bool {
+		return priorityFiles[i].rank < priorityFiles[j].rank
+	})
+
+	options.prioritized = make(map[string]bool, len(priorityFiles))
+	var copyError error
+
+	for _, file := range priorityFiles { // For each file with priority, most important first
+		sourceFile, destinationFile := filepath.Join(sourceDirectory, file.relativePath), filepath.Join(destinationDirectory, file.relativePath)
+		options.prioritized[file.relativePath] = true
+
+		if copyError = options.canceled(sourceFile); copyError != nil {
+			break
+		}
+
+		if options.checkpoint.done(file.relativePath, destinationFile) { // If a previous run already copied this file
+			options.stats.skipped()
+			continue
+		}
+
+		if sourceInfo, statErr := os.Lstat(sourceFile); statErr == nil && sourceInfo.Mode()&os.ModeSymlink != 0 && !options.FollowSymlinks {
+			copyError = copySymlink(sourceFile, destinationFile, options)
+		} else {
+			copyError = copyFile(sourceFile, destinationFile, options)
+		}
+
+		if copyError == nil {
+			copyError = options.applyMaps(destinationFile, file.relativePath, false)
+		}
+
+		if copyError == nil {
+			options.checkpoint.complete(file.relativePath)
+		} else if isFatalWalkError(copyError) {
+			break
+		}
+	}
+
+	return copyError
+}
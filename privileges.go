@@ -0,0 +1,72 @@
+package coreutils
+
+import (
+	"errors"
+	"strings"
+)
+
+// capabilityNumbers maps Linux capability names, without their CAP_ prefix, to their bit in a capability set
+var capabilityNumbers = map[string]uint{
+	"chown":              0,
+	"dac_override":       1,
+	"dac_read_search":    2,
+	"fowner":             3,
+	"fsetid":             4,
+	"kill":               5,
+	"setgid":             6,
+	"setuid":             7,
+	"setpcap":            8,
+	"linux_immutable":    9,
+	"net_bind_service":   10,
+	"net_broadcast":      11,
+	"net_admin":          12,
+	"net_raw":            13,
+	"ipc_lock":           14,
+	"ipc_owner":          15,
+	"sys_module":         16,
+	"sys_rawio":          17,
+	"sys_chroot":         18,
+	"sys_ptrace":         19,
+	"sys_pacct":          20,
+	"sys_admin":          21,
+	"sys_boot":           22,
+	"sys_nice":           23,
+	"sys_resource":       24,
+	"sys_time":           25,
+	"sys_tty_config":     26,
+	"mknod":              27,
+	"lease":              28,
+	"audit_write":        29,
+	"audit_control":      30,
+	"setfcap":            31,
+	"mac_override":       32,
+	"mac_admin":          33,
+	"syslog":             34,
+	"wake_alarm":         35,
+	"block_suspend":      36,
+	"audit_read":         37,
+	"perfmon":            38,
+	"bpf":                39,
+	"checkpoint_restore": 40,
+}
+
+// RequireRoot returns an error if the process is not running with root (or Administrator) privileges
+func RequireRoot() error {
+	if !isPrivileged() {
+		return errors.New("This operation requires root privileges. Please run it again as root or with sudo.")
+	}
+
+	return nil
+}
+
+// capabilityNumber converts a capability name such as CAP_NET_BIND_SERVICE or net_bind_service into its bit number
+func capabilityNumber(capability string) (uint, error) {
+	name := strings.TrimPrefix(strings.ToLower(capability), "cap_")
+	number, exists := capabilityNumbers[name]
+
+	if !exists { // If this isn't a capability we know of
+		return 0, errors.New(capability + " is not a valid capability.")
+	}
+
+	return number, nil
+}
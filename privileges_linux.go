@@ -0,0 +1,40 @@
+//go:build linux
+
+package coreutils
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HasCapability checks if the process has the capability, such as CAP_NET_BIND_SERVICE, in its effective set
+func HasCapability(capability string) bool {
+	number, capabilityErr := capabilityNumber(capability)
+
+	if capabilityErr != nil { // If this isn't a valid capability
+		return false
+	}
+
+	status, openErr := os.Open("/proc/self/status")
+
+	if openErr != nil { // If procfs isn't available, fall back to assuming root has every capability
+		return isPrivileged()
+	}
+
+	defer status.Close()
+
+	scanner := bufio.NewScanner(status)
+
+	for scanner.Scan() { // For each line of our status
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "CapEff:") { // If this is our effective capability set
+			effective, parseErr := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+			return (parseErr == nil) && (effective&(1<<number) != 0)
+		}
+	}
+
+	return false
+}
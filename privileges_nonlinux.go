@@ -0,0 +1,9 @@
+//go:build !linux
+
+package coreutils
+
+// HasCapability checks if the process has the capability. Only Linux has capability sets, so elsewhere this checks for root privileges.
+func HasCapability(capability string) bool {
+	_, capabilityErr := capabilityNumber(capability)
+	return (capabilityErr == nil) && isPrivileged()
+}
@@ -0,0 +1,65 @@
+//go:build unix
+
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// DropPrivileges switches the process to the user provided, along with their primary and supplementary groups.
+// This is irreversible, so it should be called once any privileged setup (such as binding ports) is complete.
+func DropPrivileges(username string) error {
+	if !isPrivileged() { // If we have no privileges to drop
+		return errors.New("Dropping privileges requires running as root.")
+	}
+
+	account, lookupErr := user.Lookup(username)
+
+	if lookupErr != nil { // If the user doesn't exist
+		return errors.New(username + " is not a valid user.")
+	}
+
+	uid, _ := strconv.Atoi(account.Uid)
+	gid, _ := strconv.Atoi(account.Gid)
+
+	var groups []int
+
+	if groupIds, groupsErr := account.GroupIds(); groupsErr == nil { // If we got the supplementary groups of the user
+		for _, groupId := range groupIds {
+			if group, parseErr := strconv.Atoi(groupId); parseErr == nil {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	// Groups must be changed before the user, since we lose the privilege to change them afterwards
+	if setErr := syscall.Setgroups(groups); setErr != nil {
+		return errors.New("Failed to set the supplementary groups of " + username + ": " + setErr.Error())
+	}
+
+	if setErr := syscall.Setgid(gid); setErr != nil {
+		return errors.New("Failed to set the group of " + username + ": " + setErr.Error())
+	}
+
+	if setErr := syscall.Setuid(uid); setErr != nil {
+		return errors.New("Failed to switch to " + username + ": " + setErr.Error())
+	}
+
+	if uid != 0 && syscall.Setuid(0) == nil { // If we could regain root, the drop did not actually take effect
+		return errors.New("Privileges could not be permanently dropped.")
+	}
+
+	os.Setenv("USER", account.Username)
+	os.Setenv("HOME", account.HomeDir)
+
+	return nil
+}
+
+// isPrivileged checks if the process is running as root
+func isPrivileged() bool {
+	return os.Geteuid() == 0
+}
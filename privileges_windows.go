@@ -0,0 +1,24 @@
+//go:build windows
+
+package coreutils
+
+import (
+	"errors"
+	"os"
+)
+
+// DropPrivileges is not supported on Windows, where processes can not switch users
+func DropPrivileges(username string) error {
+	return errors.New("Dropping privileges is not supported on Windows.")
+}
+
+// isPrivileged checks if the process is running as an Administrator, which is the only way to open a physical drive
+func isPrivileged() bool {
+	drive, openErr := os.Open(`\\.\PHYSICALDRIVE0`)
+
+	if openErr == nil {
+		drive.Close()
+	}
+
+	return openErr == nil
+}
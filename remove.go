@@ -0,0 +1,151 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrProtectedPath is returned when removing a filesystem root or the user's home directory without WithForce
+var ErrProtectedPath = errors.New("refusing to remove a filesystem root or home directory")
+
+// WithForce sets whether RemoveTree and RemoveDirectoryContents may act on a filesystem root or the user's home directory,
+// which they otherwise refuse with ErrProtectedPath
+func WithForce(force bool) Option {
+	return func(options *operationOptions) {
+		options.force = force
+	}
+}
+
+// RemoveTree will remove path and everything within it, like os.RemoveAll, unless path is a filesystem root or the user's
+// home directory, including through symlinked parents. A symlink itself is only unlinked, so it's removed wherever it points. Honors WithForce.
+func RemoveTree(path string, opts ...Option) error {
+	start := time.Now()
+	removeErr := checkProtectedPath(path, false, newOperationOptions(opts))
+
+	if removeErr == nil {
+		removeErr = preHooks("RemoveTree", path, "", 0)
+	}
+
+	if removeErr == nil {
+		removeErr = OSFilesystem{}.RemoveAll(path)
+	}
+
+	trace(TraceEvent{Op: "RemoveTree", Path: path, Duration: time.Since(start), Err: removeErr})
+	return removeErr
+}
+
+// RemoveDirectoryContents will remove everything within the directory at path, leaving the directory itself.
+// Like RemoveTree, it refuses to empty a filesystem root or the user's home directory. Honors WithForce.
+func RemoveDirectoryContents(path string, opts ...Option) error {
+	start := time.Now()
+	removeErr := checkProtectedPath(path, true, newOperationOptions(opts))
+
+	if removeErr == nil && !IsDir(path) { // If there's no directory to empty
+		removeErr = notDirectoryError(path, nil)
+	}
+
+	if removeErr == nil {
+		removeErr = preHooks("RemoveDirectoryContents", path, "", 0)
+	}
+
+	if removeErr == nil {
+		var names []string
+
+		removeErr = readDirectory(path, func(entry os.DirEntry) error {
+			names = append(names, entry.Name()) // Remove once the directory is closed, rather than while reading it
+			return nil
+		})
+
+		for _, name := range names {
+			if removeErr != nil {
+				break
+			}
+
+			removeErr = OSFilesystem{}.RemoveAll(filepath.Join(path, name))
+		}
+	}
+
+	trace(TraceEvent{Op: "RemoveDirectoryContents", Path: path, Duration: time.Since(start), Err: removeErr})
+	return removeErr
+}
+
+// RemoveIfEmpty will remove the directory at path only if it is empty, returning whether it was removed
+func RemoveIfEmpty(path string) (bool, error) {
+	start := time.Now()
+	removed, removeErr := removeIfEmpty(path)
+	trace(TraceEvent{Op: "RemoveIfEmpty", Path: path, Duration: time.Since(start), Err: removeErr})
+	return removed, removeErr
+}
+
+// removeIfEmpty removes the directory at path if it is empty
+func removeIfEmpty(path string) (bool, error) {
+	if !IsDir(path) { // If this isn't a directory
+		return false, notDirectoryError(path, nil)
+	}
+
+	empty := true
+
+	readErr := readDirectory(path, func(entry os.DirEntry) error {
+		empty = false
+		return filepath.SkipAll
+	})
+
+	if readErr != nil && readErr != filepath.SkipAll {
+		return false, readErr
+	}
+
+	if !empty { // If there's something in it
+		return false, nil
+	}
+
+	if hookErr := preHooks("RemoveIfEmpty", path, "", 0); hookErr != nil {
+		return false, hookErr
+	}
+
+	if removeErr := (OSFilesystem{}).Remove(path); removeErr != nil {
+		return false, removeErr
+	}
+
+	return true, nil
+}
+
+// checkProtectedPath returns ErrProtectedPath if path resolves to a filesystem root or the user's home directory, unless forced.
+// If followLast is false, path is only resolved through its parents, as removing a symlink doesn't touch what it points to.
+func checkProtectedPath(path string, followLast bool, options *operationOptions) error {
+	if options.force {
+		return nil
+	}
+
+	resolved := resolveProtectedPath(path)
+
+	if info, statErr := os.Lstat(path); !followLast && statErr == nil && info.Mode()&os.ModeSymlink != 0 { // If only the link is removed
+		absolute, _ := filepath.Abs(path)
+		resolved = filepath.Join(resolveProtectedPath(filepath.Dir(absolute)), filepath.Base(absolute))
+	}
+
+	if filepath.Dir(resolved) == resolved { // If this is the root of a filesystem, or a volume on Windows
+		return fmt.Errorf("%s: %w", path, ErrProtectedPath)
+	}
+
+	if home, homeErr := os.UserHomeDir(); homeErr == nil && resolved == resolveProtectedPath(home) {
+		return fmt.Errorf("%s: %w", path, ErrProtectedPath)
+	}
+
+	return nil
+}
+
+// resolveProtectedPath returns the absolute path with symlinks resolved, or as much of that as can be worked out
+func resolveProtectedPath(path string) string {
+	if absolute, absErr := filepath.Abs(path); absErr == nil {
+		path = absolute
+	}
+
+	if resolved, resolveErr := filepath.EvalSymlinks(path); resolveErr == nil {
+		path = resolved
+	}
+
+	return filepath.Clean(path)
+}
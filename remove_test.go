@@ -0,0 +1,80 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveHooks(t *testing.T) {
+	root := t.TempDir()
+	rejected := errors.New("rejected")
+	var removed []string
+
+	removePre := RegisterHook(OpDelete, PhasePre, func(event HookEvent) error {
+		if filepath.Base(event.Path) == "keep" {
+			return rejected
+		}
+
+		return nil
+	})
+
+	defer removePre()
+
+	removePost := RegisterHook(OpDelete, PhasePost, func(event HookEvent) error {
+		if event.Err == nil {
+			removed = append(removed, event.Name+" "+filepath.Base(event.Path))
+		}
+
+		return nil
+	})
+
+	defer removePost()
+
+	for _, name := range []string{"keep/file.txt", "tree/file.txt", "contents/file.txt"} {
+		if mkdirErr := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); mkdirErr != nil {
+			t.Fatal(mkdirErr)
+		}
+
+		if writeErr := os.WriteFile(filepath.Join(root, name), nil, 0644); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+
+	if os.Mkdir(filepath.Join(root, "empty"), 0755) != nil {
+		t.Fatal("failed to create the empty directory")
+	}
+
+	if removeErr := RemoveTree(filepath.Join(root, "keep")); !errors.Is(removeErr, rejected) {
+		t.Errorf("expected the pre hook to reject removing keep, got %v", removeErr)
+	}
+
+	if !IsDir(filepath.Join(root, "keep")) {
+		t.Error("expected keep to survive a rejected RemoveTree")
+	}
+
+	if removeErr := RemoveTree(filepath.Join(root, "tree")); removeErr != nil {
+		t.Fatal(removeErr)
+	}
+
+	if removeErr := RemoveDirectoryContents(filepath.Join(root, "contents")); removeErr != nil {
+		t.Fatal(removeErr)
+	}
+
+	if _, removeErr := RemoveIfEmpty(filepath.Join(root, "empty")); removeErr != nil {
+		t.Fatal(removeErr)
+	}
+
+	expected := []string{"RemoveTree tree", "RemoveDirectoryContents contents", "RemoveIfEmpty empty"}
+
+	if len(removed) != len(expected) {
+		t.Fatalf("expected post hooks for %v, got %v", expected, removed)
+	}
+
+	for index := range expected {
+		if removed[index] != expected[index] {
+			t.Errorf("expected post hooks for %v, got %v", expected, removed)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package coreutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinkResolutions is the number of symlinks SecureJoin will follow before assuming a loop
+const maxSymlinkResolutions = 255
+
+// errOpenInRootUnsupported is returned by openInRoot when the kernel can not confine opens itself
+var errOpenInRootUnsupported = errors.New("openat2 is not supported")
+
+// sandboxFilesystem is a Filesystem which confines every operation to its root
+type sandboxFilesystem struct {
+	root    string
+	rootDir *os.File // Open handle of the root, used to confine opens with openat2 where available
+}
+
+// RunInSandbox will call fn with a Filesystem confined to root. Paths given to the Filesystem are relative to root, and symlinks are
+// resolved as if root were the filesystem root, so absolute targets and parent references never leave it. Opens are confined by
+// the kernel via openat2 and RESOLVE_IN_ROOT where available, otherwise every path is resolved with SecureJoin.
+func RunInSandbox(root string, fn func(fs Filesystem) error) error {
+	absoluteRoot, absErr := filepath.Abs(root)
+
+	if absErr != nil || !IsDir(absoluteRoot) { // If the root isn't a directory
+		return notDirectoryError(root, absErr)
+	}
+
+	if evaluatedRoot, evalErr := filepath.EvalSymlinks(absoluteRoot); evalErr == nil { // The root itself is trusted, so resolve any symlinks in it up front
+		absoluteRoot = evaluatedRoot
+	}
+
+	rootDir, openErr := os.Open(absoluteRoot)
+
+	if openErr != nil { // If we failed to open the root
+		return openError(root, openErr)
+	}
+
+	defer rootDir.Close()
+
+	return fn(&sandboxFilesystem{root: absoluteRoot, rootDir: rootDir})
+}
+
+// SecureJoin joins unsafePath to root, resolving any symlinks and parent references as if root were the filesystem root,
+// so that the resulting path is always within root. Components which do not exist are joined as-is.
+func SecureJoin(root, unsafePath string) (string, error) {
+	root = filepath.Clean(root)
+	remaining := filepath.ToSlash(unsafePath)
+	resolved := ""      // Path resolved so far, relative to root
+	var resolutions int // Number of symlinks we have followed
+
+	for remaining != "" {
+		var component string
+
+		if separatorIndex := strings.IndexRune(remaining, '/'); separatorIndex == -1 { // If this is the last component
+			component, remaining = remaining, ""
+		} else {
+			component, remaining = remaining[:separatorIndex], remaining[separatorIndex+1:]
+		}
+
+		if component == "" || component == "." { // Skip empty and current directory references
+			continue
+		}
+
+		if component == ".." { // Go up a directory, but never above root
+			resolved = filepath.Dir(resolved)
+
+			if resolved == "." || resolved == string(os.PathSeparator) {
+				resolved = ""
+			}
+
+			continue
+		}
+
+		candidate := filepath.Join(resolved, component)
+		stat, statErr := os.Lstat(filepath.Join(root, candidate))
+
+		if statErr != nil || stat.Mode()&os.ModeSymlink == 0 { // If this doesn't exist yet or is not a symlink, take it as-is
+			resolved = candidate
+			continue
+		}
+
+		resolutions++
+
+		if resolutions > maxSymlinkResolutions { // If we have followed too many symlinks
+			return "", errors.New(unsafePath + " has too many levels of symlinks.")
+		}
+
+		target, readErr := os.Readlink(filepath.Join(root, candidate))
+
+		if readErr != nil {
+			return "", readError(filepath.Join(root, candidate), readErr)
+		}
+
+		target = filepath.ToSlash(target)
+
+		if filepath.IsAbs(target) || strings.HasPrefix(target, "/") { // Absolute targets are relative to root
+			resolved = ""
+		}
+
+		remaining = target + "/" + remaining // Resolve the target before continuing with the rest of the path
+	}
+
+	return filepath.Join(root, resolved), nil
+}
+
+// resolve converts a sandbox path into a host path within the root
+func (sandbox *sandboxFilesystem) resolve(name string) (string, error) {
+	return SecureJoin(sandbox.root, name)
+}
+
+// relative converts a sandbox path into a clean path relative to the root
+func (sandbox *sandboxFilesystem) relative(name string) string {
+	relativeName := strings.TrimPrefix(filepath.Clean(string(os.PathSeparator)+name), string(os.PathSeparator))
+
+	if relativeName == "" {
+		relativeName = "."
+	}
+
+	return relativeName
+}
+
+// Open opens the named file for reading
+func (sandbox *sandboxFilesystem) Open(name string) (File, error) {
+	return sandbox.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file with the flags and permissions provided
+func (sandbox *sandboxFilesystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if isWriteFlag(flag) { // If the open could modify the file, check the policy allows it
+		if hostPath, resolveErr := sandbox.resolve(name); resolveErr != nil {
+			return nil, resolveErr
+		} else if policyErr := checkPathPolicy(hostPath, true); policyErr != nil {
+			return nil, policyErr
+		}
+	}
+
+	file, openErr := openInRoot(sandbox.rootDir, sandbox.relative(name), flag, perm)
+
+	if openErr == errOpenInRootUnsupported { // If the kernel can't confine the open, resolve the path ourselves
+		var hostPath string
+
+		if hostPath, openErr = sandbox.resolve(name); openErr == nil {
+			file, openErr = os.OpenFile(hostPath, flag, perm)
+		}
+	}
+
+	if openErr != nil { // Avoid returning a typed nil File
+		return nil, openErr
+	}
+
+	return file, nil
+}
+
+// Stat returns the FileInfo of the named file, following symlinks within the root
+func (sandbox *sandboxFilesystem) Stat(name string) (os.FileInfo, error) {
+	hostPath, resolveErr := sandbox.resolve(name)
+
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	return os.Lstat(hostPath) // SecureJoin already resolved every symlink
+}
+
+// Lstat returns the FileInfo of the named file, without following the final symlink
+func (sandbox *sandboxFilesystem) Lstat(name string) (os.FileInfo, error) {
+	hostPath, resolveErr := sandbox.resolveParent(name)
+
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	return os.Lstat(hostPath)
+}
+
+// ReadDir reads the entries of the named directory
+func (sandbox *sandboxFilesystem) ReadDir(name string) ([]os.DirEntry, error) {
+	hostPath, resolveErr := sandbox.resolve(name)
+
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	return os.ReadDir(hostPath)
+}
+
+// ReadFile reads the content of the named file
+func (sandbox *sandboxFilesystem) ReadFile(name string) ([]byte, error) {
+	file, openErr := sandbox.Open(name)
+
+	if openErr != nil {
+		return nil, openErr
+	}
+
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// WriteFile writes data to the named file, creating it if necessary
+func (sandbox *sandboxFilesystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	file, openErr := sandbox.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+
+	if openErr != nil {
+		return openErr
+	}
+
+	_, writeErr := file.Write(data)
+
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	return writeErr
+}
+
+// Mkdir creates the named directory
+func (sandbox *sandboxFilesystem) Mkdir(name string, perm os.FileMode) error {
+	hostPath, resolveErr := sandbox.resolve(name)
+
+	if resolveErr != nil {
+		return resolveErr
+	}
+
+	if policyErr := checkPathPolicy(hostPath, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Mkdir(hostPath, perm)
+}
+
+// MkdirAll creates the named directory along with any parents
+func (sandbox *sandboxFilesystem) MkdirAll(name string, perm os.FileMode) error {
+	hostPath, resolveErr := sandbox.resolve(name)
+
+	if resolveErr != nil {
+		return resolveErr
+	}
+
+	if policyErr := checkPathPolicy(hostPath, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.MkdirAll(hostPath, perm)
+}
+
+// Remove removes the named file or empty directory. Symlinks are removed rather than their targets.
+func (sandbox *sandboxFilesystem) Remove(name string) error {
+	hostPath, resolveErr := sandbox.resolveParent(name)
+
+	if resolveErr != nil {
+		return resolveErr
+	}
+
+	if policyErr := checkPathPolicy(hostPath, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Remove(hostPath)
+}
+
+// RemoveAll removes the named path and any children it contains. Symlinks are removed rather than their targets.
+func (sandbox *sandboxFilesystem) RemoveAll(name string) error {
+	hostPath, resolveErr := sandbox.resolveParent(name)
+
+	if resolveErr != nil {
+		return resolveErr
+	}
+
+	if hostPath == sandbox.root { // Never remove the root of the sandbox itself
+		return errors.New("Refusing to remove the root of the sandbox.")
+	}
+
+	if policyErr := checkTreePolicy(hostPath); policyErr != nil {
+		return policyErr
+	}
+
+	return os.RemoveAll(hostPath)
+}
+
+// Rename renames oldName to newName
+func (sandbox *sandboxFilesystem) Rename(oldName, newName string) error {
+	oldPath, oldResolveErr := sandbox.resolveParent(oldName)
+
+	if oldResolveErr != nil {
+		return oldResolveErr
+	}
+
+	newPath, newResolveErr := sandbox.resolveParent(newName)
+
+	if newResolveErr != nil {
+		return newResolveErr
+	}
+
+	if policyErr := checkPathPolicy(oldPath, true); policyErr != nil {
+		return policyErr
+	} else if policyErr = checkPathPolicy(newPath, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Rename(oldPath, newPath)
+}
+
+// Symlink creates name as a symlink to target. The target is stored as-is, but is always resolved within the root by this Filesystem.
+func (sandbox *sandboxFilesystem) Symlink(target, name string) error {
+	hostPath, resolveErr := sandbox.resolveParent(name)
+
+	if resolveErr != nil {
+		return resolveErr
+	}
+
+	if policyErr := checkPathPolicy(hostPath, true); policyErr != nil {
+		return policyErr
+	}
+
+	return os.Symlink(target, hostPath)
+}
+
+// Readlink returns the target of the named symlink
+func (sandbox *sandboxFilesystem) Readlink(name string) (string, error) {
+	hostPath, resolveErr := sandbox.resolveParent(name)
+
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	return os.Readlink(hostPath)
+}
+
+// resolveParent resolves the parent directory of name within the root, leaving the final component unresolved
+// so operations like Lstat, Remove and Readlink act on a symlink itself rather than its target
+func (sandbox *sandboxFilesystem) resolveParent(name string) (string, error) {
+	relativeName := sandbox.relative(name)
+
+	if relativeName == "." { // The root has no parent within the sandbox
+		return sandbox.root, nil
+	}
+
+	parentPath, resolveErr := sandbox.resolve(filepath.Dir(relativeName))
+
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	return filepath.Join(parentPath, filepath.Base(relativeName)), nil
+}
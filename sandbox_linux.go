@@ -0,0 +1,61 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysOpenat2          = 437  // Syscall number of openat2 on the architectures using the generic table, which excludes mips
+	resolveNoMagiclinks = 0x02 // RESOLVE_NO_MAGICLINKS
+	resolveInRoot       = 0x10 // RESOLVE_IN_ROOT
+)
+
+// openHow is the open_how struct taken by openat2
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// openat2Unsupported is set once the kernel reports it does not implement openat2
+var openat2Unsupported atomic.Bool
+
+// openInRoot opens name relative to root with openat2, which resolves symlinks and parent references as if root were the
+// filesystem root, as SecureJoin does, so they can never escape it
+func openInRoot(root *os.File, name string, flag int, perm os.FileMode) (*os.File, error) {
+	if openat2Unsupported.Load() { // If we already know openat2 is unavailable
+		return nil, errOpenInRootUnsupported
+	}
+
+	namePointer, nameErr := syscall.BytePtrFromString(name)
+
+	if nameErr != nil {
+		return nil, nameErr
+	}
+
+	how := openHow{
+		flags:   uint64(flag | syscall.O_CLOEXEC),
+		resolve: resolveInRoot | resolveNoMagiclinks,
+	}
+
+	if flag&os.O_CREATE != 0 { // The mode may only be set when creating
+		how.mode = uint64(perm.Perm())
+	}
+
+	fd, _, errno := syscall.Syscall6(sysOpenat2, root.Fd(), uintptr(unsafe.Pointer(namePointer)), uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+
+	if errno == syscall.ENOSYS { // If this kernel predates openat2
+		openat2Unsupported.Store(true)
+		return nil, errOpenInRootUnsupported
+	} else if errno != 0 {
+		return nil, &os.PathError{Op: "openat2", Path: name, Err: errno}
+	}
+
+	return os.NewFile(fd, filepath.Join(root.Name(), name)), nil
+}
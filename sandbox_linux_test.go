@@ -0,0 +1,58 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunInSandboxResolvesLikeSecureJoin opens escaping symlinks through openat2 and through the SecureJoin fallback, which
+// must both resolve them within the root
+func TestRunInSandboxResolvesLikeSecureJoin(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "dir"), 0755)
+	os.WriteFile(filepath.Join(root, "inside.txt"), []byte("inside"), 0644)
+	os.Symlink("/inside.txt", filepath.Join(root, "dir", "absolute"))
+	os.Symlink("../../inside.txt", filepath.Join(root, "dir", "parent"))
+
+	previouslyUnsupported := openat2Unsupported.Load()
+	defer openat2Unsupported.Store(previouslyUnsupported)
+
+	for _, fallback := range []bool{false, true} {
+		openat2Unsupported.Store(fallback)
+
+		if !fallback { // Check the kernel has openat2, rather than testing the fallback twice
+			if rootDir, openErr := os.Open(root); openErr == nil {
+				probe, probeErr := openInRoot(rootDir, "inside.txt", os.O_RDONLY, 0)
+				rootDir.Close()
+
+				if probe != nil {
+					probe.Close()
+				}
+
+				if probeErr == errOpenInRootUnsupported {
+					t.Log("openat2 is not supported by this kernel")
+					continue
+				}
+			}
+		}
+
+		for _, name := range []string{"dir/absolute", "dir/parent"} {
+			sandboxErr := RunInSandbox(root, func(fs Filesystem) error {
+				content, readErr := fs.ReadFile(name)
+
+				if readErr == nil && string(content) != "inside" {
+					t.Errorf("expected %s to resolve to inside.txt with fallback %v, got %q", name, fallback, content)
+				}
+
+				return readErr
+			})
+
+			if sandboxErr != nil {
+				t.Errorf("expected %s to resolve within the root with fallback %v, got %v", name, fallback, sandboxErr)
+			}
+		}
+	}
+}
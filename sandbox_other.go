@@ -0,0 +1,13 @@
+//go:build !linux || mips || mipsle || mips64 || mips64le
+
+package coreutils
+
+import (
+	"os"
+)
+
+// openInRoot is only implemented on Linux outside of mips, whose openat2 syscall numbers differ, so opens elsewhere always
+// fall back to SecureJoin
+func openInRoot(root *os.File, name string, flag int, perm os.FileMode) (*os.File, error) {
+	return nil, errOpenInRootUnsupported
+}
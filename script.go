@@ -0,0 +1,105 @@
+package coreutils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrNoShebang is returned when a file doesn't start with a "#!" line naming its interpreter
+var ErrNoShebang = errors.New("no shebang")
+
+// GetInterpreter will return the interpreter named by the "#!" line at the start of the file, such as "/bin/sh". When the
+// interpreter is found through env, as in "#!/usr/bin/env python3", the name given to env is returned instead.
+func GetInterpreter(path string) (string, error) {
+	interpreter, _, shebangErr := readShebang(path)
+	return interpreter, shebangErr
+}
+
+// IsExecutableScript will check if the file is a script which can be run directly, having a "#!" line and, outside of
+// Windows where execute permissions don't exist, being executable
+func IsExecutableScript(path string) bool {
+	info, statErr := os.Stat(path)
+
+	if statErr != nil || !info.Mode().IsRegular() { // If this isn't a file
+		return false
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 { // If nobody may execute it
+		return false
+	}
+
+	_, _, shebangErr := readShebang(path)
+	return shebangErr == nil
+}
+
+// readShebang reads the interpreter and its arguments from the "#!" line at the start of the file
+func readShebang(path string) (interpreter string, args []string, err error) {
+	openFiles.acquire()
+	defer openFiles.release()
+
+	file, openErr := os.Open(path)
+
+	if openErr != nil { // If the file doesn't exist
+		return "", nil, openError(path, openErr)
+	}
+
+	defer file.Close()
+
+	firstLine, _ := bufio.NewReader(file).ReadString('\n')
+
+	if interpreter, args = parseShebang(firstLine); interpreter == "" {
+		return "", nil, fmt.Errorf("%s: %w", path, ErrNoShebang)
+	}
+
+	return interpreter, args, nil
+}
+
+// parseShebang returns the interpreter and arguments of a "#!" line, resolving "/usr/bin/env name" to name, or an empty
+// interpreter if the line isn't a shebang
+func parseShebang(line string) (interpreter string, args []string) {
+	line, isShebang := strings.CutPrefix(strings.TrimSpace(line), "#!")
+
+	if !isShebang {
+		return "", nil
+	}
+
+	fields := strings.Fields(line)
+
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	if filepath.Base(fields[0]) == "env" { // If the interpreter is found through PATH, skipping env's own options and variables
+		for i, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				return field, fields[i+2:]
+			}
+		}
+
+		return "", nil
+	}
+
+	return fields[0], fields[1:]
+}
+
+// scriptCommand rewrites running the script name on Windows, which ignores shebangs, into running its interpreter with the
+// script as an argument. Unix style interpreter paths such as /bin/bash are looked up by name in PATH. Other commands, and
+// any command elsewhere, are returned unchanged.
+func scriptCommand(name string, args []string) (string, []string) {
+	if runtime.GOOS != "windows" || !IsExecutableScript(name) {
+		return name, args
+	}
+
+	interpreter, interpreterArgs, _ := readShebang(name)
+
+	if strings.HasPrefix(interpreter, "/") { // If this is a Unix path, which means nothing on Windows
+		interpreter = filepath.Base(interpreter)
+	}
+
+	return interpreter, append(append(interpreterArgs, name), args...)
+}
@@ -0,0 +1,82 @@
+package coreutils
+
+import (
+	"os"
+)
+
+// WithSecurityAttributes sets whether copies and extractions keep the SELinux context and file capabilities of each file, which
+// are stored in the security.selinux and security.capability extended attributes and otherwise silently dropped, breaking
+// binaries which rely on them. ArchiveWriter records them in tar archives, and ExtractArchive restores them. Only Linux has these
+// attributes, so elsewhere this does nothing. Setting them usually requires root, and filesystems which can't hold them are skipped.
+func WithSecurityAttributes(preserve bool) Option {
+	return func(options *operationOptions) {
+		options.securityAttributes = preserve
+	}
+}
+
+// copySecurityAttributes copies the security attributes of sourcePath onto destinationPath, if the operation keeps them
+func (options *operationOptions) copySecurityAttributes(sourcePath, destinationPath string) error {
+	if !options.securityAttributes {
+		return nil
+	}
+
+	attributes, readErr := readSecurityAttributes(sourcePath)
+
+	if readErr != nil {
+		return readErr
+	}
+
+	return writeSecurityAttributes(destinationPath, attributes)
+}
+
+// keepSecurityAttributes calls change on path, restoring the security attributes path had beforehand if the operation keeps them,
+// since changing the owner of a file drops its capabilities
+func (options *operationOptions) keepSecurityAttributes(path string, change func() error) error {
+	if !options.securityAttributes {
+		return change()
+	}
+
+	if info, statErr := os.Lstat(path); statErr != nil || info.Mode()&os.ModeSymlink != 0 { // Attributes are read through symlinks, so they are left alone
+		return change()
+	}
+
+	attributes, readErr := readSecurityAttributes(path)
+
+	if readErr != nil {
+		return readErr
+	}
+
+	if changeErr := change(); changeErr != nil {
+		return changeErr
+	}
+
+	return writeSecurityAttributes(path, attributes)
+}
+
+// archiveSecurityAttributes converts security attributes to the extended attributes of an ArchiveEntry
+func archiveSecurityAttributes(attributes map[string][]byte) map[string]string {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	xattrs := make(map[string]string, len(attributes))
+
+	for name, value := range attributes {
+		xattrs[name] = string(value)
+	}
+
+	return xattrs
+}
+
+// entrySecurityAttributes returns the security attributes among the extended attributes of an ArchiveEntry
+func entrySecurityAttributes(xattrs map[string]string) map[string][]byte {
+	attributes := make(map[string][]byte)
+
+	for _, name := range securityAttributeNames {
+		if value, hasValue := xattrs[name]; hasValue {
+			attributes[name] = []byte(value)
+		}
+	}
+
+	return attributes
+}
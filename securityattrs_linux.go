@@ -0,0 +1,53 @@
+//go:build linux
+
+package coreutils
+
+import (
+	"syscall"
+)
+
+// securityAttributeNames are the extended attributes kept by WithSecurityAttributes
+var securityAttributeNames = []string{"security.selinux", "security.capability"}
+
+// readSecurityAttributes returns the security attributes of the file at path, by extended attribute name. Attributes the file
+// doesn't have, or its filesystem doesn't support, are left out.
+func readSecurityAttributes(path string) (map[string][]byte, error) {
+	attributes := make(map[string][]byte)
+
+	for _, name := range securityAttributeNames {
+		size, getErr := syscall.Getxattr(path, name, nil)
+
+		if getErr == syscall.ENODATA || getErr == syscall.ENOTSUP { // If the file doesn't have this attribute
+			continue
+		} else if getErr != nil {
+			return nil, readError(path, getErr)
+		}
+
+		value := make([]byte, size)
+
+		if size, getErr = syscall.Getxattr(path, name, value); getErr != nil { // If we failed to read the attribute
+			return nil, readError(path, getErr)
+		}
+
+		attributes[name] = value[:size]
+	}
+
+	return attributes, nil
+}
+
+// writeSecurityAttributes sets the security attributes on the file at path. Attributes its filesystem doesn't support are skipped.
+func writeSecurityAttributes(path string, attributes map[string][]byte) error {
+	for _, name := range securityAttributeNames {
+		value, hasValue := attributes[name]
+
+		if !hasValue {
+			continue
+		}
+
+		if setErr := syscall.Setxattr(path, name, value, 0); setErr != nil && setErr != syscall.ENOTSUP { // If we failed to set the attribute, other than because the filesystem can't hold it
+			return writeError(path, "Failed to set "+name+" on "+path, setErr)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package coreutils
+
+// securityAttributeNames is empty, as only Linux has security extended attributes
+var securityAttributeNames []string
+
+// readSecurityAttributes returns no attributes, as only Linux has security extended attributes
+func readSecurityAttributes(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// writeSecurityAttributes does nothing, as only Linux has security extended attributes
+func writeSecurityAttributes(path string, attributes map[string][]byte) error {
+	return nil
+}
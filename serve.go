@@ -0,0 +1,358 @@
+package coreutils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// partialUploadSuffix is appended to the name of a file while it is being uploaded
+const partialUploadSuffix = ".partial"
+
+// defaultMaxUploadSize is the largest file ServeDirectory accepts an upload of, unless changed by WithMaxUploadSize
+const defaultMaxUploadSize = 1 << 30
+
+// ServeAuthFunc decides whether ServeDirectory answers the request, such as by checking its credentials
+type ServeAuthFunc func(r *http.Request) bool
+
+// uploadLocks tracks the files ServeDirectory is receiving an upload of, so only one request writes each at a time
+type uploadLocks struct {
+	lock   sync.Mutex
+	active map[string]bool
+}
+
+// WithBandwidthLimit limits each transfer to bytesPerSecond. Honored by ServeDirectory for both downloads and uploads.
+func WithBandwidthLimit(bytesPerSecond int64) Option {
+	return func(options *operationOptions) {
+		options.bandwidthLimit = bytesPerSecond
+	}
+}
+
+// WithServeAuth sets the function ServeDirectory asks whether to answer each request, responding 401 Unauthorized when it
+// returns false. Without it, anyone who can reach the handler can download and upload files.
+func WithServeAuth(fn ServeAuthFunc) Option {
+	return func(options *operationOptions) {
+		options.serveAuth = fn
+	}
+}
+
+// WithUploadOverwrite sets whether ServeDirectory uploads may replace files which already exist. By default they are refused
+// with 409 Conflict.
+func WithUploadOverwrite(overwrite bool) Option {
+	return func(options *operationOptions) {
+		options.uploadOverwrite = overwrite
+	}
+}
+
+// WithMaxUploadSize sets the largest file ServeDirectory accepts an upload of, in bytes, responding 413 Request Entity Too Large
+// to larger ones. Defaults to 1 GiB.
+func WithMaxUploadSize(size int64) Option {
+	return func(options *operationOptions) {
+		options.maxUploadSize = size
+	}
+}
+
+// ServeDirectory will return an http.Handler turning dir into a small file drop box:
+//
+//   - GET and HEAD download the file at the URL path, with support for HTTP Range requests
+//   - PUT uploads the request body as the file, creating it once the upload completes
+//   - PATCH resumes an upload, appending the body at the byte given by the Upload-Offset header. Upload-Length gives the
+//     size of the whole file, completing the upload once reached. A mismatched offset gets 409 Conflict.
+//   - HEAD on a file still being uploaded returns how much has arrived in the Upload-Offset header, to resume from
+//
+// URL paths are sanitized, so nothing outside of dir can be read or written, including through symlinks. Directories are
+// never listed. Uploads replacing an existing file are refused unless WithUploadOverwrite is used, and a second request for a
+// file already being uploaded gets 409 Conflict. Honors WithServeAuth, WithUploadOverwrite, WithMaxUploadSize, WithBandwidthLimit,
+// WithFileMode, and WithDirMode.
+func ServeDirectory(dir string, opts ...Option) http.Handler {
+	options := newOperationOptions(opts)
+	uploads := &uploadLocks{active: make(map[string]bool)}
+
+	if options.maxUploadSize <= 0 {
+		options.maxUploadSize = defaultMaxUploadSize
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if options.serveAuth != nil && !options.serveAuth(r) { // If the request isn't allowed
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		filePath, pathErr := sanitizeServePath(dir, r.URL.Path)
+
+		if pathErr != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		if options.bandwidthLimit > 0 {
+			w = &throttledResponseWriter{ResponseWriter: w, throttle: newThrottle(options.bandwidthLimit)}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{&throttledReader{reader: r.Body, throttle: newThrottle(options.bandwidthLimit)}, r.Body}
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if partialInfo, statErr := os.Lstat(filePath + partialUploadSuffix); statErr == nil && partialInfo.Mode().IsRegular() && r.Method == http.MethodHead { // If this file is still being uploaded
+				w.Header().Set("Upload-Offset", strconv.FormatInt(partialInfo.Size(), 10))
+
+				if _, finalErr := os.Stat(filePath); finalErr != nil { // If there is no previous version to describe
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+
+			serveFile(w, r, filePath)
+		case http.MethodPut:
+			serveUpload(w, r, filePath, 0, true, uploads, options)
+		case http.MethodPatch:
+			offset, offsetErr := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+
+			if offsetErr != nil || offset < 0 {
+				http.Error(w, "invalid Upload-Offset", http.StatusBadRequest)
+				return
+			}
+
+			serveUpload(w, r, filePath, offset, false, uploads, options)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT, PATCH")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// serveUpload writes the request body to the partial file of filePath at offset, renaming it into place once complete.
+// PUT uploads are complete once the body ends, and PATCH uploads once they reach Upload-Length. The partial file isn't counted
+// against the open file budget, since it stays open for as long as the client takes to upload it.
+func serveUpload(w http.ResponseWriter, r *http.Request, filePath string, offset int64, whole bool, uploads *uploadLocks, options *operationOptions) {
+	start := time.Now()
+	var written int64
+	var uploadErr error
+
+	fail := func(message string, status int) { // Reply with the error, and record it for the trace
+		uploadErr = errors.New(message)
+		http.Error(w, message, status)
+	}
+
+	defer func() {
+		trace(TraceEvent{Op: "ServeDirectory", Path: filePath, Bytes: written, Mode: options.DefaultFileMode, Duration: time.Since(start), Err: uploadErr})
+	}()
+
+	partialPath := filePath + partialUploadSuffix
+
+	if policyErr := checkPathPolicy(filePath, true); policyErr != nil {
+		fail("forbidden", http.StatusForbidden)
+		return
+	} else if policyErr = checkPathPolicy(partialPath, true); policyErr != nil {
+		fail("forbidden", http.StatusForbidden)
+		return
+	}
+
+	if !uploads.claim(partialPath) { // If another request is uploading this file
+		fail("an upload of this file is already in progress", http.StatusConflict)
+		return
+	}
+
+	defer uploads.done(partialPath)
+
+	if _, existsErr := os.Lstat(filePath); existsErr == nil && !options.uploadOverwrite { // If this would replace a file
+		fail("the file already exists", http.StatusConflict)
+		return
+	}
+
+	total, lengthErr := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+
+	if offset > options.maxUploadSize || r.ContentLength > options.maxUploadSize-offset || (lengthErr == nil && total > options.maxUploadSize) { // If we know up front the file is too large
+		fail("the upload is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, options.maxUploadSize-offset)
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(filePath), options.DefaultDirMode); mkdirErr != nil {
+		fail("failed to create the directory", http.StatusInternalServerError)
+		return
+	}
+
+	if partialInfo, statErr := os.Lstat(partialPath); statErr == nil && !partialInfo.Mode().IsRegular() { // If something other than an upload, such as a symlink, has the partial file's name
+		fail("forbidden", http.StatusForbidden)
+		return
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | openNoFollow // Never follow a symlink put in place of the partial file since the check
+
+	if whole {
+		flags |= os.O_TRUNC
+	}
+
+	file, openErr := os.OpenFile(partialPath, flags, options.DefaultFileMode)
+
+	if openErr != nil {
+		fail("failed to create the file", http.StatusInternalServerError)
+		return
+	}
+
+	info, statErr := file.Stat()
+
+	if statErr == nil && info.Size() != offset { // If the client is out of step with what we've received
+		file.Close()
+		w.Header().Set("Upload-Offset", strconv.FormatInt(info.Size(), 10))
+		fail("Upload-Offset does not match the size of the upload so far", http.StatusConflict)
+		return
+	}
+
+	_, seekErr := file.Seek(offset, io.SeekStart)
+	written, copyErr := io.CopyBuffer(file, r.Body, make([]byte, options.BufferSize))
+
+	if closeErr := file.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+
+	var tooLarge *http.MaxBytesError
+
+	if errors.As(copyErr, &tooLarge) { // If the upload went past the limit, drop it, since it can never complete
+		os.Remove(partialPath)
+		fail("the upload is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if seekErr != nil || copyErr != nil { // Keep what arrived, so the client can resume
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset+written, 10))
+		fail("failed to write the upload", http.StatusInternalServerError)
+		return
+	}
+
+	accountWritten(IOCategoryWrite, written)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset+written, 10))
+	complete := whole
+
+	if !whole && lengthErr == nil {
+		complete = (offset+written >= total)
+	}
+
+	if complete {
+		if renameErr := os.Rename(partialPath, filePath); renameErr != nil {
+			fail("failed to complete the upload", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// claim marks the partial file as being uploaded to, returning false if it already is
+func (uploads *uploadLocks) claim(partialPath string) bool {
+	uploads.lock.Lock()
+	defer uploads.lock.Unlock()
+
+	if uploads.active[partialPath] {
+		return false
+	}
+
+	uploads.active[partialPath] = true
+	return true
+}
+
+// done marks the upload to the partial file as finished
+func (uploads *uploadLocks) done(partialPath string) {
+	uploads.lock.Lock()
+	delete(uploads.active, partialPath)
+	uploads.lock.Unlock()
+}
+
+// sanitizeServePath maps the URL path to a path within dir, rejecting any which would escape it, including through
+// symlinks, or which name a partial upload
+func sanitizeServePath(dir, urlPath string) (string, error) {
+	cleanPath := path.Clean("/" + urlPath)
+
+	if cleanPath == "/" || strings.HasSuffix(cleanPath, partialUploadSuffix) || strings.ContainsRune(cleanPath, 0) {
+		return "", errors.New(urlPath + " is not a valid path.")
+	}
+
+	filePath := filepath.Join(dir, filepath.FromSlash(cleanPath))
+	resolvedDir, dirErr := resolveExisting(dir)
+	resolvedPath, pathErr := resolveExisting(filePath)
+
+	if dirErr != nil || pathErr != nil || !isWithin(resolvedDir, resolvedPath) || resolvedDir == resolvedPath {
+		return "", errors.New(urlPath + " is outside of " + dir)
+	}
+
+	return filePath, nil
+}
+
+// throttle limits a transfer to a number of bytes per second
+type throttle struct {
+	bytesPerSecond int64
+	start          time.Time
+	transferred    int64
+}
+
+// newThrottle starts a throttle
+func newThrottle(bytesPerSecond int64) *throttle {
+	return &throttle{bytesPerSecond: bytesPerSecond, start: time.Now()}
+}
+
+// wait records that count bytes were transferred, sleeping until the transfer is back within the limit
+func (limiter *throttle) wait(count int) {
+	limiter.transferred += int64(count)
+	due := time.Duration(float64(limiter.transferred) / float64(limiter.bytesPerSecond) * float64(time.Second))
+
+	if ahead := due - time.Since(limiter.start); ahead > 0 {
+		time.Sleep(ahead)
+	}
+}
+
+// throttledReader limits how fast the underlying reader is read
+type throttledReader struct {
+	reader   io.Reader
+	throttle *throttle
+}
+
+// Read reads from the underlying reader, at most a second's worth at a time
+func (reader *throttledReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > reader.throttle.bytesPerSecond {
+		p = p[:reader.throttle.bytesPerSecond]
+	}
+
+	readCount, readErr := reader.reader.Read(p)
+	reader.throttle.wait(readCount)
+	return readCount, readErr
+}
+
+// throttledResponseWriter limits how fast a response is written
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	throttle *throttle
+}
+
+// Write writes to the underlying response, at most a second's worth at a time
+func (writer *throttledResponseWriter) Write(p []byte) (int, error) {
+	var written int
+
+	for len(p) > 0 {
+		chunk := p[:min(int64(len(p)), writer.throttle.bytesPerSecond)]
+		chunkWritten, writeErr := writer.ResponseWriter.Write(chunk)
+		written += chunkWritten
+		writer.throttle.wait(chunkWritten)
+
+		if writeErr != nil {
+			return written, writeErr
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
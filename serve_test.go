@@ -0,0 +1,152 @@
+package coreutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveRequest sends a request with the body to the handler, returning the response
+func serveRequest(handler http.Handler, method, urlPath, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, urlPath, strings.NewReader(body))
+
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServeDirectoryAuth(t *testing.T) {
+	handler := ServeDirectory(t.TempDir(), WithServeAuth(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer secret"
+	}))
+
+	if response := serveRequest(handler, http.MethodPut, "/file.txt", "content", nil); response.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", response.Code)
+	}
+
+	if response := serveRequest(handler, http.MethodPut, "/file.txt", "content", map[string]string{"Authorization": "Bearer secret"}); response.Code != http.StatusCreated {
+		t.Errorf("expected 201 with credentials, got %d", response.Code)
+	}
+}
+
+func TestServeDirectoryOverwrite(t *testing.T) {
+	dir := t.TempDir()
+
+	if writeErr := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("original"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if response := serveRequest(ServeDirectory(dir), http.MethodPut, "/file.txt", "replaced", nil); response.Code != http.StatusConflict {
+		t.Errorf("expected 409 replacing a file by default, got %d", response.Code)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(dir, "file.txt")); string(content) != "original" {
+		t.Errorf("expected the file to be kept, got %q", content)
+	}
+
+	if response := serveRequest(ServeDirectory(dir, WithUploadOverwrite(true)), http.MethodPut, "/file.txt", "replaced", nil); response.Code != http.StatusCreated {
+		t.Errorf("expected 201 replacing a file with WithUploadOverwrite, got %d", response.Code)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(dir, "file.txt")); string(content) != "replaced" {
+		t.Errorf("expected the file to be replaced, got %q", content)
+	}
+}
+
+func TestServeDirectoryMaxUploadSize(t *testing.T) {
+	dir := t.TempDir()
+	handler := ServeDirectory(dir, WithMaxUploadSize(4))
+
+	if response := serveRequest(handler, http.MethodPut, "/large.txt", "too large", nil); response.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a PUT over the limit, got %d", response.Code)
+	}
+
+	resume := map[string]string{"Upload-Offset": "0", "Upload-Length": "100"}
+
+	if response := serveRequest(handler, http.MethodPatch, "/large.txt", "ab", resume); response.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an Upload-Length over the limit, got %d", response.Code)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(dir, "large.txt")); !os.IsNotExist(statErr) {
+		t.Errorf("expected nothing to be written, got %v", statErr)
+	}
+}
+
+func TestServeDirectoryResumableUpload(t *testing.T) {
+	dir := t.TempDir()
+	handler := ServeDirectory(dir)
+
+	if response := serveRequest(handler, http.MethodPatch, "/file.txt", "hello ", map[string]string{"Upload-Offset": "0", "Upload-Length": "11"}); response.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for the first part, got %d", response.Code)
+	}
+
+	if response := serveRequest(handler, http.MethodPatch, "/file.txt", "world", map[string]string{"Upload-Offset": "0", "Upload-Length": "11"}); response.Code != http.StatusConflict || response.Header().Get("Upload-Offset") != "6" {
+		t.Errorf("expected 409 at offset 6 for a stale offset, got %d at %s", response.Code, response.Header().Get("Upload-Offset"))
+	}
+
+	if response := serveRequest(handler, http.MethodPatch, "/file.txt", "world", map[string]string{"Upload-Offset": "6", "Upload-Length": "11"}); response.Code != http.StatusCreated {
+		t.Fatalf("expected 201 completing the upload, got %d", response.Code)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(dir, "file.txt")); string(content) != "hello world" {
+		t.Errorf("expected the whole upload, got %q", content)
+	}
+}
+
+func TestServeDirectoryConcurrentUpload(t *testing.T) {
+	dir := t.TempDir()
+	uploads := &uploadLocks{active: make(map[string]bool)}
+	partialPath := filepath.Join(dir, "file.txt") + partialUploadSuffix
+
+	if !uploads.claim(partialPath) { // As a request still receiving its body would have
+		t.Fatal("expected to claim an idle upload")
+	}
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/file.txt", strings.NewReader("content"))
+	serveUpload(recorder, request, filepath.Join(dir, "file.txt"), 0, true, uploads, newOperationOptions([]Option{WithMaxUploadSize(defaultMaxUploadSize)}))
+
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("expected 409 while another upload is in progress, got %d", recorder.Code)
+	}
+
+	uploads.done(partialPath)
+
+	if !uploads.claim(partialPath) {
+		t.Error("expected to claim the upload once it finished")
+	}
+}
+
+func TestServeDirectoryRejectsSymlinkedPartial(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+
+	if writeErr := os.WriteFile(outside, []byte("outside"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if symlinkErr := os.Symlink(outside, filepath.Join(dir, "file.txt"+partialUploadSuffix)); symlinkErr != nil {
+		t.Skipf("symlinks are unavailable: %v", symlinkErr)
+	}
+
+	handler := ServeDirectory(dir)
+
+	if response := serveRequest(handler, http.MethodPut, "/file.txt", "replaced", nil); response.Code != http.StatusForbidden {
+		t.Errorf("expected 403 uploading through a symlinked partial file, got %d", response.Code)
+	}
+
+	if response := serveRequest(handler, http.MethodHead, "/file.txt", "", nil); response.Header().Get("Upload-Offset") != "" {
+		t.Errorf("expected the symlinked partial file not to be reported as an upload, got offset %s", response.Header().Get("Upload-Offset"))
+	}
+
+	if content, _ := os.ReadFile(outside); string(content) != "outside" {
+		t.Errorf("expected the symlink target to be untouched, got %q", content)
+	}
+}
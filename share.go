@@ -0,0 +1,167 @@
+package coreutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidShareToken is returned when a share token was tampered with, was signed with another key, or has expired
+var ErrInvalidShareToken = errors.New("invalid or expired share token")
+
+// minShareKeyLength is the shortest key SetShareKey accepts, the size of the SHA-256 HMAC tokens are signed with
+const minShareKeyLength = 32
+
+var (
+	shareKeyLock sync.Mutex
+	shareKey     []byte // Key share tokens are signed with, random per process unless set by SetShareKey
+)
+
+// SetShareKey sets the key share tokens are signed with, so tokens stay valid across restarts. The key must be at least
+// 32 random bytes, kept secret, as anyone who can guess it can forge tokens. Shorter keys are rejected, leaving the key unchanged.
+// Without it, a random key is used and tokens only last as long as the process.
+func SetShareKey(key []byte) error {
+	if len(key) < minShareKeyLength { // If the key is too short to keep tokens from being forged
+		return errors.New("Share keys must be at least " + strconv.Itoa(minShareKeyLength) + " bytes.")
+	}
+
+	shareKeyLock.Lock()
+	shareKey = append([]byte{}, key...)
+	shareKeyLock.Unlock()
+	return nil
+}
+
+// GenerateShareToken will create a URL-safe token granting access to the file at path until ttl has passed, to be handed to
+// a ShareHandler as the last element of the URL. The token is encrypted and signed with the share key rather than stored, so
+// it reveals nothing about the file, such as its path, and can't be revoked early except by changing the key with SetShareKey.
+func GenerateShareToken(path string, ttl time.Duration) (string, error) {
+	absolutePath, absErr := filepath.Abs(path)
+
+	if absErr != nil {
+		return "", absErr
+	}
+
+	if info, statErr := os.Stat(absolutePath); statErr != nil || info.IsDir() { // Only files can be shared
+		return "", errors.New(path + " is not a file.")
+	}
+
+	payload := strconv.FormatInt(GetClock().Now().Add(ttl).Unix(), 10) + "\n" + absolutePath
+	tokenCipher := shareCipher()
+	nonce := make([]byte, tokenCipher.NonceSize())
+
+	if _, randErr := rand.Read(nonce); randErr != nil {
+		return "", randErr
+	}
+
+	return base64.RawURLEncoding.EncodeToString(tokenCipher.Seal(nonce, nonce, []byte(payload), nil)), nil
+}
+
+// ShareHandler will return an http.Handler serving the files shared by tokens from GenerateShareToken, taking the token from
+// the last element of the URL path, such as /share/<token>. Only files within root are served, and requests with a missing,
+// invalid, or expired token get 404 Not Found, so they reveal nothing about which files exist. HTTP Range requests are supported.
+func ShareHandler(root string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		path, tokenErr := verifyShareToken(token)
+
+		if tokenErr != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		resolvedRoot, rootErr := resolveExisting(root)
+		resolvedPath, pathErr := resolveExisting(path)
+
+		if rootErr != nil || pathErr != nil || !isWithin(resolvedRoot, resolvedPath) { // If the file, or a symlink leading to it, is outside of root
+			http.NotFound(w, r)
+			return
+		}
+
+		serveFile(w, r, resolvedPath)
+	})
+}
+
+// serveFile serves the content of the file at path, supporting Range requests. The file isn't counted against the open file
+// budget, since it stays open for as long as the client takes to download it, and slow clients would stall every other operation.
+func serveFile(w http.ResponseWriter, r *http.Request, path string) {
+	file, openErr := os.Open(path)
+
+	if openErr != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	defer file.Close()
+
+	info, statErr := file.Stat()
+
+	if statErr != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="`+strings.NewReplacer(`"`, "", `\`, "").Replace(info.Name())+`"`)
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// verifyShareToken decrypts the token and checks its expiry, returning the path it grants access to
+func verifyShareToken(token string) (string, error) {
+	sealed, decodeErr := base64.RawURLEncoding.DecodeString(token)
+	tokenCipher := shareCipher()
+
+	if decodeErr != nil || len(sealed) < tokenCipher.NonceSize() {
+		return "", ErrInvalidShareToken
+	}
+
+	payload, openErr := tokenCipher.Open(nil, sealed[:tokenCipher.NonceSize()], sealed[tokenCipher.NonceSize():], nil)
+
+	if openErr != nil { // If the token was forged or tampered with
+		return "", ErrInvalidShareToken
+	}
+
+	expiry, path, found := strings.Cut(string(payload), "\n")
+	expiryTime, parseErr := strconv.ParseInt(expiry, 10, 64)
+
+	if !found || parseErr != nil || GetClock().Now().Unix() > expiryTime {
+		return "", ErrInvalidShareToken
+	}
+
+	return path, nil
+}
+
+// shareCipher returns the AES-GCM cipher share tokens are sealed with, keyed from the share key, creating a random share key
+// on first use
+func shareCipher() cipher.AEAD {
+	shareKeyLock.Lock()
+
+	if shareKey == nil {
+		shareKey = make([]byte, 32)
+		rand.Read(shareKey)
+	}
+
+	mac := hmac.New(sha256.New, shareKey)
+	shareKeyLock.Unlock()
+
+	mac.Write([]byte("coreutils share token")) // Derive the key, so a key of any length set by SetShareKey becomes a 256 bit AES key
+
+	block, _ := aes.NewCipher(mac.Sum(nil)) // A 32 byte key is always valid
+	tokenCipher, _ := cipher.NewGCM(block)
+
+	return tokenCipher
+}
@@ -0,0 +1,96 @@
+package coreutils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetShareKeyRejectsShortKeys(t *testing.T) {
+	for _, key := range [][]byte{nil, {}, bytes.Repeat([]byte{1}, minShareKeyLength-1)} {
+		if setErr := SetShareKey(key); setErr == nil {
+			t.Errorf("expected a %d byte key to be rejected", len(key))
+		}
+	}
+
+	if setErr := SetShareKey(bytes.Repeat([]byte{1}, minShareKeyLength)); setErr != nil {
+		t.Errorf("expected a %d byte key to be accepted, got %v", minShareKeyLength, setErr)
+	}
+}
+
+func TestShareHandlerServesOutsideTheBudget(t *testing.T) {
+	root := t.TempDir()
+	sharedFile := filepath.Join(root, "shared.txt")
+
+	if writeErr := os.WriteFile(sharedFile, []byte("shared"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	token, tokenErr := GenerateShareToken(sharedFile, time.Minute)
+
+	if tokenErr != nil {
+		t.Fatal(tokenErr)
+	}
+
+	defaults := GetDefaults()
+	defer SetDefaults(defaults)
+
+	limited := defaults
+	limited.MaxOpenFiles = 1
+	SetDefaults(limited)
+
+	openFiles.acquire() // Take the only slot, as a long-running operation would
+	defer openFiles.release()
+
+	served := make(chan *httptest.ResponseRecorder, 1)
+
+	go func() {
+		recorder := httptest.NewRecorder()
+		ShareHandler(root).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/share/"+token, nil))
+		served <- recorder
+	}()
+
+	select {
+	case recorder := <-served:
+		if recorder.Code != http.StatusOK || recorder.Body.String() != "shared" {
+			t.Errorf("expected the shared file, got %d %q", recorder.Code, recorder.Body.String())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("serving the file waited on the open file budget")
+	}
+}
+
+func TestShareTokenHidesPath(t *testing.T) {
+	root := t.TempDir()
+	sharedFile := filepath.Join(root, "shared.txt")
+
+	if writeErr := os.WriteFile(sharedFile, []byte("shared"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	token, tokenErr := GenerateShareToken(sharedFile, time.Minute)
+
+	if tokenErr != nil {
+		t.Fatal(tokenErr)
+	}
+
+	if decoded, _ := base64.RawURLEncoding.DecodeString(token); bytes.Contains(decoded, []byte("shared.txt")) {
+		t.Errorf("expected the token not to reveal the path, got %q", decoded)
+	}
+
+	if path, verifyErr := verifyShareToken(token); verifyErr != nil || path != sharedFile {
+		t.Errorf("expected the token to grant %s, got %s, %v", sharedFile, path, verifyErr)
+	}
+
+	tampered := []byte(token)
+	tampered[len(tampered)/2] ^= 1
+
+	if _, verifyErr := verifyShareToken(string(tampered)); verifyErr != ErrInvalidShareToken {
+		t.Errorf("expected a tampered token to be rejected, got %v", verifyErr)
+	}
+}
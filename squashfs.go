@@ -0,0 +1,479 @@
+package coreutils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	squashfsMagic             = "hsqs"  // Little endian magic of a squashfs superblock
+	squashfsMetadataSize      = 8192    // Maximum decompressed size of a metadata block
+	squashfsCompressionGzip   = 1       // Compression id of zlib compressed images
+	squashfsUncompressedBlock = 1 << 24 // Bit set in a data block size when the block is stored uncompressed
+	squashfsNoFragment        = 0xFFFFFFFF
+	squashfsMinBlockSize      = 4 << 10 // Smallest data block size mksquashfs allows
+	squashfsMaxBlockSize      = 1 << 20 // Largest data block size mksquashfs allows
+)
+
+// squashfsImage is a read-only squashfs (version 4) image
+type squashfsImage struct {
+	file                *os.File
+	imageSize           int64
+	blockSize           uint32
+	fragmentCount       uint32
+	rootInode           uint64
+	inodeTableStart     int64
+	directoryTableStart int64
+	fragmentTableStart  int64
+}
+
+// squashfsInode is the subset of an inode we need to list and extract it
+type squashfsInode struct {
+	inodeType       uint16
+	mode            os.FileMode
+	modTime         time.Time
+	fileSize        int64
+	blocksStart     int64
+	blockSizes      []uint32
+	fragmentIndex   uint32
+	fragmentOffset  uint32
+	directoryBlock  uint32
+	directoryOffset uint16
+	linkTarget      string
+}
+
+// newSquashfsImage reads and validates the superblock of the squashfs image
+func newSquashfsImage(file *os.File) (*squashfsImage, error) {
+	superblock := make([]byte, 96)
+
+	if _, readErr := file.ReadAt(superblock, 0); readErr != nil { // If the image is too small for a superblock
+		return nil, errors.New(file.Name() + " has a truncated superblock.")
+	}
+
+	if majorVersion := binary.LittleEndian.Uint16(superblock[28:30]); majorVersion != 4 { // If this is not a version 4 image
+		return nil, errors.New(file.Name() + " is squashfs version " + strconv.Itoa(int(majorVersion)) + ", only version 4 is supported.")
+	}
+
+	if compression := binary.LittleEndian.Uint16(superblock[20:22]); compression != squashfsCompressionGzip { // If this isn't compressed with zlib
+		return nil, errors.New(file.Name() + " uses an unsupported squashfs compression, only gzip is supported.")
+	}
+
+	info, statErr := file.Stat()
+
+	if statErr != nil {
+		return nil, openError(file.Name(), statErr)
+	}
+
+	image := &squashfsImage{
+		file:                file,
+		imageSize:           info.Size(),
+		blockSize:           binary.LittleEndian.Uint32(superblock[12:16]),
+		fragmentCount:       binary.LittleEndian.Uint32(superblock[16:20]),
+		rootInode:           binary.LittleEndian.Uint64(superblock[32:40]),
+		inodeTableStart:     int64(binary.LittleEndian.Uint64(superblock[64:72])),
+		directoryTableStart: int64(binary.LittleEndian.Uint64(superblock[72:80])),
+		fragmentTableStart:  int64(binary.LittleEndian.Uint64(superblock[80:88])),
+	}
+
+	if image.blockSize < squashfsMinBlockSize || image.blockSize > squashfsMaxBlockSize || image.blockSize&(image.blockSize-1) != 0 { // If the block size isn't a power of two mksquashfs could have used
+		return nil, errors.New(file.Name() + " has an invalid squashfs block size of " + strconv.FormatUint(uint64(image.blockSize), 10) + ".")
+	}
+
+	if image.inodeTableStart < 96 || image.directoryTableStart < image.inodeTableStart || image.directoryTableStart > image.imageSize { // If the tables aren't within the image
+		return nil, errors.New(file.Name() + " has squashfs tables outside of the image.")
+	}
+
+	return image, nil
+}
+
+// walk walks every entry of the image, starting from the root inode
+func (image *squashfsImage) walk(fn imageWalkFunc) error {
+	root, rootErr := image.readInode(image.rootInode)
+
+	if rootErr != nil {
+		return rootErr
+	}
+
+	return image.walkDirectory("", root, fn, 0)
+}
+
+// walkDirectory walks the entries of the directory inode, recursing into sub-directories
+func (image *squashfsImage) walkDirectory(directoryPath string, directory *squashfsInode, fn imageWalkFunc, depth int) error {
+	if depth > 255 { // If the image is corrupt or malicious
+		return errors.New(image.file.Name() + " has directories nested too deeply.")
+	}
+
+	if directory.fileSize <= 3 { // The size includes 3 bytes for the implicit . and .. entries, so this directory is empty
+		return nil
+	}
+
+	listing, readErr := image.readMetadata(image.directoryTableStart, int64(directory.directoryBlock), int(directory.directoryOffset), int(directory.fileSize-3))
+
+	if readErr != nil {
+		return readErr
+	}
+
+	for len(listing) > 0 { // For each directory header
+		if len(listing) < 12 {
+			return errors.New(image.file.Name() + " has a corrupt directory listing in " + directoryPath)
+		}
+
+		count := binary.LittleEndian.Uint32(listing[0:4]) + 1
+		inodeBlock := uint64(binary.LittleEndian.Uint32(listing[4:8]))
+		listing = listing[12:]
+
+		for ; count > 0; count-- { // For each entry under this header
+			if len(listing) < 8 {
+				return errors.New(image.file.Name() + " has a corrupt directory listing in " + directoryPath)
+			}
+
+			inodeOffset := uint64(binary.LittleEndian.Uint16(listing[0:2]))
+			nameSize := int(binary.LittleEndian.Uint16(listing[6:8])) + 1
+
+			if len(listing) < 8+nameSize {
+				return errors.New(image.file.Name() + " has a corrupt directory listing in " + directoryPath)
+			}
+
+			name := string(listing[8 : 8+nameSize])
+			listing = listing[8+nameSize:]
+
+			if name == "." || name == ".." || strings.ContainsAny(name, "/\x00") { // If the name is not a valid single path element
+				return errors.New(image.file.Name() + " has an invalid file name in " + directoryPath)
+			}
+
+			inode, inodeErr := image.readInode(inodeBlock<<16 | inodeOffset)
+
+			if inodeErr != nil {
+				return inodeErr
+			}
+
+			if walkErr := image.walkInode(joinImagePath(directoryPath, name), inode, fn, depth); walkErr != nil {
+				return walkErr
+			}
+		}
+	}
+
+	return nil
+}
+
+// walkInode calls fn for the inode, recursing if it is a directory
+func (image *squashfsImage) walkInode(entryPath string, inode *squashfsInode, fn imageWalkFunc, depth int) error {
+	entry := ArchiveEntry{
+		Name:       entryPath,
+		Mode:       inode.mode,
+		ModTime:    inode.modTime,
+		LinkTarget: inode.linkTarget,
+	}
+
+	switch inode.inodeType {
+	case 1, 8: // Basic and extended directories
+		entry.Mode |= os.ModeDir
+
+		if walkErr := fn(entry, nil); walkErr != nil {
+			return walkErr
+		}
+
+		return image.walkDirectory(entryPath, inode, fn, depth+1)
+	case 2, 9: // Basic and extended files
+		entry.Size = inode.fileSize
+
+		return fn(entry, func() (io.Reader, error) {
+			return image.fileContent(inode)
+		})
+	case 3, 10: // Basic and extended symlinks
+		entry.Mode |= os.ModeSymlink
+		return fn(entry, nil)
+	}
+
+	return nil // Skip devices, fifos and sockets
+}
+
+// readInode reads the inode referenced by the inode reference (block offset << 16 | offset within block)
+func (image *squashfsImage) readInode(reference uint64) (*squashfsInode, error) {
+	block := int64(reference >> 16)
+	offset := int(reference & 0xFFFF)
+
+	header, readErr := image.readMetadata(image.inodeTableStart, block, offset, 16+40) // The largest fixed size inode body we read is 40 bytes
+
+	if readErr != nil && len(header) < 16 {
+		return nil, readErr
+	}
+
+	inode := &squashfsInode{
+		inodeType: binary.LittleEndian.Uint16(header[0:2]),
+		mode:      os.FileMode(binary.LittleEndian.Uint16(header[2:4]) & 0777),
+		modTime:   time.Unix(int64(binary.LittleEndian.Uint32(header[8:12])), 0),
+	}
+
+	body := header[16:]
+	bodyErr := errors.New(image.file.Name() + " has a truncated inode.")
+
+	switch inode.inodeType {
+	case 1: // Basic directory
+		if len(body) < 16 {
+			return nil, bodyErr
+		}
+
+		inode.directoryBlock = binary.LittleEndian.Uint32(body[0:4])
+		inode.fileSize = int64(binary.LittleEndian.Uint16(body[8:10]))
+		inode.directoryOffset = binary.LittleEndian.Uint16(body[10:12])
+	case 8: // Extended directory
+		if len(body) < 24 {
+			return nil, bodyErr
+		}
+
+		inode.fileSize = int64(binary.LittleEndian.Uint32(body[4:8]))
+		inode.directoryBlock = binary.LittleEndian.Uint32(body[8:12])
+		inode.directoryOffset = binary.LittleEndian.Uint16(body[18:20])
+	case 2, 9: // Basic and extended files
+		var blockListOffset int
+
+		if inode.inodeType == 2 {
+			if len(body) < 16 {
+				return nil, bodyErr
+			}
+
+			inode.blocksStart = int64(binary.LittleEndian.Uint32(body[0:4]))
+			inode.fragmentIndex = binary.LittleEndian.Uint32(body[4:8])
+			inode.fragmentOffset = binary.LittleEndian.Uint32(body[8:12])
+			inode.fileSize = int64(binary.LittleEndian.Uint32(body[12:16]))
+			blockListOffset = 16 + 16
+		} else {
+			if len(body) < 40 {
+				return nil, bodyErr
+			}
+
+			inode.blocksStart = int64(binary.LittleEndian.Uint64(body[0:8]))
+			inode.fileSize = int64(binary.LittleEndian.Uint64(body[8:16]))
+			inode.fragmentIndex = binary.LittleEndian.Uint32(body[28:32])
+			inode.fragmentOffset = binary.LittleEndian.Uint32(body[32:36])
+			blockListOffset = 16 + 40
+		}
+
+		blockCount := inode.fileSize / int64(image.blockSize)
+
+		if inode.fragmentIndex == squashfsNoFragment && inode.fileSize%int64(image.blockSize) != 0 { // Without a fragment, the tail is stored in its own block
+			blockCount++
+		}
+
+		// The list of block sizes is stored in the inode table, where every metadata block takes at least 3 bytes and holds at most
+		// squashfsMetadataSize, so a list longer than the table could hold means the size is corrupt or malicious
+		maxBlockCount := (image.directoryTableStart - image.inodeTableStart) / 3 * squashfsMetadataSize / 4
+
+		if inode.fileSize < 0 || blockCount > maxBlockCount {
+			return nil, errors.New(image.file.Name() + " has a file larger than the image can hold.")
+		}
+
+		if blockCount > 0 { // If the file has full data blocks, read the list of their sizes
+			blockList, blockListErr := image.readMetadata(image.inodeTableStart, block, offset+blockListOffset, int(blockCount)*4)
+
+			if blockListErr != nil {
+				return nil, blockListErr
+			}
+
+			inode.blockSizes = make([]uint32, blockCount)
+
+			for index := range inode.blockSizes {
+				inode.blockSizes[index] = binary.LittleEndian.Uint32(blockList[index*4:])
+			}
+		}
+	case 3, 10: // Basic and extended symlinks
+		if len(body) < 8 {
+			return nil, bodyErr
+		}
+
+		targetSize := int(binary.LittleEndian.Uint32(body[4:8]))
+		target, targetErr := image.readMetadata(image.inodeTableStart, block, offset+16+8, targetSize)
+
+		if targetErr != nil {
+			return nil, targetErr
+		}
+
+		inode.linkTarget = string(target)
+	}
+
+	return inode, nil
+}
+
+// readMetadata reads length bytes of decompressed metadata, starting offset bytes into the metadata block at tableStart + block.
+// Reads may span multiple metadata blocks.
+func (image *squashfsImage) readMetadata(tableStart, block int64, offset, length int) ([]byte, error) {
+	var metadata []byte
+	position := tableStart + block
+
+	for len(metadata) < offset+length {
+		blockContent, nextPosition, readErr := image.readMetadataBlock(position)
+
+		if readErr != nil {
+			return metadata, readErr
+		}
+
+		if len(blockContent) == 0 { // If the block is empty, the image is corrupt
+			break
+		}
+
+		metadata = append(metadata, blockContent...)
+		position = nextPosition
+	}
+
+	if len(metadata) < offset+length { // If we ran out of metadata
+		if len(metadata) > offset {
+			return metadata[offset:], errors.New(image.file.Name() + " has truncated metadata.")
+		}
+
+		return nil, errors.New(image.file.Name() + " has truncated metadata.")
+	}
+
+	return metadata[offset : offset+length], nil
+}
+
+// readMetadataBlock reads and decompresses a single metadata block, returning its content and the position of the next block
+func (image *squashfsImage) readMetadataBlock(position int64) ([]byte, int64, error) {
+	header := make([]byte, 2)
+
+	if _, readErr := image.file.ReadAt(header, position); readErr != nil { // If the header is past the end of the image
+		return nil, 0, errors.New(image.file.Name() + " has truncated metadata.")
+	}
+
+	blockHeader := binary.LittleEndian.Uint16(header)
+	compressed := blockHeader&0x8000 == 0
+	blockSize := int64(blockHeader & 0x7FFF)
+
+	blockContent := make([]byte, blockSize)
+
+	if _, readErr := image.file.ReadAt(blockContent, position+2); readErr != nil {
+		return nil, 0, errors.New(image.file.Name() + " has truncated metadata.")
+	}
+
+	if compressed { // If the block needs to be decompressed
+		var decompressErr error
+
+		if blockContent, decompressErr = squashfsDecompress(blockContent, squashfsMetadataSize); decompressErr != nil {
+			return nil, 0, errors.New(image.file.Name() + " has corrupt metadata: " + decompressErr.Error())
+		}
+	}
+
+	return blockContent, position + 2 + blockSize, nil
+}
+
+// fileContent returns a reader over the decompressed content of the file inode
+func (image *squashfsImage) fileContent(inode *squashfsInode) (io.Reader, error) {
+	var content bytes.Buffer
+	position := inode.blocksStart
+
+	for _, blockSize := range inode.blockSizes { // For each full data block
+		storedSize := int64(blockSize &^ squashfsUncompressedBlock)
+
+		if storedSize == 0 { // Sparse blocks are not stored at all
+			remaining := inode.fileSize - int64(content.Len())
+			content.Write(make([]byte, min(int64(image.blockSize), remaining)))
+			continue
+		}
+
+		blockContent := make([]byte, storedSize)
+
+		if _, readErr := image.file.ReadAt(blockContent, position); readErr != nil {
+			return nil, errors.New(image.file.Name() + " has a truncated data block.")
+		}
+
+		position += storedSize
+
+		if blockSize&squashfsUncompressedBlock == 0 { // If the block is compressed
+			var decompressErr error
+
+			if blockContent, decompressErr = squashfsDecompress(blockContent, int(image.blockSize)); decompressErr != nil {
+				return nil, errors.New(image.file.Name() + " has a corrupt data block: " + decompressErr.Error())
+			}
+		}
+
+		content.Write(blockContent)
+	}
+
+	if inode.fragmentIndex != squashfsNoFragment { // If the tail of the file is stored in a fragment block
+		fragment, fragmentErr := image.readFragment(inode.fragmentIndex)
+
+		if fragmentErr != nil {
+			return nil, fragmentErr
+		}
+
+		tailSize := inode.fileSize - int64(content.Len())
+		tailEnd := int64(inode.fragmentOffset) + tailSize
+
+		if tailSize < 0 || tailEnd > int64(len(fragment)) { // If the tail does not fit in the fragment
+			return nil, errors.New(image.file.Name() + " has a corrupt fragment.")
+		}
+
+		content.Write(fragment[inode.fragmentOffset:tailEnd])
+	}
+
+	if int64(content.Len()) > inode.fileSize { // Truncate any padding from the final block
+		content.Truncate(int(inode.fileSize))
+	}
+
+	return &content, nil
+}
+
+// readFragment reads and decompresses the fragment block at the fragment index
+func (image *squashfsImage) readFragment(fragmentIndex uint32) ([]byte, error) {
+	if fragmentIndex >= image.fragmentCount { // If the index is out of range
+		return nil, errors.New(image.file.Name() + " has a corrupt fragment index.")
+	}
+
+	entriesPerBlock := uint32(squashfsMetadataSize / 16)
+	lookup := make([]byte, 8)
+
+	if _, readErr := image.file.ReadAt(lookup, image.fragmentTableStart+int64(fragmentIndex/entriesPerBlock)*8); readErr != nil { // Read the location of the metadata block holding this entry
+		return nil, errors.New(image.file.Name() + " has a truncated fragment table.")
+	}
+
+	entryBlock := int64(binary.LittleEndian.Uint64(lookup))
+	fragmentEntry, entryErr := image.readMetadata(entryBlock, 0, int(fragmentIndex%entriesPerBlock)*16, 16)
+
+	if entryErr != nil {
+		return nil, entryErr
+	}
+
+	fragmentStart := int64(binary.LittleEndian.Uint64(fragmentEntry[0:8]))
+	fragmentSize := binary.LittleEndian.Uint32(fragmentEntry[8:12])
+	fragment := make([]byte, fragmentSize&^squashfsUncompressedBlock)
+
+	if _, readErr := image.file.ReadAt(fragment, fragmentStart); readErr != nil {
+		return nil, errors.New(image.file.Name() + " has a truncated fragment.")
+	}
+
+	if fragmentSize&squashfsUncompressedBlock == 0 { // If the fragment is compressed
+		var decompressErr error
+
+		if fragment, decompressErr = squashfsDecompress(fragment, int(image.blockSize)); decompressErr != nil {
+			return nil, errors.New(image.file.Name() + " has a corrupt fragment: " + decompressErr.Error())
+		}
+	}
+
+	return fragment, nil
+}
+
+// squashfsDecompress inflates a zlib compressed block, refusing to produce more than maxSize bytes
+func squashfsDecompress(compressed []byte, maxSize int) ([]byte, error) {
+	zlibReader, zlibErr := zlib.NewReader(bytes.NewReader(compressed))
+
+	if zlibErr != nil {
+		return nil, zlibErr
+	}
+
+	defer zlibReader.Close()
+
+	decompressed, readErr := io.ReadAll(io.LimitReader(zlibReader, int64(maxSize)+1))
+
+	if readErr == nil && len(decompressed) > maxSize { // If the block decompressed to more than a block can hold
+		readErr = errors.New("block exceeds the maximum block size")
+	}
+
+	return decompressed, readErr
+}
@@ -0,0 +1,464 @@
+package coreutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KeyType is the algorithm of an SSH key
+type KeyType int
+
+const (
+	// KeyEd25519 is an Ed25519 key, the recommended default
+	KeyEd25519 KeyType = iota
+
+	// KeyECDSA is an ECDSA key on the NIST P-256 curve
+	KeyECDSA
+
+	// KeyRSA is a 3072 bit RSA key, for servers which don't support newer algorithms
+	KeyRSA
+)
+
+// opensshKeyMagic starts the private keys written by OpenSSH since version 6.5
+const opensshKeyMagic = "openssh-key-v1\x00"
+
+// ErrEncryptedSSHKey is returned by LoadPrivateKey for OpenSSH format keys protected by a passphrase when no passphrase is given
+var ErrEncryptedSSHKey = errors.New("the OpenSSH key is protected by a passphrase")
+
+// GenerateSSHKeyPair will create a new SSH key of the type, writing the private key to path in OpenSSH format, readable only by
+// its owner, and the public key to path with ".pub" appended in authorized_keys format. The key is not protected by a passphrase.
+// An existing private key is never replaced; an error wrapping ErrDestinationExists is returned instead.
+func GenerateSSHKeyPair(keyType KeyType, path string) error {
+	var private crypto.Signer
+	var generateErr error
+
+	switch keyType {
+	case KeyEd25519:
+		_, private, generateErr = ed25519.GenerateKey(rand.Reader)
+	case KeyECDSA:
+		private, generateErr = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyRSA:
+		private, generateErr = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		return errors.New("Unknown key type " + fmt.Sprint(int(keyType)))
+	}
+
+	if generateErr != nil {
+		return generateErr
+	}
+
+	comment := sshKeyComment()
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: marshalOpenSSHPrivateKey(private, comment)})
+
+	start := time.Now()
+	writeErr := writePrivateKey(path, privatePEM)
+	trace(TraceEvent{Op: "GenerateSSHKeyPair", Path: path, Bytes: int64(len(privatePEM)), Mode: 0600, Duration: time.Since(start), Err: writeErr})
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	publicLine := MarshalAuthorizedKey(private.Public()) + " " + comment + "\n"
+	_, writeErr = WriteFromReader(path+".pub", strings.NewReader(publicLine), 0644)
+	return writeErr
+}
+
+// writePrivateKey creates the file at path, readable only by its owner, holding the private key. The file is created exclusively,
+// so an existing key is never replaced, and is never readable by anyone else, even briefly.
+func writePrivateKey(path string, content []byte) error {
+	if policyErr := checkPathPolicy(path, true); policyErr != nil {
+		return policyErr
+	}
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(path), 0700); mkdirErr != nil { // If we failed to make the directories leading up to path
+		return writeError(path, "Failed to create the path leading up to "+path, mkdirErr)
+	}
+
+	openFiles.acquire()
+	defer openFiles.release()
+
+	file, createErr := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+
+	if errors.Is(createErr, fs.ErrExist) { // If there is already a key, which may be the only copy of it
+		return fmt.Errorf("%s: %w", path, ErrDestinationExists)
+	} else if createErr != nil {
+		return writeError(path, "Failed to create "+path, createErr)
+	}
+
+	_, writeErr := file.Write(content)
+
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	if writeErr != nil { // If the key is incomplete, don't leave it behind
+		os.Remove(path)
+		return writeError(path, "Failed to write "+path, writeErr)
+	}
+
+	return nil
+}
+
+// MarshalAuthorizedKey will encode an Ed25519, ECDSA P-256, or RSA public key as a line for authorized_keys or
+// known_hosts, such as "ssh-ed25519 AAAAC3Nza...". Returns an empty string for other keys.
+func MarshalAuthorizedKey(key crypto.PublicKey) string {
+	keyType, blob := sshPublicKeyBlob(key)
+
+	if blob == nil {
+		return ""
+	}
+
+	return keyType + " " + base64.StdEncoding.EncodeToString(blob)
+}
+
+// AddToKnownHosts will trust the public key for the host in ~/.ssh/known_hosts, unless it is already there. The host may
+// include a port, such as "example.com:2222", and the key is in authorized_keys format, such as the output of ssh-keyscan
+// without the host, or of MarshalAuthorizedKey.
+func AddToKnownHosts(host, key string) error {
+	fields := strings.Fields(key)
+
+	if len(fields) < 2 {
+		return errors.New(key + " is not a public key.")
+	}
+
+	if _, decodeErr := base64.StdEncoding.DecodeString(fields[1]); decodeErr != nil {
+		return errors.New(key + " is not a public key.")
+	}
+
+	if hostName, port, splitErr := net.SplitHostPort(host); splitErr == nil && port != "22" { // Non-standard ports are written as [host]:port
+		host = "[" + hostName + "]:" + port
+	} else if splitErr == nil {
+		host = hostName
+	}
+
+	homeDirectory, homeErr := os.UserHomeDir()
+
+	if homeErr != nil {
+		return homeErr
+	}
+
+	sshDirectory := filepath.Join(homeDirectory, ".ssh")
+
+	if mkdirErr := os.MkdirAll(sshDirectory, 0700); mkdirErr != nil {
+		return writeError(sshDirectory, "Failed to create "+sshDirectory, mkdirErr)
+	}
+
+	return appendLineOnce(filepath.Join(sshDirectory, "known_hosts"), host+" "+fields[0]+" "+fields[1])
+}
+
+// LoadPrivateKey will load an SSH private key from path, in the OpenSSH format or the PEM formats older versions of
+// ssh-keygen wrote. The passphrase decrypts keys protected by one, and is ignored for unencrypted keys. OpenSSH format keys
+// encrypted with AES return ErrEncryptedSSHKey if the passphrase is empty, and ErrIncorrectPassphrase if it is wrong.
+func LoadPrivateKey(path, passphrase string) (crypto.Signer, error) {
+	content, readErr := os.ReadFile(path)
+
+	if readErr != nil {
+		return nil, openError(path, readErr)
+	}
+
+	block, _ := pem.Decode(content)
+
+	if block == nil {
+		return nil, errors.New(path + " is not a private key.")
+	}
+
+	keyBytes := block.Bytes
+
+	if x509.IsEncryptedPEMBlock(block) { // Legacy PEM encryption is deprecated as insecure, but older keys still use it
+		var decryptErr error
+
+		if keyBytes, decryptErr = x509.DecryptPEMBlock(block, []byte(passphrase)); decryptErr != nil {
+			return nil, errors.New("Failed to decrypt " + path + ": " + decryptErr.Error())
+		}
+	}
+
+	var key any
+	var parseErr error
+
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		key, parseErr = parseOpenSSHPrivateKey(keyBytes, passphrase)
+	case "RSA PRIVATE KEY":
+		key, parseErr = x509.ParsePKCS1PrivateKey(keyBytes)
+	case "EC PRIVATE KEY":
+		key, parseErr = x509.ParseECPrivateKey(keyBytes)
+	case "PRIVATE KEY":
+		key, parseErr = x509.ParsePKCS8PrivateKey(keyBytes)
+	default:
+		parseErr = errors.New("unsupported key type " + block.Type)
+	}
+
+	if parseErr != nil {
+		if errors.Is(parseErr, ErrEncryptedSSHKey) || errors.Is(parseErr, ErrIncorrectPassphrase) {
+			return nil, fmt.Errorf("%s: %w", path, parseErr)
+		}
+
+		return nil, errors.New("Failed to parse " + path + ": " + parseErr.Error())
+	}
+
+	signer, isSigner := key.(crypto.Signer)
+
+	if !isSigner {
+		return nil, errors.New(path + " is not a supported private key.")
+	}
+
+	return signer, nil
+}
+
+// sshKeyComment returns the comment ssh-keygen would give a new key, user@host
+func sshKeyComment() string {
+	userName, hostName := "", ""
+
+	if current, userErr := user.Current(); userErr == nil {
+		userName = current.Username
+	}
+
+	hostName, _ = os.Hostname()
+
+	return userName + "@" + hostName
+}
+
+// sshPublicKeyBlob returns the name and SSH wire format of the public key, or a nil blob if the key isn't supported
+func sshPublicKeyBlob(key crypto.PublicKey) (string, []byte) {
+	switch public := key.(type) {
+	case ed25519.PublicKey:
+		return "ssh-ed25519", appendSSHString(appendSSHString(nil, []byte("ssh-ed25519")), public)
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return "", nil
+		}
+
+		point, _ := public.ECDH()
+		blob := appendSSHString(nil, []byte("ecdsa-sha2-nistp256"))
+		blob = appendSSHString(blob, []byte("nistp256"))
+		return "ecdsa-sha2-nistp256", appendSSHString(blob, point.Bytes())
+	case *rsa.PublicKey:
+		blob := appendSSHString(nil, []byte("ssh-rsa"))
+		blob = appendSSHInt(blob, big.NewInt(int64(public.E)))
+		return "ssh-rsa", appendSSHInt(blob, public.N)
+	}
+
+	return "", nil
+}
+
+// marshalOpenSSHPrivateKey encodes the private key in the unencrypted OpenSSH format
+func marshalOpenSSHPrivateKey(key crypto.Signer, comment string) []byte {
+	_, publicBlob := sshPublicKeyBlob(key.Public())
+
+	check := make([]byte, 4)
+	rand.Read(check)
+
+	private := append(append([]byte{}, check...), check...) // Matching check values confirm a successful decryption
+
+	switch typed := key.(type) {
+	case ed25519.PrivateKey:
+		private = appendSSHString(append(private, publicBlob...), typed) // The private fields follow the public ones
+	case *ecdsa.PrivateKey:
+		private = appendSSHInt(append(private, publicBlob...), typed.D)
+	case *rsa.PrivateKey: // Unlike the public key, the modulus comes before the exponent
+		typed.Precompute()
+		private = appendSSHString(private, []byte("ssh-rsa"))
+		private = appendSSHInt(private, typed.N)
+		private = appendSSHInt(private, big.NewInt(int64(typed.E)))
+		private = appendSSHInt(private, typed.D)
+		private = appendSSHInt(private, typed.Precomputed.Qinv)
+		private = appendSSHInt(private, typed.Primes[0])
+		private = appendSSHInt(private, typed.Primes[1])
+	}
+
+	private = appendSSHString(private, []byte(comment))
+
+	for padding := byte(1); len(private)%8 != 0; padding++ {
+		private = append(private, padding)
+	}
+
+	encoded := []byte(opensshKeyMagic)
+	encoded = appendSSHString(encoded, []byte("none")) // Cipher
+	encoded = appendSSHString(encoded, []byte("none")) // Key derivation function
+	encoded = appendSSHString(encoded, nil)            // Key derivation options
+	encoded = binary.BigEndian.AppendUint32(encoded, 1)
+	encoded = appendSSHString(encoded, publicBlob)
+	return appendSSHString(encoded, private)
+}
+
+// parseOpenSSHPrivateKey decodes a private key in the OpenSSH format, decrypting it with the passphrase if it is encrypted
+func parseOpenSSHPrivateKey(encoded []byte, passphrase string) (any, error) {
+	reader := &sshReader{data: encoded}
+
+	if !bytes.HasPrefix(encoded, []byte(opensshKeyMagic)) {
+		return nil, errors.New("not an OpenSSH key")
+	}
+
+	reader.data = encoded[len(opensshKeyMagic):]
+	cipherName, kdfName := string(reader.string()), string(reader.string())
+	kdfOptions := reader.string()
+	encrypted := cipherName != "none" || kdfName != "none"
+
+	if encrypted && passphrase == "" {
+		return nil, ErrEncryptedSSHKey
+	}
+
+	if keyCount := reader.uint32(); keyCount != 1 {
+		return nil, errors.New("only files with a single key are supported")
+	}
+
+	reader.string() // Public key, which is repeated in the private section
+	private := &sshReader{data: reader.string()}
+
+	if reader.failed {
+		return nil, errors.New("corrupt OpenSSH key")
+	}
+
+	if encrypted {
+		var decryptErr error
+
+		if private.data, decryptErr = decryptOpenSSHPrivateKey(cipherName, kdfName, kdfOptions, private.data, reader.data, passphrase); decryptErr != nil {
+			return nil, decryptErr
+		}
+	}
+
+	if private.uint32() != private.uint32() { // Matching check values confirm a successful decryption
+		if encrypted {
+			return nil, ErrIncorrectPassphrase
+		}
+
+		return nil, errors.New("corrupt OpenSSH key")
+	}
+
+	var key any
+
+	switch keyType := string(private.string()); keyType {
+	case "ssh-ed25519":
+		private.string() // Public key
+		seedAndPublic := private.string()
+
+		if len(seedAndPublic) != ed25519.PrivateKeySize {
+			return nil, errors.New("corrupt Ed25519 key")
+		}
+
+		key = ed25519.PrivateKey(seedAndPublic)
+	case "ecdsa-sha2-nistp256":
+		private.string() // Curve name
+		point, scalar := private.string(), private.string()
+		ecdhKey, ecdhErr := ecdhP256PrivateKey(point, scalar)
+
+		if ecdhErr != nil {
+			return nil, ecdhErr
+		}
+
+		key = ecdhKey
+	case "ssh-rsa":
+		n, e, d := private.bigInt(), private.bigInt(), private.bigInt()
+		private.bigInt() // Coefficient, which Precompute recalculates
+		p, q := private.bigInt(), private.bigInt()
+
+		if private.failed || !e.IsInt64() {
+			return nil, errors.New("corrupt RSA key")
+		}
+
+		rsaKey := &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())}, D: d, Primes: []*big.Int{p, q}}
+
+		if validateErr := rsaKey.Validate(); validateErr != nil {
+			return nil, validateErr
+		}
+
+		rsaKey.Precompute()
+		key = rsaKey
+	default:
+		return nil, errors.New("unsupported key type " + keyType)
+	}
+
+	if private.failed {
+		return nil, errors.New("corrupt OpenSSH key")
+	}
+
+	return key, nil
+}
+
+// ecdhP256PrivateKey builds an ECDSA P-256 private key from its scalar, checking it matches the public point
+func ecdhP256PrivateKey(point, scalar []byte) (*ecdsa.PrivateKey, error) {
+	scalar = bytes.TrimLeft(scalar, "\x00") // mpints may have a leading zero to stay positive
+
+	if len(scalar) > 32 || len(point) != 65 {
+		return nil, errors.New("corrupt ECDSA key")
+	}
+
+	derKey := append([]byte{0x30, 0x77, 0x02, 0x01, 0x01, 0x04, 0x20}, append(make([]byte, 32-len(scalar)), scalar...)...)
+	derKey = append(derKey, 0xa0, 0x0a, 0x06, 0x08, 0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07) // Named curve P-256
+	derKey = append(derKey, 0xa1, 0x44, 0x03, 0x42, 0x00)
+	derKey = append(derKey, point...)
+
+	return x509.ParseECPrivateKey(derKey) // derKey is the SEC 1 ECPrivateKey structure of the key
+}
+
+// appendSSHString appends a length prefixed string in SSH wire format
+func appendSSHString(encoded, value []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(encoded, uint32(len(value))), value...)
+}
+
+// appendSSHInt appends a non-negative integer as an SSH mpint
+func appendSSHInt(encoded []byte, value *big.Int) []byte {
+	magnitude := value.Bytes()
+
+	if len(magnitude) != 0 && magnitude[0]&0x80 != 0 { // Keep the number positive
+		magnitude = append([]byte{0}, magnitude...)
+	}
+
+	return appendSSHString(encoded, magnitude)
+}
+
+// sshReader reads SSH wire format values, remembering if any read ran past the end
+type sshReader struct {
+	data   []byte
+	failed bool
+}
+
+// uint32 reads a big endian uint32
+func (reader *sshReader) uint32() uint32 {
+	if len(reader.data) < 4 {
+		reader.failed = true
+		return 0
+	}
+
+	value := binary.BigEndian.Uint32(reader.data)
+	reader.data = reader.data[4:]
+	return value
+}
+
+// string reads a length prefixed string
+func (reader *sshReader) string() []byte {
+	length := reader.uint32()
+
+	if uint32(len(reader.data)) < length {
+		reader.failed = true
+		return nil
+	}
+
+	value := reader.data[:length]
+	reader.data = reader.data[length:]
+	return value
+}
+
+// bigInt reads an mpint
+func (reader *sshReader) bigInt() *big.Int {
+	return new(big.Int).SetBytes(reader.string())
+}
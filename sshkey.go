@@ -0,0 +1,145 @@
+package coreutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+// ErrIncorrectPassphrase is returned by LoadPrivateKey when the passphrase of an OpenSSH format key doesn't decrypt it
+var ErrIncorrectPassphrase = errors.New("incorrect passphrase")
+
+// bcryptMagic is the text bcrypt encrypts with the derived Blowfish key
+const bcryptMagic = "OxychromaticBlowfishSwatDynamite"
+
+// sshKeyCipher is a cipher OpenSSH encrypts private keys with
+type sshKeyCipher struct {
+	keyLength int
+	ivLength  int
+	mode      string // "ctr", "cbc", or "gcm"
+}
+
+// sshKeyCiphers are the ciphers of OpenSSH private keys LoadPrivateKey can decrypt, by their OpenSSH name. aes256-ctr is
+// what ssh-keygen uses by default.
+var sshKeyCiphers = map[string]sshKeyCipher{
+	"aes128-ctr":             {16, aes.BlockSize, "ctr"},
+	"aes192-ctr":             {24, aes.BlockSize, "ctr"},
+	"aes256-ctr":             {32, aes.BlockSize, "ctr"},
+	"aes128-cbc":             {16, aes.BlockSize, "cbc"},
+	"aes192-cbc":             {24, aes.BlockSize, "cbc"},
+	"aes256-cbc":             {32, aes.BlockSize, "cbc"},
+	"aes128-gcm@openssh.com": {16, 12, "gcm"},
+	"aes256-gcm@openssh.com": {32, 12, "gcm"},
+}
+
+// decryptOpenSSHPrivateKey decrypts the private section of an OpenSSH key with the passphrase. The options are those of the
+// key derivation function, and the rest of the key follows the private section, holding the tag of GCM ciphers.
+func decryptOpenSSHPrivateKey(cipherName, kdfName string, kdfOptions, encrypted, rest []byte, passphrase string) ([]byte, error) {
+	keyCipher, supported := sshKeyCiphers[cipherName]
+
+	if !supported {
+		return nil, errors.New("unsupported cipher " + cipherName)
+	}
+
+	if kdfName != "bcrypt" {
+		return nil, errors.New("unsupported key derivation function " + kdfName)
+	}
+
+	options := &sshReader{data: kdfOptions}
+	salt, rounds := options.string(), options.uint32()
+
+	if options.failed || len(salt) == 0 || rounds == 0 {
+		return nil, errors.New("corrupt OpenSSH key")
+	}
+
+	keyAndIV := bcryptPBKDF([]byte(passphrase), salt, int(rounds), keyCipher.keyLength+keyCipher.ivLength)
+	block, _ := aes.NewCipher(keyAndIV[:keyCipher.keyLength]) // The key length is always valid for AES
+	iv := keyAndIV[keyCipher.keyLength:]
+	decrypted := make([]byte, len(encrypted))
+
+	switch keyCipher.mode {
+	case "ctr":
+		cipher.NewCTR(block, iv).XORKeyStream(decrypted, encrypted)
+	case "cbc":
+		if len(encrypted)%aes.BlockSize != 0 {
+			return nil, errors.New("corrupt OpenSSH key")
+		}
+
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, encrypted)
+	case "gcm":
+		gcm, _ := cipher.NewGCM(block)
+
+		if len(rest) < gcm.Overhead() { // If the tag is missing
+			return nil, errors.New("corrupt OpenSSH key")
+		}
+
+		var openErr error
+
+		if decrypted, openErr = gcm.Open(nil, iv, append(encrypted[:len(encrypted):len(encrypted)], rest[:gcm.Overhead()]...), nil); openErr != nil {
+			return nil, ErrIncorrectPassphrase // The tag only fails to match when the key is wrong, or the file was tampered with
+		}
+	}
+
+	return decrypted, nil
+}
+
+// bcryptPBKDF derives a key of the provided length from the password and salt, using the bcrypt_pbkdf function OpenSSH
+// derives the keys of private keys with. Each round costs a bcrypt hash, so rounds sets how slow guessing the password is.
+func bcryptPBKDF(password, salt []byte, rounds, keyLength int) []byte {
+	blockCount := (keyLength + len(bcryptMagic) - 1) / len(bcryptMagic)
+	key := make([]byte, keyLength)
+	passwordHash := sha512.Sum512(password)
+	counter := make([]byte, 4)
+
+	for block := 1; block <= blockCount; block++ {
+		binary.BigEndian.PutUint32(counter, uint32(block))
+		saltHash := sha512.Sum512(append(salt[:len(salt):len(salt)], counter...))
+		roundOutput := bcryptHash(passwordHash[:], saltHash[:])
+		output := append([]byte{}, roundOutput...)
+
+		for round := 1; round < rounds; round++ {
+			saltHash = sha512.Sum512(roundOutput)
+			roundOutput = bcryptHash(passwordHash[:], saltHash[:])
+
+			for index := range output {
+				output[index] ^= roundOutput[index]
+			}
+		}
+
+		for index, value := range output { // The output of each block is interleaved, rather than concatenated
+			if position := index*blockCount + block - 1; position < keyLength {
+				key[position] = value
+			}
+		}
+	}
+
+	return key
+}
+
+// bcryptHash is the bcrypt variant of bcrypt_pbkdf, encrypting bcryptMagic 64 times with an expensively keyed Blowfish
+func bcryptHash(passwordHash, saltHash []byte) []byte {
+	blowfishCipher, _ := blowfish.NewSaltedCipher(passwordHash, saltHash) // A 64 byte key and salt are always valid
+
+	for iteration := 0; iteration < 64; iteration++ {
+		blowfish.ExpandKey(saltHash, blowfishCipher)
+		blowfish.ExpandKey(passwordHash, blowfishCipher)
+	}
+
+	hash := []byte(bcryptMagic)
+
+	for iteration := 0; iteration < 64; iteration++ {
+		for offset := 0; offset < len(hash); offset += blowfish.BlockSize {
+			blowfishCipher.Encrypt(hash[offset:offset+blowfish.BlockSize], hash[offset:offset+blowfish.BlockSize])
+		}
+	}
+
+	for offset := 0; offset < len(hash); offset += 4 { // Each 32 bit word is output little endian
+		binary.LittleEndian.PutUint32(hash[offset:], binary.BigEndian.Uint32(hash[offset:]))
+	}
+
+	return hash
+}
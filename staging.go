@@ -0,0 +1,123 @@
+package coreutils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// stagingArea bounds the local temp space used to stage copies
+type stagingArea struct {
+	directory string // Local directory staged copies are written to, or the system temp directory if empty
+	maxBytes  int64  // Maximum bytes staged at once across every worker
+
+	lock   sync.Mutex
+	staged int64
+}
+
+// WithStaging copies each file to a local temp directory first, then streams it to a temporary file beside the destination and
+// renames it into place, so slow or remote destinations never see a partially written file. At most maxBytes are staged at once;
+// files which don't fit are copied directly. An empty directory uses the system temp directory. Staged files are always removed.
+func WithStaging(directory string, maxBytes int64) Option {
+	return func(options *operationOptions) {
+		if maxBytes > 0 {
+			options.staging = &stagingArea{directory: directory, maxBytes: maxBytes}
+		} else {
+			options.staging = nil
+		}
+	}
+}
+
+// reserve claims space for a file of the provided size, returning false if it doesn't fit
+func (area *stagingArea) reserve(size int64) bool {
+	if area == nil {
+		return false
+	}
+
+	area.lock.Lock()
+	defer area.lock.Unlock()
+
+	if area.staged+size > area.maxBytes { // If staging this would exceed the limit
+		return false
+	}
+
+	area.staged += size
+	return true
+}
+
+// release returns the space claimed for a file of the provided size
+func (area *stagingArea) release(size int64) {
+	area.lock.Lock()
+	area.staged -= size
+	area.lock.Unlock()
+}
+
+// stagedCopy copies sourceFile to the staging area, then to a temporary file beside destinationFile which is renamed into place.
+// Returns the number of bytes copied.
+func stagedCopy(sourceFile, destinationFile string, mode os.FileMode, options *operationOptions) (int64, error) {
+	stagedFile, createErr := os.CreateTemp(options.staging.directory, "coreutils-stage-*")
+
+	if createErr != nil { // If we failed to create the staged file
+		return 0, errors.New("Failed to create a staged copy of " + sourceFile + ": " + createErr.Error())
+	}
+
+	defer os.Remove(stagedFile.Name())
+	defer stagedFile.Close()
+
+	buffer := make([]byte, options.BufferSize)
+	var copyErr error
+
+	if sourceFileStruct, openErr := os.Open(sourceFile); openErr == nil {
+		_, copyErr = io.CopyBuffer(stagedFile, sourceFileStruct, buffer)
+		sourceFileStruct.Close()
+	} else {
+		return 0, openError(sourceFile, openErr)
+	}
+
+	if copyErr != nil { // If we failed to stage the file
+		return 0, readError(sourceFile, copyErr)
+	}
+
+	if _, seekErr := stagedFile.Seek(0, io.SeekStart); seekErr != nil {
+		return 0, seekErr
+	}
+
+	destinationDirectory := filepath.Dir(destinationFile)
+
+	if mkdirErr := os.MkdirAll(destinationDirectory, options.DefaultDirMode); mkdirErr != nil { // If we failed to create the destination directory
+		return 0, writeError(destinationDirectory, "Failed to create "+destinationDirectory, mkdirErr)
+	}
+
+	partialFile, partialErr := os.CreateTemp(destinationDirectory, "."+filepath.Base(destinationFile)+".partial-*")
+
+	if partialErr != nil { // If we failed to create the temporary destination file
+		return 0, writeError(destinationFile, "Failed to create "+destinationFile, partialErr)
+	}
+
+	copiedBytes, copyErr := io.CopyBuffer(partialFile, stagedFile, buffer)
+
+	if copyErr == nil {
+		copyErr = partialFile.Sync()
+	}
+
+	if closeErr := partialFile.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+
+	if copyErr == nil {
+		copyErr = os.Chmod(partialFile.Name(), mode) // CreateTemp always uses 0600, so apply the mode of the source
+	}
+
+	if copyErr == nil {
+		copyErr = os.Rename(partialFile.Name(), destinationFile)
+	}
+
+	if copyErr != nil { // If any part of writing the destination failed, clean up the partial file
+		os.Remove(partialFile.Name())
+		return 0, writeError(destinationFile, "Failed to write "+destinationFile, copyErr)
+	}
+
+	return copiedBytes, nil
+}
@@ -0,0 +1,64 @@
+package coreutils
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats is a summary of the work done by an operation on a tree
+type Stats struct {
+	FilesCopied      int64         // Number of files successfully copied
+	FilesSkipped     int64         // Number of files and directories intentionally skipped, such as by WithExclude
+	FilesFailed      int64         // Number of files which failed to copy
+	BytesTransferred int64         // Number of bytes written to the destination
+	Duration         time.Duration // How long the operation took
+}
+
+// statsCollector safely accumulates Stats from concurrent workers
+type statsCollector struct {
+	lock  sync.Mutex
+	stats Stats
+}
+
+// copied records a successfully copied file of the provided size
+func (collector *statsCollector) copied(size int64) {
+	if collector == nil {
+		return
+	}
+
+	collector.lock.Lock()
+	collector.stats.FilesCopied++
+	collector.stats.BytesTransferred += size
+	collector.lock.Unlock()
+}
+
+// skipped records a skipped file or directory
+func (collector *statsCollector) skipped() {
+	if collector == nil {
+		return
+	}
+
+	collector.lock.Lock()
+	collector.stats.FilesSkipped++
+	collector.lock.Unlock()
+}
+
+// failed records a file which failed to copy
+func (collector *statsCollector) failed() {
+	if collector == nil {
+		return
+	}
+
+	collector.lock.Lock()
+	collector.stats.FilesFailed++
+	collector.lock.Unlock()
+}
+
+// finish returns the collected Stats, with the duration since start
+func (collector *statsCollector) finish(start time.Time) Stats {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+
+	collector.stats.Duration = time.Since(start)
+	return collector.stats
+}
@@ -0,0 +1,98 @@
+package coreutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrSymlinkLoop describes a symlink which leads back to a directory already being walked. CopyDirectory and GetFiles break such
+// loops by copying or listing the symlink itself, so it is no longer returned by them.
+var ErrSymlinkLoop = errors.New("symlink loop detected")
+
+// directoryChain is the chain of directories from the root of a recursive operation down to the directory currently being walked
+type directoryChain struct {
+	info   os.FileInfo
+	parent *directoryChain
+}
+
+// newDirectoryChain starts a chain at the root directory, or returns nil if we aren't following symlinks and so can't loop
+func newDirectoryChain(root string, options *operationOptions) *directoryChain {
+	if !options.FollowSymlinks && options.junctions != JunctionFollow {
+		return nil
+	}
+
+	rootInfo, statErr := os.Stat(root)
+
+	if statErr != nil { // If the root doesn't exist, the walk itself will report it
+		return nil
+	}
+
+	return &directoryChain{info: rootInfo}
+}
+
+// child extends the chain with a sub-directory
+func (chain *directoryChain) child(info os.FileInfo) *directoryChain {
+	if chain == nil {
+		return nil
+	}
+
+	return &directoryChain{info: info, parent: chain}
+}
+
+// checkLoop returns ErrSymlinkLoop if the directory at path, described by info, is already in the chain. Directories are
+// compared by device and inode, so a loop is caught regardless of which symlink leads back into it.
+func (chain *directoryChain) checkLoop(path string, info os.FileInfo) error {
+	for ancestor := chain; ancestor != nil; ancestor = ancestor.parent {
+		if os.SameFile(ancestor.info, info) { // If we have already walked into this directory
+			return fmt.Errorf("%s: %w", path, ErrSymlinkLoop)
+		}
+	}
+
+	return nil
+}
+
+// isFatalWalkError checks if the error from an entry should stop the whole recursive operation rather than being skipped
+func isFatalWalkError(err error) bool {
+	return errors.Is(err, ErrLimitExceeded) || errors.Is(err, ErrDestinationExists) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// copySymlink recreates the symlink sourceLink at destinationLink, pointing at the same target. The target is copied as-is,
+// so relative links within the copied tree keep working while absolute ones point where they always did.
+func copySymlink(sourceLink, destinationLink string, options *operationOptions) error {
+	if policyErr := checkPathPolicy(destinationLink, true); policyErr != nil {
+		options.stats.failed()
+		return policyErr
+	}
+
+	if skip, overwriteErr := options.checkOverwrite(sourceLink, destinationLink); overwriteErr != nil { // If something exists there and we shouldn't replace it
+		options.stats.failed()
+		return overwriteErr
+	} else if skip {
+		options.stats.skipped()
+		return nil
+	}
+
+	target, readErr := os.Readlink(sourceLink)
+
+	if readErr != nil { // If we failed to read where the symlink points
+		options.stats.failed()
+		return readError(sourceLink, readErr)
+	}
+
+	if existingInfo, statErr := os.Lstat(destinationLink); statErr == nil && !existingInfo.IsDir() { // Replace an existing file or symlink, like copies do
+		os.Remove(destinationLink)
+	}
+
+	options.recordCreated(destinationLink)
+
+	if symlinkErr := os.Symlink(target, destinationLink); symlinkErr != nil {
+		options.stats.failed()
+		return writeError(destinationLink, "Failed to create the symlink "+destinationLink, symlinkErr)
+	}
+
+	options.stats.copied(0)
+	return nil
+}
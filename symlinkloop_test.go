@@ -0,0 +1,117 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSymlinkLoop creates root/a/file.txt and root/a/loop, a symlink back to root, returning root
+func newSymlinkLoop(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	if mkdirErr := os.Mkdir(filepath.Join(root, "a"), 0755); mkdirErr != nil {
+		t.Fatal(mkdirErr)
+	}
+
+	if writeErr := os.WriteFile(filepath.Join(root, "a", "file.txt"), []byte("content"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	if linkErr := os.Symlink(root, filepath.Join(root, "a", "loop")); linkErr != nil {
+		t.Skip("symlinks aren't supported here:", linkErr)
+	}
+
+	return root
+}
+
+// withinDeadline fails the test if fn doesn't return within a few seconds, as it would if it recursed forever
+func withinDeadline(t *testing.T, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("did not finish, so the symlink loop was followed")
+	}
+}
+
+func TestGetFilesBreaksSymlinkLoop(t *testing.T) {
+	root := newSymlinkLoop(t)
+	var files []string
+	var getErr error
+
+	withinDeadline(t, func() {
+		files, getErr = GetFiles(root, true, WithFollowSymlinks(true))
+	})
+
+	if getErr != nil {
+		t.Fatal(getErr)
+	}
+
+	var sawLoop bool
+
+	for _, file := range files {
+		if strings.Count(file, "loop") > 1 { // If the walk went through the loop and came back to it
+			t.Errorf("expected the loop to be listed rather than followed, got %s", file)
+		}
+
+		sawLoop = sawLoop || filepath.Base(file) == "loop"
+	}
+
+	if !sawLoop {
+		t.Errorf("expected the symlink itself to be listed, got %v", files)
+	}
+}
+
+func TestCopyDirectoryBreaksSymlinkLoop(t *testing.T) {
+	root := newSymlinkLoop(t)
+	destination := filepath.Join(t.TempDir(), "dst")
+	var copyErr error
+
+	withinDeadline(t, func() {
+		copyErr = CopyDirectory(root, destination, WithFollowSymlinks(true))
+	})
+
+	if copyErr != nil {
+		t.Fatal(copyErr)
+	}
+
+	if info, statErr := os.Lstat(filepath.Join(destination, "a", "loop")); statErr != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected the loop to be recreated as a symlink, got %v, %v", info, statErr)
+	}
+
+	if content, readErr := os.ReadFile(filepath.Join(destination, "a", "file.txt")); readErr != nil || string(content) != "content" {
+		t.Errorf("expected the rest of the tree to be copied, got %q, %v", content, readErr)
+	}
+}
+
+func TestDirectoryChainCheckLoop(t *testing.T) {
+	root := newSymlinkLoop(t)
+	chain := newDirectoryChain(root, newOperationOptions([]Option{WithFollowSymlinks(true)}))
+	loopInfo, statErr := os.Stat(filepath.Join(root, "a", "loop")) // The directory the symlink leads to, which is root
+
+	if statErr != nil {
+		t.Fatal(statErr)
+	}
+
+	if loopErr := chain.checkLoop(filepath.Join(root, "a", "loop"), loopInfo); !errors.Is(loopErr, ErrSymlinkLoop) {
+		t.Errorf("expected ErrSymlinkLoop, got %v", loopErr)
+	}
+
+	childInfo, _ := os.Stat(filepath.Join(root, "a"))
+
+	if loopErr := chain.checkLoop(filepath.Join(root, "a"), childInfo); loopErr != nil {
+		t.Errorf("expected no loop for a sub-directory, got %v", loopErr)
+	}
+}
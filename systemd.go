@@ -0,0 +1,265 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// UnitAction is an action systemctl performs on a unit
+type UnitAction string
+
+const (
+	UnitStart   UnitAction = "start"   // UnitStart starts the unit now
+	UnitStop    UnitAction = "stop"    // UnitStop stops the unit now
+	UnitRestart UnitAction = "restart" // UnitRestart stops and starts the unit
+	UnitReload  UnitAction = "reload"  // UnitReload asks the unit to reload its configuration
+	UnitEnable  UnitAction = "enable"  // UnitEnable starts the unit with its WantedBy target, such as at boot
+	UnitDisable UnitAction = "disable" // UnitDisable undoes UnitEnable
+)
+
+// UnitSpec describes a systemd service
+type UnitSpec struct {
+	Name             string            // Name of the unit, such as "mydaemon" or "mydaemon.service", required
+	Description      string            // Human readable description of the service
+	ExecStart        string            // Command line which starts the service, required
+	Type             string            // Service type, such as "simple" or "notify". Defaults to "simple".
+	User             string            // User the service runs as, for system services
+	Group            string            // Group the service runs as, for system services
+	WorkingDirectory string            // Working directory of the service
+	Environment      map[string]string // Environment variables of the service
+	Restart          string            // When the service is restarted, such as "on-failure". Defaults to "on-failure".
+	After            []string          // Units the service starts after, such as "network-online.target"
+	WantedBy         string            // Target which starts the service when enabled. Defaults to multi-user.target, or default.target for user services.
+}
+
+var (
+	// unitNamePattern matches the characters systemd allows in unit names, which can't start with a dash, so they aren't taken as options
+	unitNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.\\@][A-Za-z0-9:_.\\@-]*$`)
+
+	// environmentNamePattern matches valid environment variable names
+	environmentNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// unitSuffixes are the unit types systemd knows, so names with one aren't given .service
+	unitSuffixes = []string{".service", ".socket", ".device", ".mount", ".automount", ".swap", ".target", ".path", ".timer", ".slice", ".scope"}
+
+	// serviceTypes and restartPolicies are the values systemd accepts for Type and Restart
+	serviceTypes    = map[string]bool{"simple": true, "exec": true, "forking": true, "oneshot": true, "dbus": true, "notify": true, "notify-reload": true, "idle": true}
+	restartPolicies = map[string]bool{"no": true, "on-success": true, "on-failure": true, "on-abnormal": true, "on-watchdog": true, "on-abort": true, "always": true}
+)
+
+// maxUnitNameLength is the longest unit name systemd accepts
+const maxUnitNameLength = 255
+
+// GenerateSystemdUnit will return the content of the .service file described by the spec, or an error if the spec is invalid,
+// such as a value containing a new line, which would inject directives of its own
+func GenerateSystemdUnit(spec UnitSpec) (string, error) {
+	if specErr := validateUnitSpec(spec); specErr != nil {
+		return "", specErr
+	}
+
+	var builder strings.Builder
+
+	serviceType, restart, wantedBy := spec.Type, spec.Restart, spec.WantedBy
+
+	if serviceType == "" {
+		serviceType = "simple"
+	}
+
+	if restart == "" {
+		restart = "on-failure"
+	}
+
+	if wantedBy == "" {
+		wantedBy = "multi-user.target"
+	}
+
+	builder.WriteString("[Unit]\n")
+
+	if spec.Description != "" {
+		builder.WriteString("Description=" + spec.Description + "\n")
+	}
+
+	if len(spec.After) != 0 {
+		builder.WriteString("After=" + strings.Join(spec.After, " ") + "\n")
+	}
+
+	builder.WriteString("\n[Service]\n")
+	builder.WriteString("Type=" + serviceType + "\n")
+	builder.WriteString("ExecStart=" + spec.ExecStart + "\n")
+	builder.WriteString("Restart=" + restart + "\n")
+
+	if spec.User != "" {
+		builder.WriteString("User=" + spec.User + "\n")
+	}
+
+	if spec.Group != "" {
+		builder.WriteString("Group=" + spec.Group + "\n")
+	}
+
+	if spec.WorkingDirectory != "" {
+		builder.WriteString("WorkingDirectory=" + spec.WorkingDirectory + "\n")
+	}
+
+	var environmentNames []string
+
+	for name := range spec.Environment {
+		environmentNames = append(environmentNames, name)
+	}
+
+	sort.Strings(environmentNames) // Keep the output stable, so reinstalling an unchanged spec doesn't rewrite it
+
+	for _, name := range environmentNames {
+		builder.WriteString("Environment=\"" + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name+"="+spec.Environment[name]) + "\"\n")
+	}
+
+	builder.WriteString("\n[Install]\n")
+	builder.WriteString("WantedBy=" + wantedBy + "\n")
+
+	return builder.String(), nil
+}
+
+// InstallUnit will write the unit described by the spec for the scope and reload systemd so it is picked up. ScopeSystem requires root.
+func InstallUnit(spec UnitSpec, scope Scope) error {
+	if scope == ScopeUser && spec.WantedBy == "" { // multi-user.target doesn't exist for user services
+		spec.WantedBy = "default.target"
+	}
+
+	var unitDirectory string
+
+	if scope == ScopeSystem {
+		if rootErr := RequireRoot(); rootErr != nil {
+			return rootErr
+		}
+
+		unitDirectory = "/etc/systemd/system"
+	} else {
+		unitDirectory = filepath.Join(xdgConfigDirectory(ScopeUser), "systemd", "user")
+	}
+
+	if mkdirErr := os.MkdirAll(unitDirectory, 0755); mkdirErr != nil { // systemd must be able to read the directory
+		return writeError(unitDirectory, "Failed to create "+unitDirectory, mkdirErr)
+	}
+
+	unit, unitErr := GenerateSystemdUnit(spec) // Validates the spec, including that the name can't leave the unit directory
+
+	if unitErr != nil {
+		return unitErr
+	}
+
+	if writeErr := WriteOrUpdateFile(filepath.Join(unitDirectory, unitName(spec.Name)), []byte(unit), 0644); writeErr != nil {
+		return writeErr
+	}
+
+	return systemctl(scope, "daemon-reload")
+}
+
+// ControlUnit will perform the action on the unit with systemctl. Units are managed as system units when running as root,
+// otherwise as the current user's units.
+func ControlUnit(name string, action UnitAction) error {
+	if nameErr := validateUnitName(name); nameErr != nil {
+		return nameErr
+	}
+
+	scope := ScopeUser
+
+	if isPrivileged() {
+		scope = ScopeSystem
+	}
+
+	return systemctl(scope, string(action), unitName(name))
+}
+
+// unitName adds the .service suffix to the name if it doesn't end in a unit type, so "my.daemon" becomes "my.daemon.service"
+func unitName(name string) string {
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return name
+		}
+	}
+
+	return name + ".service"
+}
+
+// validateUnitName returns an error if the name isn't made of the characters systemd allows in unit names, which also keeps
+// it from leaving the unit directory or being taken as an option by systemctl
+func validateUnitName(name string) error {
+	if name == "" || len(unitName(name)) > maxUnitNameLength || !unitNamePattern.MatchString(name) || strings.Trim(name, ".") == "" {
+		return errors.New("\"" + name + "\" is not a valid unit name.")
+	}
+
+	return nil
+}
+
+// validateUnitSpec returns an error if the spec is missing what every service needs, or any of its values could inject
+// directives, such as by containing a new line
+func validateUnitSpec(spec UnitSpec) error {
+	if spec.Name == "" || spec.ExecStart == "" { // If the spec is missing what every service needs
+		return errors.New("Units require a Name and ExecStart.")
+	}
+
+	if nameErr := validateUnitName(spec.Name); nameErr != nil {
+		return nameErr
+	}
+
+	if spec.Type != "" && !serviceTypes[spec.Type] {
+		return errors.New("Unit " + spec.Name + " has an unknown Type " + spec.Type)
+	}
+
+	if spec.Restart != "" && !restartPolicies[spec.Restart] {
+		return errors.New("Unit " + spec.Name + " has an unknown Restart " + spec.Restart)
+	}
+
+	dependencies := append([]string{}, spec.After...)
+
+	if spec.WantedBy != "" {
+		dependencies = append(dependencies, spec.WantedBy)
+	}
+
+	for _, dependency := range dependencies { // Dependencies are unit names, which also keeps them from holding spaces or new lines
+		if nameErr := validateUnitName(dependency); nameErr != nil {
+			return errors.New("Unit " + spec.Name + " depends on an invalid unit: " + nameErr.Error())
+		}
+	}
+
+	values := []string{spec.Description, spec.ExecStart, spec.User, spec.Group, spec.WorkingDirectory}
+
+	for name, value := range spec.Environment {
+		if !environmentNamePattern.MatchString(name) {
+			return errors.New("Unit " + spec.Name + " has an invalid environment variable name " + name)
+		}
+
+		values = append(values, value)
+	}
+
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n\x00") { // A new line would inject directives
+			return errors.New("Unit " + spec.Name + " contains a new line in one of its values.")
+		}
+	}
+
+	return nil
+}
+
+// systemctl runs systemctl with the args for the scope
+func systemctl(scope Scope, args ...string) error {
+	if !ExecutableExists("systemctl") { // If this system isn't running systemd
+		return errors.New("systemctl is not an executable.")
+	}
+
+	if scope == ScopeUser {
+		args = append([]string{"--user"}, args...)
+	}
+
+	command := Command{Name: "systemctl", Args: args}
+	runner, stderr := command.prepare()
+
+	if runErr := runner.Run(); runErr != nil {
+		return command.commandError(runErr, stderr)
+	}
+
+	return nil
+}
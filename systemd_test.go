@@ -0,0 +1,63 @@
+package coreutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateSystemdUnitRejectsInjection(t *testing.T) {
+	valid := UnitSpec{Name: "mydaemon", ExecStart: "/usr/bin/mydaemon"}
+
+	invalid := map[string]func(spec *UnitSpec){
+		"Name with a slash":         func(spec *UnitSpec) { spec.Name = "../../etc/evil" },
+		"Name of dots":              func(spec *UnitSpec) { spec.Name = ".." },
+		"Name starting with a dash": func(spec *UnitSpec) { spec.Name = "--now" },
+		"Description":               func(spec *UnitSpec) { spec.Description = "daemon\nExecStartPre=/bin/evil" },
+		"ExecStart":                 func(spec *UnitSpec) { spec.ExecStart = "/bin/true\r\nUser=root" },
+		"Type":                      func(spec *UnitSpec) { spec.Type = "simple\nUser=root" },
+		"Restart":                   func(spec *UnitSpec) { spec.Restart = "always\nUser=root" },
+		"After":                     func(spec *UnitSpec) { spec.After = []string{"network.target\nUser=root"} },
+		"After with a space":        func(spec *UnitSpec) { spec.After = []string{"a.target b.target"} },
+		"WantedBy":                  func(spec *UnitSpec) { spec.WantedBy = "multi-user.target\n[Service]" },
+		"Environment value":         func(spec *UnitSpec) { spec.Environment = map[string]string{"KEY": "value\nUser=root"} },
+		"Environment name":          func(spec *UnitSpec) { spec.Environment = map[string]string{"KEY\nUser": "root"} },
+	}
+
+	for name, corrupt := range invalid {
+		spec := valid
+		corrupt(&spec)
+
+		if unit, unitErr := GenerateSystemdUnit(spec); unitErr == nil {
+			t.Errorf("%s: expected an error, got the unit:\n%s", name, unit)
+		}
+	}
+}
+
+func TestGenerateSystemdUnit(t *testing.T) {
+	unit, unitErr := GenerateSystemdUnit(UnitSpec{
+		Name:        "my.daemon",
+		Description: "My daemon",
+		ExecStart:   "/usr/bin/mydaemon --serve",
+		Type:        "notify",
+		Environment: map[string]string{"B": `quoted "value"`, "A": "1"},
+		After:       []string{"network-online.target"},
+	})
+
+	if unitErr != nil {
+		t.Fatal(unitErr)
+	}
+
+	for _, line := range []string{"Description=My daemon\n", "After=network-online.target\n", "Type=notify\n", "Restart=on-failure\n", "Environment=\"A=1\"\nEnvironment=\"B=quoted \\\"value\\\"\"\n", "WantedBy=multi-user.target\n"} {
+		if !strings.Contains(unit, line) {
+			t.Errorf("expected the unit to contain %q, got:\n%s", line, unit)
+		}
+	}
+}
+
+func TestUnitName(t *testing.T) {
+	for name, expected := range map[string]string{"mydaemon": "mydaemon.service", "my.daemon": "my.daemon.service", "backup.timer": "backup.timer", "getty@tty1.service": "getty@tty1.service"} {
+		if actual := unitName(name); actual != expected {
+			t.Errorf("unitName(%q) = %q, expected %q", name, actual, expected)
+		}
+	}
+}
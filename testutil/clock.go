@@ -0,0 +1,141 @@
+// Package testutil provides helpers for testing code built on coreutils.
+//
+// FakeClock drives the time-dependent features of coreutils by hand, so tests of heartbeats and expiry don't have to sleep:
+//
+//	clock := testutil.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	coreutils.SetClock(clock)
+//	defer coreutils.SetClock(nil)
+//	clock.Advance(time.Hour)
+//
+// CreateSparseFile and LargeFileTorture create multi-GB files in moments, to check copies stream rather than read files into memory.
+package testutil
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/StroblIndustries/coreutils"
+)
+
+// FakeClock is a coreutils.Clock whose time only moves when Advance or Set is called
+type FakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending After or Ticker, fired once the clock reaches its deadline
+type fakeWaiter struct {
+	deadline time.Time
+	interval time.Duration // Interval of a ticker, 0 for After
+	channel  chan time.Time
+}
+
+// fakeTicker is a coreutils.Ticker driven by a FakeClock
+type fakeTicker struct {
+	clock  *FakeClock
+	waiter *fakeWaiter
+}
+
+// NewFakeClock will create a FakeClock set to start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the time of the clock
+func (clock *FakeClock) Now() time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+	return clock.now
+}
+
+// After returns a channel which is sent the time once the clock has been advanced by d
+func (clock *FakeClock) After(d time.Duration) <-chan time.Time {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+
+	waiter := &fakeWaiter{deadline: clock.now.Add(d), channel: make(chan time.Time, 1)}
+
+	if d <= 0 { // If the time has already passed
+		waiter.channel <- clock.now
+		return waiter.channel
+	}
+
+	clock.waiters = append(clock.waiters, waiter)
+
+	return waiter.channel
+}
+
+// NewTicker returns a Ticker which sends the time each time the clock passes another d. Like time.Ticker, ticks are dropped
+// rather than queued if the receiver falls behind.
+func (clock *FakeClock) NewTicker(d time.Duration) coreutils.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+
+	waiter := &fakeWaiter{deadline: clock.now.Add(d), interval: d, channel: make(chan time.Time, 1)}
+	clock.waiters = append(clock.waiters, waiter)
+
+	return &fakeTicker{clock: clock, waiter: waiter}
+}
+
+// Advance moves the clock forward by d, firing every After and Ticker whose time has come, in order
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.Set(clock.Now().Add(d))
+}
+
+// Set moves the clock to t, firing every After and Ticker whose time has come, in order. The clock never moves backwards.
+func (clock *FakeClock) Set(t time.Time) {
+	clock.lock.Lock()
+	defer clock.lock.Unlock()
+
+	for {
+		sort.SliceStable(clock.waiters, func(i, j int) bool {
+			return clock.waiters[i].deadline.Before(clock.waiters[j].deadline)
+		})
+
+		if len(clock.waiters) == 0 || clock.waiters[0].deadline.After(t) { // If nothing else is due
+			break
+		}
+
+		waiter := clock.waiters[0]
+		clock.now = waiter.deadline
+
+		select {
+		case waiter.channel <- waiter.deadline:
+		default: // Drop the tick if the last hasn't been received, as time.Ticker does
+		}
+
+		if waiter.interval > 0 { // If this is a ticker, schedule its next tick
+			waiter.deadline = waiter.deadline.Add(waiter.interval)
+		} else {
+			clock.waiters = clock.waiters[1:]
+		}
+	}
+
+	if t.After(clock.now) {
+		clock.now = t
+	}
+}
+
+// C returns the channel the ticks are sent on
+func (ticker *fakeTicker) C() <-chan time.Time {
+	return ticker.waiter.channel
+}
+
+// Stop turns off the ticker
+func (ticker *fakeTicker) Stop() {
+	ticker.clock.lock.Lock()
+	defer ticker.clock.lock.Unlock()
+
+	for index, waiter := range ticker.clock.waiters {
+		if waiter == ticker.waiter {
+			ticker.clock.waiters = append(ticker.clock.waiters[:index], ticker.clock.waiters[index+1:]...)
+			break
+		}
+	}
+}
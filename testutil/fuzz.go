@@ -0,0 +1,213 @@
+package testutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StroblIndustries/coreutils"
+)
+
+// The Fuzz functions are entry points for go test fuzzing of the parts of coreutils which parse untrusted input. Call them from a
+// fuzz target in a _test.go file, then run it with go test -fuzz:
+//
+//	func FuzzSecureJoin(f *testing.F) {
+//		testutil.FuzzSecureJoin(f)
+//	}
+
+// FuzzAbsPath fuzzes AbsPath, checking it never panics and always returns an absolute path
+func FuzzAbsPath(f *testing.F) {
+	for _, seed := range []string{"", ".", "..", "~", "~/file.txt", "../../etc/passwd", "a/./b/../c", "\x00", "~~/~", ".hidden"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, path string) {
+		if strings.ContainsRune(path, 0) { // Paths can't contain NUL, and AbsPath doesn't claim to handle them
+			t.Skip()
+		}
+
+		if absolutePath := coreutils.AbsPath(path); !filepath.IsAbs(absolutePath) {
+			t.Fatalf("AbsPath(%q) returned the relative path %q", path, absolutePath)
+		}
+	})
+}
+
+// FuzzSecureJoin fuzzes SecureJoin against a root holding symlinks which point above it, checking the joined path never escapes the root
+func FuzzSecureJoin(f *testing.F) {
+	for _, seed := range []string{"", "a/b", "../../etc/passwd", "/../../..", "up/../../x", "absolute/etc", "loop/loop/loop", "dir/../up/x", `..\..\x`} {
+		f.Add(seed)
+	}
+
+	root := f.TempDir()
+
+	if setupErr := newEscapingRoot(root); setupErr != nil {
+		f.Fatal(setupErr)
+	}
+
+	f.Fuzz(func(t *testing.T, unsafePath string) {
+		joined, joinErr := coreutils.SecureJoin(root, unsafePath)
+
+		if joinErr == nil && !within(root, joined) {
+			t.Fatalf("SecureJoin(%q) escaped the root as %q", unsafePath, joined)
+		}
+	})
+}
+
+// FuzzIgnoreMatcher fuzzes the ignore file patterns and the paths matched against them, checking matching never panics and is deterministic
+func FuzzIgnoreMatcher(f *testing.F) {
+	for _, seed := range [][2]string{{"*.tmp", "a/b.tmp"}, {"!keep\n*", "keep"}, {"/build/\n**/cache", "x/cache"}, {"a/**/b", "a/x/y/b"}, {"\\#[", "#["}, {"***", ""}} {
+		f.Add(seed[0], seed[1], false)
+	}
+
+	f.Fuzz(func(t *testing.T, patterns, relPath string, isDir bool) {
+		matcher := coreutils.NewIgnoreMatcher(strings.Split(patterns, "\n"))
+
+		if matcher.Match(relPath, isDir) != matcher.Match(relPath, isDir) {
+			t.Fatalf("Matching %q against %q is not deterministic", relPath, patterns)
+		}
+	})
+}
+
+// FuzzArchiveExtract fuzzes extracting tar, tar.gz, and zip archives, checking nothing is written or linked outside of the destination
+func FuzzArchiveExtract(f *testing.F) {
+	for _, seed := range archiveSeeds() {
+		f.Add(seed.content, seed.format)
+	}
+
+	f.Fuzz(func(t *testing.T, content []byte, format uint8) {
+		root := t.TempDir()
+		archivePath := filepath.Join(t.TempDir(), "fuzz"+archiveExtensions[int(format)%len(archiveExtensions)])
+		destination := filepath.Join(root, "out")
+
+		if writeErr := os.WriteFile(archivePath, content, 0600); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+
+		if archiveSize(archivePath) > maxFuzzArchiveSize { // Tar sparse entries can claim gigabytes of zeros in a few bytes
+			t.Skip()
+		}
+
+		if archive, openErr := coreutils.OpenArchive(archivePath); openErr == nil {
+			archive.ExtractTo(destination) // Malformed archives are expected to fail, so long as they fail safely
+			archive.Close()
+		}
+
+		rootEntries, _ := os.ReadDir(root)
+
+		for _, entry := range rootEntries { // The destination should be the only thing in root
+			if entry.Name() != "out" {
+				t.Fatalf("Extracting wrote %s outside of the destination", entry.Name())
+			}
+		}
+
+		resolvedDestination, _ := filepath.EvalSymlinks(destination)
+
+		filepath.WalkDir(destination, func(path string, entry fs.DirEntry, walkErr error) error {
+			if walkErr != nil || entry.Type()&fs.ModeSymlink == 0 {
+				return nil
+			}
+
+			if target, evalErr := filepath.EvalSymlinks(path); evalErr == nil && !within(resolvedDestination, target) {
+				t.Fatalf("Extracting created %s, a symlink to %s outside of the destination", path, target)
+			}
+
+			return nil
+		})
+	})
+}
+
+// maxFuzzArchiveSize is the most content an archive extracted by FuzzArchiveExtract may hold, so fuzzing isn't spent writing zeros
+const maxFuzzArchiveSize = 1 << 20
+
+// archiveSize returns the total size of the entries of the archive, reading as far as it can
+func archiveSize(archivePath string) int64 {
+	archive, openErr := coreutils.OpenArchive(archivePath)
+
+	if openErr != nil {
+		return 0
+	}
+
+	defer archive.Close()
+
+	var size int64
+
+	for entry, nextErr := archive.Next(); nextErr == nil && size <= maxFuzzArchiveSize; entry, nextErr = archive.Next() {
+		if entry.Size < 0 || entry.Size > maxFuzzArchiveSize { // Checked alone first, so huge sizes can't overflow the total
+			return maxFuzzArchiveSize + 1
+		}
+
+		size += entry.Size
+	}
+
+	return size
+}
+
+// archiveExtensions are the extensions of each archive format, indexed by the format fuzzed by FuzzArchiveExtract
+var archiveExtensions = []string{".tar", ".tar.gz", ".zip"}
+
+// archiveSeed is an archive used to seed FuzzArchiveExtract
+type archiveSeed struct {
+	content []byte
+	format  uint8 // Index of the format in archiveExtensions
+}
+
+// archiveSeeds returns well formed archives of each format, along with tar archives attempting to escape their destination
+func archiveSeeds() []archiveSeed {
+	var seeds []archiveSeed
+
+	for index, extension := range archiveExtensions {
+		format, _ := coreutils.ArchiveFormatFromName("seed" + extension)
+		var buffer bytes.Buffer
+		archive := coreutils.NewArchiveWriter(&buffer, format)
+		archive.AddEntry(coreutils.ArchiveEntry{Name: "dir", Mode: fs.ModeDir | 0755}, nil)
+		archive.AddEntry(coreutils.ArchiveEntry{Name: "dir/file.txt", Mode: 0644, Size: 5}, strings.NewReader("hello"))
+		archive.AddEntry(coreutils.ArchiveEntry{Name: "link", Mode: fs.ModeSymlink | 0777, LinkTarget: "dir/file.txt"}, nil)
+		archive.Close()
+		seeds = append(seeds, archiveSeed{content: buffer.Bytes(), format: uint8(index)})
+	}
+
+	escapes := [][]*tar.Header{
+		{{Name: "../escape.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+		{{Name: "up", Typeflag: tar.TypeSymlink, Linkname: ".."}, {Name: "up/escape.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+		{{Name: "self", Typeflag: tar.TypeSymlink, Linkname: "."}, {Name: "parent", Typeflag: tar.TypeSymlink, Linkname: "self/.."}},
+	}
+
+	for _, headers := range escapes {
+		var buffer bytes.Buffer
+		tarWriter := tar.NewWriter(&buffer)
+
+		for _, header := range headers {
+			tarWriter.WriteHeader(header)
+		}
+
+		tarWriter.Close()
+		seeds = append(seeds, archiveSeed{content: buffer.Bytes()})
+	}
+
+	return seeds
+}
+
+// newEscapingRoot fills root with a directory and symlinks which point above it, to tempt SecureJoin out of the root
+func newEscapingRoot(root string) error {
+	if mkdirErr := os.Mkdir(filepath.Join(root, "dir"), 0755); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	for name, target := range map[string]string{"up": "..", "absolute": "/", "loop": "loop", "dir/deep": "../../.."} {
+		if linkErr := os.Symlink(target, filepath.Join(root, name)); linkErr != nil {
+			return linkErr
+		}
+	}
+
+	return nil
+}
+
+// within checks if the target path is root or lexically contained within root
+func within(root, target string) bool {
+	relativePath, relErr := filepath.Rel(root, target)
+	return (relErr == nil) && (relativePath != "..") && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
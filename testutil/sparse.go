@@ -0,0 +1,144 @@
+package testutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/StroblIndustries/coreutils"
+)
+
+// markerLength is the length of each marker written into a sparse file
+const markerLength = 16
+
+// maxTortureAlloc is how much the copy and hash in LargeFileTorture may allocate before they are considered to be reading the file into memory
+const maxTortureAlloc = 64 << 20
+
+// CreateSparseFile will create a sparse file of the provided size, which takes almost no disk space or time however large it is.
+// Markers are written at the start and end of the file and either side of the 2GiB and 4GiB boundaries, so VerifySparseFile can
+// check copies for truncation and offset overflows without reading every byte. Everything between the markers reads as zeros.
+func CreateSparseFile(path string, size int64) error {
+	file, createErr := os.Create(path)
+
+	if createErr != nil {
+		return createErr
+	}
+
+	writeErr := file.Truncate(size)
+
+	for _, offset := range markerOffsets(size) { // For each marker that fits in the file
+		if writeErr != nil {
+			break
+		}
+
+		_, writeErr = file.WriteAt(marker(offset), offset)
+	}
+
+	if closeErr := file.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+
+	return writeErr
+}
+
+// VerifySparseFile will check the file at path is the provided size and has every marker written by CreateSparseFile
+func VerifySparseFile(path string, size int64) error {
+	file, openErr := os.Open(path)
+
+	if openErr != nil {
+		return openErr
+	}
+
+	defer file.Close()
+
+	if info, statErr := file.Stat(); statErr != nil {
+		return statErr
+	} else if info.Size() != size { // If the file was truncated or padded
+		return fmt.Errorf("%s is %d bytes rather than %d", path, info.Size(), size)
+	}
+
+	found := make([]byte, markerLength)
+
+	for _, offset := range markerOffsets(size) { // For each marker that should be in the file
+		if _, readErr := file.ReadAt(found, offset); readErr != nil {
+			return readErr
+		}
+
+		if !bytes.Equal(found, marker(offset)) { // If the content ended up at the wrong offset
+			return fmt.Errorf("%s has the wrong content at offset %d", path, offset)
+		}
+	}
+
+	return nil
+}
+
+// LargeFileTorture will create a sparse file of the provided size within dir, then copy it with coreutils.CopyFile and hash both
+// copies, failing if the copy doesn't match or either step allocates enough to suggest the file was read into memory. Use a size
+// past 4GiB to catch offset overflows. The copy is not sparse, so dir needs size bytes free.
+func LargeFileTorture(dir string, size int64) error {
+	sourceFile := filepath.Join(dir, "torture-source")
+	destinationFile := filepath.Join(dir, "torture-copy")
+
+	defer os.Remove(sourceFile)
+	defer os.Remove(destinationFile)
+
+	if createErr := CreateSparseFile(sourceFile, size); createErr != nil {
+		return createErr
+	}
+
+	var copyErr, sourceErr, destinationErr error
+	var sourceHash, destinationHash string
+
+	allocated := allocatedBy(func() {
+		if copyErr = coreutils.CopyFile(sourceFile, destinationFile); copyErr == nil {
+			sourceHash, sourceErr = coreutils.HashFile(sourceFile, coreutils.HashSHA256)
+			destinationHash, destinationErr = coreutils.HashFile(destinationFile, coreutils.HashSHA256)
+		}
+	})
+
+	if tortureErr := errors.Join(copyErr, sourceErr, destinationErr); tortureErr != nil {
+		return tortureErr
+	}
+
+	if allocated > maxTortureAlloc { // If the copy or hash held the file in memory
+		return fmt.Errorf("copying and hashing %d bytes allocated %d bytes", size, allocated)
+	}
+
+	if sourceHash != destinationHash { // If the content of the copy differs
+		return fmt.Errorf("%s has a different hash than %s", destinationFile, sourceFile)
+	}
+
+	return VerifySparseFile(destinationFile, size)
+}
+
+// markerOffsets returns the offsets of the markers which fit in a file of the provided size
+func markerOffsets(size int64) []int64 {
+	var offsets []int64
+
+	for _, offset := range []int64{0, 1<<31 - markerLength, 1 << 31, 1<<32 - markerLength, 1 << 32, size - markerLength} {
+		if offset >= 0 && offset+markerLength <= size && (len(offsets) == 0 || offset >= offsets[len(offsets)-1]+markerLength) { // Markers mustn't overlap
+			offsets = append(offsets, offset)
+		}
+	}
+
+	return offsets
+}
+
+// marker returns the content written at the offset, which encodes the offset so misplaced content is detected
+func marker(offset int64) []byte {
+	return []byte(fmt.Sprintf("%016x", offset))
+}
+
+// allocatedBy returns the number of bytes allocated on the heap while fn runs
+func allocatedBy(fn func()) uint64 {
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+
+	return after.TotalAlloc - before.TotalAlloc
+}
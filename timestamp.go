@@ -0,0 +1,73 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTimestampLayout is the layout TimestampedName uses when none is given, such as 20240131-154500
+const DefaultTimestampLayout = "20060102-150405"
+
+// TimestampedName will return a file name made of the base, the current UTC time in the layout, and the extension, such as
+// "backup-20240131-154500.tar.gz" for TimestampedName("backup", ".tar.gz", ""). The layout defaults to DefaultTimestampLayout,
+// and should order its fields from year to second, so names sort in the order they were created.
+func TimestampedName(base, ext string, layout string) string {
+	if layout == "" {
+		layout = DefaultTimestampLayout
+	}
+
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	return base + "-" + GetClock().Now().UTC().Format(layout) + ext
+}
+
+// LatestTimestampedFile will return the path of the newest file in dir named by TimestampedName with the base. Names using
+// DefaultTimestampLayout are compared by the time they contain, rather than their modification time, which copying or
+// restoring may change. If none use it, names are compared as text, which works for any layout ordered from year to second.
+func LatestTimestampedFile(dir, base string) (string, error) {
+	var latestName, latestText string
+	var latestTime time.Time
+	var foundParsed bool
+
+	prefix := base + "-"
+
+	readErr := readDirectory(dir, func(entry os.DirEntry) error {
+		name := entry.Name()
+		stamp, hasPrefix := strings.CutPrefix(name, prefix)
+
+		if !hasPrefix || entry.IsDir() {
+			return nil
+		}
+
+		if len(stamp) >= len(DefaultTimestampLayout) { // If this may use the default layout
+			if stampTime, parseErr := time.Parse(DefaultTimestampLayout, stamp[:len(DefaultTimestampLayout)]); parseErr == nil {
+				if !foundParsed || stampTime.After(latestTime) {
+					latestName, latestTime, foundParsed = name, stampTime, true
+				}
+
+				return nil
+			}
+		}
+
+		if !foundParsed && stamp > latestText { // Only used if no name has the default layout
+			latestName, latestText = name, stamp
+		}
+
+		return nil
+	})
+
+	if readErr != nil {
+		return "", readErr
+	}
+
+	if latestName == "" {
+		return "", errors.New(dir + " does not contain any files named " + prefix + "<timestamp>.")
+	}
+
+	return filepath.Join(dir, latestName), nil
+}
@@ -0,0 +1,91 @@
+package coreutils
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// TraceEvent describes a completed operation
+type TraceEvent struct {
+	Op          string        // Name of the function which performed the operation, such as CopyFile
+	Path        string        // Path the operation acted on, or its source
+	Destination string        // Destination of the operation, if it has one
+	Bytes       int64         // Number of bytes written by the operation
+	Mode        os.FileMode   // Mode files were written with, if the operation was given one
+	Duration    time.Duration // How long the operation took
+	Err         error         // Error the operation returned, if any
+}
+
+// TraceFunc is called with each TraceEvent. It is called synchronously, so it should return quickly.
+type TraceFunc func(event TraceEvent)
+
+// recentTraceLimit is the number of recent TraceEvents kept for crash reports
+const recentTraceLimit = 100
+
+var (
+	traceLock  sync.RWMutex
+	traceHooks = make(map[int]TraceFunc)
+	traceId    int
+
+	recentLock   sync.Mutex
+	recentTraces []TraceEvent // Ring of the most recent TraceEvents
+	recentNext   int          // Index in recentTraces the next TraceEvent is written to
+)
+
+// AddTraceHook registers fn to be called after each traced operation, returning a function which removes it
+func AddTraceHook(fn TraceFunc) (remove func()) {
+	traceLock.Lock()
+	traceId++
+	id := traceId
+	traceHooks[id] = fn
+	traceLock.Unlock()
+
+	return func() {
+		traceLock.Lock()
+		delete(traceHooks, id)
+		traceLock.Unlock()
+	}
+}
+
+// trace sends the event to every registered trace hook
+func trace(event TraceEvent) {
+	recentLock.Lock()
+
+	if len(recentTraces) < recentTraceLimit { // If the ring isn't full yet
+		recentTraces = append(recentTraces, event)
+	} else {
+		recentTraces[recentNext] = event
+	}
+
+	recentNext = (recentNext + 1) % recentTraceLimit
+	recentLock.Unlock()
+
+	audit(event)
+	postHooks(event)
+
+	traceLock.RLock()
+	hooks := make([]TraceFunc, 0, len(traceHooks))
+
+	for _, hook := range traceHooks {
+		hooks = append(hooks, hook)
+	}
+
+	traceLock.RUnlock()
+
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
+
+// recentTraceEvents returns the most recent TraceEvents, oldest first
+func recentTraceEvents() []TraceEvent {
+	recentLock.Lock()
+	defer recentLock.Unlock()
+
+	if len(recentTraces) < recentTraceLimit { // If the ring hasn't wrapped yet
+		return append([]TraceEvent{}, recentTraces...)
+	}
+
+	return append(append([]TraceEvent{}, recentTraces[recentNext:]...), recentTraces[:recentNext]...)
+}
@@ -0,0 +1,306 @@
+package coreutils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base32"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPairingFailed is returned when the peer of a directory transfer doesn't know the pairing code, such as when someone
+// intercepts the connection
+var ErrPairingFailed = errors.New("pairing code mismatch")
+
+// ErrWeakPairingCode is returned when a pairing code is shorter than those of GeneratePairingCode. Anyone impersonating the
+// receiver sees the sender's proof, and can guess short codes from it offline.
+var ErrWeakPairingCode = errors.New("pairing codes must be at least 16 characters")
+
+// transferExporterLabel labels the TLS keying material the pairing proofs are bound to
+const transferExporterLabel = "EXPORTER-coreutils-transfer"
+
+// minPairingCodeLength is the number of characters in a pairing code, ignoring separators, as made by GeneratePairingCode
+const minPairingCodeLength = 16
+
+// pairingTimeout is how long a connection to ReceiveDirectory has to prove it knows the pairing code
+const pairingTimeout = 10 * time.Second
+
+// maxPendingPairings is the number of connections ReceiveDirectory pairs with at once. Further connections are dropped.
+const maxPendingPairings = 32
+
+// GeneratePairingCode will create a random code for SendDirectory and ReceiveDirectory, such as "k3mf-a9xq-2pzr-4wdq", to be
+// passed from the receiver to the sender out of band, such as by reading it aloud
+func GeneratePairingCode() string {
+	random := make([]byte, 10)
+	rand.Read(random)
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(random)) // 16 characters of 5 bits each, 80 bits
+	return code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+}
+
+// normalizePairingCode returns the code without separators or case, so it can be typed either way, or ErrWeakPairingCode if
+// it is too short to withstand guessing
+func normalizePairingCode(code string) (string, error) {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	if len(normalized) < minPairingCodeLength {
+		return "", ErrWeakPairingCode
+	}
+
+	return normalized, nil
+}
+
+// SendDirectory will send the contents of dir to the peer, an address such as "192.168.1.20:7070" on which ReceiveDirectory
+// is listening, as a compressed archive over TLS. Both sides must use the same pairing code, which proves neither is talking
+// to an impostor, so certificates aren't needed. Codes shorter than those of GeneratePairingCode return ErrWeakPairingCode.
+// Honors WithExclude.
+func SendDirectory(ctx context.Context, peer, dir, code string, opts ...Option) error {
+	if !IsDir(dir) { // If this isn't a directory
+		return notDirectoryError(dir, nil)
+	}
+
+	code, codeErr := normalizePairingCode(code)
+
+	if codeErr != nil {
+		return codeErr
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		InsecureSkipVerify: true, // The peer is authenticated by the pairing code instead
+		MinVersion:         tls.VersionTLS13,
+	}}
+
+	rawConn, dialErr := dialer.DialContext(ctx, "tcp", peer)
+
+	if dialErr != nil {
+		return errors.New("Failed to connect to " + peer + ": " + dialErr.Error())
+	}
+
+	conn := rawConn.(*tls.Conn)
+	defer conn.Close()
+	stopOnCancel := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopOnCancel()
+
+	if pairErr := pairTransfer(conn, code, true); pairErr != nil {
+		return pairErr
+	}
+
+	archive := NewArchiveWriter(conn, ArchiveTarGz, opts...)
+	sendErr := archive.AddDirectory(dir)
+
+	if closeErr := archive.Close(); sendErr == nil {
+		sendErr = closeErr
+	}
+
+	if sendErr == nil {
+		sendErr = conn.CloseWrite()
+	}
+
+	if sendErr == nil { // Wait for the receiver to confirm it extracted everything
+		acknowledgement := make([]byte, 1)
+
+		if _, readErr := io.ReadFull(conn, acknowledgement); readErr != nil || acknowledgement[0] != 1 {
+			sendErr = errors.New(peer + " failed to receive " + dir)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return sendErr
+}
+
+// ReceiveDirectory will listen on listenAddr, such as ":7070", for a single SendDirectory using the same pairing code, and
+// extract what it sends into dst. Returns once the transfer completes, or ctx is done. Connections are paired with at the
+// same time, and those with the wrong pairing code are rejected without ending the wait, so a stranger can neither cut the
+// transfer short nor hold it up. Codes shorter than those of GeneratePairingCode return ErrWeakPairingCode.
+func ReceiveDirectory(ctx context.Context, listenAddr, dst, code string) error {
+	code, codeErr := normalizePairingCode(code)
+
+	if codeErr != nil {
+		return codeErr
+	}
+
+	certificate, certErr := ephemeralCertificate()
+
+	if certErr != nil {
+		return certErr
+	}
+
+	listener, listenErr := tls.Listen("tcp", listenAddr, &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS13})
+
+	if listenErr != nil {
+		return errors.New("Failed to listen on " + listenAddr + ": " + listenErr.Error())
+	}
+
+	defer listener.Close()
+	stopOnCancel := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stopOnCancel()
+
+	pairCtx, cancelPairing := context.WithCancel(ctx)
+	defer cancelPairing() // Close the connections still pairing once we're done
+
+	paired := make(chan *tls.Conn, 1)
+	acceptErr := make(chan error, 1)
+	go acceptPairings(pairCtx, listener, code, paired, acceptErr)
+
+	select {
+	case conn := <-paired:
+		listener.Close()
+		cancelPairing()
+
+		start := time.Now()
+		stopConn := context.AfterFunc(ctx, func() { conn.Close() })
+		receiveErr := receiveArchive(conn, dst)
+		stopConn()
+		conn.Close()
+
+		if ctx.Err() != nil {
+			receiveErr = ctx.Err()
+		}
+
+		trace(TraceEvent{Op: "ReceiveDirectory", Path: dst, Duration: time.Since(start), Err: receiveErr})
+		return receiveErr
+	case err := <-acceptErr:
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return err
+	}
+}
+
+// acceptPairings accepts connections from the listener until it is closed, pairing with up to maxPendingPairings of them at once.
+// The first to pair is sent to paired, and the rest are closed. The error which stopped the listener is sent to acceptErr.
+func acceptPairings(ctx context.Context, listener net.Listener, code string, paired chan<- *tls.Conn, acceptErr chan<- error) {
+	pending := make(chan struct{}, maxPendingPairings)
+	var claimLock sync.Mutex
+	claimed := false
+
+	for {
+		rawConn, err := listener.Accept()
+
+		if err != nil {
+			acceptErr <- err
+			return
+		}
+
+		select {
+		case pending <- struct{}{}:
+		default: // If we're already pairing with as many connections as we allow
+			rawConn.Close()
+			continue
+		}
+
+		go func(conn *tls.Conn) {
+			defer func() { <-pending }()
+
+			stopPairing := context.AfterFunc(ctx, func() { conn.Close() })
+			conn.SetDeadline(time.Now().Add(pairingTimeout)) // Don't let a silent stranger hold a slot for long
+			pairErr := pairTransfer(conn, code, false)
+
+			if !stopPairing() || pairErr != nil { // If we're done, or this isn't our sender
+				conn.Close()
+				return
+			}
+
+			conn.SetDeadline(time.Time{})
+
+			claimLock.Lock()
+			first := !claimed
+			claimed = true
+			claimLock.Unlock()
+
+			if !first { // If another connection already paired
+				conn.Close()
+				return
+			}
+
+			paired <- conn
+		}(rawConn.(*tls.Conn))
+	}
+}
+
+// receiveArchive extracts the archive sent over conn into dst, acknowledging it once done
+func receiveArchive(conn *tls.Conn, dst string) error {
+	archive, openErr := NewArchiveReader(conn, ArchiveTarGz)
+
+	if openErr != nil {
+		return openErr
+	}
+
+	extractErr := archive.extractTo(dst)
+	archive.Close()
+
+	if extractErr != nil {
+		return extractErr
+	}
+
+	_, ackErr := conn.Write([]byte{1})
+	return ackErr
+}
+
+// pairTransfer proves to the peer that we know the pairing code, and checks that they do too. The proofs are bound to the
+// keys of this TLS connection, so they're useless to anyone relaying it. The sender proves itself first.
+func pairTransfer(conn *tls.Conn, code string, sender bool) error {
+	if handshakeErr := conn.Handshake(); handshakeErr != nil {
+		return handshakeErr
+	}
+
+	state := conn.ConnectionState()
+	keyingMaterial, exportErr := state.ExportKeyingMaterial(transferExporterLabel, nil, 32)
+
+	if exportErr != nil {
+		return exportErr
+	}
+
+	proof := func(role string) []byte {
+		mac := hmac.New(sha256.New, []byte(code))
+		mac.Write([]byte(role))
+		mac.Write(keyingMaterial)
+		return mac.Sum(nil)
+	}
+
+	ours, theirs := proof("receiver"), proof("sender")
+
+	if sender {
+		ours, theirs = theirs, ours
+
+		if _, writeErr := conn.Write(ours); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	received := make([]byte, sha256.Size)
+
+	if _, readErr := io.ReadFull(conn, received); readErr != nil || !hmac.Equal(received, theirs) {
+		return ErrPairingFailed
+	}
+
+	if !sender {
+		if _, writeErr := conn.Write(ours); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	return nil
+}
+
+// ephemeralCertificate creates a throwaway self-signed certificate for a single transfer
+func ephemeralCertificate() (tls.Certificate, error) {
+	der, key, createErr := selfSignedCertificate([]string{"coreutils-transfer"}, 24*time.Hour)
+
+	if createErr != nil {
+		return tls.Certificate{}, createErr
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
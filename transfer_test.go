@@ -0,0 +1,92 @@
+package coreutils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGeneratePairingCode(t *testing.T) {
+	code := GeneratePairingCode()
+
+	if normalized, codeErr := normalizePairingCode(code); codeErr != nil || len(normalized) != minPairingCodeLength || len(code) != minPairingCodeLength+3 {
+		t.Errorf("expected 16 characters in four groups, got %q", code)
+	}
+
+	if GeneratePairingCode() == code {
+		t.Error("expected every code to be different")
+	}
+}
+
+func TestTransferRejectsWeakCodes(t *testing.T) {
+	ctx := context.Background()
+
+	if sendErr := SendDirectory(ctx, "127.0.0.1:1", t.TempDir(), "abcd-1234"); !errors.Is(sendErr, ErrWeakPairingCode) {
+		t.Errorf("expected SendDirectory to reject a short code, got %v", sendErr)
+	}
+
+	if receiveErr := ReceiveDirectory(ctx, "127.0.0.1:0", t.TempDir(), "abcd-1234"); !errors.Is(receiveErr, ErrWeakPairingCode) {
+		t.Errorf("expected ReceiveDirectory to reject a short code, got %v", receiveErr)
+	}
+}
+
+func TestReceiveDirectoryNotHeldUpByStrangers(t *testing.T) {
+	source := t.TempDir()
+	destination := filepath.Join(t.TempDir(), "received")
+	code := GeneratePairingCode()
+
+	if writeErr := os.WriteFile(filepath.Join(source, "file.txt"), []byte("sent"), 0644); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	listener, listenErr := net.Listen("tcp", "127.0.0.1:0") // Find a free port for the receiver
+
+	if listenErr != nil {
+		t.Fatal(listenErr)
+	}
+
+	address := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	received := make(chan error, 1)
+	go func() { received <- ReceiveDirectory(ctx, address, destination, code) }()
+
+	var stranger net.Conn
+
+	for dialErr := errors.New(""); dialErr != nil; { // Wait for the receiver to listen
+		if stranger, dialErr = net.Dial("tcp", address); dialErr != nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	defer stranger.Close() // Connects but never says anything
+
+	if sendErr := SendDirectory(ctx, address, source, GeneratePairingCode()); !errors.Is(sendErr, ErrPairingFailed) {
+		t.Errorf("expected a sender with the wrong code to fail pairing, got %v", sendErr)
+	}
+
+	start := time.Now()
+
+	if sendErr := SendDirectory(ctx, address, source, code); sendErr != nil {
+		t.Fatal(sendErr)
+	}
+
+	if elapsed := time.Since(start); elapsed > pairingTimeout/2 {
+		t.Errorf("expected the sender not to wait on the stranger, took %s", elapsed)
+	}
+
+	if receiveErr := <-received; receiveErr != nil {
+		t.Fatal(receiveErr)
+	}
+
+	if content, readErr := os.ReadFile(filepath.Join(destination, "file.txt")); readErr != nil || string(content) != "sent" {
+		t.Errorf("expected the directory to be received, got %q, %v", content, readErr)
+	}
+}
@@ -0,0 +1,36 @@
+package coreutils
+
+import (
+	"fmt"
+	"io"
+)
+
+// TransformFunc changes the content of the file at path as it is copied, such as to fill in a template, normalize line endings,
+// or minify it. It returns a reader of the new content, which is closed once the copy is done if it is an io.Closer, or an error
+// to fail the copy of that file.
+type TransformFunc func(path string, r io.Reader) (io.Reader, error)
+
+// WithTransform passes the content of each file through transform as it is copied, so the destination is written in a single
+// pass. Since the size of the new content isn't known ahead of time, progress is reported with a total of -1, and WithVerify
+// checks the destination against the transformed content rather than the source.
+func WithTransform(transform TransformFunc) Option {
+	return func(options *operationOptions) {
+		options.transform = transform
+	}
+}
+
+// transformSource passes the content of sourceFile through the TransformFunc of the operation, if it has one, returning the
+// content to copy and its size, or -1 if it isn't known
+func (options *operationOptions) transformSource(sourceFile string, content io.Reader, size int64) (io.Reader, int64, error) {
+	if options.transform == nil {
+		return content, size, nil
+	}
+
+	transformed, transformErr := options.transform(sourceFile, content)
+
+	if transformErr != nil { // If the file couldn't be transformed
+		return nil, 0, fmt.Errorf("Failed to transform %s: %w", sourceFile, transformErr)
+	}
+
+	return transformed, -1, nil
+}
@@ -0,0 +1,131 @@
+package coreutils
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	trashLock      sync.RWMutex
+	trashDirectory string // Trash set by SetTrashDirectory, used instead of the platform's
+)
+
+// SetTrashDirectory replaces the trash MoveToTrash moves paths into on every platform, including Linux, where the XDG trash
+// is used otherwise. An empty path restores the default, which outside of Linux is .trash in the user's home directory.
+func SetTrashDirectory(path string) {
+	trashLock.Lock()
+	trashDirectory = path
+	trashLock.Unlock()
+}
+
+// MoveToTrash will move the file or directory at path into the trash rather than removing it, so it can be recovered. On Linux this
+// follows the XDG Trash specification, so the desktop's file manager can list and restore it, using the trash of the user's home
+// or of the mount holding path. Elsewhere it uses the same layout in .trash in the user's home directory, or the trash set by
+// SetTrashDirectory, which must be on the same filesystem as path.
+func MoveToTrash(path string) error {
+	start := time.Now()
+	trashErr := preHooks("MoveToTrash", path, "", 0)
+
+	if trashErr == nil {
+		trashErr = moveToTrash(path)
+	}
+
+	trace(TraceEvent{Op: "MoveToTrash", Path: path, Duration: time.Since(start), Err: trashErr})
+	return trashErr
+}
+
+// moveToTrash moves path into the configured or platform trash
+func moveToTrash(path string) error {
+	absolutePath, absErr := filepath.Abs(path)
+
+	if absErr != nil {
+		return openError(path, absErr)
+	}
+
+	if _, statErr := os.Lstat(absolutePath); statErr != nil { // If there's nothing to trash
+		return openError(path, statErr)
+	}
+
+	trashLock.RLock()
+	trash := trashDirectory
+	trashLock.RUnlock()
+
+	infoPath := absolutePath
+
+	if trash == "" { // If no trash was set, use the platform's
+		var locateErr error
+
+		if trash, infoPath, locateErr = trashLocation(absolutePath); locateErr != nil {
+			return locateErr
+		}
+	}
+
+	return trashInto(trash, absolutePath, infoPath)
+}
+
+// defaultTrashDirectory returns .trash in the user's home directory
+func defaultTrashDirectory() (string, error) {
+	homeDirectory, homeErr := os.UserHomeDir()
+
+	if homeErr != nil {
+		return "", homeErr
+	}
+
+	return filepath.Join(homeDirectory, ".trash"), nil
+}
+
+// trashInto moves path into the files directory of the trash, recording infoPath, which is path as the trash's info file should
+// name it, along with the deletion date in its info directory. The info file is created first to reserve a unique name.
+func trashInto(trash, path, infoPath string) error {
+	if policyErr := checkTreePolicy(path); policyErr != nil { // The rename takes everything within path with it
+		return policyErr
+	}
+
+	filesDirectory := filepath.Join(trash, "files")
+	infoDirectory := filepath.Join(trash, "info")
+
+	for _, directory := range []string{filesDirectory, infoDirectory} {
+		if mkdirErr := (OSFilesystem{}).MkdirAll(directory, 0700); mkdirErr != nil {
+			return writeError(directory, "Failed to create the trash directory "+directory, mkdirErr)
+		}
+	}
+
+	base := filepath.Base(path)
+	extension := filepath.Ext(base)
+	name := base
+
+	for attempt := 2; ; attempt++ {
+		infoFile := filepath.Join(infoDirectory, name+".trashinfo")
+		info, createErr := OSFilesystem{}.OpenFile(infoFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+
+		if os.IsExist(createErr) { // If something with this name is already in the trash
+			name = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(base, extension), attempt, extension)
+			continue
+		} else if createErr != nil {
+			return writeError(infoFile, "Failed to create the trash info file "+infoFile, createErr)
+		}
+
+		escapedPath := (&url.URL{Path: filepath.ToSlash(infoPath)}).EscapedPath()
+		_, writeErr := fmt.Fprintf(info, "[Trash Info]\nPath=%s\nDeletionDate=%s\n", escapedPath, GetClock().Now().Format("2006-01-02T15:04:05"))
+
+		if closeErr := info.Close(); writeErr == nil {
+			writeErr = closeErr
+		}
+
+		if writeErr == nil {
+			writeErr = OSFilesystem{}.Rename(path, filepath.Join(filesDirectory, name))
+		}
+
+		if writeErr != nil { // If we failed to trash it, release the name
+			os.Remove(infoFile)
+			return writeError(path, "Failed to move "+path+" to the trash", writeErr)
+		}
+
+		return nil
+	}
+}
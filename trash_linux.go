@@ -0,0 +1,73 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// trashLocation returns the XDG trash for path, and the path its info file records. Paths on the same filesystem as the
+// home trash go there, with an absolute info path. Others go in the trash at the top of their mount, with an info path
+// relative to it so it stays valid wherever the mount is, using $topdir/.Trash/$uid if an administrator created a
+// sticky .Trash and $topdir/.Trash-$uid otherwise.
+func trashLocation(path string) (trash, infoPath string, err error) {
+	homeTrash := filepath.Join(xdgDataDirectory(ScopeUser), "Trash")
+	pathDevice, deviceErr := fileDevice(path)
+
+	if deviceErr != nil {
+		return "", "", openError(path, deviceErr)
+	}
+
+	if homeDevice, homeErr := fileDevice(nearestExisting(homeTrash)); homeErr != nil || homeDevice == pathDevice { // If the home trash can hold it, or we can't tell
+		return homeTrash, path, nil
+	}
+
+	topDirectory := filepath.Dir(path)
+
+	for parent := filepath.Dir(topDirectory); parent != topDirectory; parent = filepath.Dir(topDirectory) { // Climb to the top of the mount
+		if parentDevice, parentErr := fileDevice(parent); parentErr != nil || parentDevice != pathDevice {
+			break
+		}
+
+		topDirectory = parent
+	}
+
+	uid := strconv.Itoa(os.Getuid())
+	trash = filepath.Join(topDirectory, ".Trash-"+uid)
+
+	if info, statErr := os.Lstat(filepath.Join(topDirectory, ".Trash")); statErr == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 { // If there's a shared trash, which must not be a symlink
+		trash = filepath.Join(topDirectory, ".Trash", uid)
+	}
+
+	infoPath, err = filepath.Rel(topDirectory, path)
+	return trash, infoPath, err
+}
+
+// fileDevice returns the device holding path, without following a symlink at path
+func fileDevice(path string) (uint64, error) {
+	info, statErr := os.Lstat(path)
+
+	if statErr != nil {
+		return 0, statErr
+	}
+
+	return uint64(info.Sys().(*syscall.Stat_t).Dev), nil
+}
+
+// nearestExisting returns path, or its nearest parent which exists
+func nearestExisting(path string) string {
+	for {
+		if _, statErr := os.Lstat(path); statErr == nil {
+			return path
+		}
+
+		parent := filepath.Dir(path)
+
+		if parent == path {
+			return path
+		}
+
+		path = parent
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package coreutils
+
+// trashLocation returns the trash for path, .trash in the user's home directory, and the path its info file records
+func trashLocation(path string) (trash, infoPath string, err error) {
+	trash, err = defaultTrashDirectory()
+	return trash, path, err
+}
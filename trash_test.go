@@ -0,0 +1,63 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMoveToTrashHooksAndAudit(t *testing.T) {
+	root := t.TempDir()
+	auditLog := filepath.Join(root, "audit.jsonl")
+	rejected := errors.New("rejected")
+
+	SetTrashDirectory(filepath.Join(root, "trash"))
+	defer SetTrashDirectory("")
+
+	defaults := GetDefaults()
+	defer SetDefaults(defaults)
+
+	audited := defaults
+	audited.AuditLog = auditLog
+	SetDefaults(audited)
+
+	removeHook := RegisterHook(OpDelete, PhasePre, func(event HookEvent) error {
+		if event.Name == "MoveToTrash" && filepath.Base(event.Path) == "keep.txt" {
+			return rejected
+		}
+
+		return nil
+	})
+
+	defer removeHook()
+
+	for _, name := range []string{"keep.txt", "trash.txt"} {
+		if writeErr := os.WriteFile(filepath.Join(root, name), nil, 0644); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+
+	if trashErr := MoveToTrash(filepath.Join(root, "keep.txt")); !errors.Is(trashErr, rejected) {
+		t.Errorf("expected the pre hook to reject trashing keep.txt, got %v", trashErr)
+	}
+
+	if _, statErr := os.Lstat(filepath.Join(root, "keep.txt")); statErr != nil {
+		t.Errorf("expected keep.txt to stay put, got %v", statErr)
+	}
+
+	if trashErr := MoveToTrash(filepath.Join(root, "trash.txt")); trashErr != nil {
+		t.Fatal(trashErr)
+	}
+
+	records, readErr := os.ReadFile(auditLog)
+
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(string(records)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"op":"MoveToTrash"`) || !strings.Contains(lines[1], `"result":"success"`) {
+		t.Errorf("expected the rejected and the successful MoveToTrash to be audited, got %s", records)
+	}
+}
@@ -0,0 +1,10 @@
+package coreutils
+
+import (
+	"os"
+)
+
+// EffectiveMode returns the mode a file or directory created with the requested mode will actually have, once the process umask is applied
+func EffectiveMode(requested os.FileMode) os.FileMode {
+	return requested &^ processUmask()
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package coreutils
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// umaskLock serializes reading the umask via syscall.Umask, which briefly changes it
+var umaskLock sync.Mutex
+
+// processUmask returns the umask of the process
+func processUmask() os.FileMode {
+	if status, openErr := os.Open("/proc/self/status"); openErr == nil { // Linux exposes the umask without needing to change it
+		defer status.Close()
+		scanner := bufio.NewScanner(status)
+
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "Umask:") {
+				if umask, parseErr := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Umask:")), 8, 32); parseErr == nil {
+					return os.FileMode(umask)
+				}
+			}
+		}
+	}
+
+	umaskLock.Lock()
+	umask := syscall.Umask(0) // Umask can only be read by setting it, so immediately restore it
+	syscall.Umask(umask)
+	umaskLock.Unlock()
+
+	return os.FileMode(umask)
+}
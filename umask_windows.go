@@ -0,0 +1,12 @@
+//go:build windows
+
+package coreutils
+
+import (
+	"os"
+)
+
+// processUmask returns the umask of the process, which Windows does not have
+func processUmask() os.FileMode {
+	return 0
+}
@@ -0,0 +1,79 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ValidateFunc checks a file after it has been copied from src to dst, such as by parsing it or checking its header,
+// returning why the copy is invalid
+type ValidateFunc func(src, dst string) error
+
+// invalidCopies collects the errors of the copies a ValidateFunc rejected
+type invalidCopies struct {
+	lock sync.Mutex
+	errs []error
+}
+
+// WithValidate calls validate on each file once it has been copied. The copy continues past files which fail validation, and
+// their errors are joined into the error returned once it finishes. Invalid files are kept unless WithValidateRollback is set.
+func WithValidate(validate ValidateFunc) Option {
+	return func(options *operationOptions) {
+		options.validate = validate
+
+		if options.invalid == nil {
+			options.invalid = &invalidCopies{}
+		}
+	}
+}
+
+// WithValidateRollback sets whether files which fail WithValidate are removed from the destination, counting them in
+// Stats.FilesFailed rather than Stats.FilesCopied
+func WithValidateRollback(rollback bool) Option {
+	return func(options *operationOptions) {
+		options.rollbackInvalid = rollback
+	}
+}
+
+// validateCopy runs the ValidateFunc of the operation on the copy of sourceFile at destinationFile, recording the error and
+// rolling the copy back if requested when it fails. Returns whether the copy was kept.
+func (options *operationOptions) validateCopy(sourceFile, destinationFile string) bool {
+	if options.validate == nil {
+		return true
+	}
+
+	validateErr := options.validate(sourceFile, destinationFile)
+
+	if validateErr == nil {
+		return true
+	}
+
+	options.invalid.lock.Lock()
+	options.invalid.errs = append(options.invalid.errs, fmt.Errorf("%s: %w", destinationFile, validateErr))
+	options.invalid.lock.Unlock()
+
+	if options.rollbackInvalid { // If invalid files shouldn't be left at the destination
+		os.Remove(destinationFile)
+		return false
+	}
+
+	return true
+}
+
+// validationError joins the errors of the copies which failed validation onto operationErr
+func (options *operationOptions) validationError(operationErr error) error {
+	if options.invalid == nil {
+		return operationErr
+	}
+
+	options.invalid.lock.Lock()
+	defer options.invalid.lock.Unlock()
+
+	if len(options.invalid.errs) == 0 {
+		return operationErr
+	}
+
+	return errors.Join(append([]error{operationErr}, options.invalid.errs...)...)
+}
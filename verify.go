@@ -0,0 +1,39 @@
+package coreutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// verifyAlgo is the algorithm copies are verified with
+const verifyAlgo = HashSHA256
+
+// ErrVerifyFailed is returned when a copy made with WithVerify doesn't have the same content as its source
+var ErrVerifyFailed = errors.New("copy does not match its source")
+
+// WithVerify sets whether copies hash the source as it is read, then read the destination back and compare its hash, so
+// corruption by flaky network mounts or removable media is caught. A copy which doesn't match is removed, and the error
+// wraps ErrVerifyFailed. Off by default, since every file is read twice.
+func WithVerify(verify bool) Option {
+	return func(options *operationOptions) {
+		options.verify = verify
+	}
+}
+
+// verifyCopy hashes destinationFile and compares it against sourceDigest, removing the destination if they differ. The
+// caller must hold a slot from the open file budget for the destination.
+func verifyCopy(destinationFile, sourceDigest string) error {
+	destinationDigest, hashErr := hashFile(destinationFile, verifyAlgo)
+
+	if hashErr != nil { // If we couldn't read the copy back
+		return hashErr
+	}
+
+	if destinationDigest != sourceDigest { // If the copy was corrupted on the way
+		os.Remove(destinationFile)
+		return fmt.Errorf("%s: %w", destinationFile, ErrVerifyFailed)
+	}
+
+	return nil
+}
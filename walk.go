@@ -0,0 +1,107 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WalkFiles will call fn with each file, directory, and symlink within path, without building a list of the whole tree as GetFiles
+// does. The FileInfo describes a symlink itself rather than its target. Returning filepath.SkipDir for a directory skips its contents,
+// and filepath.SkipAll stops the walk without an error. Any other error from fn stops the walk and is returned.
+// Honors WithExclude, WithDeterministic, and the WithMaxDepth, WithMaxEntries, and WithMaxPathLength limits.
+func WalkFiles(path string, fn func(path string, info os.FileInfo) error, opts ...Option) error {
+	if !IsDir(path) { // If this isn't a directory
+		return notDirectoryError(path, nil)
+	}
+
+	walkErr := walkTree(path, newOperationOptions(opts), func(filePath, relativePath string, info os.FileInfo) error {
+		if relativePath == "." { // Don't pass the root itself
+			return nil
+		}
+
+		return fn(filePath, info)
+	})
+
+	if walkErr == filepath.SkipAll { // If fn finished early
+		return nil
+	}
+
+	return walkErr
+}
+
+// walkFunc is called by walkTree for each entry, with its path and the entry's FileInfo, which describes a symlink itself
+// rather than its target. Returning filepath.SkipDir for a directory skips its contents.
+type walkFunc func(path, relativePath string, info os.FileInfo) error
+
+// walkTree calls fn for path and every entry beneath it, never following symlinks.
+// Each directory is closed before fn is called for its entries, so fn may open files. Honors the exclude patterns and
+// limits of the options. Entries whose info can't be read are skipped.
+func walkTree(path string, options *operationOptions, fn walkFunc) error {
+	rootInfo, statErr := os.Lstat(path)
+
+	if statErr != nil { // If the path doesn't exist
+		return openError(path, statErr)
+	}
+
+	if walkErr := fn(path, ".", rootInfo); walkErr != nil || !rootInfo.IsDir() {
+		if walkErr == filepath.SkipDir {
+			return nil
+		}
+
+		return walkErr
+	}
+
+	return walkTreeDirectory(path, "", options, fn)
+}
+
+// walkTreeDirectory calls fn for each entry of the directory at path, which is relativePath within the root, recursing into sub-directories
+func walkTreeDirectory(path, relativePath string, options *operationOptions, fn walkFunc) error {
+	var entries []os.DirEntry
+
+	readErr := readDirectory(path, func(entry os.DirEntry) error {
+		entryRelativePath := filepath.Join(relativePath, entry.Name())
+
+		if options.excluded(entryRelativePath) { // If this should be skipped
+			return nil
+		}
+
+		if limitErr := options.checkLimits(filepath.Join(path, entry.Name()), entryRelativePath, entry.IsDir()); limitErr != nil {
+			return limitErr
+		}
+
+		entries = append(entries, entry)
+		return nil
+	})
+
+	if readErr != nil {
+		return readErr
+	}
+
+	options.orderEntries(entries)
+
+	var subDirectories []string
+
+	for _, entry := range entries {
+		info, infoErr := entry.Info()
+
+		if infoErr != nil { // If the entry was removed since we read the directory
+			continue
+		}
+
+		walkErr := fn(filepath.Join(path, entry.Name()), filepath.Join(relativePath, entry.Name()), info)
+
+		if walkErr == nil && entry.IsDir() { // Walk the sub-directory once we're done with this one
+			subDirectories = append(subDirectories, entry.Name())
+		} else if walkErr != nil && walkErr != filepath.SkipDir {
+			return walkErr
+		}
+	}
+
+	for _, name := range subDirectories {
+		if walkErr := walkTreeDirectory(filepath.Join(path, name), filepath.Join(relativePath, name), options, fn); walkErr != nil {
+			return walkErr
+		}
+	}
+
+	return nil
+}
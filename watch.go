@@ -0,0 +1,212 @@
+package coreutils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileOp is the kind of change a FileEvent reports
+type FileOp int
+
+const (
+	// FileCreated is reported when a file or directory is created, or moved in from outside the watched tree
+	FileCreated FileOp = iota
+
+	// FileModified is reported when the contents or attributes of a file change
+	FileModified
+
+	// FileRemoved is reported when a file or directory is removed, or moved out of the watched tree
+	FileRemoved
+
+	// FileRenamed is reported when a file or directory is moved within the watched tree
+	FileRenamed
+)
+
+// FileEvent describes a change to a file or directory within a watched tree
+type FileEvent struct {
+	Path    string // Path of the file or directory which changed
+	Op      FileOp // Kind of change
+	OldPath string // Previous path of a renamed file or directory
+}
+
+// watchPollInterval is how often a polling watch rescans the tree
+const watchPollInterval = time.Second
+
+// watchBuffer is the number of events a watch holds before it waits for them to be received
+const watchBuffer = 64
+
+// String returns the name of the FileOp
+func (op FileOp) String() string {
+	switch op {
+	case FileCreated:
+		return "created"
+	case FileModified:
+		return "modified"
+	case FileRemoved:
+		return "removed"
+	case FileRenamed:
+		return "renamed"
+	default:
+		return "unknown"
+	}
+}
+
+// WatchDirectory will watch path for files and directories being created, modified, removed, and renamed, sending a FileEvent for each
+// on the returned channel. If recursive is true, everything beneath path is watched, including directories created later. Otherwise
+// only the entries directly within path are. The returned stop function ends the watch and closes the channel, which is also closed if
+// path is removed. Events should be received promptly, as the watch waits for them to be received before reading more changes.
+// Linux is watched using inotify, falling back to polling if inotify is unavailable or out of watches, and other platforms poll every
+// second, reporting a rename where the platform can tell a moved file is the same file.
+func WatchDirectory(path string, recursive bool) (<-chan FileEvent, func(), error) {
+	if !IsDir(path) { // If this isn't a directory
+		return nil, nil, notDirectoryError(path, nil)
+	}
+
+	return watchDirectory(filepath.Clean(path), recursive)
+}
+
+// watchStop is closed when a watch is stopped
+type watchStop struct {
+	done chan struct{}
+	once sync.Once
+}
+
+// newWatchStop returns a watchStop which hasn't been stopped
+func newWatchStop() *watchStop {
+	return &watchStop{done: make(chan struct{})}
+}
+
+// stop closes done, once
+func (stop *watchStop) stop() {
+	stop.once.Do(func() {
+		close(stop.done)
+	})
+}
+
+// send sends the event, returning false instead if the watch was stopped
+func (stop *watchStop) send(events chan<- FileEvent, event FileEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-stop.done:
+		return false
+	}
+}
+
+// pollDirectory watches path by scanning it every watchPollInterval and comparing the scans
+func pollDirectory(path string, recursive bool) (<-chan FileEvent, func(), error) {
+	events := make(chan FileEvent, watchBuffer)
+	stop := newWatchStop()
+	entries := pollSnapshot(path, recursive)
+	ticker := GetClock().NewTicker(watchPollInterval)
+
+	go func() {
+		defer close(events)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop.done:
+				return
+			case <-ticker.C():
+			}
+
+			if !IsDir(path) { // If the watched directory is gone
+				for _, event := range pollChanges(entries, nil) {
+					if !stop.send(events, event) {
+						return
+					}
+				}
+
+				return
+			}
+
+			current := pollSnapshot(path, recursive)
+
+			for _, event := range pollChanges(entries, current) {
+				if !stop.send(events, event) {
+					return
+				}
+			}
+
+			entries = current
+		}
+	}()
+
+	return events, stop.stop, nil
+}
+
+// pollSnapshot returns the FileInfo of each entry within path, or only those directly within it if recursive is false
+func pollSnapshot(path string, recursive bool) map[string]os.FileInfo {
+	entries := make(map[string]os.FileInfo)
+
+	walkTree(path, newOperationOptions(nil), func(entryPath, relativePath string, info os.FileInfo) error {
+		if relativePath == "." { // The root itself isn't reported
+			return nil
+		}
+
+		entries[entryPath] = info
+
+		if info.IsDir() && !recursive {
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	return entries
+}
+
+// pollChanges compares two snapshots, returning the events which turn previous into current sorted by path.
+// A removed entry which is the same file as a created one is reported as renamed.
+func pollChanges(previous, current map[string]os.FileInfo) []FileEvent {
+	var created, removed []string
+	var changes []FileEvent
+
+	for path, info := range current {
+		if previousInfo, existed := previous[path]; !existed {
+			created = append(created, path)
+		} else if !info.IsDir() && (!info.ModTime().Equal(previousInfo.ModTime()) || info.Size() != previousInfo.Size() || info.Mode() != previousInfo.Mode()) {
+			changes = append(changes, FileEvent{Path: path, Op: FileModified})
+		}
+	}
+
+	for path := range previous {
+		if _, exists := current[path]; !exists {
+			removed = append(removed, path)
+		}
+	}
+
+	sort.Strings(created)
+	sort.Strings(removed)
+
+	for _, removedPath := range removed {
+		renamed := false
+
+		for index, createdPath := range created {
+			if os.SameFile(previous[removedPath], current[createdPath]) { // If this file was moved
+				changes = append(changes, FileEvent{Path: createdPath, Op: FileRenamed, OldPath: removedPath})
+				created = append(created[:index], created[index+1:]...)
+				renamed = true
+				break
+			}
+		}
+
+		if !renamed {
+			changes = append(changes, FileEvent{Path: removedPath, Op: FileRemoved})
+		}
+	}
+
+	for _, createdPath := range created {
+		changes = append(changes, FileEvent{Path: createdPath, Op: FileCreated})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
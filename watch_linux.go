@@ -0,0 +1,222 @@
+package coreutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// inotifyMask is the set of inotify events watched for on each directory
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+
+// inotifyWatcher turns the events of an inotify instance into FileEvents
+type inotifyWatcher struct {
+	fd        int
+	file      *os.File
+	recursive bool
+	watches   map[int32]string       // Watched directories by their watch descriptor
+	moves     map[uint32]inotifyMove // Entries moved away in the current read, by their move cookie
+	events    chan FileEvent
+	stop      *watchStop
+}
+
+// inotifyMove is an entry which was moved away, waiting to be paired with where it was moved to
+type inotifyMove struct {
+	path  string
+	isDir bool
+}
+
+// watchDirectory watches path with inotify, polling instead if inotify can't be used
+func watchDirectory(path string, recursive bool) (<-chan FileEvent, func(), error) {
+	fd, initErr := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+
+	if initErr != nil { // If inotify is unavailable or we've reached the limit of instances
+		return pollDirectory(path, recursive)
+	}
+
+	watcher := &inotifyWatcher{
+		fd:        fd,
+		file:      os.NewFile(uintptr(fd), "inotify"), // Non-blocking, so reads go through the runtime poller and can be interrupted
+		recursive: recursive,
+		watches:   make(map[int32]string),
+		moves:     make(map[uint32]inotifyMove),
+		events:    make(chan FileEvent, watchBuffer),
+		stop:      newWatchStop(),
+	}
+
+	if addErr := watcher.addTree(path, false); addErr != nil {
+		watcher.file.Close()
+
+		if errors.Is(addErr, syscall.ENOSPC) { // If we're out of inotify watches
+			return pollDirectory(path, recursive)
+		}
+
+		return nil, nil, openError(path, addErr)
+	}
+
+	go watcher.run()
+
+	return watcher.events, func() {
+		watcher.stop.stop()
+		watcher.file.SetReadDeadline(time.Now()) // Wake the pending read
+	}, nil
+}
+
+// addTree watches the directory at path, and every directory beneath it if the watch is recursive.
+// If announce is true, a FileCreated event is sent for each entry found beneath path.
+func (watcher *inotifyWatcher) addTree(path string, announce bool) error {
+	if !watcher.recursive {
+		return watcher.addWatch(path)
+	}
+
+	return walkTree(path, newOperationOptions(nil), func(entryPath, relativePath string, info os.FileInfo) error {
+		if relativePath != "." && announce && !watcher.stop.send(watcher.events, FileEvent{Path: entryPath, Op: FileCreated}) {
+			return filepath.SkipAll
+		}
+
+		if info.IsDir() {
+			return watcher.addWatch(entryPath)
+		}
+
+		return nil
+	})
+}
+
+// addWatch watches the directory at path
+func (watcher *inotifyWatcher) addWatch(path string) error {
+	wd, addErr := syscall.InotifyAddWatch(watcher.fd, path, inotifyMask)
+
+	if addErr == nil {
+		watcher.watches[int32(wd)] = path
+	}
+
+	return addErr
+}
+
+// run reads events until the watch is stopped or every watched directory is gone, then closes the events channel
+func (watcher *inotifyWatcher) run() {
+	defer close(watcher.events)
+	defer watcher.file.Close()
+
+	buffer := make([]byte, 64*1024)
+
+	for {
+		read, readErr := watcher.file.Read(buffer)
+
+		if readErr != nil { // If the watch was stopped
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= read; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			name := strings.TrimRight(string(buffer[nameStart:offset]), "\x00")
+
+			if !watcher.handle(event.Wd, event.Mask, event.Cookie, name) {
+				return
+			}
+		}
+
+		if !watcher.flushMoves() {
+			return
+		}
+	}
+}
+
+// handle sends the FileEvent for an inotify event, returning false if the watch is over
+func (watcher *inotifyWatcher) handle(wd int32, mask, cookie uint32, name string) bool {
+	if mask&syscall.IN_IGNORED != 0 { // If a watched directory is gone
+		delete(watcher.watches, wd)
+		return len(watcher.watches) != 0
+	}
+
+	directory, watched := watcher.watches[wd]
+
+	if !watched || name == "" { // Queue overflows have no watch, and changes to a directory itself are reported by its parent
+		return true
+	}
+
+	path := filepath.Join(directory, name)
+	isDir := mask&syscall.IN_ISDIR != 0
+
+	switch {
+	case mask&syscall.IN_CREATE != 0:
+		return watcher.created(path, isDir)
+	case mask&syscall.IN_MOVED_FROM != 0:
+		watcher.moves[cookie] = inotifyMove{path: path, isDir: isDir}
+	case mask&syscall.IN_MOVED_TO != 0:
+		move, paired := watcher.moves[cookie]
+
+		if !paired { // If this was moved in from outside the tree
+			return watcher.created(path, isDir)
+		}
+
+		delete(watcher.moves, cookie)
+
+		if isDir {
+			watcher.renameWatches(move.path, path)
+		}
+
+		return watcher.stop.send(watcher.events, FileEvent{Path: path, Op: FileRenamed, OldPath: move.path})
+	case mask&syscall.IN_DELETE != 0:
+		return watcher.stop.send(watcher.events, FileEvent{Path: path, Op: FileRemoved})
+	case !isDir: // Modified contents or attributes
+		return watcher.stop.send(watcher.events, FileEvent{Path: path, Op: FileModified})
+	}
+
+	return true
+}
+
+// created sends the FileEvent for a new entry, watching it and announcing its contents if it's a directory in a recursive watch,
+// as they may have been created before the watch was added
+func (watcher *inotifyWatcher) created(path string, isDir bool) bool {
+	if !watcher.stop.send(watcher.events, FileEvent{Path: path, Op: FileCreated}) {
+		return false
+	}
+
+	if isDir && watcher.recursive {
+		watcher.addTree(path, true) // The directory may already be gone again
+	}
+
+	select {
+	case <-watcher.stop.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// flushMoves reports entries moved away without being moved to somewhere in the tree as removed
+func (watcher *inotifyWatcher) flushMoves() bool {
+	for cookie, move := range watcher.moves {
+		delete(watcher.moves, cookie)
+
+		if move.isDir { // Stop watching it wherever it went
+			for wd, watchedPath := range watcher.watches {
+				if watchedPath == move.path || strings.HasPrefix(watchedPath, move.path+string(filepath.Separator)) {
+					syscall.InotifyRmWatch(watcher.fd, uint32(wd))
+				}
+			}
+		}
+
+		if !watcher.stop.send(watcher.events, FileEvent{Path: move.path, Op: FileRemoved}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// renameWatches updates the paths of the watched directories at and beneath oldPath to be beneath newPath
+func (watcher *inotifyWatcher) renameWatches(oldPath, newPath string) {
+	for wd, watchedPath := range watcher.watches {
+		if watchedPath == oldPath || strings.HasPrefix(watchedPath, oldPath+string(filepath.Separator)) {
+			watcher.watches[wd] = newPath + watchedPath[len(oldPath):]
+		}
+	}
+}
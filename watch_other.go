@@ -0,0 +1,8 @@
+//go:build !linux
+
+package coreutils
+
+// watchDirectory watches path by polling
+func watchDirectory(path string, recursive bool) (<-chan FileEvent, func(), error) {
+	return pollDirectory(path, recursive)
+}